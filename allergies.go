@@ -0,0 +1,363 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Проверка назначений по аллергиям пациента. Действующие записи анамнеза с типом
+// allergy сверяются с назначенным препаратом: по совпадению названия или по общей
+// фармакологической группе из таблицы drug_classes ("Аллергия на пенициллин" и
+// амоксициллин - группа пенициллинов). Названия сравниваются по началу слова в
+// нормализованном тексте, поэтому "пенициллин" находит и "пенициллины", и "пенициллину".
+// При совпадении назначение сохраняется с предупреждением, а при тяжелой аллергии
+// (severity severe) - только с подтверждением врача и причиной. Предупреждения
+// возвращаются в ответе и записываются в журнал аудита.
+
+// historyTypeAllergy - тип записи анамнеза об аллергии
+const historyTypeAllergy = "allergy"
+
+// severityBlocking - тяжесть аллергии, при которой назначение требует подтверждения
+const severityBlocking = "severe"
+
+// inactiveHistoryStatuses - статусы записей анамнеза, которые больше не действуют
+var inactiveHistoryStatuses = []string{"resolved", "inactive"}
+
+// errAllergyOverrideReason возвращается при подтверждении назначения без причины
+var errAllergyOverrideReason = errors.New("override_reason is required to override an allergy contraindication")
+
+// DrugClass относит препарат к фармакологической группе
+// @Description Препарат и его фармакологическая группа
+type DrugClass struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Drug      string    `gorm:"not null;uniqueIndex:idx_drug_classes_drug_class" json:"drug"`        // название препарата в нормализованном виде
+	Class     string    `gorm:"not null;uniqueIndex:idx_drug_classes_drug_class;index" json:"class"` // группа в нормализованном виде
+}
+
+type CreateDrugClassRequest struct {
+	Drug  string `json:"drug" binding:"required"`
+	Class string `json:"class" binding:"required"`
+}
+
+// AllergyWarning - совпадение назначенного препарата с аллергией пациента
+type AllergyWarning struct {
+	HistoryID uint   `json:"history_id"` // запись анамнеза об аллергии
+	Allergy   string `json:"allergy"`    // описание аллергии
+	Severity  string `json:"severity"`
+	Drug      string `json:"drug"`            // назначенный препарат
+	Class     string `json:"class,omitempty"` // общая группа; пусто при совпадении по названию
+	Blocking  bool   `json:"blocking"`        // тяжелая аллергия: назначение требует подтверждения
+}
+
+// AllergyConflictResponse представляет ответ о назначении, противопоказанном при тяжелой аллергии
+type AllergyConflictResponse struct {
+	Error           string           `json:"error"`
+	AllergyWarnings []AllergyWarning `json:"allergy_warnings"`
+}
+
+// allergyBlockError возвращается, если назначение противопоказано при тяжелой аллергии и не подтверждено
+type allergyBlockError struct {
+	Warnings []AllergyWarning
+}
+
+func (e *allergyBlockError) Error() string {
+	return "drug is contraindicated by a severe allergy; set override_allergy and override_reason to proceed"
+}
+
+// allergyCheck - результат проверки назначения по аллергиям пациента
+type allergyCheck struct {
+	Warnings       []AllergyWarning `json:"warnings"`
+	OverrideReason string           `json:"override_reason,omitempty"` // причина назначения вопреки тяжелой аллергии
+}
+
+// containsWord проверяет, что нормализованный текст содержит слово или фразу word с начала слова
+func containsWord(text, word string) bool {
+	return word != "" && strings.Contains(" "+text, " "+word)
+}
+
+// drugClassesOf возвращает группы препарата и названия из таблицы, которые он содержит
+func drugClassesOf(drug string, classes []DrugClass) (groups map[string]bool, names []string) {
+	groups = make(map[string]bool)
+	for _, dc := range classes {
+		if containsWord(drug, dc.Drug) {
+			groups[dc.Class] = true
+			names = append(names, dc.Drug)
+		}
+	}
+	return groups, names
+}
+
+// drugsInText возвращает препараты из списка known, упомянутые в тексте лечения
+func drugsInText(text string, known []string) []string {
+	text = normalizeSearchText(text)
+	seen := make(map[string]bool)
+	var drugs []string
+	for _, drug := range known {
+		if !seen[drug] && containsWord(text, drug) {
+			seen[drug] = true
+			drugs = append(drugs, drug)
+		}
+	}
+	return drugs
+}
+
+// knownDrugs возвращает препараты, которые можно распознать в тексте лечения: из таблицы
+// групп и из базы взаимодействий. Названия групп, на которые ссылаются правила
+// взаимодействий ("нпвп"), препаратами не считаются.
+func knownDrugs(tx *gorm.DB) ([]string, error) {
+	var classes []DrugClass
+	if err := tx.Find(&classes).Error; err != nil {
+		return nil, err
+	}
+	var rules []DrugInteraction
+	if err := tx.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	groups := make(map[string]bool)
+	for _, dc := range classes {
+		groups[dc.Class] = true
+	}
+	drugs := make([]string, 0, len(classes)+2*len(rules))
+	for _, dc := range classes {
+		drugs = append(drugs, dc.Drug)
+	}
+	for _, r := range rules {
+		for _, term := range []string{r.DrugA, r.DrugB} {
+			if !groups[term] {
+				drugs = append(drugs, term)
+			}
+		}
+	}
+	return drugs, nil
+}
+
+// treatmentDrugs возвращает препараты, которые появились в тексте лечения after по сравнению с before
+func treatmentDrugs(tx *gorm.DB, before, after string) ([]string, error) {
+	known, err := knownDrugs(tx)
+	if err != nil {
+		return nil, err
+	}
+	old := make(map[string]bool)
+	for _, drug := range drugsInText(before, known) {
+		old[drug] = true
+	}
+	var added []string
+	for _, drug := range drugsInText(after, known) {
+		if !old[drug] {
+			added = append(added, drug)
+		}
+	}
+	return added, nil
+}
+
+// checkAllergies сверяет препараты drugs с действующими аллергиями пациента. Если среди
+// совпадений есть тяжелая аллергия, назначение без override возвращает *allergyBlockError.
+func checkAllergies(tx *gorm.DB, patientID uint, drugs []string, override bool, reason string) (allergyCheck, error) {
+	var check allergyCheck
+	reason = strings.TrimSpace(reason)
+	if override && reason == "" {
+		return check, errAllergyOverrideReason
+	}
+	if len(drugs) == 0 {
+		return check, nil
+	}
+
+	var allergies []MedicalHistory
+	err := tx.Where("patient_id = ? AND history_type = ? AND status NOT IN ?", patientID, historyTypeAllergy, inactiveHistoryStatuses).
+		Order("id").Find(&allergies).Error
+	if err != nil {
+		return check, err
+	}
+	if len(allergies) == 0 {
+		return check, nil
+	}
+	var classes []DrugClass
+	if err := tx.Find(&classes).Error; err != nil {
+		return check, err
+	}
+
+	blocked := false
+	for _, allergy := range allergies {
+		text := normalizeSearchText(allergy.Description)
+		allergens := make(map[string]bool)
+		for _, dc := range classes {
+			if containsWord(text, dc.Drug) || containsWord(text, dc.Class) {
+				allergens[dc.Class] = true
+			}
+		}
+
+		for _, drug := range drugs {
+			name := normalizeSearchText(drug)
+			groups, names := drugClassesOf(name, classes)
+			w := AllergyWarning{
+				HistoryID: allergy.ID,
+				Allergy:   allergy.Description,
+				Severity:  allergy.Severity,
+				Drug:      drug,
+				Blocking:  allergy.Severity == severityBlocking,
+			}
+
+			matched := containsWord(text, name)
+			for _, n := range names {
+				matched = matched || containsWord(text, n)
+			}
+			if !matched {
+				var shared []string
+				for group := range groups {
+					if allergens[group] {
+						shared = append(shared, group)
+					}
+				}
+				if len(shared) == 0 {
+					continue
+				}
+				sort.Strings(shared)
+				w.Class = shared[0]
+			}
+			check.Warnings = append(check.Warnings, w)
+			blocked = blocked || w.Blocking
+		}
+	}
+
+	if blocked {
+		if !override {
+			return check, &allergyBlockError{Warnings: check.Warnings}
+		}
+		check.OverrideReason = reason
+	}
+	return check, nil
+}
+
+// recordAllergyAlert записывает в журнал аудита предупреждения об аллергии и причину подтверждения
+func recordAllergyAlert(tx *gorm.DB, c *gin.Context, ref auditRef, check allergyCheck) error {
+	if len(check.Warnings) == 0 {
+		return nil
+	}
+	return recordAuditDetails(tx, c, auditActionAllergyAlert, ref, check)
+}
+
+// respondAllergyError отправляет ответ, соответствующий ошибке проверки аллергий
+func respondAllergyError(c *gin.Context, err error) {
+	var blocked *allergyBlockError
+	switch {
+	case errors.Is(err, errAllergyOverrideReason):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.As(err, &blocked):
+		c.JSON(http.StatusConflict, AllergyConflictResponse{Error: err.Error(), AllergyWarnings: blocked.Warnings})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+// Обработчики таблицы групп препаратов
+
+// GetDrugClasses godoc
+// @Summary Получить группы препаратов
+// @Description Получить таблицу соответствия препаратов фармакологическим группам, по которой назначения сверяются с аллергиями
+// @Tags drug-classes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param class query string false "Фильтр по группе"
+// @Success 200 {array} DrugClass
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /drug-classes [get]
+func getDrugClasses(c *gin.Context) {
+	query := db.Order("class, drug")
+	if class := c.Query("class"); class != "" {
+		query = query.Where("class = ?", normalizeSearchText(class))
+	}
+	var classes []DrugClass
+	if err := query.Find(&classes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, classes)
+}
+
+// CreateDrugClass godoc
+// @Summary Добавить препарат в группу
+// @Description Отнести препарат к фармакологической группе. Название и группа хранятся в нормализованном виде (нижний регистр, е вместо ё)
+// @Tags drug-classes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param drug_class body CreateDrugClassRequest true "Препарат и группа"
+// @Success 201 {object} DrugClass
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /drug-classes [post]
+func createDrugClass(c *gin.Context) {
+	var req CreateDrugClassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	dc := DrugClass{Drug: normalizeSearchText(req.Drug), Class: normalizeSearchText(req.Class)}
+	if dc.Drug == "" || dc.Class == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "drug and class must not be empty"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&DrugClass{}).Where("drug = ? AND class = ?", dc.Drug, dc.Class).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "drug is already in this class"})
+		return
+	}
+	if err := db.Create(&dc).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, dc)
+}
+
+// DeleteDrugClass godoc
+// @Summary Удалить препарат из группы
+// @Description Удалить запись таблицы групп препаратов
+// @Tags drug-classes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID записи"
+// @Success 200 {object} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /drug-classes/{id} [delete]
+func deleteDrugClass(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid drug class id"})
+		return
+	}
+	result := db.Delete(&DrugClass{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Drug class entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, "Drug class entry deleted")
+}
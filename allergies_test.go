@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestDrugClassWrites(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestUser(t, roleDoctor, createTestDoctor(t, "Иванов Иван", "Терапевт").ID)
+		count := func() int64 {
+			t.Helper()
+			var n int64
+			if err := db.Model(&DrugClass{}).Count(&n).Error; err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+		seeded := count()
+		if seeded == 0 {
+			t.Fatal("drug classes are not seeded")
+		}
+
+		// Таблица общая для клиники: врач ее читает, но не меняет
+		decodeResponse[[]DrugClass](t, apiRequest(t, doctor, http.MethodGet, "/drug-classes", nil), http.StatusOK)
+		if w := apiRequest(t, doctor, http.MethodPost, "/drug-classes", CreateDrugClassRequest{Drug: "Амоксициллин", Class: "Макролиды"}); w.Code != http.StatusForbidden {
+			t.Errorf("POST as doctor: status %d, want 403", w.Code)
+		}
+		if w := apiRequest(t, doctor, http.MethodDelete, "/drug-classes/1", nil); w.Code != http.StatusForbidden {
+			t.Errorf("DELETE as doctor: status %d, want 403", w.Code)
+		}
+
+		for _, id := range []string{"id>0", "1%20OR%201=1", "-1"} {
+			if w := apiRequest(t, admin, http.MethodDelete, "/drug-classes/"+id, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("DELETE /drug-classes/%s: status %d, want 400", id, w.Code)
+			}
+		}
+		if w := apiRequest(t, admin, http.MethodDelete, "/drug-classes/999999", nil); w.Code != http.StatusNotFound {
+			t.Errorf("DELETE missing entry: status %d, want 404", w.Code)
+		}
+		if got := count(); got != seeded {
+			t.Fatalf("%d drug classes left, want %d", got, seeded)
+		}
+
+		created := decodeResponse[DrugClass](t, apiRequest(t, admin, http.MethodPost, "/drug-classes",
+			CreateDrugClassRequest{Drug: "Флемоксин Солютаб", Class: "Пенициллины"}), http.StatusCreated)
+		if created.Drug != "флемоксин солютаб" || created.Class != "пенициллины" {
+			t.Errorf("created = %+v, want normalized names", created)
+		}
+		if w := apiRequest(t, admin, http.MethodDelete, fmt.Sprintf("/drug-classes/%d", created.ID), nil); w.Code != http.StatusOK {
+			t.Errorf("DELETE as admin: status %d, want 200", w.Code)
+		}
+		if got := count(); got != seeded {
+			t.Errorf("%d drug classes left, want %d", got, seeded)
+		}
+	})
+}
+
+// createTestHistory добавляет пациенту запись анамнеза
+func createTestHistory(t *testing.T, patientID uint, historyType, description, severity, status string) MedicalHistory {
+	t.Helper()
+	history := MedicalHistory{PatientID: patientID, HistoryType: historyType, Description: description, Severity: severity, Status: status}
+	if err := db.Create(&history).Error; err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func TestCheckAllergies(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		penicillin := createTestHistory(t, patient.ID, historyTypeAllergy, "Аллергия на пенициллин", "moderate", "active")
+		iodine := createTestHistory(t, patient.ID, historyTypeAllergy, "Непереносимость йодсодержащего контраста", "severe", "active")
+		createTestHistory(t, patient.ID, historyTypeAllergy, "Аллергия на ибупрофен", "severe", "resolved")
+		createTestHistory(t, patient.ID, historyTypeAllergy, "Аллергия на кодеин", "severe", "inactive")
+		createTestHistory(t, patient.ID, "chronic", "Гастрит после приема диклофенака", "severe", "active")
+
+		type warning struct {
+			history  uint
+			class    string
+			blocking bool
+		}
+		tests := []struct {
+			name string
+			drug string
+			want []warning
+		}{
+			{"drug class", "Амоксициллин 500 мг", []warning{{penicillin.ID, "пенициллины", false}}},
+			{"drug class, brand name", "Флемоксин Солютаб", []warning{{penicillin.ID, "пенициллины", false}}},
+			{"drug name", "Пенициллин", []warning{{penicillin.ID, "", false}}},
+			{"severe allergy by class", "Йогексол", []warning{{iodine.ID, "препараты йода", true}}},
+			{"another class", "Цефтриаксон", nil},
+			{"resolved allergy", "Ибупрофен", nil},
+			{"inactive allergy", "Кодеин", nil},
+			{"not an allergy", "Диклофенак", nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				check, err := checkAllergies(db, patient.ID, []string{tt.drug}, true, "Согласовано с аллергологом")
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []warning
+				for _, w := range check.Warnings {
+					got = append(got, warning{w.HistoryID, w.Class, w.Blocking})
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("warnings = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestPrescriptionAllergyCheck(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		allergy := createTestHistory(t, patient.ID, historyTypeAllergy, "Аллергия на пенициллин: отек Квинке", severityBlocking, "active")
+		visit := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Status: statusInProgress})
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		path := fmt.Sprintf("/appointments/%d/prescriptions", visit.ID)
+		request := func(override bool, reason string) CreatePrescriptionRequest {
+			return CreatePrescriptionRequest{Drug: "Амоксициллин", Dose: 500, DoseUnit: "мг", Route: "oral",
+				Frequency: "3 раза в день", DurationDays: 7, OverrideAllergy: override, OverrideReason: reason}
+		}
+		prescriptions := func() int64 {
+			t.Helper()
+			var n int64
+			if err := db.Model(&Prescription{}).Count(&n).Error; err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+
+		// Тяжелая аллергия на группу препарата: назначение без подтверждения отклоняется
+		conflict := decodeResponse[AllergyConflictResponse](t, apiRequest(t, user, http.MethodPost, path, request(false, "")), http.StatusConflict)
+		if len(conflict.AllergyWarnings) != 1 || conflict.AllergyWarnings[0].HistoryID != allergy.ID ||
+			conflict.AllergyWarnings[0].Class != "пенициллины" || !conflict.AllergyWarnings[0].Blocking {
+			t.Errorf("allergy_warnings = %+v", conflict.AllergyWarnings)
+		}
+		for _, reason := range []string{"", "   "} {
+			if w := apiRequest(t, user, http.MethodPost, path, request(true, reason)); w.Code != http.StatusBadRequest {
+				t.Errorf("override with reason %q: status %d, want 400", reason, w.Code)
+			}
+		}
+		if n := prescriptions(); n != 0 {
+			t.Fatalf("%d prescriptions saved without a confirmed override", n)
+		}
+
+		// Подтверждение с причиной: назначение сохраняется, предупреждение и причина - в журнале
+		created := decodeResponse[Prescription](t, apiRequest(t, user, http.MethodPost, path,
+			request(true, "Нет альтернативы, премедикация")), http.StatusCreated)
+		if len(created.AllergyWarnings) != 1 || !created.AllergyWarnings[0].Blocking {
+			t.Errorf("allergy_warnings = %+v", created.AllergyWarnings)
+		}
+		var alert AuditLog
+		if err := db.Where("action = ?", auditActionAllergyAlert).First(&alert).Error; err != nil {
+			t.Fatal(err)
+		}
+		if alert.EntityType != auditEntityPrescription || alert.EntityID != created.ID || alert.UserID != user.ID {
+			t.Errorf("allergy alert = %+v, want prescription %d by user %d", alert, created.ID, user.ID)
+		}
+		var details allergyCheck
+		if err := json.Unmarshal(alert.Changes, &details); err != nil {
+			t.Fatal(err)
+		}
+		if details.OverrideReason != "Нет альтернативы, премедикация" || len(details.Warnings) != 1 || details.Warnings[0].HistoryID != allergy.ID {
+			t.Errorf("allergy alert details = %+v", details)
+		}
+
+		// Лечение в приеме проверяется так же
+		w := apiRequest(t, user, http.MethodPut, fmt.Sprintf("/appointments/%d", visit.ID), CreateAppointmentRequest{
+			PatientID: patient.ID, DoctorID: doctor.ID, Date: visit.Date, Treatment: "Ампициллин 1 г в/м",
+		})
+		if conflict := decodeResponse[AllergyConflictResponse](t, w, http.StatusConflict); len(conflict.AllergyWarnings) != 1 {
+			t.Errorf("treatment allergy_warnings = %+v", conflict.AllergyWarnings)
+		}
+
+		// Нетяжелая аллергия не блокирует назначение, но попадает в ответ и журнал
+		if err := db.Model(&allergy).Update("severity", "mild").Error; err != nil {
+			t.Fatal(err)
+		}
+		created = decodeResponse[Prescription](t, apiRequest(t, user, http.MethodPost, path, request(false, "")), http.StatusCreated)
+		if len(created.AllergyWarnings) != 1 || created.AllergyWarnings[0].Blocking {
+			t.Errorf("allergy_warnings = %+v, want one non-blocking", created.AllergyWarnings)
+		}
+		var alerts int64
+		if err := db.Model(&AuditLog{}).Where("action = ?", auditActionAllergyAlert).Count(&alerts).Error; err != nil {
+			t.Fatal(err)
+		}
+		if alerts != 2 {
+			t.Errorf("%d allergy alerts in the audit log, want 2", alerts)
+		}
+	})
+}
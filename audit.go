@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Действия, записываемые в журнал аудита
+const (
+	auditActionRead             = "read"
+	auditActionCreate           = "create"
+	auditActionUpdate           = "update"
+	auditActionDelete           = "delete"
+	auditActionRestore          = "restore"
+	auditActionAllergyAlert     = "allergy_alert"     // назначение совпало с аллергией пациента
+	auditActionInteractionAlert = "interaction_alert" // препарат взаимодействует с действующими назначениями
+)
+
+// Типы сущностей в журнале аудита
+const (
+	auditEntityPatient        = "patient"
+	auditEntityAppointment    = "appointment"
+	auditEntityMedicalTest    = "medical_test"
+	auditEntityMedicalHistory = "medical_history"
+	auditEntityPrescription   = "prescription"
+	auditEntityDiagnosis      = "appointment_diagnosis"
+)
+
+// AuditLog - запись журнала доступа к данным пациентов. Журнал только дополняется:
+// каждая запись содержит хеш предыдущей, поэтому изменение или удаление записей
+// обнаруживается проверкой цепочки (GET /audit/verify).
+// @Description Запись журнала аудита
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `gorm:"not null" json:"created_at"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	Username   string    `gorm:"not null" json:"username"`
+	Action     string    `gorm:"not null" json:"action"`
+	EntityType string    `gorm:"not null;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID   uint      `gorm:"not null;index:idx_audit_logs_entity" json:"entity_id"`
+	ClientIP   string    `json:"client_ip"`
+	Changes    jsonText  `gorm:"type:text" json:"changes,omitempty" swaggertype:"object"` // {"поле": {"old": ..., "new": ...}} для update, подробности для allergy_alert и interaction_alert
+	PrevHash   string    `gorm:"not null" json:"prev_hash"`
+	Hash       string    `gorm:"not null;uniqueIndex" json:"hash"`
+}
+
+// AuditVerifyResponse - результат проверки цепочки хешей журнала аудита
+type AuditVerifyResponse struct {
+	Valid    bool  `json:"valid"`
+	Checked  int   `json:"checked"`
+	BrokenAt *uint `json:"broken_at,omitempty"` // ID первой записи, не согласующейся с цепочкой
+}
+
+// errAuditChainBroken останавливает проверку журнала на первой несогласованной записи
+var errAuditChainBroken = errors.New("audit log hash chain is broken")
+
+// jsonText - JSON-документ, который хранится в текстовом столбце и выдается в API как есть.
+// Хеш записи считается от сохраненного текста, поэтому он не зависит от повторной сериализации.
+type jsonText []byte
+
+func (j jsonText) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *jsonText) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case string:
+		*j = jsonText(v)
+	case []byte:
+		*j = append(jsonText(nil), v...)
+	default:
+		return fmt.Errorf("unsupported type %T for jsonText", src)
+	}
+	return nil
+}
+
+func (j jsonText) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// auditRef - ссылка на запись, к которой обращался пользователь
+type auditRef struct {
+	EntityType string
+	EntityID   uint
+}
+
+func (p Patient) auditRef() auditRef              { return auditRef{auditEntityPatient, p.ID} }
+func (a Appointment) auditRef() auditRef          { return auditRef{auditEntityAppointment, a.ID} }
+func (t MedicalTest) auditRef() auditRef          { return auditRef{auditEntityMedicalTest, t.ID} }
+func (h MedicalHistory) auditRef() auditRef       { return auditRef{auditEntityMedicalHistory, h.ID} }
+func (p Prescription) auditRef() auditRef         { return auditRef{auditEntityPrescription, p.ID} }
+func (d AppointmentDiagnosis) auditRef() auditRef { return auditRef{auditEntityDiagnosis, d.ID} }
+
+// audited - модель, обращения к которой записываются в журнал
+type audited interface {
+	auditRef() auditRef
+}
+
+// auditRefs возвращает ссылки на все записи списка
+func auditRefs[T audited](items []T) []auditRef {
+	refs := make([]auditRef, len(items))
+	for i, item := range items {
+		refs[i] = item.auditRef()
+	}
+	return refs
+}
+
+// computeHash вычисляет хеш записи вместе с хешем предыдущей записи
+func (l AuditLog) computeHash() string {
+	payload, _ := json.Marshal([]interface{}{
+		l.PrevHash,
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+		l.UserID,
+		l.Username,
+		l.Action,
+		l.EntityType,
+		l.EntityID,
+		l.ClientIP,
+		string(l.Changes),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// appendAudit добавляет записи в конец цепочки. Должна вызываться внутри транзакции:
+// в PostgreSQL таблица блокируется до ее завершения, в SQLite пишущие транзакции
+// и так выполняются по очереди, поэтому параллельные запросы не разветвляют цепочку.
+func appendAudit(tx *gorm.DB, entries []AuditLog) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if tx.Dialector.Name() == "postgres" {
+		if err := tx.Exec("LOCK TABLE audit_logs IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+	}
+
+	var last AuditLog
+	if err := tx.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return err
+	}
+
+	prev := last.Hash
+	for i := range entries {
+		entries[i].PrevHash = prev
+		entries[i].Hash = entries[i].computeHash()
+		prev = entries[i].Hash
+	}
+	return tx.CreateInBatches(&entries, 100).Error
+}
+
+// newAuditEntry создает запись журнала от имени текущего пользователя.
+// Время округляется до микросекунд - точности хранения в PostgreSQL.
+func newAuditEntry(c *gin.Context, action string, ref auditRef) AuditLog {
+	user := currentUser(c)
+	return AuditLog{
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+		UserID:     user.ID,
+		Username:   user.Username,
+		Action:     action,
+		EntityType: ref.EntityType,
+		EntityID:   ref.EntityID,
+		ClientIP:   c.ClientIP(),
+	}
+}
+
+// recordAudit записывает изменение данных в той же транзакции, что и само изменение
+func recordAudit(tx *gorm.DB, c *gin.Context, action string, ref auditRef) error {
+	return appendAudit(tx, []AuditLog{newAuditEntry(c, action, ref)})
+}
+
+// recordAuditUpdate записывает изменение записи вместе с перечнем измененных полей
+func recordAuditUpdate(tx *gorm.DB, c *gin.Context, ref auditRef, before, after interface{}) error {
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	entry := newAuditEntry(c, auditActionUpdate, ref)
+	entry.Changes = changes
+	return appendAudit(tx, []AuditLog{entry})
+}
+
+// recordAuditDetails записывает действие с произвольными подробностями в поле changes
+func recordAuditDetails(tx *gorm.DB, c *gin.Context, action string, ref auditRef, details interface{}) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	entry := newAuditEntry(c, action, ref)
+	entry.Changes = data
+	return appendAudit(tx, []AuditLog{entry})
+}
+
+// auditRead записывает в журнал чтение записей. Если запись в журнал не удалась,
+// отвечает ошибкой: данные не выдаются без следа в журнале.
+func auditRead(c *gin.Context, refs ...auditRef) bool {
+	entries := make([]AuditLog, len(refs))
+	for i, ref := range refs {
+		entries[i] = newAuditEntry(c, auditActionRead, ref)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return appendAudit(tx, entries)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return false
+	}
+	return true
+}
+
+// auditDiff сравнивает JSON-представления записи до и после изменения и возвращает
+// измененные поля. Вложенные связи и служебные поля не сравниваются.
+func auditDiff(before, after interface{}) (jsonText, error) {
+	oldFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+
+	type fieldChange struct {
+		Old json.RawMessage `json:"old"`
+		New json.RawMessage `json:"new"`
+	}
+	changes := make(map[string]fieldChange)
+	for name := range names {
+		oldValue, newValue := oldFields[name], newFields[name]
+		if bytes.Equal(oldValue, newValue) {
+			continue
+		}
+		if oldValue == nil {
+			oldValue = json.RawMessage("null")
+		}
+		if newValue == nil {
+			newValue = json.RawMessage("null")
+		}
+		changes[name] = fieldChange{Old: oldValue, New: newValue}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changes)
+}
+
+// auditFields возвращает поля JSON-представления записи без связей и служебных полей
+func auditFields(record interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if name == "id" || name == "created_at" || len(value) > 0 && (value[0] == '{' || value[0] == '[') {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// Обработчики журнала аудита
+
+// GetAuditLog godoc
+// @Summary Получить журнал аудита
+// @Description Получить записи журнала обращений к данным пациентов в порядке их добавления
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param entity query string false "Тип сущности" Enums(patient, appointment, medical_test, medical_history, prescription, appointment_diagnosis)
+// @Param id query int false "ID записи (вместе с entity)"
+// @Param user_id query int false "ID пользователя"
+// @Param from query string false "Не раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param to query string false "Раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Порядок добавления; -id - сначала новые" Enums(id, -id) default(id)
+// @Success 200 {object} Page[AuditLog]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit [get]
+func getAuditLog(c *gin.Context) {
+	p, ok := paginate(c, auditLogList)
+	if !ok {
+		return
+	}
+	query := db.Model(&AuditLog{})
+
+	if entity := c.Query("entity"); entity != "" {
+		query = query.Where("entity_type = ?", entity)
+	}
+
+	if id := c.Query("id"); id != "" {
+		if c.Query("entity") == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "'id' requires 'entity'"})
+			return
+		}
+		query = query.Where("entity_id = ?", id)
+	}
+
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := parseTimeParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid 'from' parameter"})
+			return
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+
+	if value := c.Query("to"); value != "" {
+		to, err := parseTimeParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid 'to' parameter"})
+			return
+		}
+		query = query.Where("created_at < ?", to)
+	}
+
+	var entries []AuditLog
+	if err := p.apply(query).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	respondPage(c, p.page(entries))
+}
+
+// VerifyAuditLog godoc
+// @Summary Проверить целостность журнала аудита
+// @Description Пересчитать цепочку хешей журнала и найти первую измененную, удаленную или вставленную запись
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} AuditVerifyResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit/verify [get]
+func verifyAuditLog(c *gin.Context) {
+	result := AuditVerifyResponse{Valid: true}
+	prev := ""
+
+	var batch []AuditLog
+	err := db.Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			result.Checked++
+			if entry.PrevHash != prev || entry.computeHash() != entry.Hash {
+				id := entry.ID
+				result.Valid, result.BrokenAt = false, &id
+				return errAuditChainBroken
+			}
+			prev = entry.Hash
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// writeAuditTrail выполняет через API несколько действий, каждое из которых пишет в журнал,
+// и возвращает число записей журнала
+func writeAuditTrail(t *testing.T) int64 {
+	t.Helper()
+	admin := createTestUser(t, roleAdmin, 0)
+	doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+
+	patient := decodeResponse[Patient](t, apiRequest(t, admin, http.MethodPost, "/patients", CreatePatientRequest{
+		FullName: "Смирнова Анна", BirthDate: time.Date(1980, time.May, 17, 0, 0, 0, 0, time.UTC), Gender: "female",
+	}), http.StatusCreated)
+	path := fmt.Sprintf("/patients/%d", patient.ID)
+	decodeResponse[Patient](t, apiRequest(t, admin, http.MethodPut, path, CreatePatientRequest{
+		FullName: "Смирнова Анна Петровна", BirthDate: patient.BirthDate, Gender: "female", Phone: "+7 912 345-67-89",
+	}), http.StatusOK)
+	decodeResponse[Patient](t, apiRequest(t, admin, http.MethodGet, path, nil), http.StatusOK)
+	decodeResponse[Appointment](t, apiRequest(t, admin, http.MethodPost, "/appointments", CreateAppointmentRequest{
+		PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(10, 0),
+	}), http.StatusCreated)
+	decodeResponse[Page[Appointment]](t, apiRequest(t, admin, http.MethodGet, path+"/appointments", nil), http.StatusOK)
+
+	var count int64
+	if err := db.Model(&AuditLog{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count < 5 {
+		t.Fatalf("%d audit entries written, want at least 5", count)
+	}
+	return count
+}
+
+// verifyAudit проверяет цепочку журнала через API от имени аудитора
+func verifyAudit(t *testing.T) AuditVerifyResponse {
+	t.Helper()
+	var auditor User
+	if err := db.Where("role = ?", roleAuditor).First(&auditor).Error; err != nil {
+		auditor = createTestUser(t, roleAuditor, 0)
+	}
+	return decodeResponse[AuditVerifyResponse](t, apiRequest(t, auditor, http.MethodGet, "/audit/verify", nil), http.StatusOK)
+}
+
+// dropAuditProtection снимает запрет изменения журнала, чтобы смоделировать правку в обход приложения
+func dropAuditProtection(t *testing.T) {
+	t.Helper()
+	statements := []string{"DROP TRIGGER audit_logs_no_update", "DROP TRIGGER audit_logs_no_delete"}
+	if db.Dialector.Name() == "postgres" {
+		statements = []string{"DROP RULE audit_logs_no_update ON audit_logs", "DROP RULE audit_logs_no_delete ON audit_logs"}
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAuditComputeHash(t *testing.T) {
+	entry := AuditLog{
+		CreatedAt:  time.Date(2030, time.March, 4, 10, 0, 0, 123456000, time.UTC),
+		UserID:     1,
+		Username:   "admin",
+		Action:     auditActionUpdate,
+		EntityType: auditEntityPatient,
+		EntityID:   5,
+		ClientIP:   "192.0.2.1",
+		Changes:    jsonText(`{"phone":{"old":"","new":"+79123456789"}}`),
+		PrevHash:   "abc",
+	}
+	hash := entry.computeHash()
+	if len(hash) != 64 {
+		t.Fatalf("hash %q is not a hex SHA-256", hash)
+	}
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	same := entry
+	same.CreatedAt = entry.CreatedAt.In(moscow)
+	same.ID, same.Hash = 42, "ignored"
+	if same.computeHash() != hash {
+		t.Error("hash depends on time zone, ID or stored hash")
+	}
+
+	changes := map[string]func(*AuditLog){
+		"created_at":  func(l *AuditLog) { l.CreatedAt = l.CreatedAt.Add(time.Microsecond) },
+		"user_id":     func(l *AuditLog) { l.UserID = 2 },
+		"username":    func(l *AuditLog) { l.Username = "registrar" },
+		"action":      func(l *AuditLog) { l.Action = auditActionRead },
+		"entity_type": func(l *AuditLog) { l.EntityType = auditEntityAppointment },
+		"entity_id":   func(l *AuditLog) { l.EntityID = 6 },
+		"client_ip":   func(l *AuditLog) { l.ClientIP = "192.0.2.2" },
+		"changes":     func(l *AuditLog) { l.Changes = jsonText(`{}`) },
+		"prev_hash":   func(l *AuditLog) { l.PrevHash = "abd" },
+	}
+	for field, change := range changes {
+		changed := entry
+		change(&changed)
+		if changed.computeHash() == hash {
+			t.Errorf("changing %s does not change the hash", field)
+		}
+	}
+}
+
+func TestAuditChainVerifies(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		if got := verifyAudit(t); !got.Valid || got.Checked != 0 {
+			t.Errorf("empty log: %+v, want valid with 0 checked", got)
+		}
+
+		count := writeAuditTrail(t)
+
+		var entries []AuditLog
+		if err := db.Order("id").Find(&entries).Error; err != nil {
+			t.Fatal(err)
+		}
+		prev := ""
+		for _, entry := range entries {
+			if entry.PrevHash != prev {
+				t.Errorf("entry %d: prev_hash %q, want %q", entry.ID, entry.PrevHash, prev)
+			}
+			if entry.computeHash() != entry.Hash {
+				t.Errorf("entry %d: stored hash does not match its content", entry.ID)
+			}
+			prev = entry.Hash
+		}
+
+		got := verifyAudit(t)
+		if !got.Valid || int64(got.Checked) != count || got.BrokenAt != nil {
+			t.Errorf("verify = %+v, want valid with %d checked", got, count)
+		}
+	})
+}
+
+func TestAuditChainDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, entries []AuditLog)
+		broken func(entries []AuditLog) uint // ID записи, на которой должна остановиться проверка
+	}{
+		{
+			name: "changed field",
+			tamper: func(t *testing.T, entries []AuditLog) {
+				exec(t, "UPDATE audit_logs SET username = 'intruder' WHERE id = ?", entries[2].ID)
+			},
+			broken: func(entries []AuditLog) uint { return entries[2].ID },
+		},
+		{
+			name: "changed field with recomputed hash",
+			tamper: func(t *testing.T, entries []AuditLog) {
+				forged := entries[2]
+				forged.Username = "intruder"
+				exec(t, "UPDATE audit_logs SET username = ?, hash = ? WHERE id = ?", forged.Username, forged.computeHash(), forged.ID)
+			},
+			broken: func(entries []AuditLog) uint { return entries[3].ID },
+		},
+		{
+			name: "deleted entry",
+			tamper: func(t *testing.T, entries []AuditLog) {
+				exec(t, "DELETE FROM audit_logs WHERE id = ?", entries[2].ID)
+			},
+			broken: func(entries []AuditLog) uint { return entries[3].ID },
+		},
+		{
+			name: "appended entry not linked to the chain",
+			tamper: func(t *testing.T, entries []AuditLog) {
+				forged := AuditLog{
+					ID:         entries[len(entries)-1].ID + 1,
+					CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+					UserID:     1,
+					Username:   "intruder",
+					Action:     auditActionRead,
+					EntityType: auditEntityPatient,
+					EntityID:   1,
+				}
+				forged.Hash = forged.computeHash()
+				if err := db.Create(&forged).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			broken: func(entries []AuditLog) uint { return entries[len(entries)-1].ID + 1 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachDatabase(t, func(t *testing.T) {
+				writeAuditTrail(t)
+				var entries []AuditLog
+				if err := db.Order("id").Find(&entries).Error; err != nil {
+					t.Fatal(err)
+				}
+
+				dropAuditProtection(t)
+				tt.tamper(t, entries)
+
+				got := verifyAudit(t)
+				want := tt.broken(entries)
+				if got.Valid || got.BrokenAt == nil || *got.BrokenAt != want {
+					t.Errorf("verify = %+v, want broken at %d", got, want)
+				}
+			})
+		})
+	}
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		writeAuditTrail(t)
+		var before []AuditLog
+		if err := db.Order("id").Find(&before).Error; err != nil {
+			t.Fatal(err)
+		}
+		target := before[1]
+
+		statements := []struct {
+			name string
+			run  func() error
+		}{
+			{"raw update", func() error {
+				return db.Exec("UPDATE audit_logs SET username = 'intruder' WHERE id = ?", target.ID).Error
+			}},
+			{"model update", func() error {
+				return db.Model(&AuditLog{}).Where("id = ?", target.ID).Update("action", auditActionDelete).Error
+			}},
+			{"raw delete", func() error {
+				return db.Exec("DELETE FROM audit_logs WHERE id = ?", target.ID).Error
+			}},
+			{"delete all", func() error {
+				return db.Where("1 = 1").Delete(&AuditLog{}).Error
+			}},
+		}
+		for _, s := range statements {
+			err := s.run()
+			// SQLite прерывает запрос триггером, правила PostgreSQL молча отменяют изменение
+			if db.Dialector.Name() == "sqlite" && err == nil {
+				t.Errorf("%s: no error from the append-only trigger", s.name)
+			}
+
+			var after []AuditLog
+			if err := db.Order("id").Find(&after).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(after) != len(before) {
+				t.Fatalf("%s: %d entries left, want %d", s.name, len(after), len(before))
+			}
+			for i := range after {
+				if after[i].Username != before[i].Username || after[i].Action != before[i].Action || after[i].Hash != before[i].Hash {
+					t.Errorf("%s: entry %d changed", s.name, after[i].ID)
+				}
+			}
+		}
+
+		if got := verifyAudit(t); !got.Valid {
+			t.Errorf("verify = %+v, want valid", got)
+		}
+	})
+}
+
+// exec выполняет SQL-запрос и прерывает тест при ошибке
+func exec(t *testing.T, sql string, values ...interface{}) {
+	t.Helper()
+	if err := db.Exec(sql, values...).Error; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// User представляет учетную запись сотрудника или пациента
+// @Description Учетная запись пользователя
+type User struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Username     string    `gorm:"not null;uniqueIndex" json:"username"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	Active       bool      `gorm:"not null;default:true" json:"active"`
+	Role         string    `gorm:"not null" json:"role"`
+	DoctorID     *uint     `json:"doctor_id,omitempty"`  // для роли doctor
+	PatientID    *uint     `json:"patient_id,omitempty"` // для роли patient
+}
+
+// RefreshToken - выданный refresh-токен. В базе хранится только SHA-256 от токена.
+type RefreshToken struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+}
+
+// RevokedToken - отозванный до истечения срока access-токен
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse представляет пару выданных токенов
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// accessClaims - полезная нагрузка access-токена (JWT, HS256)
+type accessClaims struct {
+	Subject   string `json:"sub"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// jwtHeader - заголовок всех выпускаемых токенов
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// dummyPasswordHash используется для сравнения, когда пользователь не найден,
+// чтобы время ответа не выдавало существование учетной записи
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// jwtSecret возвращает ключ подписи access-токенов
+func jwtSecret() []byte {
+	return []byte(config.JWTSecret)
+}
+
+// ensureJWTSecret генерирует случайный ключ подписи, если он не задан в конфигурации
+func ensureJWTSecret() {
+	if config.JWTSecret != "" {
+		return
+	}
+	config.JWTSecret = randomToken()
+	slog.Warn("jwt_secret не задан, используется случайный ключ: выданные токены перестанут действовать после перезапуска")
+}
+
+// randomToken возвращает криптографически случайную строку
+func randomToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// hashToken возвращает SHA-256 от refresh-токена для хранения в базе
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// signAccessToken подписывает access-токен
+func signAccessToken(claims accessClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, jwtSecret())
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseAccessToken проверяет подпись и срок действия access-токена
+func parseAccessToken(token string) (accessClaims, error) {
+	var claims accessClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return claims, errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, jwtSecret())
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return claims, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return claims, errInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return claims, errInvalidToken
+	}
+	return claims, nil
+}
+
+// issueTokens выдает пользователю новую пару access- и refresh-токенов
+func issueTokens(tx *gorm.DB, user User) (TokenResponse, error) {
+	now := time.Now()
+	accessTTL := time.Duration(config.AccessTokenTTL)
+	access, err := signAccessToken(accessClaims{
+		Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		ID:        randomToken(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTTL).Unix(),
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	refresh := randomToken()
+	err = tx.Create(&RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(time.Duration(config.RefreshTokenTTL)),
+	}).Error
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+// authRequired пропускает только запросы с действующим access-токеном активного пользователя.
+// Пользователь сохраняется в контексте под ключом "user".
+func authRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+		claims, err := parseAccessToken(token)
+		if err != nil {
+			abortUnauthorized(c, err.Error())
+			return
+		}
+
+		var revoked int64
+		if err := db.Model(&RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if revoked > 0 {
+			abortUnauthorized(c, "token has been revoked")
+			return
+		}
+
+		var user User
+		if err := db.Where("active = ?", true).First(&user, claims.Subject).Error; err != nil {
+			abortUnauthorized(c, "user not found or disabled")
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("token_claims", claims)
+		c.Next()
+	}
+}
+
+// abortUnauthorized прерывает запрос с ответом 401
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="demeda"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: message})
+}
+
+// Обработчики аутентификации
+
+// Login godoc
+// @Summary Войти в систему
+// @Description Проверить имя пользователя и пароль и выдать access- и refresh-токены
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Учетные данные"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/login [post]
+func login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user User
+	err := db.Where("username = ? AND active = ?", req.Username, true).First(&user).Error
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid username or password"})
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "invalid username or password"})
+		return
+	}
+
+	tokens, err := issueTokens(db, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RefreshTokens godoc
+// @Summary Обновить токены
+// @Description Обменять refresh-токен на новую пару токенов. Использованный refresh-токен отзывается; повторное предъявление отозванного токена отзывает все refresh-токены пользователя
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body RefreshRequest true "Refresh-токен"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func refreshTokens(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var tokens TokenResponse
+	var reusedBy uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var stored RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(req.RefreshToken)).First(&stored).Error; err != nil {
+			return errInvalidToken
+		}
+
+		now := time.Now()
+		if stored.RevokedAt != nil {
+			reusedBy = stored.UserID
+			return errInvalidToken
+		}
+		if now.After(stored.ExpiresAt) {
+			return errInvalidToken
+		}
+
+		var user User
+		if err := tx.Where("active = ?", true).First(&user, stored.UserID).Error; err != nil {
+			return errInvalidToken
+		}
+		if err := tx.Model(&stored).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, user)
+		return err
+	})
+	if reusedBy != 0 {
+		// Повторное использование отозванного токена - вероятная утечка, отзываем все токены пользователя.
+		// Выполняется вне транзакции выше, которая откатывается из-за ошибки.
+		if revokeErr := db.Model(&RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", reusedBy).Update("revoked_at", time.Now()).Error; revokeErr != nil {
+			err = revokeErr
+		}
+	}
+	switch {
+	case errors.Is(err, errInvalidToken):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// Logout godoc
+// @Summary Выйти из системы
+// @Description Отозвать текущий access-токен и переданный refresh-токен
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token body RefreshRequest false "Refresh-токен"
+// @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout [post]
+func logout(c *gin.Context) {
+	claims := c.MustGet("token_claims").(accessClaims)
+	user := c.MustGet("user").(User)
+
+	var req RefreshRequest
+	c.ShouldBindJSON(&req)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Заодно удаляем записи об отозванных токенах, срок действия которых уже истек
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&RevokedToken{JTI: claims.ID, ExpiresAt: time.Unix(claims.ExpiresAt, 0)}).Error; err != nil {
+			return err
+		}
+		if req.RefreshToken == "" {
+			return nil
+		}
+		return tx.Model(&RefreshToken{}).
+			Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", hashToken(req.RefreshToken), user.ID).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, "Logged out")
+}
+
+// validateUserRole проверяет роль пользователя и ее связь с врачом или пациентом
+func validateUserRole(tx *gorm.DB, user User) error {
+	if !validRole(user.Role) {
+		return fmt.Errorf("unknown role %q", user.Role)
+	}
+	switch {
+	case user.Role == roleDoctor && user.DoctorID == nil:
+		return errors.New("role doctor requires a doctor id")
+	case user.Role == rolePatient && user.PatientID == nil:
+		return errors.New("role patient requires a patient id")
+	case user.Role != roleDoctor && user.DoctorID != nil, user.Role != rolePatient && user.PatientID != nil:
+		return fmt.Errorf("role %s cannot be linked to a doctor or patient", user.Role)
+	}
+	if user.DoctorID != nil {
+		if err := tx.First(&Doctor{}, *user.DoctorID).Error; err != nil {
+			return fmt.Errorf("doctor %d: %w", *user.DoctorID, err)
+		}
+	}
+	if user.PatientID != nil {
+		if err := tx.First(&Patient{}, *user.PatientID).Error; err != nil {
+			return fmt.Errorf("patient %d: %w", *user.PatientID, err)
+		}
+	}
+	return nil
+}
+
+// createUser создает пользователя с указанным паролем
+func createUser(tx *gorm.DB, user User, password string) (User, error) {
+	if err := validateUserRole(tx, user); err != nil {
+		return User{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+	user.PasswordHash = string(hash)
+	user.Active = true
+	return user, tx.Create(&user).Error
+}
+
+// runUser обрабатывает команду user
+func runUser(args []string) {
+	usage := "Usage:\n  demeda user add --username=name [--role=admin] [--doctor-id=id] [--patient-id=id] [--password=secret] [--config=path]\n\n" +
+		"Roles: admin, registrar, doctor (requires --doctor-id), lab_technician, patient (requires --patient-id).\n" +
+		"If --password is omitted, it is read from DEMEDA_USER_PASSWORD or the first line of stdin."
+	if len(args) == 0 || args[0] != "add" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("user add", flag.ExitOnError)
+	configPath := configFlag(flags)
+	username := flags.String("username", "", "login name")
+	password := flags.String("password", "", "password (prefer DEMEDA_USER_PASSWORD or stdin)")
+	role := flags.String("role", roleAdmin, "user role")
+	doctorID := flags.Uint("doctor-id", 0, "doctor linked to a user with role doctor")
+	patientID := flags.Uint("patient-id", 0, "patient linked to a user with role patient")
+	flags.Parse(args[1:])
+	loadConfigOrExit(*configPath)
+
+	if *username == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if *password == "" {
+		*password = os.Getenv("DEMEDA_USER_PASSWORD")
+	}
+	if *password == "" {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if len(*password) < 8 {
+		fmt.Fprintln(os.Stderr, "user: password must be at least 8 characters long")
+		os.Exit(1)
+	}
+
+	openDatabase()
+	requireCurrentSchema()
+	user := User{Username: *username, Role: *role}
+	if *doctorID != 0 {
+		user.DoctorID = doctorID
+	}
+	if *patientID != 0 {
+		user.PatientID = patientID
+	}
+	user, err := createUser(db, user, *password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "user:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("created user %q with role %s (id %d)\n", user.Username, user.Role, user.ID)
+}
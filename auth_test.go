@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPassword = "correct horse battery staple"
+
+// createLoginUser создает пользователя с паролем testPassword
+func createLoginUser(t *testing.T, username string) User {
+	t.Helper()
+	user, err := createUser(db, User{Username: username, Role: roleAdmin}, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// loginAs входит под учетной записью username и возвращает выданные токены
+func loginAs(t *testing.T, username string) TokenResponse {
+	t.Helper()
+	w := tokenRequest(t, "", http.MethodPost, "/auth/login", LoginRequest{Username: username, Password: testPassword})
+	return decodeResponse[TokenResponse](t, w, http.StatusOK)
+}
+
+// refresh обменивает refresh-токен и возвращает ответ
+func refresh(t *testing.T, token string) (TokenResponse, int) {
+	t.Helper()
+	w := tokenRequest(t, "", http.MethodPost, "/auth/refresh", RefreshRequest{RefreshToken: token})
+	if w.Code != http.StatusOK {
+		return TokenResponse{}, w.Code
+	}
+	return decodeResponse[TokenResponse](t, w, http.StatusOK), w.Code
+}
+
+// authorized проверяет, принимает ли API access-токен
+func authorized(t *testing.T, token string) bool {
+	t.Helper()
+	w := tokenRequest(t, token, http.MethodGet, "/patients", nil)
+	switch w.Code {
+	case http.StatusOK:
+		return true
+	case http.StatusUnauthorized:
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Error("401 without WWW-Authenticate")
+		}
+		return false
+	}
+	t.Fatalf("GET /patients: status %d: %s", w.Code, w.Body.String())
+	return false
+}
+
+func TestLogin(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		user := createLoginUser(t, "admin")
+		disabled := createLoginUser(t, "former")
+		if err := db.Model(&disabled).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		tokens := loginAs(t, user.Username)
+		if tokens.TokenType != "Bearer" || tokens.AccessToken == "" || tokens.RefreshToken == "" ||
+			tokens.ExpiresIn != int(time.Duration(config.AccessTokenTTL).Seconds()) {
+			t.Errorf("tokens = %+v", tokens)
+		}
+		if !authorized(t, tokens.AccessToken) {
+			t.Error("issued access token is rejected")
+		}
+
+		// Неверный пароль, неизвестный и отключенный пользователь неотличимы по ответу
+		for name, req := range map[string]LoginRequest{
+			"wrong password": {Username: user.Username, Password: "wrong password"},
+			"unknown user":   {Username: "nobody", Password: testPassword},
+			"inactive user":  {Username: disabled.Username, Password: testPassword},
+		} {
+			w := tokenRequest(t, "", http.MethodPost, "/auth/login", req)
+			got := decodeResponse[ErrorResponse](t, w, http.StatusUnauthorized)
+			if got.Error != "invalid username or password" {
+				t.Errorf("%s: error %q", name, got.Error)
+			}
+		}
+		if w := tokenRequest(t, "", http.MethodPost, "/auth/login", LoginRequest{Username: user.Username}); w.Code != http.StatusBadRequest {
+			t.Errorf("login without password: status %d, want 400", w.Code)
+		}
+
+		var stored int64
+		if err := db.Model(&RefreshToken{}).Count(&stored).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored != 1 {
+			t.Errorf("%d refresh tokens stored, want 1", stored)
+		}
+	})
+}
+
+func TestAccessToken(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		user := createLoginUser(t, "admin")
+		other := createLoginUser(t, "registrar")
+		subject := strconv.FormatUint(uint64(user.ID), 10)
+		sign := func(claims accessClaims) string {
+			t.Helper()
+			token, err := signAccessToken(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}
+		now := time.Now()
+		valid := sign(accessClaims{Subject: subject, ID: randomToken(), IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()})
+		if !authorized(t, valid) {
+			t.Fatal("valid token is rejected")
+		}
+
+		// Подмена пользователя в полезной нагрузке без пересчета подписи
+		parts := strings.Split(valid, ".")
+		forged := sign(accessClaims{Subject: strconv.FormatUint(uint64(other.ID), 10), ID: randomToken(), ExpiresAt: now.Add(time.Minute).Unix()})
+		tampered := parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+
+		secret := config.JWTSecret
+		config.JWTSecret = "another-secret-0123456789abcdef0123456789"
+		foreign := sign(accessClaims{Subject: subject, ID: randomToken(), ExpiresAt: now.Add(time.Minute).Unix()})
+		config.JWTSecret = secret
+
+		unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."
+
+		tests := map[string]string{
+			"missing":            "",
+			"expired":            sign(accessClaims{Subject: subject, ID: randomToken(), IssuedAt: now.Add(-time.Hour).Unix(), ExpiresAt: now.Add(-time.Second).Unix()}),
+			"tampered payload":   tampered,
+			"tampered signature": parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString([]byte("signature")),
+			"foreign secret":     foreign,
+			"alg none":           unsigned,
+			"malformed":          "not-a-token",
+			"unknown user":       sign(accessClaims{Subject: "999999", ID: randomToken(), ExpiresAt: now.Add(time.Minute).Unix()}),
+		}
+		for name, token := range tests {
+			if authorized(t, token) {
+				t.Errorf("%s token is accepted", name)
+			}
+		}
+
+		// Токен отключенного пользователя перестает действовать сразу
+		if err := db.Model(&user).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		if authorized(t, valid) {
+			t.Error("token of a disabled user is accepted")
+		}
+	})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		user := createLoginUser(t, "admin")
+		first := loginAs(t, user.Username)
+		other := loginAs(t, user.Username) // второй сеанс того же пользователя
+
+		second, status := refresh(t, first.RefreshToken)
+		if status != http.StatusOK {
+			t.Fatalf("refresh: status %d", status)
+		}
+		if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+			t.Error("refresh returned the same tokens")
+		}
+		if !authorized(t, second.AccessToken) {
+			t.Error("refreshed access token is rejected")
+		}
+
+		// Повторное предъявление использованного токена отзывает все refresh-токены пользователя
+		if _, status := refresh(t, first.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("reused refresh token: status %d, want 401", status)
+		}
+		for name, token := range map[string]string{"rotated": second.RefreshToken, "other session": other.RefreshToken} {
+			if _, status := refresh(t, token); status != http.StatusUnauthorized {
+				t.Errorf("%s refresh token after reuse: status %d, want 401", name, status)
+			}
+		}
+		var active int64
+		if err := db.Model(&RefreshToken{}).Where("revoked_at IS NULL").Count(&active).Error; err != nil {
+			t.Fatal(err)
+		}
+		if active != 0 {
+			t.Errorf("%d refresh tokens left active after reuse", active)
+		}
+
+		// Неизвестный и истекший токены
+		if _, status := refresh(t, randomToken()); status != http.StatusUnauthorized {
+			t.Errorf("unknown refresh token: status %d, want 401", status)
+		}
+		expired := loginAs(t, user.Username)
+		if err := db.Model(&RefreshToken{}).Where("token_hash = ?", hashToken(expired.RefreshToken)).
+			Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, status := refresh(t, expired.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("expired refresh token: status %d, want 401", status)
+		}
+
+		// Refresh-токен отключенного пользователя не обменивается
+		current := loginAs(t, user.Username)
+		if err := db.Model(&user).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, status := refresh(t, current.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("refresh token of a disabled user: status %d, want 401", status)
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		user := createLoginUser(t, "admin")
+		session := loginAs(t, user.Username)
+		other := loginAs(t, user.Username)
+
+		if w := tokenRequest(t, "", http.MethodPost, "/auth/logout", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("logout without a token: status %d, want 401", w.Code)
+		}
+		w := tokenRequest(t, session.AccessToken, http.MethodPost, "/auth/logout", RefreshRequest{RefreshToken: session.RefreshToken})
+		if w.Code != http.StatusOK {
+			t.Fatalf("logout: status %d: %s", w.Code, w.Body.String())
+		}
+
+		if authorized(t, session.AccessToken) {
+			t.Error("access token works after logout")
+		}
+		if w := tokenRequest(t, session.AccessToken, http.MethodPost, "/auth/logout", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("repeated logout: status %d, want 401", w.Code)
+		}
+
+		// Другой сеанс пользователя продолжает работать
+		if !authorized(t, other.AccessToken) {
+			t.Error("access token of another session is rejected")
+		}
+		other, status := refresh(t, other.RefreshToken)
+		if status != http.StatusOK {
+			t.Fatalf("refresh token of another session: status %d, want 200", status)
+		}
+
+		// Отозванный при выходе refresh-токен считается повторно использованным
+		if _, status := refresh(t, session.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("refresh token after logout: status %d, want 401", status)
+		}
+		if _, status := refresh(t, other.RefreshToken); status != http.StatusUnauthorized {
+			t.Errorf("refresh token of another session after reuse: status %d, want 401", status)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConflictResponse представляет ответ о пересечении приемов
+type ConflictResponse struct {
+	Error                     string `json:"error"`
+	ConflictingAppointmentIDs []uint `json:"conflicting_appointment_ids"`
+}
+
+// errDoctorInactive возвращается при записи к уволенному врачу
+var errDoctorInactive = errors.New("doctor is no longer active")
+
+// bookingConflictError возвращается, если прием пересекается с другими приемами врача или пациента
+// либо операция невозможна из-за перечисленных приемов
+type bookingConflictError struct {
+	IDs     []uint
+	Message string
+}
+
+func (e *bookingConflictError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("appointment overlaps with %d existing appointment(s)", len(e.IDs))
+}
+
+// appointmentDuration возвращает длительность приема из запроса или значение по умолчанию
+func appointmentDuration(minutes int) time.Duration {
+	if minutes <= 0 {
+		return defaultAppointmentDuration
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// findAppointmentConflicts возвращает ID приемов того же врача или пациента, пересекающихся с данным.
+// Отмененные приемы и неявки время не занимают.
+func findAppointmentConflicts(tx *gorm.DB, appointment *Appointment) ([]uint, error) {
+	if !appointment.occupiesSlot() {
+		return nil, nil
+	}
+	var ids []uint
+	err := tx.Model(&Appointment{}).
+		Where("(doctor_id = ? OR patient_id = ?) AND date < ? AND end_date > ? AND id <> ?",
+			appointment.DoctorID, appointment.PatientID, appointment.EndDate, appointment.Date, appointment.ID).
+		Where("status NOT IN ?", freeSlotStatuses).
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// saveAppointment проверяет рабочее время врача и пересечения, после чего сохраняет прием.
+// Проверка и запись выполняются в одной транзакции, поэтому параллельные запросы
+// не могут занять одно и то же время. Функция after выполняется в той же транзакции
+// после записи (например, для журнала аудита).
+func saveAppointment(appointment *Appointment, after func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := validateAndSaveAppointment(tx, appointment); err != nil {
+			return err
+		}
+		return after(tx)
+	})
+}
+
+// validateAndSaveAppointment выполняет проверки saveAppointment внутри уже открытой транзакции.
+// Строки врача и пациента блокируются (SELECT ... FOR UPDATE), чтобы в PostgreSQL
+// параллельные записи к одному врачу или пациенту выполнялись по очереди; SQLite
+// сериализует такие транзакции сам благодаря _txlock=immediate.
+func validateAndSaveAppointment(tx *gorm.DB, appointment *Appointment) error {
+	var doctor Doctor
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&doctor, appointment.DoctorID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &missingReferenceError{Field: "doctor_id", Entity: "doctor", ID: appointment.DoctorID}
+		}
+		return err
+	}
+	if !doctor.Active {
+		return errDoctorInactive
+	}
+
+	var patient Patient
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&patient, appointment.PatientID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &missingReferenceError{Field: "patient_id", Entity: "patient", ID: appointment.PatientID}
+		}
+		return err
+	}
+
+	if err := checkDoctorWorkingTime(tx, appointment.DoctorID, appointment.Date, appointment.EndDate); err != nil {
+		return err
+	}
+
+	ids, err := findAppointmentConflicts(tx, appointment)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return &bookingConflictError{IDs: ids}
+	}
+
+	return tx.Save(appointment).Error
+}
+
+// respondBookingError отправляет ответ, соответствующий ошибке записи на прием
+func respondBookingError(c *gin.Context, err error) {
+	if respondReferenceError(c, err) {
+		return
+	}
+	var conflict *bookingConflictError
+	var status *appointmentStatusError
+	switch {
+	case errors.Is(err, errOutsideWorkingHours), errors.Is(err, errDoctorInactive):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, ConflictResponse{Error: err.Error(), ConflictingAppointmentIDs: conflict.IDs})
+	case errors.As(err, &status):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCreateAppointmentConflicts(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		otherDoctor := createTestDoctor(t, "Петров Петр", "Хирург")
+		retired := createTestDoctor(t, "Сидоров Сидор", "Терапевт")
+		if err := db.Model(&retired).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+		registrar := createTestUser(t, roleRegistrar, 0)
+
+		// Занятое время: прием врача doctor с пациентом patient 10:00-10:30
+		// и отмененный прием в 12:00, который время не занимает
+		booked := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID,
+			Date: testTime(10, 0), EndDate: testTime(10, 30)})
+		createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID,
+			Date: testTime(12, 0), Status: statusCancelled})
+
+		tests := []struct {
+			name      string
+			patientID uint
+			doctorID  uint
+			start     time.Time
+			duration  int
+			status    int
+			conflicts []uint
+		}{
+			{"same doctor, overlapping start", otherPatient.ID, doctor.ID, testTime(10, 15), 30, http.StatusConflict, []uint{booked.ID}},
+			{"same doctor, inside existing", otherPatient.ID, doctor.ID, testTime(10, 5), 15, http.StatusConflict, []uint{booked.ID}},
+			{"same doctor, enclosing existing", otherPatient.ID, doctor.ID, testTime(9, 30), 90, http.StatusConflict, []uint{booked.ID}},
+			{"same doctor, exactly the same slot", otherPatient.ID, doctor.ID, testTime(10, 0), 30, http.StatusConflict, []uint{booked.ID}},
+			{"adjacent slot before", otherPatient.ID, doctor.ID, testTime(9, 30), 30, http.StatusCreated, nil},
+			{"adjacent slot after", otherPatient.ID, doctor.ID, testTime(10, 30), 30, http.StatusCreated, nil},
+			{"patient double-booked with another doctor", patient.ID, otherDoctor.ID, testTime(10, 15), 30, http.StatusConflict, []uint{booked.ID}},
+			{"patient with another doctor right after", patient.ID, otherDoctor.ID, testTime(10, 30), 30, http.StatusCreated, nil},
+			{"other doctor and patient at the same time", otherPatient.ID, otherDoctor.ID, testTime(10, 0), 30, http.StatusCreated, nil},
+			{"cancelled appointment frees the slot", otherPatient.ID, doctor.ID, testTime(12, 0), 30, http.StatusCreated, nil},
+			{"outside working hours", otherPatient.ID, doctor.ID, testTime(7, 45), 30, http.StatusBadRequest, nil},
+			{"ends after working hours", otherPatient.ID, doctor.ID, testTime(19, 45), 30, http.StatusBadRequest, nil},
+			{"inactive doctor", otherPatient.ID, retired.ID, testTime(10, 0), 30, http.StatusBadRequest, nil},
+			{"unknown patient", 999, doctor.ID, testTime(15, 0), 30, http.StatusUnprocessableEntity, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// Каждый случай проверяется на исходном расписании
+				t.Cleanup(func() {
+					db.Unscoped().Where("id > ?", booked.ID+1).Delete(&Appointment{})
+				})
+
+				w := apiRequest(t, registrar, http.MethodPost, "/appointments", CreateAppointmentRequest{
+					PatientID: tt.patientID, DoctorID: tt.doctorID, Date: tt.start, Duration: tt.duration,
+				})
+				if w.Code != tt.status {
+					t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+				}
+				if tt.status == http.StatusConflict {
+					resp := decodeResponse[ConflictResponse](t, w, http.StatusConflict)
+					if !slices.Equal(resp.ConflictingAppointmentIDs, tt.conflicts) {
+						t.Errorf("conflicting ids = %v, want %v", resp.ConflictingAppointmentIDs, tt.conflicts)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestRescheduleAppointmentConflicts(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+		registrar := createTestUser(t, roleRegistrar, 0)
+
+		createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(10, 0)})
+		second := createTestAppointment(t, Appointment{PatientID: otherPatient.ID, DoctorID: doctor.ID, Date: testTime(11, 0)})
+
+		move := func(start time.Time) int {
+			return apiRequest(t, registrar, http.MethodPut, fmt.Sprintf("/appointments/%d", second.ID), CreateAppointmentRequest{
+				PatientID: otherPatient.ID, DoctorID: doctor.ID, Date: start, Duration: 30,
+			}).Code
+		}
+		if code := move(testTime(10, 15)); code != http.StatusConflict {
+			t.Errorf("move onto another appointment: status %d, want 409", code)
+		}
+		if code := move(testTime(10, 30)); code != http.StatusOK {
+			t.Errorf("move next to another appointment: status %d, want 200", code)
+		}
+		// Прием не конфликтует сам с собой при сдвиге внутри своего времени
+		if code := move(testTime(10, 40)); code != http.StatusOK {
+			t.Errorf("shift within own slot: status %d, want 200", code)
+		}
+	})
+}
+
+func TestConcurrentBooking(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		const attempts = 8
+		patients := make([]Patient, attempts)
+		for i := range patients {
+			patients[i] = createTestPatient(t, fmt.Sprintf("Пациент %d", i+1), "")
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, attempts)
+		start := make(chan struct{})
+		for i := range patients {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				appointment := Appointment{
+					PatientID: patients[i].ID,
+					DoctorID:  doctor.ID,
+					Date:      testTime(10, 0),
+					EndDate:   testTime(10, 30),
+					Status:    statusScheduled,
+				}
+				errs[i] = saveAppointment(&appointment, func(tx *gorm.DB) error { return nil })
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		created := 0
+		for i, err := range errs {
+			var conflict *bookingConflictError
+			switch {
+			case err == nil:
+				created++
+			case errors.As(err, &conflict):
+			default:
+				t.Errorf("attempt %d: unexpected error %v", i, err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("%d of %d concurrent bookings of one slot succeeded, want exactly 1", created, attempts)
+		}
+
+		var stored int64
+		if err := db.Model(&Appointment{}).Where("doctor_id = ?", doctor.ID).Count(&stored).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored != 1 {
+			t.Errorf("%d appointments stored for the slot, want 1", stored)
+		}
+	})
+}
+
+func TestBookingWaitsForOpenTransaction(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+
+		// Первая запись проверена и сохранена, но транзакция еще не завершена
+		tx := db.Begin()
+		first := Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(10, 0), EndDate: testTime(10, 30), Status: statusScheduled}
+		if err := validateAndSaveAppointment(tx, &first); err != nil {
+			tx.Rollback()
+			t.Fatal(err)
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			second := Appointment{PatientID: otherPatient.ID, DoctorID: doctor.ID, Date: testTime(10, 15), EndDate: testTime(10, 45), Status: statusScheduled}
+			done <- saveAppointment(&second, func(tx *gorm.DB) error { return nil })
+		}()
+
+		select {
+		case err := <-done:
+			tx.Rollback()
+			t.Fatalf("second booking finished while the first transaction was open: %v", err)
+		case <-time.After(300 * time.Millisecond):
+		}
+		if err := tx.Commit().Error; err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-done:
+			var conflict *bookingConflictError
+			if !errors.As(err, &conflict) || !slices.Equal(conflict.IDs, []uint{first.ID}) {
+				t.Errorf("second booking: err = %v, want conflict with %d", err, first.ID)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("second booking did not finish after the first transaction committed")
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// defaultConfigFile - файл конфигурации, который читается, если путь не задан явно
+const defaultConfigFile = "demeda.yaml"
+
+// Config содержит настройки сервиса.
+// Приоритет источников: значения по умолчанию < файл конфигурации < переменные окружения DEMEDA_*.
+type Config struct {
+	ListenAddr         string   `yaml:"listen_addr"`
+	DatabaseDSN        string   `yaml:"database_dsn"`
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins"`
+	LogLevel           string   `yaml:"log_level"`
+	SeedProfile        string   `yaml:"seed_profile"`
+	ReadTimeout        Duration `yaml:"read_timeout"`
+	WriteTimeout       Duration `yaml:"write_timeout"`
+	IdleTimeout        Duration `yaml:"idle_timeout"`
+	ShutdownTimeout    Duration `yaml:"shutdown_timeout"`
+	JWTSecret          string   `yaml:"jwt_secret"`
+	AccessTokenTTL     Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL    Duration `yaml:"refresh_token_ttl"`
+}
+
+// Duration - time.Duration, который в YAML и переменных окружения записывается строкой вида "15s"
+type Duration time.Duration
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// config - действующая конфигурация сервиса
+var config = defaultConfig()
+
+// defaultConfig возвращает конфигурацию по умолчанию
+func defaultConfig() Config {
+	return Config{
+		ListenAddr:         ":8080",
+		DatabaseDSN:        "clinic.db",
+		CORSAllowedOrigins: []string{"*"},
+		LogLevel:           "info",
+		ReadTimeout:        Duration(15 * time.Second),
+		WriteTimeout:       Duration(30 * time.Second),
+		IdleTimeout:        Duration(60 * time.Second),
+		ShutdownTimeout:    Duration(10 * time.Second),
+		AccessTokenTTL:     Duration(15 * time.Minute),
+		RefreshTokenTTL:    Duration(30 * 24 * time.Hour),
+	}
+}
+
+// configFlag регистрирует флаг --config у команды
+func configFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", "", "path to a YAML config file (default: $DEMEDA_CONFIG or ./"+defaultConfigFile+" if present)")
+}
+
+// loadConfig собирает конфигурацию из файла и переменных окружения и проверяет ее
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+
+	required := true
+	if path == "" {
+		path = os.Getenv("DEMEDA_CONFIG")
+	}
+	if path == "" {
+		path, required = defaultConfigFile, false
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	case errors.Is(err, fs.ErrNotExist) && !required:
+	default:
+		return cfg, err
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyEnv переопределяет настройки значениями переменных окружения
+func applyEnv(cfg *Config) error {
+	texts := map[string]*string{
+		"DEMEDA_LISTEN_ADDR":  &cfg.ListenAddr,
+		"DEMEDA_DATABASE_DSN": &cfg.DatabaseDSN,
+		"DEMEDA_LOG_LEVEL":    &cfg.LogLevel,
+		"DEMEDA_SEED_PROFILE": &cfg.SeedProfile,
+		"DEMEDA_JWT_SECRET":   &cfg.JWTSecret,
+	}
+	for name, target := range texts {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	if value, ok := os.LookupEnv("DEMEDA_CORS_ALLOWED_ORIGINS"); ok {
+		cfg.CORSAllowedOrigins = splitList(value)
+	}
+
+	durations := map[string]*Duration{
+		"DEMEDA_READ_TIMEOUT":      &cfg.ReadTimeout,
+		"DEMEDA_WRITE_TIMEOUT":     &cfg.WriteTimeout,
+		"DEMEDA_IDLE_TIMEOUT":      &cfg.IdleTimeout,
+		"DEMEDA_SHUTDOWN_TIMEOUT":  &cfg.ShutdownTimeout,
+		"DEMEDA_ACCESS_TOKEN_TTL":  &cfg.AccessTokenTTL,
+		"DEMEDA_REFRESH_TOKEN_TTL": &cfg.RefreshTokenTTL,
+	}
+	for name, target := range durations {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = Duration(parsed)
+		}
+	}
+	return nil
+}
+
+// splitList разбирает список значений, разделенных запятыми
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validate проверяет настройки и возвращает все найденные ошибки сразу
+func (c Config) validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("listen_addr: %w", err))
+	}
+	if c.DatabaseDSN == "" {
+		errs = append(errs, errors.New("database_dsn must not be empty"))
+	} else if _, err := openDialector(c.DatabaseDSN); err != nil {
+		errs = append(errs, fmt.Errorf("database_dsn: %w", err))
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("cors_allowed_origins: %q is not an origin like https://example.com", origin))
+		}
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level: %q is not one of debug, info, warn, error", c.LogLevel))
+	}
+	if c.SeedProfile != "" {
+		if _, err := fs.Stat(fixturesFS, "fixtures/"+c.SeedProfile+".json"); err != nil {
+			errs = append(errs, fmt.Errorf("seed_profile: unknown profile %q", c.SeedProfile))
+		}
+	}
+	timeouts := map[string]Duration{
+		"read_timeout":      c.ReadTimeout,
+		"write_timeout":     c.WriteTimeout,
+		"idle_timeout":      c.IdleTimeout,
+		"shutdown_timeout":  c.ShutdownTimeout,
+		"access_token_ttl":  c.AccessTokenTTL,
+		"refresh_token_ttl": c.RefreshTokenTTL,
+	}
+	for name, value := range timeouts {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+
+	if c.JWTSecret != "" && len(c.JWTSecret) < 32 {
+		errs = append(errs, errors.New("jwt_secret must be at least 32 characters long"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// dsnPasswordPattern находит пароль в DSN формата "key=value"
+var dsnPasswordPattern = regexp.MustCompile(`(?i)(password=)(\S+)`)
+
+// redacted возвращает копию конфигурации, в которой скрыты секреты
+func (c Config) redacted() Config {
+	c.DatabaseDSN = redactDSN(c.DatabaseDSN)
+	if c.JWTSecret != "" {
+		c.JWTSecret = "xxxxx"
+	}
+	return c
+}
+
+// redactDSN скрывает пароль в строке подключения к базе данных
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.String()
+	}
+	return dsnPasswordPattern.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// loadConfigOrExit загружает конфигурацию в глобальную переменную config или завершает процесс
+func loadConfigOrExit(path string) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		os.Exit(1)
+	}
+	config = cfg
+}
+
+// runConfig обрабатывает команду config
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage:\n  demeda config print [--config=path]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	path := configFlag(flags)
+	flags.Parse(args[1:])
+	loadConfigOrExit(*path)
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config.redacted()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	encoder.Close()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dialectors содержит поддерживаемые драйверы баз данных. Драйвер PostgreSQL
+// регистрируется в database_postgres.go при сборке с тегом postgres.
+var dialectors = map[string]func(dsn string) gorm.Dialector{
+	"sqlite": func(dsn string) gorm.Dialector {
+		return sqlite.Open(sqliteDSN(strings.TrimPrefix(dsn, "sqlite://")))
+	},
+}
+
+// databaseDriver определяет драйвер по строке подключения: postgres://, postgresql://
+// и DSN вида "host=... dbname=..." относятся к PostgreSQL, остальное - путь к файлу SQLite
+func databaseDriver(dsn string) string {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return "postgres"
+	case strings.Contains(dsn, "host=") || strings.Contains(dsn, "dbname="):
+		return "postgres"
+	default:
+		return "sqlite"
+	}
+}
+
+// openDialector возвращает драйвер GORM для строки подключения
+func openDialector(dsn string) (gorm.Dialector, error) {
+	driver := databaseDriver(dsn)
+	open, ok := dialectors[driver]
+	if !ok {
+		supported := make([]string, 0, len(dialectors))
+		for name := range dialectors {
+			supported = append(supported, name)
+		}
+		sort.Strings(supported)
+		return nil, fmt.Errorf("database driver %q is not compiled in (supported: %s); rebuild with -tags %s",
+			driver, strings.Join(supported, ", "), driver)
+	}
+	return open(dsn), nil
+}
+
+// openDatabase подключается к базе данных. Схема создается и обновляется командой migrate
+func openDatabase() {
+	dialector, err := openDialector(config.DatabaseDSN)
+	if err != nil {
+		panic(err)
+	}
+
+	db, err = gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(gormLogLevel(config.LogLevel)),
+	})
+	if err != nil {
+		panic("Failed to connect to database")
+	}
+}
+
+// requireCurrentSchema завершает процесс, если схема базы не соответствует бинарному файлу
+func requireCurrentSchema() {
+	if err := checkSchemaVersion(db); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := initPatientSearch(db); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// sqliteHasFTS5 сообщает, собран ли драйвер SQLite с полнотекстовым поиском FTS5
+// (go-sqlite3 включает его тегом сборки sqlite_fts5)
+func sqliteHasFTS5(db *gorm.DB) bool {
+	if db.Dialector.Name() != "sqlite" {
+		return false
+	}
+	var used int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used).Error; err != nil {
+		return false
+	}
+	return used == 1
+}
+
+// sqliteDSN добавляет к DSN параметры, необходимые для корректной работы с SQLite:
+// _txlock=immediate сериализует пишущие транзакции, чтобы проверка пересечений приемов
+// и последующая запись не могли выполниться параллельно; _foreign_keys=1 выполняет
+// PRAGMA foreign_keys=ON на каждом соединении, без чего SQLite не проверяет внешние ключи
+func sqliteDSN(dsn string) string {
+	for _, param := range []string{"_txlock=immediate", "_busy_timeout=5000", "_foreign_keys=1"} {
+		name := param[:strings.Index(param, "=")+1]
+		if strings.Contains(dsn, name) {
+			continue
+		}
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + param
+		} else {
+			dsn += "?" + param
+		}
+	}
+	return dsn
+}
+
+// resetSequences выравнивает последовательности первичных ключей PostgreSQL после вставки
+// записей с явными ID. В SQLite следующий ID и так вычисляется по максимальному.
+func resetSequences(tx *gorm.DB, tables []string) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range tables {
+		err := tx.Exec(fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE((SELECT MAX(id) FROM %[1]s), 0) + 1, false)",
+			table)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gormLogLevel сопоставляет уровень логирования сервиса уровню логгера GORM
+func gormLogLevel(level string) logger.LogLevel {
+	switch level {
+	case "debug":
+		return logger.Info
+	case "error":
+		return logger.Error
+	default:
+		return logger.Warn
+	}
+}
@@ -0,0 +1,14 @@
+//go:build postgres
+
+package main
+
+import (
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dialectors["postgres"] = func(dsn string) gorm.Dialector {
+		return postgres.Open(dsn)
+	}
+}
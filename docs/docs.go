@@ -18,7 +18,12 @@ const docTemplate = `{
     "paths": {
         "/appointments": {
             "get": {
-                "description": "Получить список медицинских приемов с возможностью фильтрации",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить список медицинских приемов с возможностью фильтрации. Все заданные фильтры объединяются через AND",
                 "consumes": [
                     "application/json"
                 ],
@@ -41,18 +46,133 @@ const docTemplate = `{
                         "description": "Фильтр по ID врача",
                         "name": "doctor_id",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема не раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "today",
+                            "upcoming",
+                            "past"
+                        ],
+                        "type": "string",
+                        "description": "Приемы за сегодня, предстоящие или прошедшие",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "scheduled",
+                            "checked_in",
+                            "in_progress",
+                            "completed",
+                            "cancelled",
+                            "no_show"
+                        ],
+                        "type": "string",
+                        "description": "Статус приема",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по специализации врача без учета регистра",
+                        "name": "specialization",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подстрока диагноза без учета регистра и ё/е (требует доступа к клиническим данным)",
+                        "name": "diagnosis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код МКБ-10 или его начало, например I10 или J0 (требует доступа к клиническим данным)",
+                        "name": "diagnosis_code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только приемы с тестами (true) или без них (false)",
+                        "name": "has_tests",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленные приемы (только для администратора)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "date",
+                            "-date",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Appointment"
+                            "$ref": "#/definitions/main.Page-main_Appointment"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -62,7 +182,12 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Создать запись о медицинском приеме",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создать запись о медицинском приеме. Прием создается в статусе scheduled, диагноз и лечение указываются после начала приема",
                 "consumes": [
                     "application/json"
                 ],
@@ -97,6 +222,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ConflictResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReferenceErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -108,6 +257,11 @@ const docTemplate = `{
         },
         "/appointments/{id}": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Получить подробную информацию о медицинском приеме",
                 "consumes": [
                     "application/json"
@@ -135,6 +289,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.Appointment"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -144,7 +310,12 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Обновить информацию о медицинском приеме",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновить информацию о медицинском приеме. Перенести прием или сменить врача и пациента можно только в статусе scheduled, изменить диагноз и лечение - только в статусах in_progress и completed. Препараты, впервые упомянутые в лечении, сверяются с аллергиями пациента",
                 "consumes": [
                     "application/json"
                 ],
@@ -186,12 +357,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ConflictResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReferenceErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -201,7 +396,12 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Удалить запись о медицинском приеме",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пометить прием удаленным вместе с его тестами и назначениями. Прием можно восстановить через POST /appointments/{id}/restore",
                 "consumes": [
                     "application/json"
                 ],
@@ -228,6 +428,24 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -237,9 +455,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/appointments/{id}/tests": {
-            "get": {
-                "description": "Получить медицинские тесты конкретного приема",
+        "/appointments/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отменить запланированный прием с указанием причины. Отмененный прием остается в истории и не занимает время врача",
                 "consumes": [
                     "application/json"
                 ],
@@ -249,7 +472,7 @@ const docTemplate = `{
                 "tags": [
                     "appointments"
                 ],
-                "summary": "Получить тесты приема",
+                "summary": "Отменить прием",
                 "parameters": [
                     {
                         "type": "integer",
@@ -257,16 +480,52 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Причина отмены",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.AppointmentStatusRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.MedicalTest"
-                            }
+                            "$ref": "#/definitions/main.Appointment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -278,9 +537,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/doctors": {
-            "get": {
-                "description": "Получить список всех врачей клиники",
+        "/appointments/{id}/check-in": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перевести запланированный прием в статус checked_in",
                 "consumes": [
                     "application/json"
                 ],
@@ -288,17 +552,47 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "doctors"
+                    "appointments"
+                ],
+                "summary": "Отметить приход пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID приема",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получить список врачей",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Doctor"
-                            }
+                            "$ref": "#/definitions/main.Appointment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -310,9 +604,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/doctors/{id}": {
-            "get": {
-                "description": "Получить подробную информацию о враче",
+        "/appointments/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перевести прием из статуса in_progress в completed",
                 "consumes": [
                     "application/json"
                 ],
@@ -320,13 +619,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "doctors"
+                    "appointments"
                 ],
-                "summary": "Получить врача по ID",
+                "summary": "Завершить прием",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID врача",
+                        "description": "ID приема",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -336,7 +635,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.Doctor"
+                            "$ref": "#/definitions/main.Appointment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "404": {
@@ -344,13 +655,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/doctors/{id}/appointments": {
+        "/appointments/{id}/diagnoses": {
             "get": {
-                "description": "Получить список всех приемов конкретного врача",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить диагнозы приема по МКБ-10: сначала основной, затем сопутствующие",
                 "consumes": [
                     "application/json"
                 ],
@@ -358,13 +686,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "doctors"
+                    "appointments"
                 ],
-                "summary": "Получить приемы врача",
+                "summary": "Получить диагнозы приема",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID врача",
+                        "description": "ID приема",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -376,54 +704,26 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/main.Appointment"
+                                "$ref": "#/definitions/main.AppointmentDiagnosis"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/medical-history": {
-            "get": {
-                "description": "Получить записи медицинского анамнеза с возможностью фильтрации",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "medical-history"
-                ],
-                "summary": "Получить анамнез",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Фильтр по ID пациента",
-                        "name": "patient_id",
-                        "in": "query"
                     },
-                    {
-                        "type": "string",
-                        "description": "Фильтр по типу анамнеза",
-                        "name": "type",
-                        "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.MedicalHistory"
-                            }
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -435,7 +735,12 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Создать новую запись в медицинском анамнезе пациента",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавить к приему диагноз по коду МКБ-10 из справочника. Прием должен быть начат или завершен. Основной диагноз у приема один: новый основной диагноз делает прежний сопутствующим. Без kind диагноз становится основным, если основного еще нет",
                 "consumes": [
                     "application/json"
                 ],
@@ -443,17 +748,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "medical-history"
+                    "appointments"
                 ],
-                "summary": "Создать запись анамнеза",
+                "summary": "Поставить диагноз",
                 "parameters": [
                     {
-                        "description": "Данные анамнеза",
-                        "name": "history",
+                        "type": "integer",
+                        "description": "ID приема",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Диагноз",
+                        "name": "diagnosis",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.CreateMedicalHistoryRequest"
+                            "$ref": "#/definitions/main.CreateDiagnosisRequest"
                         }
                     }
                 ],
@@ -461,7 +773,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.MedicalHistory"
+                            "$ref": "#/definitions/main.AppointmentDiagnosis"
                         }
                     },
                     "400": {
@@ -470,6 +782,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -479,9 +815,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/medical-history/{id}": {
-            "delete": {
-                "description": "Удалить запись из медицинского анамнеза",
+        "/appointments/{id}/no-show": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отметить, что пациент не пришел на запланированный прием. Доступно после времени начала приема",
                 "consumes": [
                     "application/json"
                 ],
@@ -489,55 +830,61 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "medical-history"
+                    "appointments"
                 ],
-                "summary": "Удалить запись анамнеза",
+                "summary": "Отметить неявку",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID записи анамнеза",
+                        "description": "ID приема",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Комментарий",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.AppointmentStatusRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Appointment"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/patients": {
-            "get": {
-                "description": "Получить список всех пациентов",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "patients"
-                ],
-                "summary": "Получить список пациентов",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Patient"
-                            }
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -547,9 +894,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/appointments/{id}/prescriptions": {
             "post": {
-                "description": "Создать запись нового пациента в системе",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавить назначение препарата к приему. Прием должен быть начат или завершен. Срок задается duration_days или end_date; без них назначение бессрочное. Препарат сверяется с аллергиями пациента: при совпадении назначение сохраняется с предупреждениями, при тяжелой аллергии - только с override_allergy и override_reason",
                 "consumes": [
                     "application/json"
                 ],
@@ -557,17 +911,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "patients"
+                    "appointments"
                 ],
-                "summary": "Создать нового пациента",
+                "summary": "Назначить препарат",
                 "parameters": [
                     {
-                        "description": "Данные пациента",
-                        "name": "patient",
+                        "type": "integer",
+                        "description": "ID приема",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Назначение",
+                        "name": "prescription",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.CreatePatientRequest"
+                            "$ref": "#/definitions/main.CreatePrescriptionRequest"
                         }
                     }
                 ],
@@ -575,7 +936,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/main.Patient"
+                            "$ref": "#/definitions/main.Prescription"
                         }
                     },
                     "400": {
@@ -584,6 +945,30 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.AllergyConflictResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -593,9 +978,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/patients/{id}": {
-            "get": {
-                "description": "Получить подробную информацию о пациенте включая анамнез и приемы",
+        "/appointments/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Восстановить удаленный прием вместе с тестами и назначениями, удаленными вместе с ним. Пациент приема не должен быть удален",
                 "consumes": [
                     "application/json"
                 ],
@@ -603,13 +993,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "patients"
+                    "appointments"
                 ],
-                "summary": "Получить пациента по ID",
+                "summary": "Восстановить прием",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID пациента",
+                        "description": "ID приема",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -619,7 +1009,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.Patient"
+                            "$ref": "#/definitions/main.Appointment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "404": {
@@ -628,6 +1030,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ConflictResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -635,9 +1043,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Обновить информацию о существующем пациенте",
+            }
+        },
+        "/appointments/{id}/start": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Перевести прием из статуса checked_in в in_progress. После начала приема можно записывать диагноз и лечение",
                 "consumes": [
                     "application/json"
                 ],
@@ -645,36 +1060,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "patients"
+                    "appointments"
                 ],
-                "summary": "Обновить данные пациента",
+                "summary": "Начать прием",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID пациента",
+                        "description": "ID приема",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Обновленные данные пациента",
-                        "name": "patient",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.CreatePatientRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/main.Patient"
+                            "$ref": "#/definitions/main.Appointment"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
@@ -685,6 +1097,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -692,9 +1110,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удалить запись пациента из системы",
+            }
+        },
+        "/appointments/{id}/tests": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить медицинские тесты конкретного приема",
                 "consumes": [
                     "application/json"
                 ],
@@ -702,23 +1127,154 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "patients"
+                    "appointments"
                 ],
-                "summary": "Удалить пациента",
+                "summary": "Получить тесты приема",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "ID пациента",
+                        "description": "ID приема",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "name",
+                            "-name",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Page-main_MedicalTest"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавить результат медицинского теста к приему",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointments"
+                ],
+                "summary": "Добавить результат теста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID приема",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Результат теста",
+                        "name": "test",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateMedicalTestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.MedicalTest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -730,9 +1286,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/patients/{id}/appointments": {
+        "/audit": {
             "get": {
-                "description": "Получить список всех приемов конкретного пациента",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить записи журнала обращений к данным пациентов в порядке их добавления",
                 "consumes": [
                     "application/json"
                 ],
@@ -740,28 +1301,103 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "patients"
+                    "audit"
                 ],
-                "summary": "Получить приемы пациента",
+                "summary": "Получить журнал аудита",
                 "parameters": [
+                    {
+                        "enum": [
+                            "patient",
+                            "appointment",
+                            "medical_test",
+                            "medical_history",
+                            "prescription",
+                            "appointment_diagnosis"
+                        ],
+                        "type": "string",
+                        "description": "Тип сущности",
+                        "name": "entity",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID пациента",
+                        "description": "ID записи (вместе с entity)",
                         "name": "id",
-                        "in": "path",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Не раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Порядок добавления; -id - сначала новые",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.Appointment"
+                            "$ref": "#/definitions/main.Page-main_AuditLog"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -771,9 +1407,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/patients/{id}/medical-history": {
+        "/audit/verify": {
             "get": {
-                "description": "Получить медицинский анамнез конкретного пациента",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пересчитать цепочку хешей журнала и найти первую измененную, удаленную или вставленную запись",
                 "consumes": [
                     "application/json"
                 ],
@@ -781,26 +1422,128 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "patients"
+                    "audit"
                 ],
-                "summary": "Получить анамнез пациента",
+                "summary": "Проверить целостность журнала аудита",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.AuditVerifyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Проверить имя пользователя и пароль и выдать access- и refresh-токены",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Войти в систему",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID пациента",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Учетные данные",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/main.MedicalHistory"
-                            }
+                            "$ref": "#/definitions/main.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отозвать текущий access-токен и переданный refresh-токен",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Выйти из системы",
+                "parameters": [
+                    {
+                        "description": "Refresh-токен",
+                        "name": "token",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
                         }
                     },
                     "500": {
@@ -811,278 +1554,6995 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.Appointment": {
-            "description": "Информация о медицинском приеме",
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Обменять refresh-токен на новую пару токенов. Использованный refresh-токен отзывается; повторное предъявление отозванного токена отзывает все refresh-токены пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Обновить токены",
+                "parameters": [
+                    {
+                        "description": "Refresh-токен",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/diagnoses/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Изменить вид диагноза (основной или сопутствующий) и комментарий. Новый основной диагноз делает прежний сопутствующим. Код не меняется: ошибочный диагноз удаляется и ставится заново",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointments"
+                ],
+                "summary": "Изменить диагноз",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID диагноза",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Вид и комментарий",
+                        "name": "diagnosis",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateDiagnosisRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.AppointmentDiagnosis"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удалить ошибочно поставленный диагноз приема. Удаление записывается в журнал аудита",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "appointments"
+                ],
+                "summary": "Удалить диагноз",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID диагноза",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить список работающих врачей клиники",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Получить список врачей",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Включить уволенных врачей",
+                        "name": "include_inactive",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по специализации без учета регистра",
+                        "name": "specialization",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "full_name",
+                            "-full_name",
+                            "specialization",
+                            "-specialization",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Doctor"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавить нового врача в штат клиники",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Добавить врача",
+                "parameters": [
+                    {
+                        "description": "Данные врача",
+                        "name": "doctor",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateDoctorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Doctor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить подробную информацию о враче",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Получить врача по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Doctor"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновить информацию о враче",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Обновить данные врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновленные данные врача",
+                        "name": "doctor",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateDoctorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Doctor"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Деактивировать врача. Прошедшие приемы сохраняются за врачом. Будущие приемы обрабатываются согласно future_appointments: block (по умолчанию) - отказать, если они есть; reassign - перевести к врачу reassign_to; cancel - отменить (приемы переводятся в статус cancelled). Завершенные и отмененные приемы и неявки не учитываются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Уволить врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "block",
+                            "reassign",
+                            "cancel"
+                        ],
+                        "type": "string",
+                        "default": "block",
+                        "description": "Что делать с будущими приемами",
+                        "name": "future_appointments",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID врача, к которому переводятся приемы (обязателен для reassign)",
+                        "name": "reassign_to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ConflictResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors/{id}/appointments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить список всех приемов конкретного врача",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Получить приемы врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "date",
+                            "-date",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Appointment"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors/{id}/schedule": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить недельный график работы врача. Дни недели нумеруются от 1 (понедельник) до 7 (воскресенье)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Получить график работы врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.DoctorSchedule"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Заменить недельный график работы врача. Дни недели нумеруются от 1 (понедельник) до 7 (воскресенье), время указывается в формате ЧЧ:ММ",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Обновить график работы врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Недельный график",
+                        "name": "schedule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateDoctorScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.DoctorSchedule"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors/{id}/schedule/exceptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить список отпусков и других периодов, когда врач не принимает",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Получить исключения из графика врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.DoctorScheduleException"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавить период [start_date, end_date), в который врач не принимает (отпуск, больничный)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Добавить исключение в график врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Период отсутствия",
+                        "name": "exception",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateScheduleExceptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.DoctorScheduleException"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors/{id}/schedule/exceptions/{exceptionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удалить период отсутствия врача",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Удалить исключение из графика врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID исключения",
+                        "name": "exceptionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/doctors/{id}/slots": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Рассчитать свободные для записи интервалы с учетом графика, перерывов, исключений и существующих приемов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "doctors"
+                ],
+                "summary": "Получить свободные слоты врача",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало периода (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Длительность слота в минутах",
+                        "name": "duration",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TimeSlot"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/drug-classes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить таблицу соответствия препаратов фармакологическим группам, по которой назначения сверяются с аллергиями",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drug-classes"
+                ],
+                "summary": "Получить группы препаратов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Фильтр по группе",
+                        "name": "class",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.DrugClass"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отнести препарат к фармакологической группе. Название и группа хранятся в нормализованном виде (нижний регистр, е вместо ё)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drug-classes"
+                ],
+                "summary": "Добавить препарат в группу",
+                "parameters": [
+                    {
+                        "description": "Препарат и группа",
+                        "name": "drug_class",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateDrugClassRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.DrugClass"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/drug-classes/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удалить запись таблицы групп препаратов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "drug-classes"
+                ],
+                "summary": "Удалить препарат из группы",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Загрузить данные из другой клиники: Bundle типа transaction с ресурсами Patient, Practitioner, Encounter, Observation и Condition (только POST). Ресурсы записываются как пациенты, врачи, приемы, тесты и записи анамнеза в одной транзакции. Ресурс с известным идентификатором (identifier) не создается повторно, а сопоставляется с загруженной ранее записью. Ссылки между ресурсами задаются через fullUrl записей, на записи клиники (Patient/5) или по идентификатору (Patient?identifier=system|value). Для создания записей нужны права на их изменение. Если хотя бы одна запись не загружена, ничего не сохраняется, а OperationOutcome перечисляет ошибки всех записей",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Загрузить Bundle FHIR",
+                "parameters": [
+                    {
+                        "description": "Bundle типа transaction",
+                        "name": "bundle",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRTransactionBundle"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/AllergyIntolerance": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск аллергий из анамнеза в формате FHIR AllergyIntolerance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск аллергий (FHIR AllergyIntolerance)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Пациент (Patient/ID или ID)",
+                        "name": "patient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID через запятую",
+                        "name": "_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/AllergyIntolerance/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить аллергию из анамнеза в формате FHIR AllergyIntolerance",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Аллергия (FHIR AllergyIntolerance)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID записи анамнеза",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRAllergyIntolerance"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Appointment": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск приемов в формате FHIR Appointment",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск записей на прием (FHIR Appointment)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Пациент (Patient/ID или ID)",
+                        "name": "patient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Врач (Practitioner/ID или ID)",
+                        "name": "practitioner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема с необязательным префиксом eq, ne, gt, ge, lt, le; можно повторить",
+                        "name": "date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "booked",
+                            "checked-in",
+                            "arrived",
+                            "fulfilled",
+                            "cancelled",
+                            "noshow"
+                        ],
+                        "type": "string",
+                        "description": "Статусы Appointment через запятую",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID через запятую",
+                        "name": "_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Appointment/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить прием в формате FHIR Appointment",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Запись на прием (FHIR Appointment)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID приема",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRAppointment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Condition": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск состояний пациента в формате FHIR Condition. Выдаются записи анамнеза, кроме аллергий и семейного анамнеза (категория problem-list-item, ID history-\u003cid\u003e), и диагнозы приемов по МКБ-10 (категория encounter-diagnosis, ID diagnosis-\u003cid\u003e); сначала анамнез, затем диагнозы. Каждый источник выдается, только если роль может его читать",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск состояний (FHIR Condition)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Пациент (Patient/ID или ID)",
+                        "name": "patient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Прием (Encounter/ID или ID); только диагнозы приемов",
+                        "name": "encounter",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "problem-list-item",
+                            "encounter-diagnosis"
+                        ],
+                        "type": "string",
+                        "description": "Категория",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Код МКБ-10; только диагнозы приемов",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Condition/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить запись анамнеза (history-\u003cid\u003e) или диагноз приема (diagnosis-\u003cid\u003e) в формате FHIR Condition",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Состояние (FHIR Condition)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "history-\u003cID записи анамнеза\u003e или diagnosis-\u003cID диагноза\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRCondition"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Encounter": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск приемов в формате FHIR Encounter. Диагнозы по МКБ-10 выдаются ссылками на Condition, если роль может читать клинические данные",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск визитов (FHIR Encounter)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Пациент (Patient/ID или ID)",
+                        "name": "patient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Врач (Practitioner/ID или ID)",
+                        "name": "practitioner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема с необязательным префиксом eq, ne, gt, ge, lt, le; можно повторить",
+                        "name": "date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "planned",
+                            "arrived",
+                            "in-progress",
+                            "finished",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Статусы Encounter через запятую",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID через запятую",
+                        "name": "_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Encounter/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить прием в формате FHIR Encounter",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Визит (FHIR Encounter)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID приема",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIREncounter"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Observation": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск медицинских тестов в формате FHIR Observation. Дата наблюдения - дата приема",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск результатов тестов (FHIR Observation)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Пациент (Patient/ID или ID)",
+                        "name": "patient",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Прием (Encounter/ID или ID)",
+                        "name": "encounter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема с необязательным префиксом eq, ne, gt, ge, lt, le; можно повторить",
+                        "name": "date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID через запятую",
+                        "name": "_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Observation/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить медицинский тест в формате FHIR Observation",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Результат теста (FHIR Observation)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID теста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRObservation"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Patient": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск пациентов в формате FHIR R4. Пациент видит только себя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск пациентов (FHIR Patient)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало слов ФИО",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "male",
+                            "female"
+                        ],
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата рождения с необязательным префиксом eq, ne, gt, ge, lt, le",
+                        "name": "birthdate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID через запятую",
+                        "name": "_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Patient/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить пациента в формате FHIR R4",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Пациент (FHIR Patient)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRPatient"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Practitioner": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Поиск врачей в формате FHIR R4",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Поиск врачей (FHIR Practitioner)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало слов ФИО",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только работающие (true) или деактивированные (false)",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID через запятую",
+                        "name": "_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "_count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение страницы",
+                        "name": "_offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRBundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/Practitioner/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить врача в формате FHIR R4",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "Врач (FHIR Practitioner)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID врача",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRPractitioner"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIROperationOutcome"
+                        }
+                    }
+                }
+            }
+        },
+        "/fhir/R4/metadata": {
+            "get": {
+                "description": "Описание возможностей сервера FHIR R4: поддерживаемые ресурсы, операции и параметры поиска. Доступно без аутентификации",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fhir"
+                ],
+                "summary": "CapabilityStatement сервера FHIR",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.FHIRCapabilityStatement"
+                        }
+                    }
+                }
+            }
+        },
+        "/icd10": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Подбор кодов МКБ-10 для автодополнения. Строка, похожая на код (I1, J06.9), ищется по началу кода, иначе - по началу слов названия без учета регистра и ё/е. Результаты упорядочены по коду",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "icd10"
+                ],
+                "summary": "Поиск кодов МКБ-10",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало кода или слова названия",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество результатов (по умолчанию 20, не больше 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.ICD10Code"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/icd10/{code}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить название кода МКБ-10 из справочника",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "icd10"
+                ],
+                "summary": "Получить код МКБ-10",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Код МКБ-10",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ICD10Code"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/interactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить записи базы лекарственных взаимодействий; с drug - только относящиеся к препарату или его группам",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "interactions"
+                ],
+                "summary": "Получить базу взаимодействий",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Препарат",
+                        "name": "drug",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.DrugInteraction"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/medical-history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить записи медицинского анамнеза с возможностью фильтрации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical-history"
+                ],
+                "summary": "Получить анамнез",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по ID пациента",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по типу анамнеза",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленные записи (только для администратора)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "start_date",
+                            "-start_date",
+                            "history_type",
+                            "-history_type",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_MedicalHistory"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создать новую запись в медицинском анамнезе пациента",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical-history"
+                ],
+                "summary": "Создать запись анамнеза",
+                "parameters": [
+                    {
+                        "description": "Данные анамнеза",
+                        "name": "history",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateMedicalHistoryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.MedicalHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/main.ReferenceErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/medical-history/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пометить запись анамнеза удаленной. Запись можно восстановить через POST /medical-history/{id}/restore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical-history"
+                ],
+                "summary": "Удалить запись анамнеза",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID записи анамнеза",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/medical-history/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Восстановить удаленную запись анамнеза. Пациент не должен быть удален",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "medical-history"
+                ],
+                "summary": "Восстановить запись анамнеза",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID записи анамнеза",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MedicalHistory"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить список всех пациентов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить список пациентов",
+                "parameters": [
+                    {
+                        "enum": [
+                            "male",
+                            "female"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по полу",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленных пациентов (только для администратора)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "full_name",
+                            "-full_name",
+                            "birth_date",
+                            "-birth_date",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Patient"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создать запись нового пациента в системе",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Создать нового пациента",
+                "parameters": [
+                    {
+                        "description": "Данные пациента",
+                        "name": "patient",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatePatientRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Patient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Найти пациентов по ФИО (без учета регистра, ё/е и порядка слов), телефону (по цифрам, в любом формате) или email. Результаты упорядочены по релевантности, постраничная выдача не поддерживается",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Поиск пациентов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Строка поиска: слова ФИО, номер телефона или email",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимум результатов (по умолчанию 20, не больше 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Patient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить подробную информацию о пациенте включая анамнез и приемы",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить пациента по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Patient"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновить информацию о существующем пациенте",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Обновить данные пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновленные данные пациента",
+                        "name": "patient",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatePatientRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Patient"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пометить пациента удаленным вместе с его приемами, их тестами и назначениями и анамнезом. Записи можно восстановить через POST /patients/{id}/restore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Удалить пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/abnormal-results": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить результаты тестов пациента вне референсного интервала (флаги L, H, LL, HH)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить отклонения в анализах пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только критические отклонения (LL, HH)",
+                        "name": "critical",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "name",
+                            "-name",
+                            "date",
+                            "-date",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "date",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_MedicalTest"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/appointments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить список всех приемов конкретного пациента",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить приемы пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "date",
+                            "-date",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Appointment"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/interactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверить, взаимодействует ли препарат с действующими назначениями пациента, не создавая назначения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Проверить взаимодействия препарата",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Препарат",
+                        "name": "drug",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.InteractionWarning"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/medical-history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить медицинский анамнез конкретного пациента",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить анамнез пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "start_date",
+                            "-start_date",
+                            "history_type",
+                            "-history_type",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_MedicalHistory"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/medications": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить назначения препаратов пациента; с active=true - только действующие",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить лекарства пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только действующие (true) или только отмененные и завершенные (false)",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "start_date",
+                            "-start_date",
+                            "drug",
+                            "-drug",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Prescription"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Восстановить удаленного пациента вместе с приемами, тестами, назначениями и анамнезом, удаленными вместе с ним",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Восстановить пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Patient"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ConflictResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/tests/trends": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить ряд числовых результатов теста пациента по датам приемов с учетом синонимов названия и пересчетом единиц, а также минимум, максимум, среднее и наклон",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "patients"
+                ],
+                "summary": "Получить динамику показателя пациента",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID пациента",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название теста или его синоним",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Единица измерения ряда (по умолчанию - единица последнего результата)",
+                        "name": "unit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема не раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TestTrend"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/prescriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить назначения препаратов с возможностью фильтрации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Получить список назначений",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по ID пациента",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по ID приема",
+                        "name": "appointment_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только действующие (true) или только отмененные и завершенные (false)",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленные назначения (только для администратора)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "-id",
+                            "start_date",
+                            "-start_date",
+                            "drug",
+                            "-drug",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "id",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_Prescription"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/prescriptions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить назначение препарата",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Получить назначение по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID назначения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Prescription"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Изменить препарат, дозировку или срок действующего назначения. Новый препарат сверяется с аллергиями пациента, как при назначении",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Обновить назначение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID назначения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновленное назначение",
+                        "name": "prescription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreatePrescriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Prescription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.AllergyConflictResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пометить назначение удаленным, например ошибочно внесенное. Для прекращения приема препарата используйте POST /prescriptions/{id}/discontinue. Назначение можно восстановить через POST /prescriptions/{id}/restore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Удалить назначение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID назначения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/prescriptions/{id}/discontinue": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Прекратить прием препарата с указанием причины. Отмененное назначение остается в истории",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Отменить назначение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID назначения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина отмены",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.DiscontinuePrescriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Prescription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/prescriptions/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Восстановить удаленное назначение препарата. Прием назначения не должен быть удален",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "prescriptions"
+                ],
+                "summary": "Восстановить назначение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID назначения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Prescription"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tests": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить результаты медицинских тестов по всем приемам с возможностью фильтрации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Получить список тестов",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по ID пациента",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по названию теста",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема не раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата приема раньше (RFC3339 или ГГГГ-ММ-ДД)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленные тесты (только для администратора)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не больше 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы (next_cursor из предыдущего ответа)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "date",
+                            "-date",
+                            "id",
+                            "-id",
+                            "name",
+                            "-name",
+                            "created_at",
+                            "-created_at"
+                        ],
+                        "type": "string",
+                        "default": "date",
+                        "description": "Сортировка; '-' перед полем - по убыванию",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Page-main_MedicalTest"
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылка на следующую страницу (rel=\\\"next\\\")"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tests/synonyms": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить таблицу синонимов, по которой названия тестов сводятся к основному при построении динамики",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Получить синонимы названий тестов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TestSynonym"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавить альтернативное название теста. Синоним хранится в нормализованном виде (нижний регистр, е вместо ё)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Добавить синоним названия теста",
+                "parameters": [
+                    {
+                        "description": "Синоним и основное название",
+                        "name": "synonym",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateTestSynonymRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.TestSynonym"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tests/synonyms/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удалить альтернативное название теста",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Удалить синоним названия теста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID синонима",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tests/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Получить результат медицинского теста",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Получить тест по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID теста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MedicalTest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновить результат медицинского теста",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Обновить результат теста",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID теста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновленный результат теста",
+                        "name": "test",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateMedicalTestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MedicalTest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пометить результат теста удаленным. Тест можно восстановить через POST /tests/{id}/restore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Удалить тест",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID теста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tests/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Восстановить удаленный результат теста. Прием теста не должен быть удален",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tests"
+                ],
+                "summary": "Восстановить тест",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID теста",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.MedicalTest"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/main.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.AllergyConflictResponse": {
+            "type": "object",
+            "properties": {
+                "allergy_warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AllergyWarning"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AllergyWarning": {
+            "type": "object",
+            "properties": {
+                "allergy": {
+                    "description": "описание аллергии",
+                    "type": "string"
+                },
+                "blocking": {
+                    "description": "тяжелая аллергия: назначение требует подтверждения",
+                    "type": "boolean"
+                },
+                "class": {
+                    "description": "общая группа; пусто при совпадении по названию",
+                    "type": "string"
+                },
+                "drug": {
+                    "description": "назначенный препарат",
+                    "type": "string"
+                },
+                "history_id": {
+                    "description": "запись анамнеза об аллергии",
+                    "type": "integer"
+                },
+                "severity": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Appointment": {
+            "description": "Информация о медицинском приеме",
+            "type": "object",
+            "properties": {
+                "allergy_warnings": {
+                    "description": "Предупреждения об аллергии на препараты, впервые упомянутые в лечении; только в ответе на изменение",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AllergyWarning"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "diagnoses": {
+                    "description": "Диагнозы по МКБ-10: основной и сопутствующие",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AppointmentDiagnosis"
+                    }
+                },
+                "diagnosis": {
+                    "description": "заметка врача; кодированные диагнозы - в diagnoses",
+                    "type": "string"
+                },
+                "doctor": {
+                    "$ref": "#/definitions/main.Doctor"
+                },
+                "doctor_id": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "interaction_warnings": {
+                    "description": "Взаимодействия препаратов, впервые упомянутых в лечении, с действующими назначениями; только в ответе на изменение",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.InteractionWarning"
+                    }
+                },
+                "medical_tests": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.MedicalTest"
+                    }
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "patient": {
+                    "$ref": "#/definitions/main.Patient"
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "scheduled, checked_in, in_progress, completed, cancelled, no_show",
+                    "type": "string"
+                },
+                "status_reason": {
+                    "description": "причина отмены или комментарий к неявке",
+                    "type": "string"
+                },
+                "treatment": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AppointmentDiagnosis": {
+            "description": "Кодированный диагноз приема",
+            "type": "object",
+            "properties": {
+                "appointment_id": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "primary, secondary",
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "title": {
+                    "description": "название из справочника на момент постановки",
+                    "type": "string"
+                }
+            }
+        },
+        "main.AppointmentStatusRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "причина; для отмены обязательна",
+                    "type": "string"
+                }
+            }
+        },
+        "main.AuditLog": {
+            "description": "Запись журнала аудита",
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "{\"поле\": {\"old\": ..., \"new\": ...}} для update, подробности для allergy_alert и interaction_alert",
+                    "type": "object"
+                },
+                "client_ip": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "entity_type": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "prev_hash": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.AuditVerifyResponse": {
+            "type": "object",
+            "properties": {
+                "broken_at": {
+                    "description": "ID первой записи, не согласующейся с цепочкой",
+                    "type": "integer"
+                },
+                "checked": {
+                    "type": "integer"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "main.ConflictResponse": {
+            "type": "object",
+            "properties": {
+                "conflicting_appointment_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateAppointmentRequest": {
+            "type": "object",
+            "required": [
+                "date",
+                "doctor_id",
+                "patient_id"
+            ],
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "diagnosis": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "integer"
+                },
+                "duration": {
+                    "description": "длительность в минутах; при создании по умолчанию 30, при изменении - прежняя",
+                    "type": "integer",
+                    "maximum": 480,
+                    "minimum": 5
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "override_allergy": {
+                    "description": "Подтверждение лечения вопреки тяжелой аллергии пациента, с обязательной причиной",
+                    "type": "boolean"
+                },
+                "override_reason": {
+                    "type": "string"
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "treatment": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateDiagnosisRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "primary, secondary; по умолчанию основной, если у приема его еще нет",
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateDoctorRequest": {
+            "type": "object",
+            "required": [
+                "full_name",
+                "specialization"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "specialization": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateDrugClassRequest": {
+            "type": "object",
+            "required": [
+                "class",
+                "drug"
+            ],
+            "properties": {
+                "class": {
+                    "type": "string"
+                },
+                "drug": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateMedicalHistoryRequest": {
+            "type": "object",
+            "required": [
+                "description",
+                "history_type",
+                "patient_id"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "history_type": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateMedicalTestRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "critical_range": {
+                    "description": "необязательный интервал для флагов LL и HH",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reference_range": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreatePatientRequest": {
+            "type": "object",
+            "required": [
+                "birth_date",
+                "full_name",
+                "gender"
+            ],
+            "properties": {
+                "birth_date": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreatePrescriptionRequest": {
+            "type": "object",
+            "required": [
+                "dose",
+                "dose_unit",
+                "drug",
+                "frequency",
+                "route"
+            ],
+            "properties": {
+                "dose": {
+                    "type": "number"
+                },
+                "dose_unit": {
+                    "type": "string"
+                },
+                "drug": {
+                    "type": "string"
+                },
+                "duration_days": {
+                    "description": "длительность курса в днях",
+                    "type": "integer",
+                    "maximum": 3650,
+                    "minimum": 1
+                },
+                "end_date": {
+                    "description": "вместо duration_days",
+                    "type": "string"
+                },
+                "frequency": {
+                    "type": "string"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "override_allergy": {
+                    "description": "Подтверждение назначения вопреки тяжелой аллергии пациента, с обязательной причиной",
+                    "type": "boolean"
+                },
+                "override_reason": {
+                    "type": "string"
+                },
+                "route": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "по умолчанию - дата приема",
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateScheduleExceptionRequest": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "start_date"
+            ],
+            "properties": {
+                "end_date": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CreateTestSynonymRequest": {
+            "type": "object",
+            "required": [
+                "alias",
+                "name"
+            ],
+            "properties": {
+                "alias": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DiscontinuePrescriptionRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Doctor": {
+            "description": "Информация о враче",
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "appointments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Appointment"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deactivated_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "schedule_exceptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DoctorScheduleException"
+                    }
+                },
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.DoctorSchedule"
+                    }
+                },
+                "specialization": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DoctorSchedule": {
+            "description": "Рабочие часы врача",
+            "type": "object",
+            "properties": {
+                "break_end": {
+                    "type": "string"
+                },
+                "break_start": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "integer"
+                },
+                "end_time": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "start_time": {
+                    "type": "string"
+                },
+                "weekday": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.DoctorScheduleDayRequest": {
+            "type": "object",
+            "required": [
+                "end_time",
+                "start_time",
+                "weekday"
+            ],
+            "properties": {
+                "break_end": {
+                    "type": "string"
+                },
+                "break_start": {
+                    "type": "string"
+                },
+                "end_time": {
+                    "type": "string"
+                },
+                "start_time": {
+                    "type": "string"
+                },
+                "weekday": {
+                    "type": "integer",
+                    "maximum": 7,
+                    "minimum": 1
+                }
+            }
+        },
+        "main.DoctorScheduleException": {
+            "description": "Исключение из графика работы врача",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "doctor_id": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.DrugClass": {
+            "description": "Препарат и его фармакологическая группа",
+            "type": "object",
+            "properties": {
+                "class": {
+                    "description": "группа в нормализованном виде",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "drug": {
+                    "description": "название препарата в нормализованном виде",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.DrugInteraction": {
+            "description": "Лекарственное взаимодействие",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "drug_a": {
+                    "description": "препарат или группа в нормализованном виде",
+                    "type": "string"
+                },
+                "drug_b": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "rationale": {
+                    "type": "string"
+                },
+                "severity": {
+                    "description": "minor, moderate, major, contraindicated",
+                    "type": "string"
+                }
+            }
+        },
+        "main.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRAllergyIntolerance": {
+            "description": "Ресурс AllergyIntolerance FHIR R4",
+            "type": "object",
+            "properties": {
+                "clinicalStatus": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "code": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "criticality": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRAnnotation"
+                    }
+                },
+                "onsetDateTime": {
+                    "type": "string"
+                },
+                "patient": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "recordedDate": {
+                    "type": "string"
+                },
+                "resourceType": {
+                    "description": "AllergyIntolerance",
+                    "type": "string"
+                },
+                "verificationStatus": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                }
+            }
+        },
+        "main.FHIRAnnotation": {
+            "type": "object",
+            "properties": {
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRAppointment": {
+            "description": "Ресурс Appointment FHIR R4",
+            "type": "object",
+            "properties": {
+                "cancelationReason": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "minutesDuration": {
+                    "type": "integer"
+                },
+                "participant": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRAppointmentParticipant"
+                    }
+                },
+                "resourceType": {
+                    "description": "Appointment",
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRAppointmentParticipant": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "status": {
+                    "description": "accepted",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRBundle": {
+            "description": "Bundle FHIR R4",
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRBundleEntry"
+                    }
+                },
+                "link": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRBundleLink"
+                    }
+                },
+                "resourceType": {
+                    "description": "Bundle",
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "searchset, transaction-response",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRBundleEntry": {
+            "type": "object",
+            "properties": {
+                "fullUrl": {
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "object"
+                },
+                "response": {
+                    "description": "Результат обработки записи транзакции",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.FHIRBundleResponse"
+                        }
+                    ]
+                },
+                "search": {
+                    "$ref": "#/definitions/main.FHIRBundleSearch"
+                }
+            }
+        },
+        "main.FHIRBundleLink": {
+            "type": "object",
+            "properties": {
+                "relation": {
+                    "description": "self, next, previous",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRBundleRequest": {
+            "type": "object",
+            "properties": {
+                "method": {
+                    "description": "POST",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "тип ресурса",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRBundleResponse": {
+            "type": "object",
+            "properties": {
+                "location": {
+                    "description": "Patient/5",
+                    "type": "string"
+                },
+                "outcome": {
+                    "$ref": "#/definitions/main.FHIROperationOutcome"
+                },
+                "status": {
+                    "description": "\"201 Created\", \"200 OK\"",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRBundleSearch": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "match",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilityImpl": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilityInteraction": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "read, search-type",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilityResource": {
+            "type": "object",
+            "properties": {
+                "interaction": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCapabilityInteraction"
+                    }
+                },
+                "searchParam": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCapabilitySearchParam"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilityRest": {
+            "type": "object",
+            "properties": {
+                "interaction": {
+                    "description": "transaction",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCapabilityInteraction"
+                    }
+                },
+                "mode": {
+                    "description": "server",
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCapabilityResource"
+                    }
+                },
+                "security": {
+                    "$ref": "#/definitions/main.FHIRCapabilitySecurity"
+                }
+            }
+        },
+        "main.FHIRCapabilitySearchParam": {
+            "type": "object",
+            "properties": {
+                "documentation": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "token, string, date, reference",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilitySecurity": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilitySoftware": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCapabilityStatement": {
+            "description": "CapabilityStatement FHIR R4",
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "fhirVersion": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "implementation": {
+                    "$ref": "#/definitions/main.FHIRCapabilityImpl"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "resourceType": {
+                    "description": "CapabilityStatement",
+                    "type": "string"
+                },
+                "rest": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCapabilityRest"
+                    }
+                },
+                "software": {
+                    "$ref": "#/definitions/main.FHIRCapabilitySoftware"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCodeableConcept": {
+            "type": "object",
+            "properties": {
+                "coding": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCoding"
+                    }
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCoding": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "display": {
+                    "type": "string"
+                },
+                "system": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRCondition": {
+            "description": "Ресурс Condition FHIR R4",
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCodeableConcept"
+                    }
+                },
+                "clinicalStatus": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "code": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "encounter": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRAnnotation"
+                    }
+                },
+                "onsetDateTime": {
+                    "type": "string"
+                },
+                "recordedDate": {
+                    "type": "string"
+                },
+                "resourceType": {
+                    "description": "Condition",
+                    "type": "string"
+                },
+                "severity": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "subject": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "verificationStatus": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                }
+            }
+        },
+        "main.FHIRContactPoint": {
+            "type": "object",
+            "properties": {
+                "system": {
+                    "description": "phone, email",
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIREncounter": {
+            "description": "Ресурс Encounter FHIR R4",
+            "type": "object",
+            "properties": {
+                "appointment": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRReference"
+                    }
+                },
+                "class": {
+                    "$ref": "#/definitions/main.FHIRCoding"
+                },
+                "diagnosis": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIREncounterDiagnosis"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "participant": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIREncounterParticipant"
+                    }
+                },
+                "period": {
+                    "$ref": "#/definitions/main.FHIRPeriod"
+                },
+                "resourceType": {
+                    "description": "Encounter",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                }
+            }
+        },
+        "main.FHIREncounterDiagnosis": {
+            "type": "object",
+            "properties": {
+                "condition": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "use": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                }
+            }
+        },
+        "main.FHIREncounterParticipant": {
+            "type": "object",
+            "properties": {
+                "individual": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                }
+            }
+        },
+        "main.FHIRHumanName": {
+            "type": "object",
+            "properties": {
+                "family": {
+                    "type": "string"
+                },
+                "given": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "text": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRObservation": {
+            "description": "Ресурс Observation FHIR R4",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/main.FHIRCodeableConcept"
+                },
+                "effectiveDateTime": {
+                    "type": "string"
+                },
+                "encounter": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "interpretation": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRCodeableConcept"
+                    }
+                },
+                "referenceRange": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRObservationReferenceRange"
+                    }
+                },
+                "resourceType": {
+                    "description": "Observation",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "$ref": "#/definitions/main.FHIRReference"
+                },
+                "valueQuantity": {
+                    "$ref": "#/definitions/main.FHIRQuantity"
+                },
+                "valueString": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRObservationReferenceRange": {
+            "type": "object",
+            "properties": {
+                "high": {
+                    "$ref": "#/definitions/main.FHIRQuantity"
+                },
+                "low": {
+                    "$ref": "#/definitions/main.FHIRQuantity"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIROperationOutcome": {
+            "description": "OperationOutcome FHIR R4",
+            "type": "object",
+            "properties": {
+                "issue": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIROperationOutcomeIssue"
+                    }
+                },
+                "resourceType": {
+                    "description": "OperationOutcome",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIROperationOutcomeIssue": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "invalid, not-found, forbidden, exception, ...",
+                    "type": "string"
+                },
+                "diagnostics": {
+                    "type": "string"
+                },
+                "expression": {
+                    "description": "Путь к элементу запроса, к которому относится ошибка (Bundle.entry[2].resource)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "severity": {
+                    "description": "fatal, error, warning, information",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRPatient": {
+            "description": "Ресурс Patient FHIR R4",
+            "type": "object",
+            "properties": {
+                "birthDate": {
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRHumanName"
+                    }
+                },
+                "resourceType": {
+                    "description": "Patient",
+                    "type": "string"
+                },
+                "telecom": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRContactPoint"
+                    }
+                }
+            }
+        },
+        "main.FHIRPeriod": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRPractitioner": {
+            "description": "Ресурс Practitioner FHIR R4",
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRHumanName"
+                    }
+                },
+                "qualification": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRPractitionerQualification"
+                    }
+                },
+                "resourceType": {
+                    "description": "Practitioner",
+                    "type": "string"
+                },
+                "telecom": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRContactPoint"
+                    }
+                }
+            }
+        },
+        "main.FHIRPractitionerQualification": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "специализация",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.FHIRCodeableConcept"
+                        }
+                    ]
+                }
+            }
+        },
+        "main.FHIRQuantity": {
+            "type": "object",
+            "properties": {
+                "unit": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "main.FHIRReference": {
+            "type": "object",
+            "properties": {
+                "display": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRTransactionBundle": {
+            "description": "Bundle FHIR R4 типа transaction",
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FHIRTransactionEntry"
+                    }
+                },
+                "resourceType": {
+                    "description": "Bundle",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "transaction",
+                    "type": "string"
+                }
+            }
+        },
+        "main.FHIRTransactionEntry": {
+            "type": "object",
+            "properties": {
+                "fullUrl": {
+                    "description": "например, urn:uuid:...; по нему ссылаются другие записи",
+                    "type": "string"
+                },
+                "request": {
+                    "$ref": "#/definitions/main.FHIRBundleRequest"
+                },
+                "resource": {
+                    "type": "object"
+                }
+            }
+        },
+        "main.ICD10Code": {
+            "description": "Код и название по МКБ-10",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.InteractionWarning": {
+            "type": "object",
+            "properties": {
+                "drug": {
+                    "description": "новый препарат",
+                    "type": "string"
+                },
+                "interaction_id": {
+                    "description": "запись базы взаимодействий",
+                    "type": "integer"
+                },
+                "other_drug": {
+                    "description": "препарат действующего назначения",
+                    "type": "string"
+                },
+                "prescription_id": {
+                    "description": "действующее назначение",
+                    "type": "integer"
+                },
+                "rationale": {
+                    "type": "string"
+                },
+                "severity": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MedicalHistory": {
+            "description": "Медицинский анамнез пациента",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "history_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "patient": {
+                    "$ref": "#/definitions/main.Patient"
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "severity": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.MedicalTest": {
+            "description": "Результаты медицинских тестов",
+            "type": "object",
+            "properties": {
+                "appointment": {
+                    "$ref": "#/definitions/main.Appointment"
+                },
+                "appointment_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "critical_range": {
+                    "description": "интервал, вне которого результат критический",
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "flag": {
+                    "description": "N, L, H, LL, HH; пусто, если результат не числовой",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "range_high": {
+                    "description": "верхняя граница референсного интервала",
+                    "type": "number"
+                },
+                "range_high2": {
+                    "type": "number"
+                },
+                "range_low": {
+                    "description": "нижняя граница референсного интервала",
+                    "type": "number"
+                },
+                "range_low2": {
+                    "description": "границы для второй части составного результата",
+                    "type": "number"
+                },
+                "reference_range": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "string"
+                },
+                "result_value": {
+                    "description": "числовой результат; для составного (120/80) - первая часть",
+                    "type": "number"
+                },
+                "result_value2": {
+                    "description": "вторая часть составного результата",
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Appointment": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Appointment"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_AuditLog": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AuditLog"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Doctor": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Doctor"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_MedicalHistory": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.MedicalHistory"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_MedicalTest": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.MedicalTest"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Patient": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Patient"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Page-main_Prescription": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Prescription"
+                    }
+                },
+                "next_cursor": {
+                    "description": "курсор следующей страницы; пуст на последней",
+                    "type": "string"
+                }
+            }
+        },
+        "main.Patient": {
+            "description": "Информация о пациенте",
             "type": "object",
             "properties": {
+                "appointments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Appointment"
+                    }
+                },
+                "birth_date": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "date": {
+                "deleted_at": {
                     "type": "string"
                 },
-                "diagnosis": {
+                "email": {
                     "type": "string"
                 },
-                "doctor": {
-                    "$ref": "#/definitions/main.Doctor"
+                "full_name": {
+                    "type": "string"
                 },
-                "doctor_id": {
-                    "type": "integer"
+                "gender": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "medical_tests": {
+                "medical_history": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.MedicalTest"
+                        "$ref": "#/definitions/main.MedicalHistory"
                     }
                 },
-                "notes": {
-                    "type": "string"
-                },
-                "patient": {
-                    "$ref": "#/definitions/main.Patient"
-                },
-                "patient_id": {
-                    "type": "integer"
-                },
-                "treatment": {
+                "phone": {
                     "type": "string"
                 }
             }
         },
-        "main.CreateAppointmentRequest": {
+        "main.Prescription": {
+            "description": "Назначение препарата на приеме",
             "type": "object",
-            "required": [
-                "date",
-                "doctor_id",
-                "patient_id"
-            ],
             "properties": {
-                "date": {
+                "active": {
+                    "description": "не отменено и не закончилось",
+                    "type": "boolean"
+                },
+                "allergy_warnings": {
+                    "description": "Предупреждения об аллергии пациента на препарат; только в ответе на создание и изменение",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.AllergyWarning"
+                    }
+                },
+                "appointment_id": {
+                    "type": "integer"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "diagnosis": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "discontinue_reason": {
+                    "type": "string"
+                },
+                "discontinued_at": {
                     "type": "string"
                 },
                 "doctor_id": {
+                    "description": "назначивший врач - врач приема",
                     "type": "integer"
                 },
-                "notes": {
+                "dose": {
+                    "type": "number"
+                },
+                "dose_unit": {
+                    "description": "мг, мл, таб, капли, ЕД",
                     "type": "string"
                 },
-                "patient_id": {
+                "drug": {
+                    "type": "string"
+                },
+                "duration_days": {
+                    "description": "0 - срок задан датой окончания или не ограничен",
                     "type": "integer"
                 },
-                "treatment": {
-                    "type": "string"
-                }
-            }
-        },
-        "main.CreateMedicalHistoryRequest": {
-            "type": "object",
-            "required": [
-                "description",
-                "history_type",
-                "patient_id"
-            ],
-            "properties": {
-                "description": {
+                "end_date": {
+                    "description": "нет у бессрочного назначения",
                     "type": "string"
                 },
-                "history_type": {
+                "frequency": {
+                    "description": "\"2 раза в день\", \"каждые 8 часов\"",
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
+                "interaction_warnings": {
+                    "description": "Взаимодействия препарата с другими действующими назначениями пациента; только в ответе на создание и изменение",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.InteractionWarning"
+                    }
+                },
                 "notes": {
                     "type": "string"
                 },
                 "patient_id": {
                     "type": "integer"
                 },
-                "severity": {
+                "route": {
+                    "description": "oral, sublingual, inhalation, iv, im, sc, ...",
                     "type": "string"
                 },
                 "start_date": {
                     "type": "string"
+                }
+            }
+        },
+        "main.ReferenceErrorResponse": {
+            "type": "object",
+            "properties": {
+                "entity": {
+                    "description": "тип отсутствующей записи: patient, doctor, appointment",
+                    "type": "string"
                 },
-                "status": {
+                "error": {
+                    "type": "string"
+                },
+                "field": {
+                    "description": "поле запроса со ссылкой, например patient_id",
                     "type": "string"
+                },
+                "id": {
+                    "type": "integer"
                 }
             }
         },
-        "main.CreatePatientRequest": {
+        "main.RefreshRequest": {
             "type": "object",
             "required": [
-                "birth_date",
-                "full_name",
-                "gender"
+                "refresh_token"
             ],
             "properties": {
-                "birth_date": {
+                "refresh_token": {
                     "type": "string"
-                },
-                "email": {
+                }
+            }
+        },
+        "main.TestSynonym": {
+            "description": "Синоним названия теста",
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "description": "альтернативное название",
                     "type": "string"
                 },
-                "full_name": {
+                "created_at": {
                     "type": "string"
                 },
-                "gender": {
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "phone": {
+                "name": {
+                    "description": "основное название",
                     "type": "string"
                 }
             }
         },
-        "main.Doctor": {
-            "description": "Информация о враче",
+        "main.TestTrend": {
             "type": "object",
             "properties": {
-                "appointments": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.Appointment"
-                    }
+                "excluded": {
+                    "description": "результаты без числового значения или с непереводимой единицей",
+                    "type": "integer"
                 },
-                "created_at": {
+                "name": {
+                    "description": "основное название показателя",
                     "type": "string"
                 },
-                "email": {
-                    "type": "string"
+                "names": {
+                    "description": "названия, считающиеся этим показателем, в нормализованном виде",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "full_name": {
-                    "type": "string"
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.TrendPoint"
+                    }
                 },
-                "id": {
-                    "type": "integer"
+                "stats": {
+                    "description": "нет, если нет ни одной точки",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.TrendStats"
+                        }
+                    ]
                 },
-                "phone": {
-                    "type": "string"
+                "stats2": {
+                    "description": "для второй части составного результата",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.TrendStats"
+                        }
+                    ]
                 },
-                "specialization": {
+                "unit": {
                     "type": "string"
                 }
             }
         },
-        "main.ErrorResponse": {
+        "main.TimeSlot": {
+            "description": "Свободный слот для записи к врачу",
             "type": "object",
             "properties": {
-                "error": {
+                "end": {
+                    "type": "string"
+                },
+                "start": {
                     "type": "string"
                 }
             }
         },
-        "main.MedicalHistory": {
-            "description": "Медицинский анамнез пациента",
+        "main.TokenResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "history_type": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "notes": {
+                "access_token": {
                     "type": "string"
                 },
-                "patient": {
-                    "$ref": "#/definitions/main.Patient"
-                },
-                "patient_id": {
+                "expires_in": {
                     "type": "integer"
                 },
-                "severity": {
-                    "type": "string"
-                },
-                "start_date": {
+                "refresh_token": {
                     "type": "string"
                 },
-                "status": {
+                "token_type": {
                     "type": "string"
                 }
             }
         },
-        "main.MedicalTest": {
-            "description": "Результаты медицинских тестов",
+        "main.TrendPoint": {
             "type": "object",
             "properties": {
-                "appointment": {
-                    "$ref": "#/definitions/main.Appointment"
-                },
                 "appointment_id": {
                     "type": "integer"
                 },
-                "created_at": {
+                "date": {
+                    "description": "дата приема",
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "flag": {
+                    "type": "string"
                 },
                 "name": {
+                    "description": "название теста, как оно записано",
                     "type": "string"
                 },
-                "reference_range": {
-                    "type": "string"
+                "test_id": {
+                    "type": "integer"
                 },
-                "result": {
-                    "type": "string"
+                "value": {
+                    "type": "number"
                 },
-                "unit": {
-                    "type": "string"
+                "value2": {
+                    "description": "вторая часть составного результата",
+                    "type": "number"
                 }
             }
         },
-        "main.Patient": {
-            "description": "Информация о пациенте",
+        "main.TrendStats": {
             "type": "object",
             "properties": {
-                "appointments": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/main.Appointment"
-                    }
+                "max": {
+                    "type": "number"
                 },
-                "birth_date": {
-                    "type": "string"
-                },
-                "created_at": {
-                    "type": "string"
+                "mean": {
+                    "type": "number"
                 },
-                "email": {
-                    "type": "string"
+                "min": {
+                    "type": "number"
                 },
-                "full_name": {
+                "slope_per_day": {
+                    "description": "наклон линейной регрессии, единиц в сутки; 0 для одной точки",
+                    "type": "number"
+                }
+            }
+        },
+        "main.UpdateDiagnosisRequest": {
+            "type": "object",
+            "required": [
+                "kind"
+            ],
+            "properties": {
+                "kind": {
                     "type": "string"
                 },
-                "gender": {
+                "note": {
                     "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "medical_history": {
+                }
+            }
+        },
+        "main.UpdateDoctorScheduleRequest": {
+            "type": "object",
+            "properties": {
+                "days": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/main.MedicalHistory"
+                        "$ref": "#/definitions/main.DoctorScheduleDayRequest"
                     }
-                },
-                "phone": {
-                    "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Access-токен в формате \"Bearer \u003ctoken\u003e\", выдается POST /auth/login",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeleteDoctorReassignTarget(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		other := createTestDoctor(t, "Петров Петр", "Терапевт")
+		retired := createTestDoctor(t, "Сидоров Сидор", "Терапевт")
+		if err := db.Model(&retired).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		tomorrow := time.Now().AddDate(0, 0, 1)
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID,
+			Date: time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local)})
+
+		path := fmt.Sprintf("/doctors/%d?future_appointments=reassign", doctor.ID)
+		for _, query := range []string{
+			"",
+			"&reassign_to=",
+			"&reassign_to=id%3E0",
+			"&reassign_to=1%20OR%201=1",
+			fmt.Sprintf("&reassign_to=%d", doctor.ID),
+			fmt.Sprintf("&reassign_to=%d", retired.ID),
+			"&reassign_to=999999",
+		} {
+			if w := apiRequest(t, admin, http.MethodDelete, path+query, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("DELETE %s: status %d, want 400: %s", path+query, w.Code, w.Body.String())
+			}
+		}
+
+		// Ни один из отклоненных запросов не перевел прием и не уволил врача
+		var stored Appointment
+		if err := db.First(&stored, appointment.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.DoctorID != doctor.ID {
+			t.Errorf("appointment moved to doctor %d", stored.DoctorID)
+		}
+		if err := db.First(&doctor, doctor.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !doctor.Active {
+			t.Error("doctor deactivated by a rejected request")
+		}
+
+		if w := apiRequest(t, admin, http.MethodDelete, path+fmt.Sprintf("&reassign_to=%d", other.ID), nil); w.Code != http.StatusOK {
+			t.Fatalf("reassign to %d: status %d: %s", other.ID, w.Code, w.Body.String())
+		}
+		if err := db.First(&stored, appointment.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.DoctorID != other.ID {
+			t.Errorf("appointment doctor = %d, want %d", stored.DoctorID, other.ID)
+		}
+	})
+}
+
+func TestDeleteDoctorModes(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		tomorrow := time.Now().AddDate(0, 0, 1)
+		at := func(day time.Time, hour int) time.Time {
+			return time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, time.Local)
+		}
+		yesterday := at(time.Now().AddDate(0, 0, -1), 10)
+
+		// setup создает врача с прошедшими, будущими и уже закрытыми приемами.
+		// open - будущие приемы, которые нужно обработать, в порядке времени.
+		setup := func(name string) (doctor Doctor, open, other []Appointment) {
+			t.Helper()
+			doctor = createTestDoctor(t, name, "Терапевт")
+			add := func(date time.Time, status string) Appointment {
+				patient := createTestPatient(t, "Пациент "+name+" "+date.Format("15:04 02.01"), "")
+				return createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: date, Status: status})
+			}
+			open = []Appointment{add(at(tomorrow, 10), statusScheduled), add(at(tomorrow, 14), statusScheduled)}
+			other = []Appointment{
+				add(yesterday, statusCompleted),
+				add(yesterday.Add(time.Hour), statusScheduled),
+				add(at(tomorrow, 12), statusCancelled),
+			}
+			return doctor, open, other
+		}
+		load := func(appointments []Appointment) []Appointment {
+			t.Helper()
+			stored := make([]Appointment, len(appointments))
+			for i, a := range appointments {
+				if err := db.First(&stored[i], a.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			return stored
+		}
+		unchanged := func(mode string, appointments []Appointment) {
+			t.Helper()
+			for i, stored := range load(appointments) {
+				if stored.DoctorID != appointments[i].DoctorID || stored.Status != appointments[i].Status {
+					t.Errorf("%s: appointment %d = doctor %d, %s; want doctor %d, %s", mode, stored.ID,
+						stored.DoctorID, stored.Status, appointments[i].DoctorID, appointments[i].Status)
+				}
+			}
+		}
+		active := func(doctor Doctor) bool {
+			t.Helper()
+			var stored Doctor
+			if err := db.First(&stored, doctor.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Active == (stored.DeactivatedAt != nil) {
+				t.Errorf("doctor %d: active = %v, deactivated_at = %v", stored.ID, stored.Active, stored.DeactivatedAt)
+			}
+			return stored.Active
+		}
+		remove := func(doctor Doctor, query string) *httptest.ResponseRecorder {
+			t.Helper()
+			return apiRequest(t, admin, http.MethodDelete, fmt.Sprintf("/doctors/%d?%s", doctor.ID, query), nil)
+		}
+
+		// block (по умолчанию): отказ со списком будущих приемов
+		doctor, open, other := setup("Иванов Иван")
+		for _, query := range []string{"", "future_appointments=block"} {
+			conflict := decodeResponse[ConflictResponse](t, remove(doctor, query), http.StatusConflict)
+			if want := []uint{open[0].ID, open[1].ID}; !slices.Equal(conflict.ConflictingAppointmentIDs, want) {
+				t.Errorf("block: conflicting = %v, want %v", conflict.ConflictingAppointmentIDs, want)
+			}
+		}
+		if w := remove(doctor, "future_appointments=archive"); w.Code != http.StatusBadRequest {
+			t.Errorf("unknown mode: status %d, want 400", w.Code)
+		}
+		if !active(doctor) {
+			t.Error("block: doctor deactivated despite future appointments")
+		}
+		unchanged("block", append(open, other...))
+
+		// Без будущих приемов block увольняет врача
+		free := createTestDoctor(t, "Козлов Олег", "Терапевт")
+		if w := remove(free, ""); w.Code != http.StatusOK {
+			t.Errorf("block without appointments: status %d: %s", w.Code, w.Body.String())
+		}
+		if active(free) {
+			t.Error("block: doctor without appointments is still active")
+		}
+
+		// cancel: будущие приемы отменяются, прошедшие остаются как были
+		if w := remove(doctor, "future_appointments=cancel"); w.Code != http.StatusOK {
+			t.Fatalf("cancel: status %d: %s", w.Code, w.Body.String())
+		}
+		for _, stored := range load(open) {
+			if stored.Status != statusCancelled || stored.DoctorID != doctor.ID {
+				t.Errorf("cancel: appointment %d = doctor %d, %s; want cancelled", stored.ID, stored.DoctorID, stored.Status)
+			}
+		}
+		unchanged("cancel", other)
+		if active(doctor) {
+			t.Error("cancel: doctor is still active")
+		}
+
+		// reassign: занятое время у нового врача откатывает увольнение целиком
+		doctor, open, other = setup("Петров Петр")
+		target := createTestDoctor(t, "Сидоров Сидор", "Терапевт")
+		busy := createTestAppointment(t, Appointment{PatientID: createTestPatient(t, "Орлова Ольга", "").ID,
+			DoctorID: target.ID, Date: open[1].Date})
+		reassign := fmt.Sprintf("future_appointments=reassign&reassign_to=%d", target.ID)
+		conflict := decodeResponse[ConflictResponse](t, remove(doctor, reassign), http.StatusConflict)
+		if !slices.Equal(conflict.ConflictingAppointmentIDs, []uint{busy.ID}) {
+			t.Errorf("reassign: conflicting = %v, want [%d]", conflict.ConflictingAppointmentIDs, busy.ID)
+		}
+		unchanged("reassign with conflict", append(open, other...))
+		if !active(doctor) {
+			t.Error("reassign: doctor deactivated despite the conflict")
+		}
+
+		if err := db.Delete(&busy).Error; err != nil {
+			t.Fatal(err)
+		}
+		if w := remove(doctor, reassign); w.Code != http.StatusOK {
+			t.Fatalf("reassign: status %d: %s", w.Code, w.Body.String())
+		}
+		for _, stored := range load(open) {
+			if stored.DoctorID != target.ID || stored.Status != statusScheduled {
+				t.Errorf("reassign: appointment %d = doctor %d, %s; want doctor %d, scheduled", stored.ID, stored.DoctorID, stored.Status, target.ID)
+			}
+		}
+		unchanged("reassign", other)
+		if active(doctor) {
+			t.Error("reassign: doctor is still active")
+		}
+	})
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FHIR R4 API. Данные клиники выдаются ресурсами FHIR в формате JSON
+// (application/fhir+json): Patient, Practitioner (врач), Encounter и Appointment (прием),
+// Observation (результат теста), Condition (запись анамнеза и диагноз приема по МКБ-10)
+// и AllergyIntolerance (аллергия из анамнеза). Права доступа, ограничение своими
+// записями и журнал аудита те же, что у основного API. Ошибки возвращаются ресурсом
+// OperationOutcome, результаты поиска - Bundle типа searchset со страницами по _count и _offset.
+
+const (
+	fhirVersion     = "4.0.1"
+	fhirContentType = "application/fhir+json; charset=utf-8"
+	fhirBasePath    = "/fhir/R4"
+)
+
+// Системы кодирования FHIR
+const (
+	fhirSystemICD10               = "http://hl7.org/fhir/sid/icd-10"
+	fhirSystemSNOMED              = "http://snomed.info/sct"
+	fhirSystemActCode             = "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+	fhirSystemInterpretation      = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+	fhirSystemConditionCategory   = "http://terminology.hl7.org/CodeSystem/condition-category"
+	fhirSystemConditionClinical   = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	fhirSystemConditionVerStatus  = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+	fhirSystemAllergyClinical     = "http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical"
+	fhirSystemAllergyVerification = "http://terminology.hl7.org/CodeSystem/allergyintolerance-verification"
+	fhirSystemDiagnosisRole       = "http://terminology.hl7.org/CodeSystem/diagnosis-role"
+)
+
+// Типы данных FHIR
+
+type FHIRCoding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type FHIRCodeableConcept struct {
+	Coding []FHIRCoding `json:"coding,omitempty"`
+	Text   string       `json:"text,omitempty"`
+}
+
+type FHIRReference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type FHIRIdentifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+type FHIRHumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+type FHIRContactPoint struct {
+	System string `json:"system,omitempty"` // phone, email
+	Value  string `json:"value,omitempty"`
+}
+
+type FHIRPeriod struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type FHIRQuantity struct {
+	Value *float64 `json:"value,omitempty"`
+	Unit  string   `json:"unit,omitempty"`
+}
+
+type FHIRAnnotation struct {
+	Text string `json:"text"`
+}
+
+// FHIRBundle - набор ресурсов: результат поиска или ответ на транзакцию
+// @Description Bundle FHIR R4
+type FHIRBundle struct {
+	ResourceType string            `json:"resourceType"` // Bundle
+	Type         string            `json:"type"`         // searchset, transaction-response
+	Total        *int              `json:"total,omitempty"`
+	Link         []FHIRBundleLink  `json:"link,omitempty"`
+	Entry        []FHIRBundleEntry `json:"entry,omitempty"`
+}
+
+type FHIRBundleLink struct {
+	Relation string `json:"relation"` // self, next, previous
+	URL      string `json:"url"`
+}
+
+type FHIRBundleEntry struct {
+	FullURL  string            `json:"fullUrl,omitempty"`
+	Resource interface{}       `json:"resource,omitempty" swaggertype:"object"`
+	Search   *FHIRBundleSearch `json:"search,omitempty"`
+
+	// Результат обработки записи транзакции
+	Response *FHIRBundleResponse `json:"response,omitempty"`
+}
+
+type FHIRBundleSearch struct {
+	Mode string `json:"mode"` // match
+}
+
+type FHIRBundleResponse struct {
+	Status   string                `json:"status"`             // "201 Created", "200 OK"
+	Location string                `json:"location,omitempty"` // Patient/5
+	Outcome  *FHIROperationOutcome `json:"outcome,omitempty"`
+}
+
+// FHIROperationOutcome - описание ошибки обработки запроса
+// @Description OperationOutcome FHIR R4
+type FHIROperationOutcome struct {
+	ResourceType string                      `json:"resourceType"` // OperationOutcome
+	Issue        []FHIROperationOutcomeIssue `json:"issue"`
+}
+
+type FHIROperationOutcomeIssue struct {
+	Severity    string `json:"severity"` // fatal, error, warning, information
+	Code        string `json:"code"`     // invalid, not-found, forbidden, exception, ...
+	Diagnostics string `json:"diagnostics,omitempty"`
+
+	// Путь к элементу запроса, к которому относится ошибка (Bundle.entry[2].resource)
+	Expression []string `json:"expression,omitempty"`
+}
+
+// FHIRCapabilityStatement описывает возможности сервера FHIR
+// @Description CapabilityStatement FHIR R4
+type FHIRCapabilityStatement struct {
+	ResourceType   string                 `json:"resourceType"` // CapabilityStatement
+	Status         string                 `json:"status"`
+	Date           string                 `json:"date"`
+	Kind           string                 `json:"kind"`
+	Software       FHIRCapabilitySoftware `json:"software"`
+	Implementation FHIRCapabilityImpl     `json:"implementation"`
+	FHIRVersion    string                 `json:"fhirVersion"`
+	Format         []string               `json:"format"`
+	Rest           []FHIRCapabilityRest   `json:"rest"`
+}
+
+type FHIRCapabilitySoftware struct {
+	Name string `json:"name"`
+}
+
+type FHIRCapabilityImpl struct {
+	Description string `json:"description"`
+	URL         string `json:"url,omitempty"`
+}
+
+type FHIRCapabilityRest struct {
+	Mode        string                      `json:"mode"` // server
+	Security    *FHIRCapabilitySecurity     `json:"security,omitempty"`
+	Resource    []FHIRCapabilityResource    `json:"resource"`
+	Interaction []FHIRCapabilityInteraction `json:"interaction,omitempty"` // transaction
+}
+
+type FHIRCapabilitySecurity struct {
+	Description string `json:"description"`
+}
+
+type FHIRCapabilityResource struct {
+	Type        string                      `json:"type"`
+	Interaction []FHIRCapabilityInteraction `json:"interaction"`
+	SearchParam []FHIRCapabilitySearchParam `json:"searchParam,omitempty"`
+}
+
+type FHIRCapabilityInteraction struct {
+	Code string `json:"code"` // read, search-type
+}
+
+type FHIRCapabilitySearchParam struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"` // token, string, date, reference
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// fhirResourceTypes - ресурсы, которые выдает сервер, и их параметры поиска
+var fhirResourceTypes = []FHIRCapabilityResource{
+	{Type: "Patient", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "_id", Type: "token"},
+		{Name: "name", Type: "string", Documentation: "Начало любого слова ФИО без учета регистра"},
+		{Name: "birthdate", Type: "date"},
+		{Name: "gender", Type: "token"},
+	}},
+	{Type: "Practitioner", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "_id", Type: "token"},
+		{Name: "name", Type: "string", Documentation: "Начало любого слова ФИО без учета регистра"},
+		{Name: "active", Type: "token"},
+	}},
+	{Type: "Encounter", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "_id", Type: "token"},
+		{Name: "patient", Type: "reference"},
+		{Name: "practitioner", Type: "reference"},
+		{Name: "date", Type: "date"},
+		{Name: "status", Type: "token"},
+	}},
+	{Type: "Appointment", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "_id", Type: "token"},
+		{Name: "patient", Type: "reference"},
+		{Name: "practitioner", Type: "reference"},
+		{Name: "date", Type: "date"},
+		{Name: "status", Type: "token"},
+	}},
+	{Type: "Observation", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "_id", Type: "token"},
+		{Name: "patient", Type: "reference"},
+		{Name: "encounter", Type: "reference"},
+		{Name: "date", Type: "date"},
+	}},
+	{Type: "Condition", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "patient", Type: "reference"},
+		{Name: "encounter", Type: "reference"},
+		{Name: "category", Type: "token", Documentation: "problem-list-item (анамнез) или encounter-diagnosis (диагноз приема)"},
+		{Name: "code", Type: "token", Documentation: "Код МКБ-10 диагноза приема"},
+	}},
+	{Type: "AllergyIntolerance", SearchParam: []FHIRCapabilitySearchParam{
+		{Name: "_id", Type: "token"},
+		{Name: "patient", Type: "reference"},
+	}},
+}
+
+// fhirPaging - параметры страницы поиска
+type fhirPaging struct {
+	count  int
+	offset int
+}
+
+// errFHIRBadReference возвращается при ссылке на ресурс другого типа или с неверным ID
+var errFHIRBadReference = errors.New("invalid reference")
+
+// respondFHIR отправляет ресурс FHIR с типом содержимого application/fhir+json
+func respondFHIR(c *gin.Context, status int, resource interface{}) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(resource); err != nil {
+		status = http.StatusInternalServerError
+		body.Reset()
+		_ = encoder.Encode(fhirOutcome("exception", err.Error()))
+	}
+	c.Data(status, fhirContentType, body.Bytes())
+}
+
+// fhirOutcome создает OperationOutcome с одной ошибкой
+func fhirOutcome(code, diagnostics string) FHIROperationOutcome {
+	return FHIROperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []FHIROperationOutcomeIssue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
+
+// respondFHIRError отправляет ошибку в виде OperationOutcome
+func respondFHIRError(c *gin.Context, status int, code, diagnostics string) {
+	respondFHIR(c, status, fhirOutcome(code, diagnostics))
+}
+
+// requireFHIRPermission - аналог requirePermission для маршрутов FHIR: отказ возвращается
+// ресурсом OperationOutcome
+func requireFHIRPermission(p permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if currentUser(c).scope(p) == scopeNone {
+			respondFHIRError(c, http.StatusForbidden, "forbidden", "access denied")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// fhirAuthorize проверяет право на запись; при отказе отвечает 403
+func fhirAuthorize(c *gin.Context, p permission, r resource) bool {
+	if currentUser(c).can(p, r) {
+		return true
+	}
+	respondFHIRError(c, http.StatusForbidden, "forbidden", "access denied")
+	return false
+}
+
+// fhirNotFound отвечает 404 для ресурса resourceType с идентификатором id
+func fhirNotFound(c *gin.Context, resourceType, id string) {
+	respondFHIRError(c, http.StatusNotFound, "not-found", fmt.Sprintf("%s/%s not found", resourceType, id))
+}
+
+// fhirBaseURL возвращает адрес сервера FHIR для fullUrl и ссылок Bundle
+func fhirBaseURL(c *gin.Context) string {
+	return fhirOrigin(c) + fhirBasePath
+}
+
+// fhirOrigin возвращает схему и хост запроса с учетом прокси (X-Forwarded-Proto)
+func fhirOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// fhirRef возвращает относительную ссылку на ресурс ("Patient/5")
+func fhirRef(resourceType string, id uint) *FHIRReference {
+	return &FHIRReference{Reference: resourceType + "/" + strconv.FormatUint(uint64(id), 10)}
+}
+
+// fhirDateTime форматирует момент времени как dateTime FHIR; нулевое время - пустая строка
+func fhirDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// fhirDate форматирует дату как date FHIR
+func fhirDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// fhirHumanName разбирает ФИО: первое слово - фамилия, остальные - имя и отчество
+func fhirHumanName(fullName string) []FHIRHumanName {
+	words := strings.Fields(fullName)
+	if len(words) == 0 {
+		return nil
+	}
+	name := FHIRHumanName{Use: "official", Text: strings.Join(words, " "), Family: words[0]}
+	if len(words) > 1 {
+		name.Given = words[1:]
+	}
+	return []FHIRHumanName{name}
+}
+
+// fhirTelecom возвращает телефон и email
+func fhirTelecom(phone, email string) []FHIRContactPoint {
+	var telecom []FHIRContactPoint
+	if phone != "" {
+		telecom = append(telecom, FHIRContactPoint{System: "phone", Value: phone})
+	}
+	if email != "" {
+		telecom = append(telecom, FHIRContactPoint{System: "email", Value: email})
+	}
+	return telecom
+}
+
+// parseFHIRID разбирает числовой идентификатор ресурса
+func parseFHIRID(id string) (uint, bool) {
+	n, err := strconv.ParseUint(id, 10, 0)
+	return uint(n), err == nil && n > 0
+}
+
+// parseFHIRReference разбирает значение параметра-ссылки: "5", "Patient/5" или полный адрес
+func parseFHIRReference(value, resourceType string) (uint, error) {
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		prefix := strings.TrimSuffix(value[:i], "/")
+		if prefix != resourceType && !strings.HasSuffix(prefix, "/"+resourceType) {
+			return 0, errFHIRBadReference
+		}
+		value = value[i+1:]
+	}
+	id, ok := parseFHIRID(value)
+	if !ok {
+		return 0, errFHIRBadReference
+	}
+	return id, nil
+}
+
+// fhirReferenceFilter добавляет условие по параметру-ссылке name, если он задан
+func fhirReferenceFilter(c *gin.Context, query *gorm.DB, name, resourceType, column string) (*gorm.DB, error) {
+	value := c.Query(name)
+	if value == "" {
+		return query, nil
+	}
+	id, err := parseFHIRReference(value, resourceType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid '%s' parameter: expected %s reference", name, resourceType)
+	}
+	return query.Where(column+" = ?", id), nil
+}
+
+// fhirIDFilter добавляет условие по параметру _id (несколько ID через запятую)
+func fhirIDFilter(c *gin.Context, query *gorm.DB, column string) (*gorm.DB, error) {
+	value := c.Query("_id")
+	if value == "" {
+		return query, nil
+	}
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		id, ok := parseFHIRID(strings.TrimSpace(part))
+		if !ok {
+			return nil, errors.New("invalid '_id' parameter")
+		}
+		ids = append(ids, id)
+	}
+	return query.Where(column+" IN ?", ids), nil
+}
+
+// fhirDateFilter добавляет условия по параметру-дате name. Значение - дата (ГГГГ-ММ-ДД) или
+// момент RFC3339 с необязательным префиксом eq, ne, gt, ge, lt, le; дата без времени
+// обозначает весь день. Параметр можно повторить: date=ge2024-01-01&date=lt2024-02-01.
+func fhirDateFilter(c *gin.Context, query *gorm.DB, name, column string) (*gorm.DB, error) {
+	for _, value := range c.QueryArray(name) {
+		prefix := "eq"
+		if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+			prefix, value = value[:2], value[2:]
+		}
+		start, err := parseTimeParam(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' parameter", name)
+		}
+		end := start.Add(time.Second)
+		if len(value) == len("2006-01-02") {
+			end = start.AddDate(0, 0, 1)
+		}
+
+		switch prefix {
+		case "eq":
+			query = query.Where(column+" >= ? AND "+column+" < ?", start, end)
+		case "ne":
+			query = query.Where("("+column+" < ? OR "+column+" >= ?)", start, end)
+		case "gt":
+			query = query.Where(column+" >= ?", end)
+		case "ge":
+			query = query.Where(column+" >= ?", start)
+		case "lt":
+			query = query.Where(column+" < ?", start)
+		case "le":
+			query = query.Where(column+" < ?", end)
+		default:
+			return nil, fmt.Errorf("invalid '%s' parameter: unsupported prefix %q", name, prefix)
+		}
+	}
+	return query, nil
+}
+
+// fhirNameFilter добавляет условие параметра name: каждое слово значения - начало слова
+// нормализованного ФИО в столбце column
+func fhirNameFilter(c *gin.Context, query *gorm.DB, column string) *gorm.DB {
+	for _, word := range strings.Fields(normalizeSearchText(c.Query("name"))) {
+		query = query.Where("(' ' || "+column+") LIKE ?", "% "+word+"%")
+	}
+	return query
+}
+
+// parseFHIRPaging разбирает параметры страницы _count и _offset
+func parseFHIRPaging(c *gin.Context) (fhirPaging, error) {
+	paging := fhirPaging{count: defaultPageLimit}
+	if value := c.Query("_count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > maxPageLimit {
+			return paging, fmt.Errorf("_count must be between 0 and %d", maxPageLimit)
+		}
+		paging.count = n
+	}
+	if value := c.Query("_offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return paging, errors.New("_offset must be a non-negative integer")
+		}
+		paging.offset = n
+	}
+	return paging, nil
+}
+
+// fhirEntry создает запись Bundle для найденного ресурса
+func fhirEntry(c *gin.Context, resourceType, id string, resource interface{}) FHIRBundleEntry {
+	return FHIRBundleEntry{
+		FullURL:  fhirBaseURL(c) + "/" + resourceType + "/" + id,
+		Resource: resource,
+		Search:   &FHIRBundleSearch{Mode: "match"},
+	}
+}
+
+// fhirSearchBundle собирает Bundle результатов поиска со ссылками на соседние страницы
+func fhirSearchBundle(c *gin.Context, total int, paging fhirPaging, entries []FHIRBundleEntry) FHIRBundle {
+	link := func(relation string, offset int) FHIRBundleLink {
+		params := c.Request.URL.Query()
+		params.Set("_count", strconv.Itoa(paging.count))
+		params.Set("_offset", strconv.Itoa(offset))
+		u := url.URL{Path: c.Request.URL.Path, RawQuery: params.Encode()}
+		return FHIRBundleLink{Relation: relation, URL: fhirOrigin(c) + u.String()}
+	}
+
+	bundle := FHIRBundle{ResourceType: "Bundle", Type: "searchset", Total: &total, Entry: entries}
+	bundle.Link = append(bundle.Link, link("self", paging.offset))
+	if paging.count > 0 && paging.offset+paging.count < total {
+		bundle.Link = append(bundle.Link, link("next", paging.offset+paging.count))
+	}
+	if paging.offset > 0 {
+		prev := paging.offset - paging.count
+		if prev < 0 {
+			prev = 0
+		}
+		bundle.Link = append(bundle.Link, link("previous", prev))
+	}
+	return bundle
+}
+
+// fhirConverter преобразует запись в ресурс FHIR: возвращает ID ресурса, сам ресурс и
+// ссылки на записи, раскрытые в ресурсе, для журнала аудита
+type fhirConverter[T any] func(c *gin.Context, record T) (string, interface{}, []auditRef)
+
+// fhirSearch выполняет поиск: считает найденные записи, выбирает страницу, записывает
+// чтение в журнал аудита и отвечает Bundle
+func fhirSearch[T any](c *gin.Context, query *gorm.DB, order, resourceType string, convert fhirConverter[T]) {
+	paging, err := parseFHIRPaging(c)
+	if err != nil {
+		respondFHIRError(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	var total int64
+	var records []T
+	if err := query.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error; err != nil {
+		respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+		return
+	}
+	if paging.count > 0 {
+		if err := query.Order(order).Offset(paging.offset).Limit(paging.count).Find(&records).Error; err != nil {
+			respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+			return
+		}
+	}
+
+	var refs []auditRef
+	entries := make([]FHIRBundleEntry, len(records))
+	for i, record := range records {
+		id, resource, recordRefs := convert(c, record)
+		entries[i] = fhirEntry(c, resourceType, id, resource)
+		refs = append(refs, recordRefs...)
+	}
+	if !auditRead(c, refs...) {
+		return
+	}
+	respondFHIR(c, http.StatusOK, fhirSearchBundle(c, int(total), paging, entries))
+}
+
+// fhirRead отвечает ресурсом одной записи и записывает чтение в журнал аудита
+func fhirRead[T any](c *gin.Context, record T, convert fhirConverter[T]) {
+	_, resource, refs := convert(c, record)
+	if !auditRead(c, refs...) {
+		return
+	}
+	respondFHIR(c, http.StatusOK, resource)
+}
+
+// GetFHIRMetadata godoc
+// @Summary CapabilityStatement сервера FHIR
+// @Description Описание возможностей сервера FHIR R4: поддерживаемые ресурсы, операции и параметры поиска. Доступно без аутентификации
+// @Tags fhir
+// @Produce json
+// @Success 200 {object} FHIRCapabilityStatement
+// @Router /fhir/R4/metadata [get]
+func getFHIRMetadata(c *gin.Context) {
+	resources := make([]FHIRCapabilityResource, len(fhirResourceTypes))
+	for i, r := range fhirResourceTypes {
+		r.Interaction = []FHIRCapabilityInteraction{{Code: "read"}, {Code: "search-type"}}
+		resources[i] = r
+	}
+	respondFHIR(c, http.StatusOK, FHIRCapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         fhirDateTime(time.Now()),
+		Kind:         "instance",
+		Software:     FHIRCapabilitySoftware{Name: "demeda"},
+		Implementation: FHIRCapabilityImpl{
+			Description: "Medical clinic API",
+			URL:         fhirBaseURL(c),
+		},
+		FHIRVersion: fhirVersion,
+		Format:      []string{"json"},
+		Rest: []FHIRCapabilityRest{{
+			Mode:        "server",
+			Security:    &FHIRCapabilitySecurity{Description: "Bearer access token issued by POST /auth/login"},
+			Resource:    resources,
+			Interaction: []FHIRCapabilityInteraction{{Code: "transaction"}},
+		}},
+	})
+}
@@ -0,0 +1,883 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Загрузка данных из других клиник: POST /fhir/R4 принимает Bundle типа transaction
+// с ресурсами Patient, Practitioner, Encounter, Observation и Condition и записывает их
+// как пациентов, врачей, приемы, тесты и записи анамнеза в одной транзакции. Ресурсы
+// с уже известным идентификатором (identifier) не создаются повторно, а сопоставляются
+// с загруженной ранее записью. Если хотя бы одна запись Bundle не загружена, транзакция
+// откатывается, и в ответе перечисляются ошибки всех записей.
+
+// historyTypeCondition - тип записи анамнеза, загруженной из ресурса Condition
+const historyTypeCondition = "condition"
+
+// fhirImportOrder - порядок обработки ресурсов: ресурс загружается после тех, на которые ссылается
+var fhirImportOrder = []string{"Patient", "Practitioner", "Encounter", "Observation", "Condition"}
+
+// fhirEncounterImportStatuses сопоставляет статус Encounter статусу приема
+var fhirEncounterImportStatuses = map[string]string{
+	"planned":     statusScheduled,
+	"arrived":     statusCheckedIn,
+	"triaged":     statusCheckedIn,
+	"in-progress": statusInProgress,
+	"onleave":     statusInProgress,
+	"finished":    statusCompleted,
+	"cancelled":   statusCancelled,
+}
+
+// ExternalIdentifier связывает запись клиники с ее идентификатором во внешней системе
+type ExternalIdentifier struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt    time.Time `json:"-"`
+	ResourceType string    `gorm:"not null;uniqueIndex:idx_external_identifiers_key" json:"resource_type"` // Patient, Practitioner, ...
+	System       string    `gorm:"not null;uniqueIndex:idx_external_identifiers_key" json:"system"`
+	Value        string    `gorm:"not null;uniqueIndex:idx_external_identifiers_key" json:"value"`
+	EntityID     uint      `gorm:"not null;index" json:"entity_id"`
+}
+
+// FHIRTransactionBundle - Bundle типа transaction для загрузки
+// @Description Bundle FHIR R4 типа transaction
+type FHIRTransactionBundle struct {
+	ResourceType string                 `json:"resourceType"` // Bundle
+	Type         string                 `json:"type"`         // transaction
+	Entry        []FHIRTransactionEntry `json:"entry"`
+}
+
+type FHIRTransactionEntry struct {
+	FullURL  string             `json:"fullUrl,omitempty"` // например, urn:uuid:...; по нему ссылаются другие записи
+	Resource json.RawMessage    `json:"resource" swaggertype:"object"`
+	Request  *FHIRBundleRequest `json:"request"`
+}
+
+type FHIRBundleRequest struct {
+	Method string `json:"method"` // POST
+	URL    string `json:"url"`    // тип ресурса
+}
+
+// Входные ресурсы: ресурсы чтения с идентификаторами и полями, которые сервер не выдает
+
+type fhirPatientInput struct {
+	FHIRPatient
+	Identifier []FHIRIdentifier `json:"identifier"`
+}
+
+type fhirPractitionerInput struct {
+	FHIRPractitioner
+	Identifier []FHIRIdentifier `json:"identifier"`
+	Active     *bool            `json:"active"`
+}
+
+type fhirEncounterInput struct {
+	FHIREncounter
+	Identifier []FHIRIdentifier `json:"identifier"`
+}
+
+type fhirObservationInput struct {
+	FHIRObservation
+	Identifier           []FHIRIdentifier     `json:"identifier"`
+	ValueCodeableConcept *FHIRCodeableConcept `json:"valueCodeableConcept"`
+}
+
+type fhirConditionInput struct {
+	FHIRCondition
+	Identifier []FHIRIdentifier `json:"identifier"`
+}
+
+// fhirEntryError - ошибка загрузки одной записи Bundle; Code - код issue OperationOutcome
+type fhirEntryError struct {
+	Code    string
+	Message string
+}
+
+func (e *fhirEntryError) Error() string { return e.Message }
+
+func fhirInvalid(format string, args ...interface{}) error {
+	return &fhirEntryError{Code: "invalid", Message: fmt.Sprintf(format, args...)}
+}
+
+// errFHIRImportFailed откатывает транзакцию загрузки, если хотя бы одна запись не загружена
+var errFHIRImportFailed = errors.New("bundle import failed")
+
+// fhirImportEntry - запись Bundle и результат ее загрузки
+type fhirImportEntry struct {
+	ResourceType string
+	Resource     json.RawMessage
+	ID           uint // ID созданной или найденной записи; 0, пока запись не загружена
+	Matched      bool // запись найдена по идентификатору
+	Identifier   string
+	Err          error
+}
+
+// fhirImporter загружает записи одного Bundle
+type fhirImporter struct {
+	c       *gin.Context
+	entries []fhirImportEntry
+	byURL   map[string]int // fullUrl записи (и ссылка вида Patient/id для полного адреса) -> номер записи
+}
+
+// parseTransactionBundle проверяет Bundle и определяет тип ресурса каждой записи
+func parseTransactionBundle(bundle FHIRTransactionBundle) (*fhirImporter, []FHIROperationOutcomeIssue) {
+	if bundle.ResourceType != "Bundle" || bundle.Type != "transaction" {
+		return nil, []FHIROperationOutcomeIssue{{Severity: "error", Code: "invalid", Diagnostics: "expected a Bundle of type transaction"}}
+	}
+
+	imp := &fhirImporter{entries: make([]fhirImportEntry, len(bundle.Entry)), byURL: map[string]int{}}
+	var issues []FHIROperationOutcomeIssue
+	for i, entry := range bundle.Entry {
+		var header struct {
+			ResourceType string `json:"resourceType"`
+		}
+		err := json.Unmarshal(entry.Resource, &header)
+		switch {
+		case err != nil || header.ResourceType == "":
+			err = fhirInvalid("resource must be a JSON object with resourceType")
+		case !fhirImportable(header.ResourceType):
+			err = &fhirEntryError{Code: "not-supported", Message: fmt.Sprintf("resource type %s cannot be imported", header.ResourceType)}
+		case entry.Request == nil || entry.Request.Method != http.MethodPost:
+			err = &fhirEntryError{Code: "not-supported", Message: "only POST requests are supported"}
+		case entry.Request.URL != "" && strings.SplitN(entry.Request.URL, "?", 2)[0] != header.ResourceType:
+			err = fhirInvalid("request url %q does not match resource type %s", entry.Request.URL, header.ResourceType)
+		}
+		if err != nil {
+			issues = append(issues, fhirEntryIssue(i, err))
+			continue
+		}
+
+		imp.entries[i] = fhirImportEntry{ResourceType: header.ResourceType, Resource: entry.Resource}
+		if entry.FullURL != "" {
+			if _, ok := imp.byURL[entry.FullURL]; ok {
+				issues = append(issues, fhirEntryIssue(i, fhirInvalid("duplicate fullUrl %q", entry.FullURL)))
+				continue
+			}
+			imp.byURL[entry.FullURL] = i
+			// Полный адрес http://host/fhir/Patient/123 позволяет ссылаться на запись как Patient/123
+			if j := strings.LastIndex(entry.FullURL, "/"+header.ResourceType+"/"); j >= 0 && strings.Contains(entry.FullURL, "://") {
+				imp.byURL[entry.FullURL[j+1:]] = i
+			}
+		}
+	}
+	return imp, issues
+}
+
+// fhirImportable проверяет, что ресурс этого типа можно загрузить
+func fhirImportable(resourceType string) bool {
+	for _, t := range fhirImportOrder {
+		if t == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// fhirEntryIssue описывает ошибку записи Bundle с номером i
+func fhirEntryIssue(i int, err error) FHIROperationOutcomeIssue {
+	issue := FHIROperationOutcomeIssue{
+		Severity:    "error",
+		Code:        "exception",
+		Diagnostics: err.Error(),
+		Expression:  []string{fmt.Sprintf("Bundle.entry[%d]", i)},
+	}
+	var entryErr *fhirEntryError
+	if errors.As(err, &entryErr) {
+		issue.Code = entryErr.Code
+	}
+	return issue
+}
+
+// run загружает записи в порядке fhirImportOrder. Каждая запись загружается во вложенной
+// транзакции (точке сохранения), поэтому после ошибки одной записи остальные продолжают
+// проверяться; ошибки, не относящиеся к данным записи, прерывают загрузку.
+func (imp *fhirImporter) run(tx *gorm.DB) error {
+	failed := false
+	for _, resourceType := range fhirImportOrder {
+		for i := range imp.entries {
+			entry := &imp.entries[i]
+			if entry.ResourceType != resourceType {
+				continue
+			}
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return imp.load(tx, entry)
+			})
+			var entryErr *fhirEntryError
+			switch {
+			case errors.As(err, &entryErr):
+				entry.ID, entry.Err, failed = 0, err, true
+			case err != nil:
+				return err
+			}
+		}
+	}
+	if failed {
+		return errFHIRImportFailed
+	}
+	return nil
+}
+
+// load загружает одну запись Bundle
+func (imp *fhirImporter) load(tx *gorm.DB, entry *fhirImportEntry) error {
+	switch entry.ResourceType {
+	case "Patient":
+		var r fhirPatientInput
+		if err := json.Unmarshal(entry.Resource, &r); err != nil {
+			return fhirInvalid("invalid Patient: %v", err)
+		}
+		return imp.loadPatient(tx, entry, r)
+	case "Practitioner":
+		var r fhirPractitionerInput
+		if err := json.Unmarshal(entry.Resource, &r); err != nil {
+			return fhirInvalid("invalid Practitioner: %v", err)
+		}
+		return imp.loadPractitioner(tx, entry, r)
+	case "Encounter":
+		var r fhirEncounterInput
+		if err := json.Unmarshal(entry.Resource, &r); err != nil {
+			return fhirInvalid("invalid Encounter: %v", err)
+		}
+		return imp.loadEncounter(tx, entry, r)
+	case "Observation":
+		var r fhirObservationInput
+		if err := json.Unmarshal(entry.Resource, &r); err != nil {
+			return fhirInvalid("invalid Observation: %v", err)
+		}
+		return imp.loadObservation(tx, entry, r)
+	default:
+		var r fhirConditionInput
+		if err := json.Unmarshal(entry.Resource, &r); err != nil {
+			return fhirInvalid("invalid Condition: %v", err)
+		}
+		return imp.loadCondition(tx, entry, r)
+	}
+}
+
+// require возвращает ошибку записи Bundle, если у пользователя нет права p на запись r
+func (imp *fhirImporter) require(p permission, r resource) error {
+	if currentUser(imp.c).can(p, r) {
+		return nil
+	}
+	return &fhirEntryError{Code: "forbidden", Message: "access denied"}
+}
+
+// match ищет загруженную ранее запись по идентификаторам ресурса. Возвращает 0, если ни один
+// идентификатор не известен.
+func (imp *fhirImporter) match(tx *gorm.DB, entry *fhirImportEntry, model interface{}, identifiers []FHIRIdentifier) (uint, error) {
+	var found uint
+	for _, identifier := range identifiers {
+		if identifier.Value == "" {
+			return 0, fhirInvalid("identifier.value is required")
+		}
+		var known ExternalIdentifier
+		err := tx.Where("resource_type = ? AND system = ? AND value = ?", entry.ResourceType, identifier.System, identifier.Value).
+			Take(&known).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if found != 0 && found != known.EntityID {
+			return 0, &fhirEntryError{Code: "conflict", Message: fmt.Sprintf("identifiers match different records: %s/%d and %s/%d",
+				entry.ResourceType, found, entry.ResourceType, known.EntityID)}
+		}
+		found = known.EntityID
+		entry.Identifier = fhirIdentifierToken(identifier)
+	}
+	if found == 0 {
+		return 0, nil
+	}
+
+	var count int64
+	if err := tx.Model(model).Where("id = ?", found).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, &fhirEntryError{Code: "conflict", Message: fmt.Sprintf("identifier %s belongs to deleted %s/%d; restore it before importing",
+			entry.Identifier, entry.ResourceType, found)}
+	}
+	return found, nil
+}
+
+// saveIdentifiers связывает идентификаторы ресурса с записью
+func saveIdentifiers(tx *gorm.DB, resourceType string, id uint, identifiers []FHIRIdentifier) error {
+	for _, identifier := range identifiers {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ExternalIdentifier{
+			ResourceType: resourceType,
+			System:       identifier.System,
+			Value:        identifier.Value,
+			EntityID:     id,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fhirIdentifierToken записывает идентификатор как токен поиска FHIR: system|value
+func fhirIdentifierToken(identifier FHIRIdentifier) string {
+	if identifier.System == "" {
+		return identifier.Value
+	}
+	return identifier.System + "|" + identifier.Value
+}
+
+// resolve возвращает ID записи, на которую ссылается ref. Ссылка может указывать на запись
+// того же Bundle (fullUrl), на запись клиники (Patient/5) или на загруженную ранее запись
+// по идентификатору (Patient?identifier=system|value).
+func (imp *fhirImporter) resolve(tx *gorm.DB, ref *FHIRReference, resourceType, path string) (uint, error) {
+	if ref == nil || ref.Reference == "" {
+		return 0, fhirInvalid("%s is required", path)
+	}
+	if i, ok := imp.byURL[ref.Reference]; ok {
+		target := imp.entries[i]
+		switch {
+		case target.ResourceType != resourceType:
+			return 0, fhirInvalid("%s must reference %s, got %s", path, resourceType, target.ResourceType)
+		case target.ID == 0:
+			return 0, fhirInvalid("%s references Bundle.entry[%d], which was not imported", path, i)
+		}
+		return target.ID, nil
+	}
+
+	model, entity := fhirImportModel(resourceType)
+	if token, ok := strings.CutPrefix(ref.Reference, resourceType+"?identifier="); ok {
+		identifier := FHIRIdentifier{Value: token}
+		if system, value, found := strings.Cut(token, "|"); found {
+			identifier = FHIRIdentifier{System: system, Value: value}
+		}
+		var known ExternalIdentifier
+		err := tx.Where("resource_type = ? AND system = ? AND value = ?", resourceType, identifier.System, identifier.Value).
+			Take(&known).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, &fhirEntryError{Code: "not-found", Message: fmt.Sprintf("%s: no %s with identifier %s", path, resourceType, token)}
+		}
+		if err != nil {
+			return 0, err
+		}
+		return known.EntityID, requireFHIRReference(tx, model, path, entity, known.EntityID)
+	}
+
+	id, err := parseFHIRReference(ref.Reference, resourceType)
+	if err != nil {
+		return 0, fhirInvalid("%s: invalid %s reference %q", path, resourceType, ref.Reference)
+	}
+	return id, requireFHIRReference(tx, model, path, entity, id)
+}
+
+// requireFHIRReference - requireReference с ошибкой записи Bundle
+func requireFHIRReference(tx *gorm.DB, model interface{}, path, entity string, id uint) error {
+	err := requireReference(tx, model, path, entity, id)
+	var missing *missingReferenceError
+	if errors.As(err, &missing) {
+		return &fhirEntryError{Code: "not-found", Message: missing.Error()}
+	}
+	return err
+}
+
+// fhirImportModel возвращает модель и название сущности для типа ресурса
+func fhirImportModel(resourceType string) (interface{}, string) {
+	switch resourceType {
+	case "Patient":
+		return &Patient{}, "patient"
+	case "Practitioner":
+		return &Doctor{}, "doctor"
+	case "Encounter":
+		return &Appointment{}, "appointment"
+	case "Observation":
+		return &MedicalTest{}, "medical test"
+	default:
+		return &MedicalHistory{}, "medical history record"
+	}
+}
+
+// matched отмечает запись найденной по идентификатору и связывает с ней новые идентификаторы
+func (imp *fhirImporter) matched(tx *gorm.DB, entry *fhirImportEntry, id uint, identifiers []FHIRIdentifier) error {
+	entry.ID, entry.Matched = id, true
+	return saveIdentifiers(tx, entry.ResourceType, id, identifiers)
+}
+
+// created отмечает запись созданной и сохраняет ее идентификаторы
+func (imp *fhirImporter) created(tx *gorm.DB, entry *fhirImportEntry, id uint, identifiers []FHIRIdentifier) error {
+	entry.ID = id
+	return saveIdentifiers(tx, entry.ResourceType, id, identifiers)
+}
+
+// fhirFullName собирает ФИО из имени FHIR: фамилия, затем имена; text - если частей нет
+func fhirFullName(names []FHIRHumanName) string {
+	if len(names) == 0 {
+		return ""
+	}
+	name := names[0]
+	for _, n := range names {
+		if n.Use == "official" {
+			name = n
+			break
+		}
+	}
+	parts := append([]string{name.Family}, name.Given...)
+	if fullName := strings.Join(strings.Fields(strings.Join(parts, " ")), " "); fullName != "" {
+		return fullName
+	}
+	return strings.Join(strings.Fields(name.Text), " ")
+}
+
+// fhirContact возвращает первые телефон и email
+func fhirContact(telecom []FHIRContactPoint) (phone, email string) {
+	for _, t := range telecom {
+		switch {
+		case t.System == "phone" && phone == "":
+			phone = t.Value
+		case t.System == "email" && email == "":
+			email = t.Value
+		}
+	}
+	return phone, email
+}
+
+// fhirConceptText возвращает текст понятия: text, иначе название или код первого кода
+func fhirConceptText(concept FHIRCodeableConcept) string {
+	if concept.Text != "" {
+		return concept.Text
+	}
+	for _, coding := range concept.Coding {
+		if coding.Display != "" {
+			return coding.Display
+		}
+		if coding.Code != "" {
+			return coding.Code
+		}
+	}
+	return ""
+}
+
+// parseFHIRDateTime разбирает date или dateTime FHIR
+func parseFHIRDateTime(value, path string) (time.Time, error) {
+	t, err := parseTimeParam(value)
+	if err != nil {
+		return time.Time{}, fhirInvalid("%s: expected YYYY-MM-DD or RFC3339 date-time, got %q", path, value)
+	}
+	return t, nil
+}
+
+func (imp *fhirImporter) loadPatient(tx *gorm.DB, entry *fhirImportEntry, r fhirPatientInput) error {
+	id, err := imp.match(tx, entry, &Patient{}, r.Identifier)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if err := imp.require(permPatientsRead, resource{PatientID: id}); err != nil {
+			return err
+		}
+		return imp.matched(tx, entry, id, r.Identifier)
+	}
+	if err := imp.require(permPatientsWrite, resource{}); err != nil {
+		return err
+	}
+
+	patient := Patient{FullName: fhirFullName(r.Name), Gender: r.Gender}
+	patient.Phone, patient.Email = fhirContact(r.Telecom)
+	if patient.FullName == "" {
+		return fhirInvalid("Patient.name is required")
+	}
+	if patient.Gender != "male" && patient.Gender != "female" {
+		return fhirInvalid("Patient.gender must be male or female")
+	}
+	if r.BirthDate == "" {
+		return fhirInvalid("Patient.birthDate is required")
+	}
+	if patient.BirthDate, err = time.Parse("2006-01-02", r.BirthDate); err != nil {
+		return fhirInvalid("Patient.birthDate: expected YYYY-MM-DD, got %q", r.BirthDate)
+	}
+
+	if err := tx.Create(&patient).Error; err != nil {
+		return err
+	}
+	if err := recordAudit(tx, imp.c, auditActionCreate, patient.auditRef()); err != nil {
+		return err
+	}
+	return imp.created(tx, entry, patient.ID, r.Identifier)
+}
+
+func (imp *fhirImporter) loadPractitioner(tx *gorm.DB, entry *fhirImportEntry, r fhirPractitionerInput) error {
+	id, err := imp.match(tx, entry, &Doctor{}, r.Identifier)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if err := imp.require(permDoctorsRead, resource{DoctorID: id}); err != nil {
+			return err
+		}
+		return imp.matched(tx, entry, id, r.Identifier)
+	}
+	if err := imp.require(permDoctorsWrite, resource{}); err != nil {
+		return err
+	}
+
+	doctor := Doctor{FullName: fhirFullName(r.Name), Active: r.Active == nil || *r.Active}
+	doctor.Phone, doctor.Email = fhirContact(r.Telecom)
+	if len(r.Qualification) > 0 {
+		doctor.Specialization = fhirConceptText(r.Qualification[0].Code)
+	}
+	if doctor.FullName == "" {
+		return fhirInvalid("Practitioner.name is required")
+	}
+	if doctor.Specialization == "" {
+		return fhirInvalid("Practitioner.qualification is required: specialization is taken from its code")
+	}
+	if !doctor.Active {
+		now := time.Now()
+		doctor.DeactivatedAt = &now
+	}
+
+	if err := tx.Create(&doctor).Error; err != nil {
+		return err
+	}
+	return imp.created(tx, entry, doctor.ID, r.Identifier)
+}
+
+// loadEncounter загружает визит как прием. Запланированный визит (planned) проходит те же
+// проверки, что и запись на прием; визиты в других статусах уже состоялись в другой клинике
+// и записываются без проверки расписания и пересечений.
+func (imp *fhirImporter) loadEncounter(tx *gorm.DB, entry *fhirImportEntry, r fhirEncounterInput) error {
+	id, err := imp.match(tx, entry, &Appointment{}, r.Identifier)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		var existing Appointment
+		if err := tx.First(&existing, id).Error; err != nil {
+			return err
+		}
+		if err := imp.require(permAppointmentsRead, appointmentResource(existing)); err != nil {
+			return err
+		}
+		return imp.matched(tx, entry, id, r.Identifier)
+	}
+
+	status, ok := fhirEncounterImportStatuses[r.Status]
+	if !ok {
+		return fhirInvalid("Encounter.status %q cannot be imported", r.Status)
+	}
+	appointment := Appointment{Status: status}
+	if appointment.PatientID, err = imp.resolve(tx, r.Subject, "Patient", "Encounter.subject"); err != nil {
+		return err
+	}
+	var practitioner *FHIRReference
+	for _, p := range r.Participant {
+		if p.Individual != nil {
+			practitioner = p.Individual
+			break
+		}
+	}
+	if appointment.DoctorID, err = imp.resolve(tx, practitioner, "Practitioner", "Encounter.participant.individual"); err != nil {
+		return err
+	}
+	if r.Period == nil || r.Period.Start == "" {
+		return fhirInvalid("Encounter.period.start is required")
+	}
+	if appointment.Date, err = parseFHIRDateTime(r.Period.Start, "Encounter.period.start"); err != nil {
+		return err
+	}
+	appointment.EndDate = appointment.Date.Add(appointmentDuration(0))
+	if r.Period.End != "" {
+		if appointment.EndDate, err = parseFHIRDateTime(r.Period.End, "Encounter.period.end"); err != nil {
+			return err
+		}
+		if !appointment.EndDate.After(appointment.Date) {
+			return fhirInvalid("Encounter.period.end must be after period.start")
+		}
+	}
+	if err := imp.require(permAppointmentsWrite, appointmentResource(appointment)); err != nil {
+		return err
+	}
+
+	if status == statusScheduled {
+		err = validateAndSaveAppointment(tx, &appointment)
+	} else {
+		err = tx.Create(&appointment).Error
+	}
+	if err != nil {
+		return fhirBookingError(err)
+	}
+	if err := recordAudit(tx, imp.c, auditActionCreate, appointment.auditRef()); err != nil {
+		return err
+	}
+	return imp.created(tx, entry, appointment.ID, r.Identifier)
+}
+
+// fhirBookingError превращает ошибку записи на прием в ошибку записи Bundle
+func fhirBookingError(err error) error {
+	var missing *missingReferenceError
+	var conflict *bookingConflictError
+	switch {
+	case errors.As(err, &missing):
+		return &fhirEntryError{Code: "not-found", Message: err.Error()}
+	case errors.Is(err, errOutsideWorkingHours), errors.Is(err, errDoctorInactive):
+		return &fhirEntryError{Code: "business-rule", Message: err.Error()}
+	case errors.As(err, &conflict):
+		return &fhirEntryError{Code: "conflict", Message: err.Error()}
+	}
+	return err
+}
+
+func (imp *fhirImporter) loadObservation(tx *gorm.DB, entry *fhirImportEntry, r fhirObservationInput) error {
+	id, err := imp.match(tx, entry, &MedicalTest{}, r.Identifier)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		var existing MedicalTest
+		if err := tx.Preload("Appointment").First(&existing, id).Error; err != nil {
+			return err
+		}
+		if err := imp.require(permTestsRead, appointmentResource(existing.Appointment)); err != nil {
+			return err
+		}
+		return imp.matched(tx, entry, id, r.Identifier)
+	}
+
+	test := MedicalTest{Name: fhirConceptText(r.Code)}
+	if test.Name == "" {
+		return fhirInvalid("Observation.code is required")
+	}
+	if test.AppointmentID, err = imp.resolve(tx, r.Encounter, "Encounter", "Observation.encounter"); err != nil {
+		return err
+	}
+	var appointment Appointment
+	if err := tx.First(&appointment, test.AppointmentID).Error; err != nil {
+		return err
+	}
+	if r.Subject != nil {
+		patientID, err := imp.resolve(tx, r.Subject, "Patient", "Observation.subject")
+		if err != nil {
+			return err
+		}
+		if patientID != appointment.PatientID {
+			return fhirInvalid("Observation.subject does not match the patient of Observation.encounter")
+		}
+	}
+	if err := imp.require(permTestsWrite, appointmentResource(appointment)); err != nil {
+		return err
+	}
+
+	switch {
+	case r.ValueQuantity != nil && r.ValueQuantity.Value != nil:
+		test.Result = strconv.FormatFloat(*r.ValueQuantity.Value, 'f', -1, 64)
+		test.Unit = r.ValueQuantity.Unit
+	case r.ValueString != "":
+		test.Result = r.ValueString
+	case r.ValueCodeableConcept != nil:
+		test.Result = fhirConceptText(*r.ValueCodeableConcept)
+	}
+	if len(r.ReferenceRange) > 0 {
+		test.ReferenceRange = fhirRangeText(r.ReferenceRange[0])
+	}
+
+	if err := tx.Create(&test).Error; err != nil {
+		return err
+	}
+	if err := recordAudit(tx, imp.c, auditActionCreate, test.auditRef()); err != nil {
+		return err
+	}
+	return imp.created(tx, entry, test.ID, r.Identifier)
+}
+
+// fhirRangeText записывает референсный интервал в виде, который разбирает parseLabRange
+func fhirRangeText(r FHIRObservationReferenceRange) string {
+	format := func(q *FHIRQuantity) string {
+		if q == nil || q.Value == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*q.Value, 'f', -1, 64)
+	}
+	low, high := format(r.Low), format(r.High)
+	switch {
+	case low != "" && high != "":
+		return low + "-" + high
+	case high != "":
+		return "<" + high
+	case low != "":
+		return ">" + low
+	}
+	return r.Text
+}
+
+// loadCondition загружает состояние как запись анамнеза типа condition. Код МКБ-10, если он
+// есть, добавляется к описанию.
+func (imp *fhirImporter) loadCondition(tx *gorm.DB, entry *fhirImportEntry, r fhirConditionInput) error {
+	id, err := imp.match(tx, entry, &MedicalHistory{}, r.Identifier)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		var existing MedicalHistory
+		if err := tx.First(&existing, id).Error; err != nil {
+			return err
+		}
+		if err := imp.require(permHistoryRead, resource{PatientID: existing.PatientID}); err != nil {
+			return err
+		}
+		return imp.matched(tx, entry, id, r.Identifier)
+	}
+
+	history := MedicalHistory{
+		HistoryType: historyTypeCondition,
+		Description: fhirConceptText(r.Code),
+		Status:      "active",
+	}
+	for _, coding := range r.Code.Coding {
+		if coding.System == fhirSystemICD10 && coding.Code != "" && !strings.Contains(history.Description, coding.Code) {
+			history.Description = strings.TrimSpace(coding.Code + " " + history.Description)
+		}
+	}
+	if history.Description == "" {
+		return fhirInvalid("Condition.code is required")
+	}
+	if history.PatientID, err = imp.resolve(tx, &r.Subject, "Patient", "Condition.subject"); err != nil {
+		return err
+	}
+	if r.ClinicalStatus != nil {
+		for _, coding := range r.ClinicalStatus.Coding {
+			if coding.Code == "inactive" || coding.Code == "remission" || coding.Code == "resolved" {
+				history.Status = "resolved"
+			}
+		}
+	}
+	if r.Severity != nil {
+		history.Severity = fhirSeverityName(*r.Severity)
+	}
+	if r.OnsetDateTime != "" {
+		if history.StartDate, err = parseFHIRDateTime(r.OnsetDateTime, "Condition.onsetDateTime"); err != nil {
+			return err
+		}
+	}
+	var notes []string
+	for _, note := range r.Note {
+		notes = append(notes, note.Text)
+	}
+	history.Notes = strings.Join(notes, "\n")
+	if err := imp.require(permHistoryWrite, resource{PatientID: history.PatientID}); err != nil {
+		return err
+	}
+
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+	if err := recordAudit(tx, imp.c, auditActionCreate, history.auditRef()); err != nil {
+		return err
+	}
+	return imp.created(tx, entry, history.ID, r.Identifier)
+}
+
+// fhirSeverityName возвращает тяжесть (mild, moderate, severe) по коду SNOMED CT или тексту
+func fhirSeverityName(concept FHIRCodeableConcept) string {
+	for name, severity := range fhirSeverities {
+		for _, coding := range concept.Coding {
+			if coding.System == severity.System && coding.Code == severity.Code {
+				return name
+			}
+		}
+		if strings.EqualFold(concept.Text, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// response описывает результат загрузки записи для ответа transaction-response
+func (imp *fhirImporter) response(entry fhirImportEntry) *FHIRBundleResponse {
+	id := strconv.FormatUint(uint64(entry.ID), 10)
+	if entry.ResourceType == "Condition" {
+		id = fhirConditionHistoryPrefix + id
+	}
+	location := entry.ResourceType + "/" + id
+	if !entry.Matched {
+		return &FHIRBundleResponse{Status: "201 Created", Location: location}
+	}
+	return &FHIRBundleResponse{
+		Status:   "200 OK",
+		Location: location,
+		Outcome: &FHIROperationOutcome{
+			ResourceType: "OperationOutcome",
+			Issue: []FHIROperationOutcomeIssue{{
+				Severity:    "information",
+				Code:        "duplicate",
+				Diagnostics: fmt.Sprintf("matched existing %s by identifier %s", location, entry.Identifier),
+			}},
+		},
+	}
+}
+
+// ImportFHIRBundle godoc
+// @Summary Загрузить Bundle FHIR
+// @Description Загрузить данные из другой клиники: Bundle типа transaction с ресурсами Patient, Practitioner, Encounter, Observation и Condition (только POST). Ресурсы записываются как пациенты, врачи, приемы, тесты и записи анамнеза в одной транзакции. Ресурс с известным идентификатором (identifier) не создается повторно, а сопоставляется с загруженной ранее записью. Ссылки между ресурсами задаются через fullUrl записей, на записи клиники (Patient/5) или по идентификатору (Patient?identifier=system|value). Для создания записей нужны права на их изменение. Если хотя бы одна запись не загружена, ничего не сохраняется, а OperationOutcome перечисляет ошибки всех записей
+// @Tags fhir
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param bundle body FHIRTransactionBundle true "Bundle типа transaction"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 422 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4 [post]
+func importFHIRBundle(c *gin.Context) {
+	var bundle FHIRTransactionBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		respondFHIRError(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	imp, issues := parseTransactionBundle(bundle)
+	if len(issues) > 0 {
+		respondFHIR(c, http.StatusBadRequest, FHIROperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+		return
+	}
+	imp.c = c
+
+	err := db.Transaction(imp.run)
+	if errors.Is(err, errFHIRImportFailed) {
+		status := http.StatusUnprocessableEntity
+		outcome := FHIROperationOutcome{ResourceType: "OperationOutcome"}
+		for i, entry := range imp.entries {
+			if entry.Err == nil {
+				continue
+			}
+			issue := fhirEntryIssue(i, entry.Err)
+			switch issue.Code {
+			case "forbidden":
+				status = http.StatusForbidden
+			case "invalid":
+				if status != http.StatusForbidden {
+					status = http.StatusBadRequest
+				}
+			}
+			outcome.Issue = append(outcome.Issue, issue)
+		}
+		respondFHIR(c, status, outcome)
+		return
+	}
+	if err != nil {
+		respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+		return
+	}
+
+	response := FHIRBundle{ResourceType: "Bundle", Type: "transaction-response", Entry: make([]FHIRBundleEntry, len(imp.entries))}
+	for i, entry := range imp.entries {
+		response.Entry[i] = FHIRBundleEntry{Response: imp.response(entry)}
+	}
+	respondFHIR(c, http.StatusOK, response)
+}
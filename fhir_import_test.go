@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fhirObject - ресурс или запись Bundle в виде JSON-объекта
+type fhirObject = map[string]any
+
+// transactionEntry возвращает запись Bundle с запросом POST для ресурса
+func transactionEntry(fullURL string, resource fhirObject) fhirObject {
+	entry := fhirObject{
+		"resource": resource,
+		"request":  fhirObject{"method": "POST", "url": resource["resourceType"]},
+	}
+	if fullURL != "" {
+		entry["fullUrl"] = fullURL
+	}
+	return entry
+}
+
+// importBundle загружает Bundle типа transaction, проверяет статус и возвращает ответ,
+// проверенный по схеме FHIR
+func importBundle(t *testing.T, user User, status int, entries ...fhirObject) []byte {
+	t.Helper()
+	bundle := fhirObject{"resourceType": "Bundle", "type": "transaction", "entry": entries}
+	w := apiRequest(t, user, http.MethodPost, fhirBasePath, bundle)
+	if w.Code != status {
+		t.Fatalf("POST %s: status = %d, want %d: %s", fhirBasePath, w.Code, status, w.Body.String())
+	}
+	validateFHIR(t, w.Body.Bytes())
+	return w.Body.Bytes()
+}
+
+// importIssues загружает Bundle, который должен быть отклонен, и возвращает ошибки записей
+func importIssues(t *testing.T, user User, status int, entries ...fhirObject) []FHIROperationOutcomeIssue {
+	t.Helper()
+	var outcome FHIROperationOutcome
+	if err := json.Unmarshal(importBundle(t, user, status, entries...), &outcome); err != nil {
+		t.Fatal(err)
+	}
+	return outcome.Issue
+}
+
+// importResponses загружает Bundle и возвращает результаты записей
+func importResponses(t *testing.T, user User, entries ...fhirObject) []FHIRBundleResponse {
+	t.Helper()
+	var bundle FHIRBundle
+	if err := json.Unmarshal(importBundle(t, user, http.StatusOK, entries...), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Entry) != len(entries) {
+		t.Fatalf("%d entries in the response, want %d", len(bundle.Entry), len(entries))
+	}
+	responses := make([]FHIRBundleResponse, len(bundle.Entry))
+	for i, entry := range bundle.Entry {
+		if entry.Response == nil {
+			t.Fatalf("entry %d without response", i)
+		}
+		responses[i] = *entry.Response
+	}
+	return responses
+}
+
+// locationID возвращает ID записи из location ответа (Patient/5)
+func locationID(t *testing.T, response FHIRBundleResponse, resourceType string) uint {
+	t.Helper()
+	value, ok := strings.CutPrefix(response.Location, resourceType+"/")
+	if !ok {
+		t.Fatalf("location %q is not a %s", response.Location, resourceType)
+	}
+	id, ok := parseFHIRID(strings.TrimPrefix(value, fhirConditionHistoryPrefix))
+	if !ok {
+		t.Fatalf("location %q has no numeric ID", response.Location)
+	}
+	return id
+}
+
+// rowCounts возвращает число записей в таблицах, которые заполняет загрузка
+func rowCounts(t *testing.T) map[string]int64 {
+	t.Helper()
+	counts := map[string]int64{}
+	for _, table := range []string{"patients", "doctors", "appointments", "medical_tests", "medical_histories", "external_identifiers", "audit_logs"} {
+		var n int64
+		if err := db.Table(table).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		counts[table] = n
+	}
+	return counts
+}
+
+func fhirTestPatient(identifier string) fhirObject {
+	return fhirObject{
+		"resourceType": "Patient",
+		"identifier":   []fhirObject{{"system": "urn:oid:1.2.643.5.1.13", "value": identifier}},
+		"name":         []fhirObject{{"family": "Смирнова", "given": []string{"Анна", "Петровна"}}},
+		"gender":       "female",
+		"birthDate":    "1980-05-17",
+		"telecom":      []fhirObject{{"system": "phone", "value": "+7 912 345-67-89"}},
+	}
+}
+
+func fhirTestPractitioner(identifier string) fhirObject {
+	return fhirObject{
+		"resourceType":  "Practitioner",
+		"identifier":    []fhirObject{{"system": "urn:oid:1.2.643.5.1.13.2.1", "value": identifier}},
+		"name":          []fhirObject{{"family": "Иванов", "given": []string{"Иван"}}},
+		"qualification": []fhirObject{{"code": fhirObject{"text": "Терапевт"}}},
+	}
+}
+
+func fhirTestEncounter(status, patient, practitioner string, start time.Time) fhirObject {
+	return fhirObject{
+		"resourceType": "Encounter",
+		"status":       status,
+		"class":        fhirObject{"system": "http://terminology.hl7.org/CodeSystem/v3-ActCode", "code": "AMB"},
+		"subject":      fhirObject{"reference": patient},
+		"participant":  []fhirObject{{"individual": fhirObject{"reference": practitioner}}},
+		"period":       fhirObject{"start": start.Format(time.RFC3339), "end": start.Add(30 * time.Minute).Format(time.RFC3339)},
+	}
+}
+
+func TestFHIRImportResolvesReferences(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		patientURL := "urn:uuid:6f1c3b2a-0d5e-4c1a-9b7e-2f6a8c9d0e11"
+		practitionerURL := "urn:uuid:0a9b8c7d-6e5f-4a3b-8c2d-1e0f9a8b7c6d"
+		encounterURL := "urn:uuid:d4c3b2a1-f0e9-4d8c-b7a6-958473625140"
+
+		// Записи перечислены в обратном порядке: ссылки разрешаются независимо от порядка в Bundle
+		entries := []fhirObject{
+			transactionEntry("", fhirObject{
+				"resourceType":  "Condition",
+				"subject":       fhirObject{"reference": patientURL},
+				"code":          fhirObject{"coding": []fhirObject{{"system": fhirSystemICD10, "code": "I10", "display": "Гипертензия"}}},
+				"onsetDateTime": "2015-03-01",
+			}),
+			transactionEntry("", fhirObject{
+				"resourceType":  "Observation",
+				"status":        "final",
+				"code":          fhirObject{"text": "Калий"},
+				"subject":       fhirObject{"reference": patientURL},
+				"encounter":     fhirObject{"reference": encounterURL},
+				"valueQuantity": fhirObject{"value": 4.2, "unit": "ммоль/л"},
+				"referenceRange": []fhirObject{{
+					"low":  fhirObject{"value": 3.5},
+					"high": fhirObject{"value": 5.1},
+				}},
+			}),
+			transactionEntry(encounterURL, fhirTestEncounter("finished", patientURL, practitionerURL, testTime(10, 0))),
+			transactionEntry(practitionerURL, fhirTestPractitioner("D-1")),
+			transactionEntry(patientURL, fhirTestPatient("P-1")),
+		}
+		responses := importResponses(t, admin, entries...)
+		for i, r := range responses {
+			if r.Status != "201 Created" {
+				t.Errorf("entry %d: status %q, want 201 Created", i, r.Status)
+			}
+		}
+
+		patientID := locationID(t, responses[4], "Patient")
+		doctorID := locationID(t, responses[3], "Practitioner")
+		var appointment Appointment
+		if err := db.First(&appointment, locationID(t, responses[2], "Encounter")).Error; err != nil {
+			t.Fatal(err)
+		}
+		if appointment.PatientID != patientID || appointment.DoctorID != doctorID || appointment.Status != statusCompleted {
+			t.Errorf("appointment = %+v, want patient %d, doctor %d, completed", appointment, patientID, doctorID)
+		}
+		var test MedicalTest
+		if err := db.First(&test, locationID(t, responses[1], "Observation")).Error; err != nil {
+			t.Fatal(err)
+		}
+		if test.AppointmentID != appointment.ID || test.Result != "4.2" || test.Unit != "ммоль/л" || test.ReferenceRange != "3.5-5.1" {
+			t.Errorf("test = %+v", test)
+		}
+		var history MedicalHistory
+		if err := db.First(&history, locationID(t, responses[0], "Condition")).Error; err != nil {
+			t.Fatal(err)
+		}
+		if history.PatientID != patientID || history.HistoryType != historyTypeCondition || !strings.HasPrefix(history.Description, "I10") {
+			t.Errorf("history = %+v", history)
+		}
+
+		// Ссылки на записи клиники и по идентификатору загруженной ранее записи
+		responses = importResponses(t, admin, transactionEntry("", fhirTestEncounter("finished",
+			"Patient?identifier=urn:oid:1.2.643.5.1.13|P-1", fmt.Sprintf("Practitioner/%d", doctorID), testTime(15, 0))))
+		var referenced Appointment
+		if err := db.First(&referenced, locationID(t, responses[0], "Encounter")).Error; err != nil {
+			t.Fatal(err)
+		}
+		if referenced.PatientID != patientID || referenced.DoctorID != doctorID {
+			t.Errorf("appointment = %+v, want patient %d, doctor %d", referenced, patientID, doctorID)
+		}
+
+		issues := importIssues(t, admin, http.StatusBadRequest,
+			transactionEntry("", fhirTestEncounter("finished", "urn:uuid:unknown", fmt.Sprintf("Practitioner/%d", doctorID), testTime(16, 0))))
+		if len(issues) != 1 || issues[0].Code != "invalid" {
+			t.Errorf("issues for an unknown urn:uuid = %+v, want invalid", issues)
+		}
+	})
+}
+
+func TestFHIRImportMatchesIdentifiers(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		patientURL := "urn:uuid:11111111-2222-4333-8444-555555555555"
+		practitionerURL := "urn:uuid:66666666-7777-4888-9999-000000000000"
+		encounter := fhirTestEncounter("finished", patientURL, practitionerURL, testTime(10, 0))
+		encounter["identifier"] = []fhirObject{{"system": "https://other-clinic.example/visits", "value": "V-1"}}
+		entries := []fhirObject{
+			transactionEntry(patientURL, fhirTestPatient("P-1")),
+			transactionEntry(practitionerURL, fhirTestPractitioner("D-1")),
+			transactionEntry("", encounter),
+		}
+		first := importResponses(t, admin, entries...)
+		before := rowCounts(t)
+
+		// Повторная загрузка того же Bundle сопоставляет записи, а не создает копии
+		second := importResponses(t, admin, entries...)
+		for i := range entries {
+			if second[i].Status != "200 OK" || second[i].Location != first[i].Location {
+				t.Errorf("entry %d: %q %q, want 200 OK %q", i, second[i].Status, second[i].Location, first[i].Location)
+			}
+			if second[i].Outcome == nil || len(second[i].Outcome.Issue) != 1 || second[i].Outcome.Issue[0].Code != "duplicate" {
+				t.Errorf("entry %d: outcome %+v, want duplicate", i, second[i].Outcome)
+			}
+		}
+		after := rowCounts(t)
+		for _, table := range []string{"patients", "doctors", "appointments", "external_identifiers"} {
+			if after[table] != before[table] {
+				t.Errorf("%s: %d rows after the second import, want %d", table, after[table], before[table])
+			}
+		}
+
+		// Новый идентификатор известного пациента привязывается к той же записи
+		patient := fhirTestPatient("P-1")
+		patient["identifier"] = append(patient["identifier"].([]fhirObject), fhirObject{"system": "urn:snils", "value": "112-233-445 95"})
+		if got := importResponses(t, admin, transactionEntry("", patient)); got[0].Location != first[0].Location {
+			t.Errorf("patient with an extra identifier: %q, want %q", got[0].Location, first[0].Location)
+		}
+		byNewIdentifier := fhirTestPatient("")
+		byNewIdentifier["identifier"] = []fhirObject{{"system": "urn:snils", "value": "112-233-445 95"}}
+		if got := importResponses(t, admin, transactionEntry("", byNewIdentifier)); got[0].Status != "200 OK" || got[0].Location != first[0].Location {
+			t.Errorf("patient by the new identifier: %q %q, want 200 OK %q", got[0].Status, got[0].Location, first[0].Location)
+		}
+
+		// Идентификатор удаленного пациента: загрузка отклоняется, удаленная запись не дублируется
+		patientID := locationID(t, first[0], "Patient")
+		if w := apiRequest(t, admin, http.MethodDelete, fmt.Sprintf("/patients/%d", patientID), nil); w.Code != http.StatusOK {
+			t.Fatalf("DELETE patient: status %d: %s", w.Code, w.Body.String())
+		}
+		before = rowCounts(t)
+		issues := importIssues(t, admin, http.StatusUnprocessableEntity, transactionEntry("", fhirTestPatient("P-1")))
+		if len(issues) != 1 || issues[0].Code != "conflict" || !strings.Contains(issues[0].Diagnostics, "deleted") ||
+			len(issues[0].Expression) != 1 || issues[0].Expression[0] != "Bundle.entry[0]" {
+			t.Errorf("issues = %+v, want a conflict with the deleted patient", issues)
+		}
+		if after := rowCounts(t); after["patients"] != before["patients"] {
+			t.Errorf("patients: %d rows, want %d", after["patients"], before["patients"])
+		}
+
+		// Идентификаторы, указывающие на разные записи
+		other := importResponses(t, admin, transactionEntry("", fhirTestPractitioner("D-2")))
+		both := fhirTestPractitioner("D-1")
+		both["identifier"] = append(both["identifier"].([]fhirObject), fhirTestPractitioner("D-2")["identifier"].([]fhirObject)...)
+		issues = importIssues(t, admin, http.StatusUnprocessableEntity, transactionEntry("", both))
+		if len(issues) != 1 || issues[0].Code != "conflict" || !strings.Contains(issues[0].Diagnostics, other[0].Location) {
+			t.Errorf("issues = %+v, want a conflict between two practitioners", issues)
+		}
+	})
+}
+
+func TestFHIRImportRollsBack(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patientURL := "urn:uuid:aaaaaaaa-bbbb-4ccc-8ddd-eeeeeeeeeeee"
+		before := rowCounts(t)
+
+		// Пациент и врач корректны, визит ссылается на несуществующего врача, у состояния нет кода
+		issues := importIssues(t, admin, http.StatusBadRequest,
+			transactionEntry(patientURL, fhirTestPatient("P-1")),
+			transactionEntry("", fhirTestPractitioner("D-1")),
+			transactionEntry("", fhirTestEncounter("finished", patientURL, "Practitioner/999999", testTime(10, 0))),
+			transactionEntry("", fhirTestEncounter("finished", patientURL, fmt.Sprintf("Practitioner/%d", doctor.ID), testTime(11, 0))),
+			transactionEntry("", fhirObject{"resourceType": "Condition", "subject": fhirObject{"reference": patientURL}, "code": fhirObject{}}),
+		)
+		want := map[string]string{"Bundle.entry[2]": "not-found", "Bundle.entry[4]": "invalid"}
+		if len(issues) != len(want) {
+			t.Errorf("issues = %+v, want errors of entries 2 and 4", issues)
+		}
+		for _, issue := range issues {
+			if len(issue.Expression) != 1 || want[issue.Expression[0]] != issue.Code {
+				t.Errorf("unexpected issue %+v", issue)
+			}
+		}
+
+		// Ни одна запись, включая корректные, и ни одна запись журнала аудита не сохранены
+		after := rowCounts(t)
+		for table, n := range before {
+			if after[table] != n {
+				t.Errorf("%s: %d rows after a failed import, want %d", table, after[table], n)
+			}
+		}
+		if got := verifyAudit(t); !got.Valid {
+			t.Errorf("audit chain after a failed import: %+v", got)
+		}
+
+		// Тот же Bundle без ошибочных записей загружается
+		responses := importResponses(t, admin,
+			transactionEntry(patientURL, fhirTestPatient("P-1")),
+			transactionEntry("", fhirTestEncounter("finished", patientURL, fmt.Sprintf("Practitioner/%d", doctor.ID), testTime(11, 0))),
+		)
+		if responses[0].Status != "201 Created" || responses[1].Status != "201 Created" {
+			t.Errorf("responses = %+v", responses)
+		}
+	})
+}
+
+func TestFHIRImportPermissions(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		otherDoctor := createTestDoctor(t, "Петров Петр", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		registrar := createTestUser(t, roleRegistrar, 0)
+		before := rowCounts(t)
+
+		// Регистратор создает пациентов, но не записи анамнеза: Bundle отклоняется целиком
+		patientURL := "urn:uuid:12345678-1234-4234-8234-123456789012"
+		issues := importIssues(t, registrar, http.StatusForbidden,
+			transactionEntry(patientURL, fhirTestPatient("P-1")),
+			transactionEntry("", fhirObject{"resourceType": "Condition", "subject": fhirObject{"reference": patientURL}, "code": fhirObject{"text": "Гастрит"}}),
+			transactionEntry("", fhirTestPractitioner("D-1")),
+		)
+		want := map[string]string{"Bundle.entry[1]": "forbidden", "Bundle.entry[2]": "forbidden"}
+		if len(issues) != len(want) {
+			t.Errorf("issues = %+v, want forbidden entries 1 and 2", issues)
+		}
+		for _, issue := range issues {
+			if len(issue.Expression) != 1 || want[issue.Expression[0]] != issue.Code {
+				t.Errorf("unexpected issue %+v", issue)
+			}
+		}
+
+		// Врач загружает визиты только к себе
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		patientRef := fmt.Sprintf("Patient/%d", patient.ID)
+		issues = importIssues(t, user, http.StatusForbidden,
+			transactionEntry("", fhirTestEncounter("finished", patientRef, fmt.Sprintf("Practitioner/%d", doctor.ID), testTime(10, 0))),
+			transactionEntry("", fhirTestEncounter("finished", patientRef, fmt.Sprintf("Practitioner/%d", otherDoctor.ID), testTime(11, 0))),
+		)
+		if len(issues) != 1 || issues[0].Code != "forbidden" || issues[0].Expression[0] != "Bundle.entry[1]" {
+			t.Errorf("issues = %+v, want forbidden entry 1", issues)
+		}
+
+		after := rowCounts(t)
+		for table, n := range before {
+			if after[table] != n {
+				t.Errorf("%s: %d rows after a forbidden import, want %d", table, after[table], n)
+			}
+		}
+
+		// Пациенту и аудитору загрузка недоступна
+		for _, user := range []User{createTestUser(t, rolePatient, patient.ID), createTestUser(t, roleAuditor, 0)} {
+			issues := importIssues(t, user, http.StatusForbidden, transactionEntry("", fhirTestPatient("P-2")))
+			if len(issues) != 1 || issues[0].Code != "forbidden" {
+				t.Errorf("%s: issues = %+v, want forbidden", user.Role, issues)
+			}
+		}
+	})
+}
+
+func TestFHIRImportPlannedEncounter(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+		existing := createTestAppointment(t, Appointment{PatientID: otherPatient.ID, DoctorID: doctor.ID, Date: testTime(10, 0)})
+		patientRef, doctorRef := fmt.Sprintf("Patient/%d", patient.ID), fmt.Sprintf("Practitioner/%d", doctor.ID)
+
+		tests := []struct {
+			name  string
+			start time.Time
+			code  string
+		}{
+			{"overlaps an existing appointment", testTime(10, 15), "conflict"},
+			{"outside working hours", testTime(21, 0), "business-rule"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				issues := importIssues(t, admin, http.StatusUnprocessableEntity,
+					transactionEntry("", fhirTestEncounter("planned", patientRef, doctorRef, tt.start)))
+				if len(issues) != 1 || issues[0].Code != tt.code {
+					t.Errorf("issues = %+v, want %s", issues, tt.code)
+				}
+				if tt.code == "conflict" && !strings.Contains(issues[0].Diagnostics, fmt.Sprint(existing.ID)) {
+					t.Errorf("conflict %q does not name appointment %d", issues[0].Diagnostics, existing.ID)
+				}
+			})
+		}
+
+		// Состоявшийся визит в то же время записывается без проверки расписания
+		responses := importResponses(t, admin,
+			transactionEntry("", fhirTestEncounter("finished", patientRef, doctorRef, testTime(10, 15))),
+			transactionEntry("", fhirTestEncounter("planned", patientRef, doctorRef, testTime(11, 0))),
+		)
+		var planned Appointment
+		if err := db.First(&planned, locationID(t, responses[1], "Encounter")).Error; err != nil {
+			t.Fatal(err)
+		}
+		if planned.Status != statusScheduled || !planned.Date.Equal(testTime(11, 0)) || !planned.EndDate.Equal(testTime(11, 30)) {
+			t.Errorf("planned appointment = %+v", planned)
+		}
+
+		// Запланированный визит к неактивному врачу
+		if err := db.Model(&doctor).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		issues := importIssues(t, admin, http.StatusUnprocessableEntity,
+			transactionEntry("", fhirTestEncounter("planned", patientRef, doctorRef, testTime(15, 0))))
+		if len(issues) != 1 || issues[0].Code != "business-rule" {
+			t.Errorf("issues = %+v, want business-rule", issues)
+		}
+	})
+}
@@ -0,0 +1,1011 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Ресурсы FHIR R4, в которые преобразуются записи клиники. Поля, которых нет в данных,
+// не заполняются.
+
+// FHIRPatient - пациент
+// @Description Ресурс Patient FHIR R4
+type FHIRPatient struct {
+	ResourceType string             `json:"resourceType"` // Patient
+	ID           string             `json:"id"`
+	Name         []FHIRHumanName    `json:"name,omitempty"`
+	Telecom      []FHIRContactPoint `json:"telecom,omitempty"`
+	Gender       string             `json:"gender,omitempty"`
+	BirthDate    string             `json:"birthDate,omitempty"`
+}
+
+// FHIRPractitioner - врач
+// @Description Ресурс Practitioner FHIR R4
+type FHIRPractitioner struct {
+	ResourceType  string                          `json:"resourceType"` // Practitioner
+	ID            string                          `json:"id"`
+	Active        bool                            `json:"active"`
+	Name          []FHIRHumanName                 `json:"name,omitempty"`
+	Telecom       []FHIRContactPoint              `json:"telecom,omitempty"`
+	Qualification []FHIRPractitionerQualification `json:"qualification,omitempty"`
+}
+
+type FHIRPractitionerQualification struct {
+	Code FHIRCodeableConcept `json:"code"` // специализация
+}
+
+// FHIREncounter - состоявшийся или запланированный прием как визит пациента
+// @Description Ресурс Encounter FHIR R4
+type FHIREncounter struct {
+	ResourceType string                     `json:"resourceType"` // Encounter
+	ID           string                     `json:"id"`
+	Status       string                     `json:"status"`
+	Class        FHIRCoding                 `json:"class"`
+	Subject      *FHIRReference             `json:"subject,omitempty"`
+	Participant  []FHIREncounterParticipant `json:"participant,omitempty"`
+	Appointment  []FHIRReference            `json:"appointment,omitempty"`
+	Period       *FHIRPeriod                `json:"period,omitempty"`
+	Diagnosis    []FHIREncounterDiagnosis   `json:"diagnosis,omitempty"`
+}
+
+type FHIREncounterParticipant struct {
+	Individual *FHIRReference `json:"individual,omitempty"`
+}
+
+type FHIREncounterDiagnosis struct {
+	Condition FHIRReference        `json:"condition"`
+	Use       *FHIRCodeableConcept `json:"use,omitempty"`
+	Rank      int                  `json:"rank,omitempty"`
+}
+
+// FHIRAppointment - запись на прием
+// @Description Ресурс Appointment FHIR R4
+type FHIRAppointment struct {
+	ResourceType      string                       `json:"resourceType"` // Appointment
+	ID                string                       `json:"id"`
+	Status            string                       `json:"status"`
+	CancelationReason *FHIRCodeableConcept         `json:"cancelationReason,omitempty"`
+	Start             string                       `json:"start,omitempty"`
+	End               string                       `json:"end,omitempty"`
+	MinutesDuration   int                          `json:"minutesDuration,omitempty"`
+	Comment           string                       `json:"comment,omitempty"`
+	Participant       []FHIRAppointmentParticipant `json:"participant"`
+}
+
+type FHIRAppointmentParticipant struct {
+	Actor  *FHIRReference `json:"actor,omitempty"`
+	Status string         `json:"status"` // accepted
+}
+
+// FHIRObservation - результат медицинского теста
+// @Description Ресурс Observation FHIR R4
+type FHIRObservation struct {
+	ResourceType      string                          `json:"resourceType"` // Observation
+	ID                string                          `json:"id"`
+	Status            string                          `json:"status"`
+	Code              FHIRCodeableConcept             `json:"code"`
+	Subject           *FHIRReference                  `json:"subject,omitempty"`
+	Encounter         *FHIRReference                  `json:"encounter,omitempty"`
+	EffectiveDateTime string                          `json:"effectiveDateTime,omitempty"`
+	ValueQuantity     *FHIRQuantity                   `json:"valueQuantity,omitempty"`
+	ValueString       string                          `json:"valueString,omitempty"`
+	Interpretation    []FHIRCodeableConcept           `json:"interpretation,omitempty"`
+	ReferenceRange    []FHIRObservationReferenceRange `json:"referenceRange,omitempty"`
+}
+
+type FHIRObservationReferenceRange struct {
+	Low  *FHIRQuantity `json:"low,omitempty"`
+	High *FHIRQuantity `json:"high,omitempty"`
+	Text string        `json:"text,omitempty"`
+}
+
+// FHIRCondition - запись анамнеза (history-<id>) или диагноз приема по МКБ-10 (diagnosis-<id>)
+// @Description Ресурс Condition FHIR R4
+type FHIRCondition struct {
+	ResourceType       string                `json:"resourceType"` // Condition
+	ID                 string                `json:"id"`
+	ClinicalStatus     *FHIRCodeableConcept  `json:"clinicalStatus,omitempty"`
+	VerificationStatus *FHIRCodeableConcept  `json:"verificationStatus,omitempty"`
+	Category           []FHIRCodeableConcept `json:"category,omitempty"`
+	Severity           *FHIRCodeableConcept  `json:"severity,omitempty"`
+	Code               FHIRCodeableConcept   `json:"code"`
+	Subject            FHIRReference         `json:"subject"`
+	Encounter          *FHIRReference        `json:"encounter,omitempty"`
+	OnsetDateTime      string                `json:"onsetDateTime,omitempty"`
+	RecordedDate       string                `json:"recordedDate,omitempty"`
+	Note               []FHIRAnnotation      `json:"note,omitempty"`
+}
+
+// FHIRAllergyIntolerance - аллергия из анамнеза
+// @Description Ресурс AllergyIntolerance FHIR R4
+type FHIRAllergyIntolerance struct {
+	ResourceType       string               `json:"resourceType"` // AllergyIntolerance
+	ID                 string               `json:"id"`
+	ClinicalStatus     *FHIRCodeableConcept `json:"clinicalStatus,omitempty"`
+	VerificationStatus *FHIRCodeableConcept `json:"verificationStatus,omitempty"`
+	Criticality        string               `json:"criticality,omitempty"`
+	Code               FHIRCodeableConcept  `json:"code"`
+	Patient            FHIRReference        `json:"patient"`
+	OnsetDateTime      string               `json:"onsetDateTime,omitempty"`
+	RecordedDate       string               `json:"recordedDate,omitempty"`
+	Note               []FHIRAnnotation     `json:"note,omitempty"`
+}
+
+// Идентификаторы Condition: запись анамнеза и диагноз приема хранятся в разных таблицах
+const (
+	fhirConditionHistoryPrefix   = "history-"
+	fhirConditionDiagnosisPrefix = "diagnosis-"
+)
+
+// Категории Condition
+const (
+	fhirCategoryProblemList = "problem-list-item"
+	fhirCategoryDiagnosis   = "encounter-diagnosis"
+)
+
+// fhirEncounterStatuses сопоставляет статус приема статусу Encounter
+var fhirEncounterStatuses = map[string]string{
+	statusScheduled:  "planned",
+	statusCheckedIn:  "arrived",
+	statusInProgress: "in-progress",
+	statusCompleted:  "finished",
+	statusCancelled:  "cancelled",
+	statusNoShow:     "cancelled",
+}
+
+// fhirAppointmentStatuses сопоставляет статус приема статусу Appointment
+var fhirAppointmentStatuses = map[string]string{
+	statusScheduled:  "booked",
+	statusCheckedIn:  "checked-in",
+	statusInProgress: "arrived",
+	statusCompleted:  "fulfilled",
+	statusCancelled:  "cancelled",
+	statusNoShow:     "noshow",
+}
+
+// fhirSeverities - коды SNOMED CT тяжести состояния
+var fhirSeverities = map[string]FHIRCoding{
+	"mild":     {System: fhirSystemSNOMED, Code: "255604002", Display: "Mild"},
+	"moderate": {System: fhirSystemSNOMED, Code: "6736007", Display: "Moderate"},
+	"severe":   {System: fhirSystemSNOMED, Code: "24484000", Display: "Severe"},
+}
+
+// fhirInterpretations - коды интерпретации результата по флагу теста
+var fhirInterpretations = map[string]string{
+	flagNormal:       "Normal",
+	flagLow:          "Low",
+	flagHigh:         "High",
+	flagCriticalLow:  "Critical low",
+	flagCriticalHigh: "Critical high",
+}
+
+// fhirConcept создает CodeableConcept с одним кодом
+func fhirConcept(system, code, display string) *FHIRCodeableConcept {
+	return &FHIRCodeableConcept{Coding: []FHIRCoding{{System: system, Code: code, Display: display}}}
+}
+
+// fhirNote возвращает примечание записи, если оно есть
+func fhirNote(text string) []FHIRAnnotation {
+	if text == "" {
+		return nil
+	}
+	return []FHIRAnnotation{{Text: text}}
+}
+
+// fhirStatusFilter добавляет условие по параметру status: коды FHIR через запятую
+// переводятся в статусы приема по таблице statuses
+func fhirStatusFilter(c *gin.Context, query *gorm.DB, statuses map[string]string) (*gorm.DB, error) {
+	value := c.Query("status")
+	if value == "" {
+		return query, nil
+	}
+	var matched []string
+	for _, code := range strings.Split(value, ",") {
+		found := false
+		for status, fhirStatus := range statuses {
+			if fhirStatus == strings.TrimSpace(code) {
+				matched = append(matched, status)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid 'status' parameter: unknown status %q", code)
+		}
+	}
+	return query.Where("status IN ?", matched), nil
+}
+
+// Преобразование записей в ресурсы
+
+func fhirPatient(_ *gin.Context, p Patient) (string, interface{}, []auditRef) {
+	id := strconv.FormatUint(uint64(p.ID), 10)
+	return id, FHIRPatient{
+		ResourceType: "Patient",
+		ID:           id,
+		Name:         fhirHumanName(p.FullName),
+		Telecom:      fhirTelecom(p.Phone, p.Email),
+		Gender:       p.Gender,
+		BirthDate:    fhirDate(p.BirthDate),
+	}, []auditRef{p.auditRef()}
+}
+
+func fhirPractitioner(_ *gin.Context, d Doctor) (string, interface{}, []auditRef) {
+	id := strconv.FormatUint(uint64(d.ID), 10)
+	practitioner := FHIRPractitioner{
+		ResourceType: "Practitioner",
+		ID:           id,
+		Active:       d.Active,
+		Name:         fhirHumanName(d.FullName),
+		Telecom:      fhirTelecom(d.Phone, d.Email),
+	}
+	if d.Specialization != "" {
+		practitioner.Qualification = []FHIRPractitionerQualification{{Code: FHIRCodeableConcept{Text: d.Specialization}}}
+	}
+	return id, practitioner, nil
+}
+
+// fhirEncounter преобразует прием в Encounter; диагнозы приема становятся ссылками на Condition
+func fhirEncounter(c *gin.Context, a Appointment) (string, interface{}, []auditRef) {
+	redactAppointment(currentUser(c), &a)
+	id := strconv.FormatUint(uint64(a.ID), 10)
+	encounter := FHIREncounter{
+		ResourceType: "Encounter",
+		ID:           id,
+		Status:       fhirEncounterStatuses[a.Status],
+		Class:        FHIRCoding{System: fhirSystemActCode, Code: "AMB", Display: "ambulatory"},
+		Subject:      fhirRef("Patient", a.PatientID),
+		Participant:  []FHIREncounterParticipant{{Individual: fhirRef("Practitioner", a.DoctorID)}},
+		Appointment:  []FHIRReference{*fhirRef("Appointment", a.ID)},
+		Period:       &FHIRPeriod{Start: fhirDateTime(a.Date), End: fhirDateTime(a.EndDate)},
+	}
+	for i, d := range a.Diagnoses {
+		diagnosis := FHIREncounterDiagnosis{
+			Condition: FHIRReference{
+				Reference: "Condition/" + fhirConditionDiagnosisPrefix + strconv.FormatUint(uint64(d.ID), 10),
+				Display:   d.Code + " " + d.Title,
+			},
+			Rank: i + 1,
+		}
+		if d.Kind == diagnosisPrimary {
+			diagnosis.Use = fhirConcept(fhirSystemDiagnosisRole, "CC", "Chief complaint")
+		} else {
+			diagnosis.Use = fhirConcept(fhirSystemDiagnosisRole, "CM", "Comorbidity diagnosis")
+		}
+		encounter.Diagnosis = append(encounter.Diagnosis, diagnosis)
+	}
+	return id, encounter, append([]auditRef{a.auditRef()}, auditRefs(a.Diagnoses)...)
+}
+
+func fhirAppointment(_ *gin.Context, a Appointment) (string, interface{}, []auditRef) {
+	id := strconv.FormatUint(uint64(a.ID), 10)
+	appointment := FHIRAppointment{
+		ResourceType: "Appointment",
+		ID:           id,
+		Status:       fhirAppointmentStatuses[a.Status],
+		Start:        fhirDateTime(a.Date),
+		End:          fhirDateTime(a.EndDate),
+		Comment:      a.Notes,
+		Participant: []FHIRAppointmentParticipant{
+			{Actor: fhirRef("Patient", a.PatientID), Status: "accepted"},
+			{Actor: fhirRef("Practitioner", a.DoctorID), Status: "accepted"},
+		},
+	}
+	if !a.EndDate.IsZero() {
+		appointment.MinutesDuration = int(a.EndDate.Sub(a.Date) / time.Minute)
+	}
+	if a.StatusReason != "" && (a.Status == statusCancelled || a.Status == statusNoShow) {
+		appointment.CancelationReason = &FHIRCodeableConcept{Text: a.StatusReason}
+	}
+	return id, appointment, []auditRef{a.auditRef()}
+}
+
+// fhirObservation преобразует тест в Observation. Числовой результат выдается как
+// valueQuantity, составной (120/80) и текстовый - как valueString.
+func fhirObservation(_ *gin.Context, t MedicalTest) (string, interface{}, []auditRef) {
+	id := strconv.FormatUint(uint64(t.ID), 10)
+	observation := FHIRObservation{
+		ResourceType:      "Observation",
+		ID:                id,
+		Status:            "registered",
+		Code:              FHIRCodeableConcept{Text: t.Name},
+		Subject:           fhirRef("Patient", t.Appointment.PatientID),
+		Encounter:         fhirRef("Encounter", t.AppointmentID),
+		EffectiveDateTime: fhirDateTime(t.Appointment.Date),
+	}
+	if t.Result != "" {
+		observation.Status = "final"
+		if t.ResultValue != nil && t.ResultValue2 == nil {
+			observation.ValueQuantity = &FHIRQuantity{Value: t.ResultValue, Unit: t.Unit}
+		} else {
+			observation.ValueString = strings.TrimSpace(t.Result + " " + t.Unit)
+		}
+	}
+	if display, ok := fhirInterpretations[t.Flag]; ok {
+		observation.Interpretation = []FHIRCodeableConcept{*fhirConcept(fhirSystemInterpretation, t.Flag, display)}
+	}
+
+	switch {
+	case (t.RangeLow != nil || t.RangeHigh != nil) && t.ResultValue2 == nil:
+		r := FHIRObservationReferenceRange{Text: t.ReferenceRange}
+		if t.RangeLow != nil {
+			r.Low = &FHIRQuantity{Value: t.RangeLow, Unit: t.Unit}
+		}
+		if t.RangeHigh != nil {
+			r.High = &FHIRQuantity{Value: t.RangeHigh, Unit: t.Unit}
+		}
+		observation.ReferenceRange = []FHIRObservationReferenceRange{r}
+	case t.ReferenceRange != "":
+		observation.ReferenceRange = []FHIRObservationReferenceRange{{Text: t.ReferenceRange}}
+	}
+	return id, observation, []auditRef{t.auditRef()}
+}
+
+// fhirHistoryCondition преобразует запись анамнеза (кроме аллергий и семейного анамнеза) в Condition
+func fhirHistoryCondition(_ *gin.Context, h MedicalHistory) (string, interface{}, []auditRef) {
+	id := fhirConditionHistoryPrefix + strconv.FormatUint(uint64(h.ID), 10)
+	clinical := "active"
+	if h.Status == "resolved" {
+		clinical = "resolved"
+	}
+	condition := FHIRCondition{
+		ResourceType:   "Condition",
+		ID:             id,
+		ClinicalStatus: fhirConcept(fhirSystemConditionClinical, clinical, ""),
+		Category:       []FHIRCodeableConcept{*fhirConcept(fhirSystemConditionCategory, fhirCategoryProblemList, "Problem List Item")},
+		Code:           FHIRCodeableConcept{Text: h.Description},
+		Subject:        *fhirRef("Patient", h.PatientID),
+		OnsetDateTime:  fhirDateTime(h.StartDate),
+		RecordedDate:   fhirDateTime(h.CreatedAt),
+		Note:           fhirNote(h.Notes),
+	}
+	if severity, ok := fhirSeverities[h.Severity]; ok {
+		condition.Severity = &FHIRCodeableConcept{Coding: []FHIRCoding{severity}}
+	}
+	return id, condition, []auditRef{h.auditRef()}
+}
+
+// fhirDiagnosisRow - диагноз приема вместе с пациентом и датой приема
+type fhirDiagnosisRow struct {
+	AppointmentDiagnosis `gorm:"embedded"`
+	PatientID            uint
+	DoctorID             uint
+	AppointmentDate      time.Time
+}
+
+// fhirDiagnosisCondition преобразует диагноз приема по МКБ-10 в Condition
+func fhirDiagnosisCondition(_ *gin.Context, d fhirDiagnosisRow) (string, interface{}, []auditRef) {
+	id := fhirConditionDiagnosisPrefix + strconv.FormatUint(uint64(d.ID), 10)
+	return id, FHIRCondition{
+		ResourceType:       "Condition",
+		ID:                 id,
+		VerificationStatus: fhirConcept(fhirSystemConditionVerStatus, "confirmed", ""),
+		Category:           []FHIRCodeableConcept{*fhirConcept(fhirSystemConditionCategory, fhirCategoryDiagnosis, "Encounter Diagnosis")},
+		Code:               FHIRCodeableConcept{Coding: []FHIRCoding{{System: fhirSystemICD10, Code: d.Code, Display: d.Title}}, Text: d.Title},
+		Subject:            *fhirRef("Patient", d.PatientID),
+		Encounter:          fhirRef("Encounter", d.AppointmentID),
+		RecordedDate:       fhirDateTime(d.AppointmentDate),
+		Note:               fhirNote(d.Note),
+	}, []auditRef{d.auditRef()}
+}
+
+// fhirAllergy преобразует аллергию из анамнеза в AllergyIntolerance
+func fhirAllergy(_ *gin.Context, h MedicalHistory) (string, interface{}, []auditRef) {
+	id := strconv.FormatUint(uint64(h.ID), 10)
+	clinical := "active"
+	if h.Status == "resolved" || h.Status == "inactive" {
+		clinical = h.Status
+	}
+	allergy := FHIRAllergyIntolerance{
+		ResourceType:       "AllergyIntolerance",
+		ID:                 id,
+		ClinicalStatus:     fhirConcept(fhirSystemAllergyClinical, clinical, ""),
+		VerificationStatus: fhirConcept(fhirSystemAllergyVerification, "confirmed", ""),
+		Criticality:        "low",
+		Code:               FHIRCodeableConcept{Text: h.Description},
+		Patient:            *fhirRef("Patient", h.PatientID),
+		OnsetDateTime:      fhirDateTime(h.StartDate),
+		RecordedDate:       fhirDateTime(h.CreatedAt),
+		Note:               fhirNote(h.Notes),
+	}
+	if h.Severity == "severe" {
+		allergy.Criticality = "high"
+	}
+	return id, allergy, []auditRef{h.auditRef()}
+}
+
+// fhirFilters применяет фильтры по порядку до первой ошибки; при ошибке отвечает 400
+func fhirFilters(c *gin.Context, query *gorm.DB, filters ...func(*gorm.DB) (*gorm.DB, error)) (*gorm.DB, bool) {
+	for _, filter := range filters {
+		var err error
+		if query, err = filter(query); err != nil {
+			respondFHIRError(c, http.StatusBadRequest, "invalid", err.Error())
+			return nil, false
+		}
+	}
+	return query, true
+}
+
+// Обработчики
+
+// SearchFHIRPatients godoc
+// @Summary Поиск пациентов (FHIR Patient)
+// @Description Поиск пациентов в формате FHIR R4. Пациент видит только себя
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param name query string false "Начало слов ФИО"
+// @Param gender query string false "Пол" Enums(male, female)
+// @Param birthdate query string false "Дата рождения с необязательным префиксом eq, ne, gt, ge, lt, le"
+// @Param _id query string false "ID через запятую"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Patient [get]
+func searchFHIRPatients(c *gin.Context) {
+	query, ok := fhirFilters(c, scopeQuery(c, permPatientsRead, db, "id", ""),
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirIDFilter(c, q, "id") },
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirNameFilter(c, q, "search_name"), nil },
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirDateFilter(c, q, "birthdate", "birth_date") },
+	)
+	if !ok {
+		return
+	}
+	if gender := c.Query("gender"); gender != "" {
+		query = query.Where("gender = ?", gender)
+	}
+	fhirSearch(c, query, "id", "Patient", fhirPatient)
+}
+
+// GetFHIRPatient godoc
+// @Summary Пациент (FHIR Patient)
+// @Description Получить пациента в формате FHIR R4
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID пациента"
+// @Success 200 {object} FHIRPatient
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Patient/{id} [get]
+func getFHIRPatient(c *gin.Context) {
+	var patient Patient
+	id, ok := parseFHIRID(c.Param("id"))
+	if !ok || db.First(&patient, id).Error != nil {
+		fhirNotFound(c, "Patient", c.Param("id"))
+		return
+	}
+	if !fhirAuthorize(c, permPatientsRead, resource{PatientID: patient.ID}) {
+		return
+	}
+	fhirRead(c, patient, fhirPatient)
+}
+
+// SearchFHIRPractitioners godoc
+// @Summary Поиск врачей (FHIR Practitioner)
+// @Description Поиск врачей в формате FHIR R4
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param name query string false "Начало слов ФИО"
+// @Param active query bool false "Только работающие (true) или деактивированные (false)"
+// @Param _id query string false "ID через запятую"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Practitioner [get]
+func searchFHIRPractitioners(c *gin.Context) {
+	query, ok := fhirFilters(c, db,
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirIDFilter(c, q, "id") },
+	)
+	if !ok {
+		return
+	}
+	if value := c.Query("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			respondFHIRError(c, http.StatusBadRequest, "invalid", "invalid 'active' parameter")
+			return
+		}
+		query = query.Where("active = ?", active)
+	}
+
+	// У врачей нет нормализованного ФИО в базе; врачей немного, поэтому имя
+	// сравнивается в Go, а в запрос попадают найденные ID
+	if words := strings.Fields(normalizeSearchText(c.Query("name"))); len(words) > 0 {
+		var doctors []Doctor
+		if err := db.Select("id", "full_name").Find(&doctors).Error; err != nil {
+			respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+			return
+		}
+		ids := []uint{}
+		for _, d := range doctors {
+			if fhirNameMatches(d.FullName, words) {
+				ids = append(ids, d.ID)
+			}
+		}
+		query = query.Where("id IN ?", ids)
+	}
+	fhirSearch(c, query, "id", "Practitioner", fhirPractitioner)
+}
+
+// fhirNameMatches проверяет, что каждое слово поиска - начало какого-либо слова ФИО
+func fhirNameMatches(fullName string, words []string) bool {
+	name := " " + normalizeSearchText(fullName)
+	for _, word := range words {
+		if !strings.Contains(name, " "+word) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetFHIRPractitioner godoc
+// @Summary Врач (FHIR Practitioner)
+// @Description Получить врача в формате FHIR R4
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID врача"
+// @Success 200 {object} FHIRPractitioner
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Practitioner/{id} [get]
+func getFHIRPractitioner(c *gin.Context) {
+	var doctor Doctor
+	id, ok := parseFHIRID(c.Param("id"))
+	if !ok || db.First(&doctor, id).Error != nil {
+		fhirNotFound(c, "Practitioner", c.Param("id"))
+		return
+	}
+	fhirRead(c, doctor, fhirPractitioner)
+}
+
+// appointmentFHIRQuery строит запрос приемов для поиска Encounter и Appointment
+func appointmentFHIRQuery(c *gin.Context, statuses map[string]string) (*gorm.DB, bool) {
+	return fhirFilters(c, scopeQuery(c, permAppointmentsRead, db, "patient_id", "doctor_id"),
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirIDFilter(c, q, "id") },
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "patient", "Patient", "patient_id")
+		},
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "practitioner", "Practitioner", "doctor_id")
+		},
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirDateFilter(c, q, "date", "date") },
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirStatusFilter(c, q, statuses) },
+	)
+}
+
+// loadFHIRAppointment загружает прием для чтения Encounter или Appointment
+func loadFHIRAppointment(c *gin.Context, resourceType string, query *gorm.DB) (Appointment, bool) {
+	var appointment Appointment
+	id, ok := parseFHIRID(c.Param("id"))
+	if !ok || query.First(&appointment, id).Error != nil {
+		fhirNotFound(c, resourceType, c.Param("id"))
+		return appointment, false
+	}
+	return appointment, fhirAuthorize(c, permAppointmentsRead, appointmentResource(appointment))
+}
+
+// preloadFHIRDiagnoses загружает диагнозы приемов, если пользователю разрешено читать клинические данные
+func preloadFHIRDiagnoses(c *gin.Context, query *gorm.DB) *gorm.DB {
+	if currentUser(c).scope(permClinicalRead) == scopeNone {
+		return query
+	}
+	return query.Preload("Diagnoses", func(tx *gorm.DB) *gorm.DB { return tx.Order(diagnosisOrder) })
+}
+
+// SearchFHIREncounters godoc
+// @Summary Поиск визитов (FHIR Encounter)
+// @Description Поиск приемов в формате FHIR Encounter. Диагнозы по МКБ-10 выдаются ссылками на Condition, если роль может читать клинические данные
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param patient query string false "Пациент (Patient/ID или ID)"
+// @Param practitioner query string false "Врач (Practitioner/ID или ID)"
+// @Param date query string false "Дата приема с необязательным префиксом eq, ne, gt, ge, lt, le; можно повторить"
+// @Param status query string false "Статусы Encounter через запятую" Enums(planned, arrived, in-progress, finished, cancelled)
+// @Param _id query string false "ID через запятую"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Encounter [get]
+func searchFHIREncounters(c *gin.Context) {
+	query, ok := appointmentFHIRQuery(c, fhirEncounterStatuses)
+	if !ok {
+		return
+	}
+	fhirSearch(c, preloadFHIRDiagnoses(c, query), "date, id", "Encounter", fhirEncounter)
+}
+
+// GetFHIREncounter godoc
+// @Summary Визит (FHIR Encounter)
+// @Description Получить прием в формате FHIR Encounter
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID приема"
+// @Success 200 {object} FHIREncounter
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Encounter/{id} [get]
+func getFHIREncounter(c *gin.Context) {
+	appointment, ok := loadFHIRAppointment(c, "Encounter", preloadFHIRDiagnoses(c, db))
+	if !ok {
+		return
+	}
+	fhirRead(c, appointment, fhirEncounter)
+}
+
+// SearchFHIRAppointments godoc
+// @Summary Поиск записей на прием (FHIR Appointment)
+// @Description Поиск приемов в формате FHIR Appointment
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param patient query string false "Пациент (Patient/ID или ID)"
+// @Param practitioner query string false "Врач (Practitioner/ID или ID)"
+// @Param date query string false "Дата приема с необязательным префиксом eq, ne, gt, ge, lt, le; можно повторить"
+// @Param status query string false "Статусы Appointment через запятую" Enums(booked, checked-in, arrived, fulfilled, cancelled, noshow)
+// @Param _id query string false "ID через запятую"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Appointment [get]
+func searchFHIRAppointments(c *gin.Context) {
+	query, ok := appointmentFHIRQuery(c, fhirAppointmentStatuses)
+	if !ok {
+		return
+	}
+	fhirSearch(c, query, "date, id", "Appointment", fhirAppointment)
+}
+
+// GetFHIRAppointment godoc
+// @Summary Запись на прием (FHIR Appointment)
+// @Description Получить прием в формате FHIR Appointment
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID приема"
+// @Success 200 {object} FHIRAppointment
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Appointment/{id} [get]
+func getFHIRAppointment(c *gin.Context) {
+	appointment, ok := loadFHIRAppointment(c, "Appointment", db)
+	if !ok {
+		return
+	}
+	fhirRead(c, appointment, fhirAppointment)
+}
+
+// SearchFHIRObservations godoc
+// @Summary Поиск результатов тестов (FHIR Observation)
+// @Description Поиск медицинских тестов в формате FHIR Observation. Дата наблюдения - дата приема
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param patient query string false "Пациент (Patient/ID или ID)"
+// @Param encounter query string false "Прием (Encounter/ID или ID)"
+// @Param date query string false "Дата приема с необязательным префиксом eq, ne, gt, ge, lt, le; можно повторить"
+// @Param _id query string false "ID через запятую"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Observation [get]
+func searchFHIRObservations(c *gin.Context) {
+	query := db.Joins("JOIN appointments ON appointments.id = medical_tests.appointment_id AND appointments.deleted_at IS NULL").
+		Preload("Appointment")
+	query, ok := fhirFilters(c, scopeQuery(c, permTestsRead, query, "appointments.patient_id", "appointments.doctor_id"),
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirIDFilter(c, q, "medical_tests.id") },
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "patient", "Patient", "appointments.patient_id")
+		},
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "encounter", "Encounter", "medical_tests.appointment_id")
+		},
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirDateFilter(c, q, "date", "appointments.date") },
+	)
+	if !ok {
+		return
+	}
+	fhirSearch(c, query, "appointments.date, medical_tests.id", "Observation", fhirObservation)
+}
+
+// GetFHIRObservation godoc
+// @Summary Результат теста (FHIR Observation)
+// @Description Получить медицинский тест в формате FHIR Observation
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID теста"
+// @Success 200 {object} FHIRObservation
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Observation/{id} [get]
+func getFHIRObservation(c *gin.Context) {
+	var test MedicalTest
+	id, ok := parseFHIRID(c.Param("id"))
+	if !ok || db.Preload("Appointment").First(&test, id).Error != nil {
+		fhirNotFound(c, "Observation", c.Param("id"))
+		return
+	}
+	if !fhirAuthorize(c, permTestsRead, appointmentResource(test.Appointment)) {
+		return
+	}
+	fhirRead(c, test, fhirObservation)
+}
+
+// historyConditionQuery строит запрос записей анамнеза, выдаваемых как Condition
+func historyConditionQuery(c *gin.Context) *gorm.DB {
+	return scopeQuery(c, permHistoryRead, db, "patient_id", "").
+		Where("history_type NOT IN ?", []string{historyTypeAllergy, "family"})
+}
+
+// fhirDiagnosisColumns - столбцы fhirDiagnosisRow
+const fhirDiagnosisColumns = "appointment_diagnoses.*, appointments.patient_id, appointments.doctor_id, appointments.date AS appointment_date"
+
+// diagnosisRowsQuery строит запрос диагнозов вместе с приемами; столбцы выбираются fhirDiagnosisColumns
+func diagnosisRowsQuery() *gorm.DB {
+	return db.Table("appointment_diagnoses").
+		Joins("JOIN appointments ON appointments.id = appointment_diagnoses.appointment_id AND appointments.deleted_at IS NULL")
+}
+
+// diagnosisConditionQuery строит запрос диагнозов приемов, выдаваемых как Condition
+func diagnosisConditionQuery(c *gin.Context) *gorm.DB {
+	return scopeQuery(c, permClinicalRead, diagnosisRowsQuery(), "appointments.patient_id", "appointments.doctor_id")
+}
+
+// SearchFHIRConditions godoc
+// @Summary Поиск состояний (FHIR Condition)
+// @Description Поиск состояний пациента в формате FHIR Condition. Выдаются записи анамнеза, кроме аллергий и семейного анамнеза (категория problem-list-item, ID history-<id>), и диагнозы приемов по МКБ-10 (категория encounter-diagnosis, ID diagnosis-<id>); сначала анамнез, затем диагнозы. Каждый источник выдается, только если роль может его читать
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param patient query string false "Пациент (Patient/ID или ID)"
+// @Param encounter query string false "Прием (Encounter/ID или ID); только диагнозы приемов"
+// @Param category query string false "Категория" Enums(problem-list-item, encounter-diagnosis)
+// @Param code query string false "Код МКБ-10; только диагнозы приемов"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Condition [get]
+func searchFHIRConditions(c *gin.Context) {
+	user := currentUser(c)
+	withHistory := user.scope(permHistoryRead) != scopeNone
+	withDiagnoses := user.scope(permClinicalRead) != scopeNone
+	if !withHistory && !withDiagnoses {
+		respondFHIRError(c, http.StatusForbidden, "forbidden", "access denied")
+		return
+	}
+	paging, err := parseFHIRPaging(c)
+	if err != nil {
+		respondFHIRError(c, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+
+	switch category := c.Query("category"); category {
+	case "":
+	case fhirCategoryProblemList:
+		withDiagnoses = false
+	case fhirCategoryDiagnosis:
+		withHistory = false
+	default:
+		respondFHIRError(c, http.StatusBadRequest, "invalid", fmt.Sprintf("invalid 'category' parameter: unknown category %q", category))
+		return
+	}
+	if c.Query("encounter") != "" || c.Query("code") != "" {
+		withHistory = false
+	}
+
+	history, ok := fhirFilters(c, historyConditionQuery(c),
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "patient", "Patient", "patient_id")
+		},
+	)
+	if !ok {
+		return
+	}
+	diagnoses, ok := fhirFilters(c, diagnosisConditionQuery(c),
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "patient", "Patient", "appointments.patient_id")
+		},
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "encounter", "Encounter", "appointment_diagnoses.appointment_id")
+		},
+	)
+	if !ok {
+		return
+	}
+	if code := c.Query("code"); code != "" {
+		diagnoses = diagnoses.Where("appointment_diagnoses.code = ?", normalizeICD10Code(code))
+	}
+
+	// Страница собирается из двух источников: сначала записи анамнеза, затем диагнозы
+	var historyTotal, diagnosisTotal int64
+	var records []MedicalHistory
+	var rows []fhirDiagnosisRow
+	if withHistory {
+		if err := history.Session(&gorm.Session{}).Model(&MedicalHistory{}).Count(&historyTotal).Error; err != nil {
+			respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+			return
+		}
+		if paging.count > 0 && int64(paging.offset) < historyTotal {
+			if err := history.Order("id").Offset(paging.offset).Limit(paging.count).Find(&records).Error; err != nil {
+				respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+				return
+			}
+		}
+	}
+	if withDiagnoses {
+		if err := diagnoses.Session(&gorm.Session{}).Count(&diagnosisTotal).Error; err != nil {
+			respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+			return
+		}
+		offset := paging.offset - int(historyTotal)
+		if offset < 0 {
+			offset = 0
+		}
+		if limit := paging.count - len(records); limit > 0 {
+			if err := diagnoses.Select(fhirDiagnosisColumns).Order("appointments.date, appointment_diagnoses.id").Offset(offset).Limit(limit).Find(&rows).Error; err != nil {
+				respondFHIRError(c, http.StatusInternalServerError, "exception", err.Error())
+				return
+			}
+		}
+	}
+
+	var refs []auditRef
+	var entries []FHIRBundleEntry
+	for _, h := range records {
+		id, resource, recordRefs := fhirHistoryCondition(c, h)
+		entries = append(entries, fhirEntry(c, "Condition", id, resource))
+		refs = append(refs, recordRefs...)
+	}
+	for _, d := range rows {
+		id, resource, recordRefs := fhirDiagnosisCondition(c, d)
+		entries = append(entries, fhirEntry(c, "Condition", id, resource))
+		refs = append(refs, recordRefs...)
+	}
+	if !auditRead(c, refs...) {
+		return
+	}
+	respondFHIR(c, http.StatusOK, fhirSearchBundle(c, int(historyTotal+diagnosisTotal), paging, entries))
+}
+
+// GetFHIRCondition godoc
+// @Summary Состояние (FHIR Condition)
+// @Description Получить запись анамнеза (history-<id>) или диагноз приема (diagnosis-<id>) в формате FHIR Condition
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "history-<ID записи анамнеза> или diagnosis-<ID диагноза>"
+// @Success 200 {object} FHIRCondition
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/Condition/{id} [get]
+func getFHIRCondition(c *gin.Context) {
+	param := c.Param("id")
+	switch {
+	case strings.HasPrefix(param, fhirConditionHistoryPrefix):
+		var history MedicalHistory
+		id, ok := parseFHIRID(strings.TrimPrefix(param, fhirConditionHistoryPrefix))
+		if !ok || db.Where("history_type NOT IN ?", []string{historyTypeAllergy, "family"}).First(&history, id).Error != nil {
+			break
+		}
+		if fhirAuthorize(c, permHistoryRead, resource{PatientID: history.PatientID}) {
+			fhirRead(c, history, fhirHistoryCondition)
+		}
+		return
+	case strings.HasPrefix(param, fhirConditionDiagnosisPrefix):
+		var row fhirDiagnosisRow
+		id, ok := parseFHIRID(strings.TrimPrefix(param, fhirConditionDiagnosisPrefix))
+		if !ok {
+			break
+		}
+		if diagnosisRowsQuery().Select(fhirDiagnosisColumns).Where("appointment_diagnoses.id = ?", id).Take(&row).Error != nil {
+			break
+		}
+		if fhirAuthorize(c, permClinicalRead, resource{PatientID: row.PatientID, DoctorID: row.DoctorID}) {
+			fhirRead(c, row, fhirDiagnosisCondition)
+		}
+		return
+	}
+	fhirNotFound(c, "Condition", param)
+}
+
+// SearchFHIRAllergies godoc
+// @Summary Поиск аллергий (FHIR AllergyIntolerance)
+// @Description Поиск аллергий из анамнеза в формате FHIR AllergyIntolerance
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param patient query string false "Пациент (Patient/ID или ID)"
+// @Param _id query string false "ID через запятую"
+// @Param _count query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param _offset query int false "Смещение страницы"
+// @Success 200 {object} FHIRBundle
+// @Failure 400 {object} FHIROperationOutcome
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/AllergyIntolerance [get]
+func searchFHIRAllergies(c *gin.Context) {
+	query := scopeQuery(c, permHistoryRead, db, "patient_id", "").Where("history_type = ?", historyTypeAllergy)
+	query, ok := fhirFilters(c, query,
+		func(q *gorm.DB) (*gorm.DB, error) { return fhirIDFilter(c, q, "id") },
+		func(q *gorm.DB) (*gorm.DB, error) {
+			return fhirReferenceFilter(c, q, "patient", "Patient", "patient_id")
+		},
+	)
+	if !ok {
+		return
+	}
+	fhirSearch(c, query, "id", "AllergyIntolerance", fhirAllergy)
+}
+
+// GetFHIRAllergy godoc
+// @Summary Аллергия (FHIR AllergyIntolerance)
+// @Description Получить аллергию из анамнеза в формате FHIR AllergyIntolerance
+// @Tags fhir
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID записи анамнеза"
+// @Success 200 {object} FHIRAllergyIntolerance
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} FHIROperationOutcome
+// @Failure 404 {object} FHIROperationOutcome
+// @Failure 500 {object} FHIROperationOutcome
+// @Router /fhir/R4/AllergyIntolerance/{id} [get]
+func getFHIRAllergy(c *gin.Context) {
+	var history MedicalHistory
+	id, ok := parseFHIRID(c.Param("id"))
+	if !ok || db.Where("history_type = ?", historyTypeAllergy).First(&history, id).Error != nil {
+		fhirNotFound(c, "AllergyIntolerance", c.Param("id"))
+		return
+	}
+	if !fhirAuthorize(c, permHistoryRead, resource{PatientID: history.PatientID}) {
+		return
+	}
+	fhirRead(c, history, fhirAllergy)
+}
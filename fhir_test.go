@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Ресурсы FHIR проверяются по JSON-схеме FHIR R4. По умолчанию используется выдержка
+// из официальной схемы в testdata/fhir.schema.json; переменная FHIR_SCHEMA задает путь
+// к полной схеме (http://hl7.org/fhir/R4/fhir.schema.json).
+
+var (
+	fhirSchemaOnce     sync.Once
+	fhirSchemaCompiler *jsonschema.Compiler
+	fhirSchemaLocation string
+	fhirSchemaErr      error
+	fhirSchemasMu      sync.Mutex
+	fhirSchemas        = map[string]*jsonschema.Schema{}
+)
+
+// fhirSchema возвращает схему ресурса resourceType (#/definitions/<resourceType>)
+func fhirSchema(t *testing.T, resourceType string) *jsonschema.Schema {
+	t.Helper()
+	fhirSchemaOnce.Do(func() {
+		path := os.Getenv("FHIR_SCHEMA")
+		if path == "" {
+			path = filepath.Join("testdata", "fhir.schema.json")
+		}
+		if fhirSchemaLocation, fhirSchemaErr = filepath.Abs(path); fhirSchemaErr != nil {
+			return
+		}
+		fhirSchemaCompiler = jsonschema.NewCompiler()
+	})
+	if fhirSchemaErr != nil {
+		t.Fatal(fhirSchemaErr)
+	}
+
+	fhirSchemasMu.Lock()
+	defer fhirSchemasMu.Unlock()
+	if schema, ok := fhirSchemas[resourceType]; ok {
+		return schema
+	}
+	schema, err := fhirSchemaCompiler.Compile(fhirSchemaLocation + "#/definitions/" + resourceType)
+	if err != nil {
+		t.Fatalf("FHIR schema for %s: %v", resourceType, err)
+	}
+	fhirSchemas[resourceType] = schema
+	return schema
+}
+
+// validateFHIR проверяет ресурс по схеме его типа и возвращает тип ресурса
+func validateFHIR(t *testing.T, data []byte) string {
+	t.Helper()
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid JSON: %v: %s", err, data)
+	}
+	object, _ := doc.(map[string]any)
+	resourceType, _ := object["resourceType"].(string)
+	if resourceType == "" {
+		t.Fatalf("no resourceType: %s", data)
+	}
+	if err := fhirSchema(t, resourceType).Validate(doc); err != nil {
+		t.Errorf("%s does not match the FHIR R4 schema: %v\n%s", resourceType, err, data)
+	}
+	return resourceType
+}
+
+// fhirGet выполняет запрос к API FHIR, проверяет статус и тип содержимого и проверяет ответ по схеме
+func fhirGet(t *testing.T, user User, path string, status int) []byte {
+	t.Helper()
+	w := apiRequest(t, user, http.MethodGet, fhirBasePath+path, nil)
+	if w.Code != status {
+		t.Fatalf("GET %s: status = %d, want %d: %s", path, w.Code, status, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != fhirContentType {
+		t.Errorf("GET %s: Content-Type = %q, want %q", path, got, fhirContentType)
+	}
+	validateFHIR(t, w.Body.Bytes())
+	return w.Body.Bytes()
+}
+
+// createFHIRFixtures загружает демонстрационный набор данных и добавляет записи, которых
+// в нем нет: отмененный и запланированный приемы, тесты без результата и с критическим
+// отклонением, неактивного врача, снятую аллергию, диагноз с примечанием
+func createFHIRFixtures(t *testing.T) {
+	t.Helper()
+	fixture, err := loadFixture("demo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := seedDatabase(db, fixture, false); err != nil {
+		t.Fatal(err)
+	}
+
+	doctor := createTestDoctor(t, "Орлова Вера Павловна", "Кардиолог")
+	retired := createTestDoctor(t, "Соколов", "Хирург")
+	if err := db.Model(&retired).Update("active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	patient := Patient{FullName: "Зайцева Ёлка", BirthDate: time.Date(2001, time.February, 3, 0, 0, 0, 0, time.UTC),
+		Gender: "female", Phone: "+7 900 000-00-00", Email: "zaytseva@example.com"}
+	if err := db.Create(&patient).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(9, 0),
+		Status: statusCancelled, StatusReason: "Пациент заболел", Notes: "Перенести на следующую неделю"})
+	createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(11, 0),
+		Status: statusNoShow})
+	createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(12, 0),
+		Status: statusCheckedIn})
+	visit := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(14, 0),
+		Diagnosis: "Гипокалиемия"})
+
+	for _, test := range []MedicalTest{
+		{Name: "Калий", Result: "2.4", Unit: "ммоль/л", ReferenceRange: "3.5-5.1", CriticalRange: "2.5-6.5"},
+		{Name: "Глюкоза", Unit: "ммоль/л", ReferenceRange: "3.9-6.1"},
+		{Name: "Артериальное давление", Result: "120/80", ReferenceRange: "90-139/60-89"},
+		{Name: "Креатинин", Result: "<50", Unit: "мкмоль/л", ReferenceRange: ">=44"},
+		{Name: "Посев мочи", Result: "роста нет"},
+	} {
+		test.AppointmentID = visit.ID
+		if err := db.Create(&test).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, diagnosis := range []AppointmentDiagnosis{
+		{Code: "E87.6", Title: "Гипокалиемия", Kind: diagnosisPrimary, Note: "Повторить анализ через неделю"},
+		{Code: "I10", Title: "Эссенциальная [первичная] гипертензия", Kind: diagnosisSecondary},
+	} {
+		diagnosis.AppointmentID = visit.ID
+		if err := db.Create(&diagnosis).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, history := range []MedicalHistory{
+		{HistoryType: "allergy", Description: "Аллергия на латекс", Status: "resolved", Severity: "mild"},
+		{HistoryType: "allergy", Description: "Аллергия на йод", Status: "inactive", StartDate: time.Date(1999, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{HistoryType: "chronic", Description: "Гастрит", Status: "resolved"},
+	} {
+		history.PatientID = patient.ID
+		if err := db.Create(&history).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFHIRResourcesMatchSchema(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		createFHIRFixtures(t)
+		admin := createTestUser(t, roleAdmin, 0)
+
+		fhirGet(t, admin, "/metadata", http.StatusOK)
+
+		// Врач и пациент видят свои записи, у врача часть полей приема может быть скрыта
+		users := map[string]User{
+			roleAdmin:   admin,
+			roleDoctor:  createTestUser(t, roleDoctor, 1),
+			rolePatient: createTestUser(t, rolePatient, 1),
+		}
+		for _, resourceType := range []string{"Patient", "Practitioner", "Encounter", "Appointment", "Observation", "Condition", "AllergyIntolerance"} {
+			for role, user := range users {
+				t.Run(resourceType+" as "+role, func(t *testing.T) {
+					var bundle FHIRBundle
+					if err := json.Unmarshal(fhirGet(t, user, "/"+resourceType+"?_count="+fmt.Sprint(maxPageLimit), http.StatusOK), &bundle); err != nil {
+						t.Fatal(err)
+					}
+					if len(bundle.Entry) == 0 {
+						t.Fatalf("no %s resources rendered", resourceType)
+					}
+
+					// Каждый ресурс выдается и отдельно по своему ID
+					for _, entry := range bundle.Entry {
+						path := strings.TrimPrefix(entry.FullURL, "http://example.com"+fhirBasePath)
+						if !strings.HasPrefix(path, "/"+resourceType+"/") {
+							t.Fatalf("fullUrl %q does not point to a %s", entry.FullURL, resourceType)
+						}
+						fhirGet(t, user, path, http.StatusOK)
+					}
+				})
+			}
+		}
+
+		// Bundle со ссылками на соседние страницы и ошибки OperationOutcome
+		fhirGet(t, admin, "/Patient?_count=2&_offset=2", http.StatusOK)
+		fhirGet(t, admin, "/Patient/999999", http.StatusNotFound)
+		fhirGet(t, admin, "/Patient?_count=-1", http.StatusBadRequest)
+		fhirGet(t, createTestUser(t, roleAuditor, 0), "/Patient", http.StatusForbidden)
+	})
+}
+
+func TestFHIRSchemaRejectsInvalidResources(t *testing.T) {
+	// Выдержка схемы должна отвергать ресурсы, которые отвергла бы полная схема
+	tests := []struct {
+		name     string
+		resource string
+	}{
+		{"unknown gender", `{"resourceType":"Patient","id":"1","gender":"м"}`},
+		{"empty string", `{"resourceType":"Patient","id":"1","name":[{"text":""}]}`},
+		{"date with time", `{"resourceType":"Patient","id":"1","birthDate":"1980-05-17T00:00:00Z"}`},
+		{"invalid id", `{"resourceType":"Patient","id":"history 1"}`},
+		{"unknown element", `{"resourceType":"Practitioner","id":"1","specialization":"Терапевт"}`},
+		{"qualification without code", `{"resourceType":"Practitioner","id":"1","qualification":[{}]}`},
+		{"encounter without class", `{"resourceType":"Encounter","id":"1","status":"planned"}`},
+		{"encounter status", `{"resourceType":"Encounter","id":"1","status":"scheduled","class":{"code":"AMB"}}`},
+		{"instant without time zone", `{"resourceType":"Appointment","id":"1","status":"booked","start":"2030-03-04T10:00:00","participant":[{"status":"accepted"}]}`},
+		{"appointment without participant", `{"resourceType":"Appointment","id":"1","status":"booked"}`},
+		{"observation without code", `{"resourceType":"Observation","id":"1","status":"final"}`},
+		{"quantity value as string", `{"resourceType":"Observation","id":"1","status":"final","code":{"text":"Калий"},"valueQuantity":{"value":"2.4"}}`},
+		{"condition without subject", `{"resourceType":"Condition","id":"history-1","code":{"text":"Гастрит"}}`},
+		{"allergy criticality", `{"resourceType":"AllergyIntolerance","id":"1","criticality":"severe","patient":{"reference":"Patient/1"}}`},
+		{"capability fhir version", `{"resourceType":"CapabilityStatement","fhirVersion":"4.0"}`},
+		{"bundle entry resource", `{"resourceType":"Bundle","type":"searchset","entry":[{"resource":{"resourceType":"Patient","gender":"x"}}]}`},
+		{"outcome issue code", `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"bad-request"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := jsonschema.UnmarshalJSON(strings.NewReader(tt.resource))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resourceType := doc.(map[string]any)["resourceType"].(string)
+			if err := fhirSchema(t, resourceType).Validate(doc); err == nil {
+				t.Errorf("%s is accepted by the schema", tt.resource)
+			}
+		})
+	}
+}
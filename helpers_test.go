@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	config.JWTSecret = "test-secret-0123456789abcdef0123456789abcdef"
+	os.Exit(m.Run())
+}
+
+// forEachDatabase выполняет тест на каждой поддерживаемой СУБД. Перед запуском глобальная
+// переменная db указывает на пустую базу со всеми миграциями.
+func forEachDatabase(t *testing.T, test func(t *testing.T)) {
+	forEachDatabaseAt(t, latestSchemaVersion(), test)
+}
+
+// forEachDatabaseAt выполняет тест на базе, схема которой доведена до версии version.
+// PostgreSQL проверяется, если переменная DEMEDA_TEST_POSTGRES_DSN задает строку подключения
+// и тесты собраны с тегом postgres; каждый тест получает собственную схему.
+func forEachDatabaseAt(t *testing.T, version uint, test func(t *testing.T)) {
+	t.Run("sqlite", func(t *testing.T) {
+		openTestDatabase(t, filepath.Join(t.TempDir(), "clinic.db"), version)
+		test(t)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		openTestDatabase(t, createTestSchema(t), version)
+		test(t)
+	})
+}
+
+var testSchemaCounter atomic.Uint64
+
+// createTestSchema создает в базе DEMEDA_TEST_POSTGRES_DSN пустую схему, удаляемую после теста,
+// и возвращает строку подключения к ней. Пропускает тест, если PostgreSQL не настроен.
+func createTestSchema(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv("DEMEDA_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("DEMEDA_TEST_POSTGRES_DSN is not set")
+	}
+	dialector, err := openDialector(dsn)
+	if err != nil {
+		t.Skip(err)
+	}
+	conn, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := fmt.Sprintf("demeda_test_%d_%d", os.Getpid(), testSchemaCounter.Add(1))
+	if err := conn.Exec("DROP SCHEMA IF EXISTS " + schema + " CASCADE").Error; err != nil {
+		sqlDB.Close()
+		t.Fatal(err)
+	}
+	if err := conn.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		sqlDB.Close()
+		t.Fatal(err)
+	}
+	// Очистка выполняется после закрытия подключения теста, зарегистрированного позже
+	t.Cleanup(func() {
+		if err := conn.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		sqlDB.Close()
+	})
+
+	switch {
+	case strings.Contains(dsn, "://") && strings.Contains(dsn, "?"):
+		return dsn + "&search_path=" + schema
+	case strings.Contains(dsn, "://"):
+		return dsn + "?search_path=" + schema
+	default:
+		return dsn + " search_path=" + schema
+	}
+}
+
+// openTestDatabase подключается к базе, применяет миграции до версии version и подменяет
+// глобальную db до конца теста
+func openTestDatabase(t *testing.T, dsn string, version uint) {
+	t.Helper()
+	dialector, err := openDialector(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateUp(conn, version); err != nil {
+		sqlDB.Close()
+		t.Fatal(err)
+	}
+	if err := initPatientSearch(conn); err != nil {
+		sqlDB.Close()
+		t.Fatal(err)
+	}
+
+	previous := db
+	db = conn
+	t.Cleanup(func() {
+		db = previous
+		sqlDB.Close()
+	})
+}
+
+// testDay - понедельник, на который назначаются приемы в тестах
+var testDay = time.Date(2030, time.March, 4, 0, 0, 0, 0, time.Local)
+
+// testTime возвращает время в тестовый день
+func testTime(hour, minute int) time.Time {
+	return testDay.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+// createTestDoctor создает врача, принимающего ежедневно с 08:00 до 20:00
+func createTestDoctor(t *testing.T, fullName, specialization string) Doctor {
+	t.Helper()
+	doctor := Doctor{FullName: fullName, Specialization: specialization, Active: true}
+	if err := db.Create(&doctor).Error; err != nil {
+		t.Fatal(err)
+	}
+	for weekday := 1; weekday <= 7; weekday++ {
+		schedule := DoctorSchedule{DoctorID: doctor.ID, Weekday: weekday, StartTime: "08:00", EndTime: "20:00"}
+		if err := db.Create(&schedule).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return doctor
+}
+
+// createTestPatient создает пациента
+func createTestPatient(t *testing.T, fullName, phone string) Patient {
+	t.Helper()
+	patient := Patient{
+		FullName:  fullName,
+		BirthDate: time.Date(1980, time.May, 17, 0, 0, 0, 0, time.UTC),
+		Gender:    "female",
+		Phone:     phone,
+	}
+	if err := db.Create(&patient).Error; err != nil {
+		t.Fatal(err)
+	}
+	return patient
+}
+
+// createTestAppointment сохраняет прием без проверок расписания. Пустые поля заполняются
+// значениями по умолчанию: начало в 10:00 тестового дня, длительность 30 минут, статус scheduled.
+func createTestAppointment(t *testing.T, appointment Appointment) Appointment {
+	t.Helper()
+	if appointment.Date.IsZero() {
+		appointment.Date = testTime(10, 0)
+	}
+	if appointment.EndDate.IsZero() {
+		appointment.EndDate = appointment.Date.Add(defaultAppointmentDuration)
+	}
+	if appointment.Status == "" {
+		appointment.Status = statusScheduled
+	}
+	if err := db.Create(&appointment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return appointment
+}
+
+// createTestUser создает пользователя с ролью; для ролей doctor и patient передается ID связанной записи
+func createTestUser(t *testing.T, role string, linkedID uint) User {
+	t.Helper()
+	user := User{Username: role + "-" + strconv.Itoa(int(linkedID)), PasswordHash: "-", Active: true, Role: role}
+	switch role {
+	case roleDoctor:
+		user.DoctorID = &linkedID
+	case rolePatient:
+		user.PatientID = &linkedID
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+var (
+	testRouterOnce sync.Once
+	testRouter     *gin.Engine
+)
+
+// apiRequest выполняет запрос к API от имени пользователя. body сериализуется в JSON.
+func apiRequest(t *testing.T, user User, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	token := ""
+	if user.ID != 0 {
+		now := time.Now()
+		var err error
+		token, err = signAccessToken(accessClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			ID:        randomToken(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tokenRequest(t, token, method, path, body)
+}
+
+// tokenRequest выполняет запрос к API с access-токеном token; пустой токен - запрос без аутентификации
+func tokenRequest(t *testing.T, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	testRouterOnce.Do(func() { testRouter = setupRouter() })
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+// decodeResponse разбирает JSON-ответ, предварительно проверив код статуса
+func decodeResponse[T any](t *testing.T, w *httptest.ResponseRecorder, status int) T {
+	t.Helper()
+	var result T
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode response: %v: %s", err, w.Body.String())
+	}
+	return result
+}
+
+// testContext возвращает контекст запроса, аутентифицированного как user
+func testContext(user User) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Set("user", user)
+	return c, w
+}
@@ -0,0 +1,556 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Кодированные диагнозы приема по МКБ-10. Справочник кодов (таблица icd10_codes)
+// загружается командой icd10 import из файла CSV или JSON; без файла загружается
+// встроенный набор распространенных кодов. У приема может быть несколько диагнозов:
+// один основной (primary) и сопутствующие (secondary). Диагноз хранит код и название
+// из справочника на момент постановки, поэтому обновление справочника не меняет
+// поставленные диагнозы. Текстовое поле diagnosis приема остается заметкой врача.
+
+// Виды диагноза приема
+const (
+	diagnosisPrimary   = "primary"
+	diagnosisSecondary = "secondary"
+)
+
+const (
+	defaultICD10SearchSize = 20
+	maxICD10SearchSize     = 100
+)
+
+// diagnosisOrder - порядок диагнозов приема: основной, затем сопутствующие по времени добавления
+const diagnosisOrder = "CASE kind WHEN 'primary' THEN 0 ELSE 1 END, id"
+
+// icd10Columns - столбцы файла справочника МКБ-10
+var icd10Columns = []string{"code", "title"}
+
+var (
+	// код МКБ-10: буква, две цифры и необязательная подрубрика через точку (I10, J06.9)
+	icd10CodeRe = regexp.MustCompile(`^[A-Z][0-9]{2}(\.[0-9A-Z]{1,4})?$`)
+	// начало кода для поиска и фильтрации (J, J0, J06, J06.)
+	icd10PrefixRe = regexp.MustCompile(`^[A-Z]([0-9]{1,2}|[0-9]{2}\.[0-9A-Z]{0,4})?$`)
+)
+
+// errDiagnosisExists возвращается при повторной постановке того же кода в приеме
+var errDiagnosisExists = errors.New("appointment already has this diagnosis")
+
+// errDiagnosisNotInProgress возвращается при изменении диагнозов до начала приема
+var errDiagnosisNotInProgress = errors.New("diagnoses can only be recorded once the visit is in progress")
+
+// ICD10Code - запись справочника МКБ-10
+// @Description Код и название по МКБ-10
+type ICD10Code struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"-"`
+	Code       string    `gorm:"not null;uniqueIndex" json:"code"`
+	Title      string    `gorm:"not null" json:"title"`
+	SearchText string    `gorm:"not null" json:"-"` // нормализованное название для поиска
+}
+
+func (ICD10Code) TableName() string { return "icd10_codes" }
+
+// AppointmentDiagnosis - диагноз приема по МКБ-10
+// @Description Кодированный диагноз приема
+type AppointmentDiagnosis struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	AppointmentID uint      `gorm:"not null;index;uniqueIndex:idx_appointment_diagnoses_code" json:"appointment_id"`
+	Code          string    `gorm:"not null;index;uniqueIndex:idx_appointment_diagnoses_code" json:"code"`
+	Title         string    `gorm:"not null" json:"title"` // название из справочника на момент постановки
+	Kind          string    `gorm:"not null" json:"kind"`  // primary, secondary
+	Note          string    `json:"note,omitempty"`
+}
+
+type CreateDiagnosisRequest struct {
+	Code string `json:"code" binding:"required"`
+	Kind string `json:"kind"` // primary, secondary; по умолчанию основной, если у приема его еще нет
+	Note string `json:"note"`
+}
+
+type UpdateDiagnosisRequest struct {
+	Kind string `json:"kind" binding:"required"`
+	Note string `json:"note"`
+}
+
+// normalizeICD10Code приводит код к виду справочника: верхний регистр, точка перед подрубрикой
+func normalizeICD10Code(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, ",", ".")
+	return strings.TrimSuffix(code, ".")
+}
+
+// icd10CodePrefix возвращает нормализованное начало кода, если строка похожа на код МКБ-10
+func icd10CodePrefix(s string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	code = strings.ReplaceAll(code, ",", ".")
+	return code, icd10PrefixRe.MatchString(code)
+}
+
+// BeforeSave обновляет нормализованное название для поиска
+func (c *ICD10Code) BeforeSave(tx *gorm.DB) error {
+	c.SearchText = normalizeSearchText(c.Title)
+	return nil
+}
+
+// parseICD10Codes читает справочник из JSON-массива (файл .json) или из CSV с
+// заголовком code,title. Повторный код заменяет предыдущий.
+func parseICD10Codes(name string, data []byte) ([]ICD10Code, error) {
+	var items []ICD10Code
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+	} else {
+		rows, err := readCSVColumns(data, icd10Columns)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			items = append(items, ICD10Code{Code: row[0], Title: row[1]})
+		}
+	}
+
+	index := make(map[string]int)
+	var result []ICD10Code
+	for n, item := range items {
+		item.Code = normalizeICD10Code(item.Code)
+		item.Title = strings.TrimSpace(item.Title)
+		if !icd10CodeRe.MatchString(item.Code) {
+			return nil, fmt.Errorf("record %d: invalid ICD-10 code %q", n+1, item.Code)
+		}
+		if item.Title == "" {
+			return nil, fmt.Errorf("record %d: title must not be empty", n+1)
+		}
+		if i, ok := index[item.Code]; ok {
+			result[i] = item
+			continue
+		}
+		index[item.Code] = len(result)
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// importICD10Codes загружает справочник в одной транзакции: названия существующих кодов
+// обновляются, новые коды добавляются; с replace справочник предварительно очищается
+func importICD10Codes(db *gorm.DB, items []ICD10Code, replace bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Exec("DELETE FROM icd10_codes").Error; err != nil {
+				return err
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{"title", "search_text"}),
+		}).CreateInBatches(&items, 500).Error
+	})
+}
+
+// runICD10 обрабатывает команду icd10
+func runICD10(args []string) {
+	usage := "Usage:\n  demeda icd10 import [--file=path] [--replace] [--config=path]\n\n" +
+		"Without --file, the set of common codes built into the binary is imported. A file is either CSV with the header\n" +
+		"code,title or a JSON array (.json) of objects with the same fields."
+	if len(args) == 0 || args[0] != "import" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("icd10 import", flag.ExitOnError)
+	configPath := configFlag(flags)
+	file := flags.String("file", "", "path to a CSV or JSON file, overrides the built-in code set")
+	replace := flags.Bool("replace", false, "delete all existing codes before loading")
+	flags.Parse(args[1:])
+	loadConfigOrExit(*configPath)
+
+	name, data := "icd10.csv", defaultICD10Codes
+	if *file != "" {
+		var err error
+		if data, err = os.ReadFile(*file); err != nil {
+			fmt.Fprintln(os.Stderr, "icd10:", err)
+			os.Exit(1)
+		}
+		name = *file
+	}
+	items, err := parseICD10Codes(name, data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "icd10: invalid code list:", err)
+		os.Exit(1)
+	}
+
+	openDatabase()
+	requireCurrentSchema()
+	if err := importICD10Codes(db, items, *replace); err != nil {
+		fmt.Fprintln(os.Stderr, "icd10:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Загружено кодов МКБ-10: %d\n", len(items))
+}
+
+// Обработчики справочника МКБ-10
+
+// SearchICD10 godoc
+// @Summary Поиск кодов МКБ-10
+// @Description Подбор кодов МКБ-10 для автодополнения. Строка, похожая на код (I1, J06.9), ищется по началу кода, иначе - по началу слов названия без учета регистра и ё/е. Результаты упорядочены по коду
+// @Tags icd10
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Начало кода или слова названия"
+// @Param limit query int false "Количество результатов (по умолчанию 20, не больше 100)"
+// @Success 200 {array} ICD10Code
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /icd10 [get]
+func searchICD10(c *gin.Context) {
+	limit := defaultICD10SearchSize
+	if value := c.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxICD10SearchSize {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("limit must be between 1 and %d", maxICD10SearchSize)})
+			return
+		}
+	}
+
+	query := db.Order("code").Limit(limit)
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		if prefix, ok := icd10CodePrefix(q); ok {
+			query = query.Where("code LIKE ?", prefix+"%")
+		} else {
+			words := strings.Fields(normalizeSearchText(q))
+			if len(words) == 0 {
+				c.JSON(http.StatusOK, []ICD10Code{})
+				return
+			}
+			for _, word := range words {
+				query = query.Where("(' ' || search_text) LIKE ?", "% "+word+"%")
+			}
+		}
+	}
+
+	codes := []ICD10Code{}
+	if err := query.Find(&codes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, codes)
+}
+
+// GetICD10Code godoc
+// @Summary Получить код МКБ-10
+// @Description Получить название кода МКБ-10 из справочника
+// @Tags icd10
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Код МКБ-10"
+// @Success 200 {object} ICD10Code
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /icd10/{code} [get]
+func getICD10Code(c *gin.Context) {
+	var code ICD10Code
+	if err := db.Where("code = ?", normalizeICD10Code(c.Param("code"))).First(&code).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "ICD-10 code not found"})
+		return
+	}
+	c.JSON(http.StatusOK, code)
+}
+
+// Обработчики диагнозов приема
+
+// validDiagnosisKind проверяет вид диагноза
+func validDiagnosisKind(kind string) bool {
+	return kind == diagnosisPrimary || kind == diagnosisSecondary
+}
+
+// demotePrimaryDiagnosis делает прежний основной диагноз приема сопутствующим
+func demotePrimaryDiagnosis(tx *gorm.DB, c *gin.Context, appointmentID, exceptID uint) error {
+	var previous []AppointmentDiagnosis
+	err := tx.Where("appointment_id = ? AND kind = ? AND id <> ?", appointmentID, diagnosisPrimary, exceptID).
+		Find(&previous).Error
+	if err != nil {
+		return err
+	}
+	for _, d := range previous {
+		before := d
+		d.Kind = diagnosisSecondary
+		if err := tx.Save(&d).Error; err != nil {
+			return err
+		}
+		if err := recordAuditUpdate(tx, c, d.auditRef(), before, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDiagnosis загружает диагноз с его приемом и проверяет право perm; при ошибке ответ уже отправлен
+func loadDiagnosis(c *gin.Context, perm permission) (AppointmentDiagnosis, Appointment, bool) {
+	var diagnosis AppointmentDiagnosis
+	var appointment Appointment
+	if err := db.First(&diagnosis, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Diagnosis not found"})
+		return diagnosis, appointment, false
+	}
+	if err := db.First(&appointment, diagnosis.AppointmentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Diagnosis not found"})
+		return diagnosis, appointment, false
+	}
+	if !authorize(c, perm, appointmentResource(appointment)) {
+		return diagnosis, appointment, false
+	}
+	return diagnosis, appointment, true
+}
+
+// GetAppointmentDiagnoses godoc
+// @Summary Получить диагнозы приема
+// @Description Получить диагнозы приема по МКБ-10: сначала основной, затем сопутствующие
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Success 200 {array} AppointmentDiagnosis
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/diagnoses [get]
+func getAppointmentDiagnoses(c *gin.Context) {
+	var appointment Appointment
+	if err := db.First(&appointment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, permClinicalRead, appointmentResource(appointment)) {
+		return
+	}
+
+	diagnoses := []AppointmentDiagnosis{}
+	if err := db.Where("appointment_id = ?", appointment.ID).Order(diagnosisOrder).Find(&diagnoses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !auditRead(c, auditRefs(diagnoses)...) {
+		return
+	}
+	c.JSON(http.StatusOK, diagnoses)
+}
+
+// CreateAppointmentDiagnosis godoc
+// @Summary Поставить диагноз
+// @Description Добавить к приему диагноз по коду МКБ-10 из справочника. Прием должен быть начат или завершен. Основной диагноз у приема один: новый основной диагноз делает прежний сопутствующим. Без kind диагноз становится основным, если основного еще нет
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Param diagnosis body CreateDiagnosisRequest true "Диагноз"
+// @Success 201 {object} AppointmentDiagnosis
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/diagnoses [post]
+func createAppointmentDiagnosis(c *gin.Context) {
+	var appointment Appointment
+	if err := db.First(&appointment, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, permClinicalWrite, appointmentResource(appointment)) {
+		return
+	}
+
+	var req CreateDiagnosisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.Kind != "" && !validDiagnosisKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "kind must be one of: primary, secondary"})
+		return
+	}
+	if !appointment.clinicalAllowed() {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: errDiagnosisNotInProgress.Error()})
+		return
+	}
+
+	var code ICD10Code
+	if err := db.Where("code = ?", normalizeICD10Code(req.Code)).First(&code).Error; err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("unknown ICD-10 code %q", req.Code)})
+		return
+	}
+
+	diagnosis := AppointmentDiagnosis{
+		AppointmentID: appointment.ID,
+		Code:          code.Code,
+		Title:         code.Title,
+		Kind:          req.Kind,
+		Note:          strings.TrimSpace(req.Note),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []AppointmentDiagnosis
+		if err := tx.Where("appointment_id = ?", appointment.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+		hasPrimary := false
+		for _, d := range existing {
+			if d.Code == diagnosis.Code {
+				return errDiagnosisExists
+			}
+			hasPrimary = hasPrimary || d.Kind == diagnosisPrimary
+		}
+		if diagnosis.Kind == "" {
+			diagnosis.Kind = diagnosisSecondary
+			if !hasPrimary {
+				diagnosis.Kind = diagnosisPrimary
+			}
+		}
+		if diagnosis.Kind == diagnosisPrimary {
+			if err := demotePrimaryDiagnosis(tx, c, appointment.ID, 0); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&diagnosis).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditActionCreate, diagnosis.auditRef())
+	})
+	if errors.Is(err, errDiagnosisExists) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, diagnosis)
+}
+
+// UpdateDiagnosis godoc
+// @Summary Изменить диагноз
+// @Description Изменить вид диагноза (основной или сопутствующий) и комментарий. Новый основной диагноз делает прежний сопутствующим. Код не меняется: ошибочный диагноз удаляется и ставится заново
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID диагноза"
+// @Param diagnosis body UpdateDiagnosisRequest true "Вид и комментарий"
+// @Success 200 {object} AppointmentDiagnosis
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /diagnoses/{id} [put]
+func updateDiagnosis(c *gin.Context) {
+	diagnosis, appointment, ok := loadDiagnosis(c, permClinicalWrite)
+	if !ok {
+		return
+	}
+
+	var req UpdateDiagnosisRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !validDiagnosisKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "kind must be one of: primary, secondary"})
+		return
+	}
+	if !appointment.clinicalAllowed() {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: errDiagnosisNotInProgress.Error()})
+		return
+	}
+
+	before := diagnosis
+	diagnosis.Kind = req.Kind
+	diagnosis.Note = strings.TrimSpace(req.Note)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if diagnosis.Kind == diagnosisPrimary {
+			if err := demotePrimaryDiagnosis(tx, c, appointment.ID, diagnosis.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&diagnosis).Error; err != nil {
+			return err
+		}
+		return recordAuditUpdate(tx, c, diagnosis.auditRef(), before, diagnosis)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, diagnosis)
+}
+
+// DeleteDiagnosis godoc
+// @Summary Удалить диагноз
+// @Description Удалить ошибочно поставленный диагноз приема. Удаление записывается в журнал аудита
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID диагноза"
+// @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /diagnoses/{id} [delete]
+func deleteDiagnosis(c *gin.Context) {
+	diagnosis, appointment, ok := loadDiagnosis(c, permClinicalWrite)
+	if !ok {
+		return
+	}
+	if !appointment.clinicalAllowed() {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: errDiagnosisNotInProgress.Error()})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&diagnosis).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditActionDelete, diagnosis.auditRef())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, "Diagnosis deleted")
+}
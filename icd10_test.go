@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+// loadTestICD10Codes загружает небольшой справочник МКБ-10
+func loadTestICD10Codes(t *testing.T) {
+	t.Helper()
+	items, err := parseICD10Codes("icd10.csv", []byte("code,title\n"+
+		"I10,Эссенциальная [первичная] гипертензия\n"+
+		"I11.9,Гипертензивная [гипертоническая] болезнь с преимущественным поражением сердца\n"+
+		"E11,Инсулиннезависимый сахарный диабет\n"+
+		"E11.9,Инсулиннезависимый сахарный диабет без осложнений\n"+
+		"J06.9,Острая инфекция верхних дыхательных путей неуточненная\n"+
+		"J20.9,Острый бронхит неуточнённый\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := importICD10Codes(db, items, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchICD10(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		loadTestICD10Codes(t)
+		doctor := createTestUser(t, roleDoctor, createTestDoctor(t, "Иванов Иван", "Терапевт").ID)
+		search := func(query string) []string {
+			t.Helper()
+			w := apiRequest(t, doctor, http.MethodGet, "/icd10?"+query, nil)
+			var codes []string
+			for _, code := range decodeResponse[[]ICD10Code](t, w, http.StatusOK) {
+				codes = append(codes, code.Code)
+			}
+			return codes
+		}
+
+		tests := []struct {
+			q    string
+			want []string
+		}{
+			// Начало кода, без учета регистра и с запятой вместо точки
+			{"I1", []string{"I10", "I11.9"}},
+			{"e11", []string{"E11", "E11.9"}},
+			{"E11.", []string{"E11.9"}},
+			{"j06,", []string{"J06.9"}},
+			{"J", []string{"J06.9", "J20.9"}},
+			{"K", nil},
+			// Начала слов названия, без учета регистра и ё/е
+			{"гиперт", []string{"I10", "I11.9"}},
+			{"ГИПЕРТЕНЗИЯ", []string{"I10"}},
+			{"диабет инсулин", []string{"E11", "E11.9"}},
+			{"сахарный осложнений", []string{"E11.9"}},
+			{"острый", []string{"J20.9"}},
+			{"неуточнен", []string{"J06.9", "J20.9"}},
+			{"неуточнённая", []string{"J06.9"}},
+			{"тензия", nil},
+			{"!!!", nil},
+		}
+		for _, tt := range tests {
+			if got := search("q=" + url.QueryEscape(tt.q)); !slices.Equal(got, tt.want) {
+				t.Errorf("q=%q: codes = %v, want %v", tt.q, got, tt.want)
+			}
+		}
+
+		if got := search("q=I&limit=1"); !slices.Equal(got, []string{"I10"}) {
+			t.Errorf("limit=1: codes = %v, want [I10]", got)
+		}
+		for _, limit := range []string{"0", "101", "many"} {
+			if w := apiRequest(t, doctor, http.MethodGet, "/icd10?limit="+limit, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("limit=%s: status %d, want 400", limit, w.Code)
+			}
+		}
+
+		code := decodeResponse[ICD10Code](t, apiRequest(t, doctor, http.MethodGet, "/icd10/j06,9", nil), http.StatusOK)
+		if code.Code != "J06.9" {
+			t.Errorf("GET /icd10/j06,9 = %+v, want J06.9", code)
+		}
+		if w := apiRequest(t, doctor, http.MethodGet, "/icd10/Z99", nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET unknown code: status %d, want 404", w.Code)
+		}
+	})
+}
+
+func TestAppointmentDiagnoses(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		loadTestICD10Codes(t)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Status: statusInProgress})
+		path := fmt.Sprintf("/appointments/%d/diagnoses", appointment.ID)
+		add := func(req CreateDiagnosisRequest, status int) AppointmentDiagnosis {
+			t.Helper()
+			return decodeResponse[AppointmentDiagnosis](t, apiRequest(t, user, http.MethodPost, path, req), status)
+		}
+		kinds := func() map[string]string {
+			t.Helper()
+			w := apiRequest(t, user, http.MethodGet, path, nil)
+			result := make(map[string]string)
+			for _, d := range decodeResponse[[]AppointmentDiagnosis](t, w, http.StatusOK) {
+				result[d.Code] = d.Kind
+			}
+			return result
+		}
+
+		scheduled := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(11, 0)})
+		if w := apiRequest(t, user, http.MethodPost, fmt.Sprintf("/appointments/%d/diagnoses", scheduled.ID),
+			CreateDiagnosisRequest{Code: "I10"}); w.Code != http.StatusConflict {
+			t.Errorf("diagnosis before the visit: status %d, want 409", w.Code)
+		}
+
+		// Первый диагноз без kind становится основным, следующий - сопутствующим
+		hypertension := add(CreateDiagnosisRequest{Code: " i10 "}, http.StatusCreated)
+		if hypertension.Code != "I10" || hypertension.Kind != diagnosisPrimary || hypertension.Title == "" {
+			t.Errorf("first diagnosis = %+v, want primary I10 with title", hypertension)
+		}
+		diabetes := add(CreateDiagnosisRequest{Code: "E11,9"}, http.StatusCreated)
+		if diabetes.Code != "E11.9" || diabetes.Kind != diagnosisSecondary {
+			t.Errorf("second diagnosis = %+v, want secondary E11.9", diabetes)
+		}
+
+		// Повтор кода в том же приеме - конфликт, в каком бы виде код ни был записан
+		for _, req := range []CreateDiagnosisRequest{
+			{Code: "I10"},
+			{Code: "i10", Kind: diagnosisSecondary},
+			{Code: "E11.9", Kind: diagnosisPrimary},
+		} {
+			if w := apiRequest(t, user, http.MethodPost, path, req); w.Code != http.StatusConflict {
+				t.Errorf("duplicate %+v: status %d, want 409", req, w.Code)
+			}
+		}
+		for _, req := range []CreateDiagnosisRequest{{Code: "Z99"}, {Code: "J06.9", Kind: "main"}} {
+			if w := apiRequest(t, user, http.MethodPost, path, req); w.Code != http.StatusBadRequest {
+				t.Errorf("invalid %+v: status %d, want 400", req, w.Code)
+			}
+		}
+		want := map[string]string{"I10": diagnosisPrimary, "E11.9": diagnosisSecondary}
+		if got := kinds(); !maps.Equal(got, want) {
+			t.Fatalf("diagnoses after rejected requests = %v, want %v", got, want)
+		}
+
+		// Новый основной диагноз делает прежний сопутствующим
+		infection := add(CreateDiagnosisRequest{Code: "J06.9", Kind: diagnosisPrimary}, http.StatusCreated)
+		want = map[string]string{"J06.9": diagnosisPrimary, "I10": diagnosisSecondary, "E11.9": diagnosisSecondary}
+		if got := kinds(); !maps.Equal(got, want) {
+			t.Errorf("diagnoses after new primary = %v, want %v", got, want)
+		}
+		w := apiRequest(t, user, http.MethodGet, path, nil)
+		if list := decodeResponse[[]AppointmentDiagnosis](t, w, http.StatusOK); list[0].ID != infection.ID {
+			t.Errorf("first listed diagnosis = %+v, want the primary one", list[0])
+		}
+
+		// То же при смене вида существующего диагноза
+		updated := decodeResponse[AppointmentDiagnosis](t, apiRequest(t, user, http.MethodPut, fmt.Sprintf("/diagnoses/%d", diabetes.ID),
+			UpdateDiagnosisRequest{Kind: diagnosisPrimary}), http.StatusOK)
+		if updated.Kind != diagnosisPrimary {
+			t.Errorf("updated = %+v, want primary", updated)
+		}
+		want = map[string]string{"E11.9": diagnosisPrimary, "I10": diagnosisSecondary, "J06.9": diagnosisSecondary}
+		if got := kinds(); !maps.Equal(got, want) {
+			t.Errorf("diagnoses after update = %v, want %v", got, want)
+		}
+	})
+}
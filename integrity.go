@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReferenceErrorResponse представляет ответ о ссылке на несуществующую запись
+type ReferenceErrorResponse struct {
+	Error  string `json:"error"`
+	Field  string `json:"field"`  // поле запроса со ссылкой, например patient_id
+	Entity string `json:"entity"` // тип отсутствующей записи: patient, doctor, appointment
+	ID     uint   `json:"id"`
+}
+
+// missingReferenceError возвращается, если запрос ссылается на несуществующую или удаленную запись
+type missingReferenceError struct {
+	Field  string
+	Entity string
+	ID     uint
+}
+
+func (e *missingReferenceError) Error() string {
+	return fmt.Sprintf("%s %d referenced by %s does not exist", e.Entity, e.ID, e.Field)
+}
+
+// requireReference проверяет, что запись model с указанным ID существует и не удалена
+func requireReference(tx *gorm.DB, model interface{}, field, entity string, id uint) error {
+	var count int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return &missingReferenceError{Field: field, Entity: entity, ID: id}
+	}
+	return nil
+}
+
+// respondReferenceError отвечает 422, если err - ссылка на несуществующую запись
+func respondReferenceError(c *gin.Context, err error) bool {
+	var missing *missingReferenceError
+	if !errors.As(err, &missing) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, ReferenceErrorResponse{
+		Error:  missing.Error(),
+		Field:  missing.Field,
+		Entity: missing.Entity,
+		ID:     missing.ID,
+	})
+	return true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReferenceErrors(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		removed := createTestPatient(t, "Кузнецова Мария", "")
+		if err := db.Delete(&removed).Error; err != nil {
+			t.Fatal(err)
+		}
+		const missing = 999999
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID})
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			body   interface{}
+			want   ReferenceErrorResponse
+		}{
+			{"missing patient", http.MethodPost, "/appointments",
+				CreateAppointmentRequest{PatientID: missing, DoctorID: doctor.ID, Date: testTime(12, 0)},
+				ReferenceErrorResponse{Field: "patient_id", Entity: "patient", ID: missing}},
+			{"deleted patient", http.MethodPost, "/appointments",
+				CreateAppointmentRequest{PatientID: removed.ID, DoctorID: doctor.ID, Date: testTime(12, 0)},
+				ReferenceErrorResponse{Field: "patient_id", Entity: "patient", ID: removed.ID}},
+			{"missing doctor", http.MethodPost, "/appointments",
+				CreateAppointmentRequest{PatientID: patient.ID, DoctorID: missing, Date: testTime(12, 0)},
+				ReferenceErrorResponse{Field: "doctor_id", Entity: "doctor", ID: missing}},
+			{"update to deleted patient", http.MethodPut, fmt.Sprintf("/appointments/%d", appointment.ID),
+				CreateAppointmentRequest{PatientID: removed.ID, DoctorID: doctor.ID, Date: appointment.Date},
+				ReferenceErrorResponse{Field: "patient_id", Entity: "patient", ID: removed.ID}},
+			{"update to missing doctor", http.MethodPut, fmt.Sprintf("/appointments/%d", appointment.ID),
+				CreateAppointmentRequest{PatientID: patient.ID, DoctorID: missing, Date: appointment.Date},
+				ReferenceErrorResponse{Field: "doctor_id", Entity: "doctor", ID: missing}},
+			{"history of missing patient", http.MethodPost, "/medical_history",
+				CreateMedicalHistoryRequest{PatientID: missing, HistoryType: "chronic", Description: "Гипертония"},
+				ReferenceErrorResponse{Field: "patient_id", Entity: "patient", ID: missing}},
+			{"history of deleted patient", http.MethodPost, "/medical_history",
+				CreateMedicalHistoryRequest{PatientID: removed.ID, HistoryType: "chronic", Description: "Гипертония"},
+				ReferenceErrorResponse{Field: "patient_id", Entity: "patient", ID: removed.ID}},
+		}
+		for _, tt := range tests {
+			got := decodeResponse[ReferenceErrorResponse](t, apiRequest(t, admin, tt.method, tt.path, tt.body), http.StatusUnprocessableEntity)
+			if got.Field != tt.want.Field || got.Entity != tt.want.Entity || got.ID != tt.want.ID || got.Error == "" {
+				t.Errorf("%s: response = %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+
+		var appointments, history int64
+		db.Unscoped().Model(&Appointment{}).Count(&appointments)
+		db.Unscoped().Model(&MedicalHistory{}).Count(&history)
+		if appointments != 1 || history != 0 {
+			t.Errorf("%d appointments and %d history records saved, want 1 and 0", appointments, history)
+		}
+		var stored Appointment
+		if err := db.First(&stored, appointment.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.PatientID != patient.ID || stored.DoctorID != doctor.ID {
+			t.Errorf("appointment = %+v, want unchanged references", stored)
+		}
+	})
+}
+
+func TestForeignKeys(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID})
+		const missing = 999999
+
+		// Строки, записанные в обход обработчиков, тоже не могут ссылаться на несуществующие записи
+		orphans := []struct {
+			name   string
+			record interface{}
+		}{
+			{"appointment of missing patient", &Appointment{PatientID: missing, DoctorID: doctor.ID, Date: testTime(12, 0), EndDate: testTime(12, 30), Status: statusScheduled}},
+			{"appointment of missing doctor", &Appointment{PatientID: patient.ID, DoctorID: missing, Date: testTime(12, 0), EndDate: testTime(12, 30), Status: statusScheduled}},
+			{"test of missing appointment", &MedicalTest{AppointmentID: missing, Name: "Глюкоза"}},
+			{"history of missing patient", &MedicalHistory{PatientID: missing, HistoryType: "chronic", Description: "Гипертония"}},
+			{"schedule of missing doctor", &DoctorSchedule{DoctorID: missing, Weekday: 1, StartTime: "08:00", EndTime: "20:00"}},
+			{"exception of missing doctor", &DoctorScheduleException{DoctorID: missing, StartDate: testDay, EndDate: testDay.AddDate(0, 0, 1)}},
+		}
+		for _, tt := range orphans {
+			if err := db.Create(tt.record).Error; err == nil {
+				t.Errorf("%s: inserted, want a foreign key violation", tt.name)
+			}
+		}
+
+		// Физическое удаление записи, на которую есть ссылки, запрещено
+		if err := db.Unscoped().Delete(&Patient{}, patient.ID).Error; err == nil {
+			t.Error("patient with appointments deleted, want a foreign key violation")
+		}
+		if err := db.Unscoped().Delete(&Appointment{}, appointment.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Unscoped().Delete(&Patient{}, patient.ID).Error; err != nil {
+			t.Errorf("delete patient without references: %v", err)
+		}
+	})
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Проверка лекарственных взаимодействий. База взаимодействий (таблица drug_interactions)
+// хранит пары препаратов или фармакологических групп из drug_classes с тяжестью и
+// обоснованием. Базовый набор встроен в бинарный файл; его копию загружает в пустую
+// таблицу миграция 17, а команда interactions import обновляет базу из встроенного или
+// собственного файла CSV или JSON. Новый препарат
+// пациента сверяется с его действующими назначениями; найденные взаимодействия
+// возвращаются в ответе и записываются в журнал аудита, но назначение не блокируют.
+
+// Тяжесть взаимодействия
+const (
+	interactionMinor           = "minor"
+	interactionModerate        = "moderate"
+	interactionMajor           = "major"
+	interactionContraindicated = "contraindicated"
+)
+
+// interactionSeverities - тяжести взаимодействий по возрастанию
+var interactionSeverities = []string{interactionMinor, interactionModerate, interactionMajor, interactionContraindicated}
+
+// interactionColumns - столбцы файла базы взаимодействий
+var interactionColumns = []string{"drug_a", "drug_b", "severity", "rationale"}
+
+// DrugInteraction - взаимодействие двух препаратов или групп препаратов
+// @Description Лекарственное взаимодействие
+type DrugInteraction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	DrugA     string    `gorm:"not null;uniqueIndex:idx_drug_interactions_pair" json:"drug_a"` // препарат или группа в нормализованном виде
+	DrugB     string    `gorm:"not null;uniqueIndex:idx_drug_interactions_pair;index" json:"drug_b"`
+	Severity  string    `gorm:"not null" json:"severity"` // minor, moderate, major, contraindicated
+	Rationale string    `gorm:"not null" json:"rationale"`
+}
+
+// InteractionWarning - взаимодействие нового препарата с действующим назначением пациента
+type InteractionWarning struct {
+	Drug           string `json:"drug"`            // новый препарат
+	PrescriptionID uint   `json:"prescription_id"` // действующее назначение
+	OtherDrug      string `json:"other_drug"`      // препарат действующего назначения
+	Severity       string `json:"severity"`
+	Rationale      string `json:"rationale"`
+	InteractionID  uint   `json:"interaction_id"` // запись базы взаимодействий
+}
+
+// interactionRank упорядочивает тяжести взаимодействий; неизвестная тяжесть - -1
+func interactionRank(severity string) int {
+	for i, s := range interactionSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeInteraction приводит названия к нормализованному виду и порядку drug_a <= drug_b
+func normalizeInteraction(i *DrugInteraction) error {
+	i.DrugA = normalizeSearchText(i.DrugA)
+	i.DrugB = normalizeSearchText(i.DrugB)
+	i.Severity = strings.ToLower(strings.TrimSpace(i.Severity))
+	i.Rationale = strings.TrimSpace(i.Rationale)
+	if i.DrugA == "" || i.DrugB == "" {
+		return errors.New("drug_a and drug_b must not be empty")
+	}
+	if interactionRank(i.Severity) < 0 {
+		return fmt.Errorf("severity must be one of: %s", strings.Join(interactionSeverities, ", "))
+	}
+	if i.Rationale == "" {
+		return errors.New("rationale must not be empty")
+	}
+	if i.DrugA > i.DrugB {
+		i.DrugA, i.DrugB = i.DrugB, i.DrugA
+	}
+	return nil
+}
+
+// parseInteractions читает базу взаимодействий из JSON-массива (файл .json) или из CSV
+// с заголовком drug_a,drug_b,severity,rationale. Повторная пара заменяет предыдущую.
+func parseInteractions(name string, data []byte) ([]DrugInteraction, error) {
+	var items []DrugInteraction
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if items, err = parseInteractionsCSV(data); err != nil {
+			return nil, err
+		}
+	}
+
+	index := make(map[[2]string]int)
+	var result []DrugInteraction
+	for n, item := range items {
+		item.ID = 0
+		if err := normalizeInteraction(&item); err != nil {
+			return nil, fmt.Errorf("record %d: %w", n+1, err)
+		}
+		pair := [2]string{item.DrugA, item.DrugB}
+		if i, ok := index[pair]; ok {
+			result[i] = item
+			continue
+		}
+		index[pair] = len(result)
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// parseInteractionsCSV читает записи CSV; порядок столбцов задается заголовком
+func parseInteractionsCSV(data []byte) ([]DrugInteraction, error) {
+	rows, err := readCSVColumns(data, interactionColumns)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]DrugInteraction, len(rows))
+	for i, row := range rows {
+		items[i] = DrugInteraction{DrugA: row[0], DrugB: row[1], Severity: row[2], Rationale: row[3]}
+	}
+	return items, nil
+}
+
+// importInteractions загружает взаимодействия в одной транзакции: существующие пары
+// обновляются, новые добавляются; с replace таблица предварительно очищается
+func importInteractions(db *gorm.DB, items []DrugInteraction, replace bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Exec("DELETE FROM drug_interactions").Error; err != nil {
+				return err
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "drug_a"}, {Name: "drug_b"}},
+			DoUpdates: clause.AssignmentColumns([]string{"severity", "rationale"}),
+		}).CreateInBatches(&items, 100).Error
+	})
+}
+
+// runInteractions обрабатывает команду interactions
+func runInteractions(args []string) {
+	usage := "Usage:\n  demeda interactions import [--file=path] [--replace] [--config=path]\n\n" +
+		"Without --file, the knowledge base built into the binary is imported. A file is either CSV with the header\n" +
+		"drug_a,drug_b,severity,rationale or a JSON array (.json) of objects with the same fields."
+	if len(args) == 0 || args[0] != "import" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("interactions import", flag.ExitOnError)
+	configPath := configFlag(flags)
+	file := flags.String("file", "", "path to a CSV or JSON file, overrides the built-in knowledge base")
+	replace := flags.Bool("replace", false, "delete all existing interactions before loading")
+	flags.Parse(args[1:])
+	loadConfigOrExit(*configPath)
+
+	name, data := "interactions.csv", defaultInteractions
+	if *file != "" {
+		var err error
+		if data, err = os.ReadFile(*file); err != nil {
+			fmt.Fprintln(os.Stderr, "interactions:", err)
+			os.Exit(1)
+		}
+		name = *file
+	}
+	items, err := parseInteractions(name, data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "interactions: invalid knowledge base:", err)
+		os.Exit(1)
+	}
+
+	openDatabase()
+	requireCurrentSchema()
+	if err := importInteractions(db, items, *replace); err != nil {
+		fmt.Fprintln(os.Stderr, "interactions:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Загружено взаимодействий: %d\n", len(items))
+}
+
+// drugTerms - нормализованное название препарата и его группы
+type drugTerms struct {
+	name    string
+	classes map[string]bool
+}
+
+func newDrugTerms(drug string, classes []DrugClass) drugTerms {
+	name := normalizeSearchText(drug)
+	groups, _ := drugClassesOf(name, classes)
+	return drugTerms{name: name, classes: groups}
+}
+
+// matches проверяет, что препарат подходит под название или группу из базы взаимодействий
+func (t drugTerms) matches(term string) bool {
+	return t.classes[term] || containsWord(t.name, term)
+}
+
+// findInteraction возвращает самое тяжелое взаимодействие препаратов a и b или nil
+func findInteraction(rules []DrugInteraction, a, b drugTerms) *DrugInteraction {
+	var found *DrugInteraction
+	for i, r := range rules {
+		if !(a.matches(r.DrugA) && b.matches(r.DrugB)) && !(a.matches(r.DrugB) && b.matches(r.DrugA)) {
+			continue
+		}
+		if found == nil || interactionRank(r.Severity) > interactionRank(found.Severity) {
+			found = &rules[i]
+		}
+	}
+	return found
+}
+
+// checkInteractions сверяет препараты drugs с действующими назначениями пациента, кроме
+// назначения excludeID (изменяемого). Предупреждения упорядочены от самых тяжелых.
+func checkInteractions(tx *gorm.DB, patientID uint, drugs []string, excludeID uint) ([]InteractionWarning, error) {
+	if len(drugs) == 0 {
+		return nil, nil
+	}
+	query := tx.Where("patient_id = ? AND id <> ?", patientID, excludeID).
+		Where(activePrescriptionCondition, time.Now())
+	var active []Prescription
+	if err := query.Order("id").Find(&active).Error; err != nil {
+		return nil, err
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+	var rules []DrugInteraction
+	if err := tx.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	var classes []DrugClass
+	if err := tx.Find(&classes).Error; err != nil {
+		return nil, err
+	}
+
+	var warnings []InteractionWarning
+	for _, drug := range drugs {
+		terms := newDrugTerms(drug, classes)
+		for _, p := range active {
+			r := findInteraction(rules, terms, newDrugTerms(p.Drug, classes))
+			if r == nil {
+				continue
+			}
+			warnings = append(warnings, InteractionWarning{
+				Drug:           drug,
+				PrescriptionID: p.ID,
+				OtherDrug:      p.Drug,
+				Severity:       r.Severity,
+				Rationale:      r.Rationale,
+				InteractionID:  r.ID,
+			})
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return interactionRank(warnings[i].Severity) > interactionRank(warnings[j].Severity)
+	})
+	return warnings, nil
+}
+
+// recordInteractionAlert записывает в журнал аудита найденные взаимодействия
+func recordInteractionAlert(tx *gorm.DB, c *gin.Context, ref auditRef, warnings []InteractionWarning) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+	return recordAuditDetails(tx, c, auditActionInteractionAlert, ref, map[string]interface{}{"warnings": warnings})
+}
+
+// Обработчики базы взаимодействий
+
+// GetDrugInteractions godoc
+// @Summary Получить базу взаимодействий
+// @Description Получить записи базы лекарственных взаимодействий; с drug - только относящиеся к препарату или его группам
+// @Tags interactions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param drug query string false "Препарат"
+// @Success 200 {array} DrugInteraction
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /interactions [get]
+func getDrugInteractions(c *gin.Context) {
+	var rules []DrugInteraction
+	if err := db.Order("drug_a, drug_b").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	drug := c.Query("drug")
+	if drug == "" {
+		c.JSON(http.StatusOK, rules)
+		return
+	}
+
+	var classes []DrugClass
+	if err := db.Find(&classes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	terms := newDrugTerms(drug, classes)
+	matched := []DrugInteraction{}
+	for _, r := range rules {
+		if terms.matches(r.DrugA) || terms.matches(r.DrugB) {
+			matched = append(matched, r)
+		}
+	}
+	c.JSON(http.StatusOK, matched)
+}
+
+// CheckPatientInteractions godoc
+// @Summary Проверить взаимодействия препарата
+// @Description Проверить, взаимодействует ли препарат с действующими назначениями пациента, не создавая назначения
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пациента"
+// @Param drug query string true "Препарат"
+// @Success 200 {array} InteractionWarning
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /patients/{id}/interactions [get]
+func checkPatientInteractions(c *gin.Context) {
+	patientID, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid patient id"})
+		return
+	}
+	drug := strings.TrimSpace(c.Query("drug"))
+	if drug == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "drug is required"})
+		return
+	}
+	if !authorize(c, permPrescriptionsRead, resource{PatientID: uint(patientID)}) {
+		return
+	}
+
+	warnings, err := checkInteractions(db, uint(patientID), []string{drug}, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if warnings == nil {
+		warnings = []InteractionWarning{}
+	}
+	c.JSON(http.StatusOK, warnings)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFindInteraction(t *testing.T) {
+	classes := []DrugClass{
+		{Drug: "ибупрофен", Class: "нпвп"},
+		{Drug: "диклофенак", Class: "нпвп"},
+		{Drug: "аспирин", Class: "нпвп"},
+		{Drug: "лизиноприл", Class: "ингибиторы апф"},
+		{Drug: "эналаприл", Class: "ингибиторы апф"},
+		{Drug: "кларитромицин", Class: "макролиды"},
+	}
+	rules := []DrugInteraction{
+		{ID: 1, DrugA: "ингибиторы апф", DrugB: "нпвп", Severity: interactionModerate},
+		{ID: 2, DrugA: "нпвп", DrugB: "нпвп", Severity: interactionModerate},
+		{ID: 3, DrugA: "аспирин", DrugB: "ибупрофен", Severity: interactionMinor},
+		{ID: 4, DrugA: "варфарин", DrugB: "ибупрофен", Severity: interactionMinor},
+		{ID: 5, DrugA: "варфарин", DrugB: "нпвп", Severity: interactionMajor},
+		{ID: 6, DrugA: "кларитромицин", DrugB: "симвастатин", Severity: interactionContraindicated},
+		{ID: 7, DrugA: "макролиды", DrugB: "симвастатин", Severity: interactionMajor},
+		{ID: 8, DrugA: "ингибиторы апф", DrugB: "спиронолактон", Severity: interactionMajor},
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+		want uint // ID правила; 0 - взаимодействия нет
+	}{
+		{"drug pair", "симвастатин", "кларитромицин", 6},
+		{"drug pair, reversed", "кларитромицин", "симвастатин", 6},
+		{"drug and class", "лизиноприл", "спиронолактон", 8},
+		{"class and class", "эналаприл", "диклофенак", 1},
+		{"class and class, reversed", "диклофенак", "эналаприл", 1},
+		{"two drugs of the same class", "ибупрофен", "диклофенак", 2},
+		{"class rule more severe than drug rule", "варфарин", "ибупрофен", 5},
+		{"drug rule more severe than class rule", "симвастатин", "кларитромицин", 6},
+		{"class rule more severe than pair rule within a class", "аспирин", "ибупрофен", 2},
+		{"brand name with suffix", "Эналаприл-Тева 10 мг", "Ибупрофен", 1},
+		{"case and ё", "ЛИЗИНОПРИЛ", "Спиронолактон", 8},
+		{"no interaction", "парацетамол", "ибупрофен", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, order := range []string{"rules in order", "rules reversed"} {
+				list := slices.Clone(rules)
+				if order == "rules reversed" {
+					slices.Reverse(list)
+				}
+				got := findInteraction(list, newDrugTerms(tt.a, classes), newDrugTerms(tt.b, classes))
+				var id uint
+				if got != nil {
+					id = got.ID
+				}
+				if id != tt.want {
+					t.Errorf("%s: rule %d, want %d", order, id, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInteractions(t *testing.T) {
+	csv := "\ufeffSeverity,Drug_B,Drug_A,Rationale,Source\n" +
+		"Major,Варфарин,НПВП,Риск кровотечения,-\n" +
+		"minor,Парацетамол,Варфарин,Рост МНО,-\n" +
+		"moderate,нпвп,варфарин,Повторная пара заменяет предыдущую,-\n"
+	items, err := parseInteractions("local.csv", []byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, i := range items {
+		got = append(got, fmt.Sprintf("%s|%s|%s|%s", i.DrugA, i.DrugB, i.Severity, i.Rationale))
+	}
+	want := []string{
+		"варфарин|нпвп|moderate|Повторная пара заменяет предыдущую",
+		"варфарин|парацетамол|minor|Рост МНО",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("items = %q, want %q", got, want)
+	}
+
+	invalid := map[string]string{
+		"unknown severity": "drug_a,drug_b,severity,rationale\nварфарин,нпвп,severe,-\n",
+		"empty drug":       "drug_a,drug_b,severity,rationale\nварфарин,,major,-\n",
+		"empty rationale":  "drug_a,drug_b,severity,rationale\nварфарин,нпвп,major,\n",
+		"missing column":   "drug_a,drug_b,severity\nварфарин,нпвп,major\n",
+	}
+	for name, data := range invalid {
+		if _, err := parseInteractions("local.csv", []byte(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	if _, err := parseInteractions("interactions.csv", defaultInteractions); err != nil {
+		t.Errorf("built-in knowledge base: %v", err)
+	}
+}
+
+// createTestPrescription создает назначение пациенту в рамках приема appointment
+func createTestPrescription(t *testing.T, appointment Appointment, drug string, configure func(*Prescription)) Prescription {
+	t.Helper()
+	prescription := Prescription{
+		AppointmentID: appointment.ID,
+		PatientID:     appointment.PatientID,
+		DoctorID:      appointment.DoctorID,
+		Drug:          drug,
+		Dose:          1,
+		DoseUnit:      "таб",
+		Route:         "oral",
+		Frequency:     "1 раз в день",
+		StartDate:     time.Now().AddDate(0, 0, -7),
+	}
+	if configure != nil {
+		configure(&prescription)
+	}
+	if err := db.Create(&prescription).Error; err != nil {
+		t.Fatal(err)
+	}
+	return prescription
+}
+
+func TestCheckInteractions(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID})
+		otherAppointment := createTestAppointment(t, Appointment{PatientID: otherPatient.ID, DoctorID: doctor.ID, Date: testTime(11, 0)})
+
+		// База взаимодействий заполнена миграцией, команда interactions import не запускалась
+		ibuprofen := createTestPrescription(t, appointment, "Ибупрофен 400 мг", nil)
+		warfarin := createTestPrescription(t, appointment, "Варфарин", nil)
+		simvastatin := createTestPrescription(t, appointment, "Симвастатин", nil)
+		yesterday := time.Now().AddDate(0, 0, -1)
+		createTestPrescription(t, appointment, "Спиронолактон", func(p *Prescription) { p.DiscontinuedAt = &yesterday })
+		createTestPrescription(t, appointment, "Омепразол", func(p *Prescription) { p.EndDate = &yesterday })
+		createTestPrescription(t, otherAppointment, "Спиронолактон", nil)
+
+		type warning struct {
+			prescription uint
+			severity     string
+		}
+		tests := []struct {
+			name      string
+			drugs     []string
+			excludeID uint
+			want      []warning
+		}{
+			{"ordered by severity", []string{"Кларитромицин"}, 0, []warning{
+				{simvastatin.ID, interactionContraindicated},
+				{warfarin.ID, interactionMajor},
+			}},
+			{"class-level rule", []string{"Лизиноприл"}, 0, []warning{{ibuprofen.ID, interactionModerate}}},
+			{"several drugs", []string{"Диклофенак", "Кларитромицин"}, 0, []warning{
+				{simvastatin.ID, interactionContraindicated},
+				{warfarin.ID, interactionMajor},
+				{warfarin.ID, interactionMajor},
+				{ibuprofen.ID, interactionModerate},
+			}},
+			{"changed prescription is excluded", []string{"Кларитромицин"}, simvastatin.ID, []warning{{warfarin.ID, interactionMajor}}},
+			{"no interaction", []string{"Амоксициллин"}, 0, nil},
+			{"no drugs", nil, 0, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				warnings, err := checkInteractions(db, patient.ID, tt.drugs, tt.excludeID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []warning
+				for _, w := range warnings {
+					got = append(got, warning{w.PrescriptionID, w.Severity})
+					if w.Rationale == "" || w.InteractionID == 0 {
+						t.Errorf("warning without rationale or rule: %+v", w)
+					}
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("warnings = %v, want %v", got, tt.want)
+				}
+			})
+		}
+
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		w := apiRequest(t, user, http.MethodGet, fmt.Sprintf("/patients/%d/interactions?drug=%s", patient.ID, url.QueryEscape("Лизиноприл")), nil)
+		got := decodeResponse[[]InteractionWarning](t, w, http.StatusOK)
+		if len(got) != 1 || got[0].PrescriptionID != ibuprofen.ID || got[0].Severity != interactionModerate {
+			t.Errorf("GET interactions = %+v", got)
+		}
+	})
+}
+
+func TestMigrationDrugInteractionsSeed(t *testing.T) {
+	pair := func(t *testing.T) []string {
+		t.Helper()
+		var rules []DrugInteraction
+		if err := db.Order("drug_a, drug_b").Find(&rules).Error; err != nil {
+			t.Fatal(err)
+		}
+		var pairs []string
+		for _, r := range rules {
+			pairs = append(pairs, r.DrugA+"|"+r.DrugB+"|"+r.Severity)
+		}
+		return pairs
+	}
+
+	t.Run("empty table", func(t *testing.T) {
+		forEachDatabaseAt(t, 16, func(t *testing.T) {
+			if _, err := migrateUp(db, 17); err != nil {
+				t.Fatal(err)
+			}
+			got := pair(t)
+			if len(got) != len(m0017DrugInteractions) {
+				t.Errorf("%d interactions loaded, want %d", len(got), len(m0017DrugInteractions))
+			}
+			for _, want := range []string{"ингибиторы апф|нпвп|moderate", "кларитромицин|симвастатин|contraindicated"} {
+				if !slices.Contains(got, want) {
+					t.Errorf("%s is not loaded", want)
+				}
+			}
+
+			// Откат удаляет неизмененные записи наполнения и оставляет измененные
+			if err := db.Model(&DrugInteraction{}).Where("drug_a = ? AND drug_b = ?", "варфарин", "нпвп").
+				Update("severity", interactionContraindicated).Error; err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrateDown(db, 1); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := pair(t), []string{"варфарин|нпвп|contraindicated"}; !slices.Equal(got, want) {
+				t.Errorf("after down: %v, want %v", got, want)
+			}
+		})
+	})
+
+	t.Run("imported knowledge base is kept", func(t *testing.T) {
+		forEachDatabaseAt(t, 16, func(t *testing.T) {
+			if err := importInteractions(db, []DrugInteraction{
+				{DrugA: "варфарин", DrugB: "нпвп", Severity: interactionMinor, Rationale: "Местная база"},
+			}, false); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrateUp(db, 17); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := pair(t), []string{"варфарин|нпвп|minor"}; !slices.Equal(got, want) {
+				t.Errorf("interactions = %v, want %v", got, want)
+			}
+		})
+	})
+}
+
+func TestTreatmentInteractions(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Кардиолог")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		earlier := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(9, 0), Status: statusCompleted})
+		createTestPrescription(t, earlier, "Нитроглицерин 0.5 мг", nil)
+		visit := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Status: statusInProgress})
+		user := createTestUser(t, roleDoctor, doctor.ID)
+
+		update := func(treatment string) Appointment {
+			t.Helper()
+			w := apiRequest(t, user, http.MethodPut, fmt.Sprintf("/appointments/%d", visit.ID), CreateAppointmentRequest{
+				PatientID: patient.ID, DoctorID: doctor.ID, Date: visit.Date, Treatment: treatment,
+			})
+			return decodeResponse[Appointment](t, w, http.StatusOK)
+		}
+
+		// Силденафил есть только в базе взаимодействий, в таблице групп его нет
+		got := update("Силденафил 50 мг за час до нагрузки")
+		if len(got.InteractionWarnings) != 1 || got.InteractionWarnings[0].Severity != interactionContraindicated ||
+			got.InteractionWarnings[0].Drug != "силденафил" {
+			t.Errorf("interaction_warnings = %+v, want contraindicated силденафил", got.InteractionWarnings)
+		}
+
+		// Препарат, уже упомянутый в лечении, повторно не проверяется; название группы препаратом не считается
+		if got := update("Силденафил 50 мг, при болях НПВП"); len(got.InteractionWarnings) != 0 {
+			t.Errorf("interaction_warnings = %+v, want none", got.InteractionWarnings)
+		}
+
+		var alerts int64
+		if err := db.Model(&AuditLog{}).Where("action = ?", auditActionInteractionAlert).Count(&alerts).Error; err != nil {
+			t.Fatal(err)
+		}
+		if alerts != 1 {
+			t.Errorf("%d interaction alerts in the audit log, want 1", alerts)
+		}
+	})
+}
+
+func TestDrugsInText(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		known, err := knownDrugs(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := drugsInText("Метформин 500 мг, амоксициллин; варфарин по МНО, НПВП не назначать", known)
+		slices.Sort(got)
+		if want := []string{"амоксициллин", "варфарин", "метформин"}; !slices.Equal(got, want) {
+			t.Errorf("drugs = %q, want %q", got, want)
+		}
+	})
+}
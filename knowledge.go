@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Встроенные справочники. Файлы из knowledge/ встраиваются в бинарный файл и
+// загружаются в базу командами импорта; вместо них можно загрузить собственный файл.
+
+// defaultInteractions - встроенная база лекарственных взаимодействий
+//
+//go:embed knowledge/interactions.csv
+var defaultInteractions []byte
+
+// defaultICD10Codes - встроенный набор распространенных кодов МКБ-10
+//
+//go:embed knowledge/icd10.csv
+var defaultICD10Codes []byte
+
+// readCSVColumns читает CSV с заголовком и возвращает значения столбцов columns в заданном
+// порядке. Порядок столбцов в файле задается заголовком, лишние столбцы пропускаются.
+func readCSVColumns(data []byte, columns []string) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	positions := make([]int, len(columns))
+	for i, name := range columns {
+		pos, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("header: missing column %q", name)
+		}
+		positions[i] = pos
+	}
+
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make([]string, len(columns))
+		for i, pos := range positions {
+			row[i] = record[pos]
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Результаты и референсные интервалы тестов вводятся свободным текстом. Числовой
+// результат ("5.2", "5,2 ммоль/л", "<0.1") и интервал ("3.5-5.2", "<5.2", ">60",
+// "от 3.5 до 5.2") разбираются при сохранении теста, числа сохраняются отдельно, и
+// результат получает флаг отклонения. Составные значения (давление "120/80" с
+// интервалом "90-139/60-89") сравниваются покомпонентно, флаг теста - худший из флагов
+// компонентов. Нечисловые результаты ("отрицательно") флаг не получают.
+
+// Флаги результата теста (обозначения HL7)
+const (
+	flagNormal       = "N"
+	flagLow          = "L"
+	flagHigh         = "H"
+	flagCriticalLow  = "LL"
+	flagCriticalHigh = "HH"
+)
+
+// abnormalFlags - флаги результатов вне референсного интервала
+var abnormalFlags = []string{flagLow, flagHigh, flagCriticalLow, flagCriticalHigh}
+
+// criticalFlags - флаги результатов вне критического интервала
+var criticalFlags = []string{flagCriticalLow, flagCriticalHigh}
+
+const labNumber = `([-+]?\d+(?:[.,]\d+)?)`
+
+var (
+	// число, затем необязательная вторая часть через "/" и единицы измерения
+	labResultRe = regexp.MustCompile(`^(?:[<>≤≥]=?\s*)?` + labNumber + `(?:\s*/\s*` + labNumber + `)?(?:\s+\D*)?$`)
+
+	labRangeRes = []struct {
+		re        *regexp.Regexp
+		low, high int // номера групп с границами; 0 - граница отсутствует
+	}{
+		{regexp.MustCompile(`^` + labNumber + `\s*(?:-|–|—|\.\.)\s*` + labNumber + `$`), 1, 2},
+		{regexp.MustCompile(`^(?i:от)\s*` + labNumber + `\s*(?i:до)\s*` + labNumber + `$`), 1, 2},
+		{regexp.MustCompile(`^(?:<=?|≤|(?i:до))\s*` + labNumber + `$`), 0, 1},
+		{regexp.MustCompile(`^(?:>=?|≥|(?i:от))\s*` + labNumber + `$`), 1, 0},
+	}
+
+	labUnitSuffixRe = regexp.MustCompile(`\s+\D*$`)
+)
+
+// labRange - числовой интервал; отсутствующая граница не ограничивает значение
+type labRange struct {
+	Low  *float64
+	High *float64
+}
+
+// parseLabNumber разбирает число с точкой или запятой в качестве десятичного разделителя
+func parseLabNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return v, err == nil
+}
+
+// parseLabResult возвращает числовые компоненты результата или nil для нечислового результата
+func parseLabResult(s string) []float64 {
+	m := labResultRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil
+	}
+	var values []float64
+	for _, part := range m[1:] {
+		if part == "" {
+			continue
+		}
+		v, ok := parseLabNumber(part)
+		if !ok {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// parseLabRange возвращает интервалы компонентов или nil, если текст не является интервалом
+func parseLabRange(s string) []labRange {
+	s = strings.TrimSpace(s)
+	if r, ok := parseLabRangeComponent(labUnitSuffixRe.ReplaceAllString(s, "")); ok {
+		return []labRange{r}
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return nil
+	}
+	ranges := make([]labRange, len(parts))
+	for i, part := range parts {
+		r, ok := parseLabRangeComponent(strings.TrimSpace(labUnitSuffixRe.ReplaceAllString(strings.TrimSpace(part), "")))
+		if !ok {
+			return nil
+		}
+		ranges[i] = r
+	}
+	return ranges
+}
+
+// parseLabRangeComponent разбирает интервал одного компонента
+func parseLabRangeComponent(s string) (labRange, bool) {
+	for _, form := range labRangeRes {
+		m := form.re.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		var r labRange
+		for _, bound := range []struct {
+			group  int
+			target **float64
+		}{{form.low, &r.Low}, {form.high, &r.High}} {
+			if bound.group == 0 {
+				continue
+			}
+			v, ok := parseLabNumber(m[bound.group])
+			if !ok {
+				return labRange{}, false
+			}
+			*bound.target = &v
+		}
+		if r.Low != nil && r.High != nil && *r.Low > *r.High {
+			return labRange{}, false
+		}
+		return r, true
+	}
+	return labRange{}, false
+}
+
+// labFlag вычисляет флаг значения по референсному и необязательному критическому интервалу
+func labFlag(value float64, reference labRange, critical *labRange) string {
+	switch {
+	case critical != nil && critical.Low != nil && value < *critical.Low:
+		return flagCriticalLow
+	case critical != nil && critical.High != nil && value > *critical.High:
+		return flagCriticalHigh
+	case reference.Low != nil && value < *reference.Low:
+		return flagLow
+	case reference.High != nil && value > *reference.High:
+		return flagHigh
+	}
+	return flagNormal
+}
+
+// flagSeverity упорядочивает флаги по значимости для выбора худшего
+func flagSeverity(flag string) int {
+	switch flag {
+	case flagCriticalLow, flagCriticalHigh:
+		return 3
+	case flagLow, flagHigh:
+		return 2
+	case flagNormal:
+		return 1
+	}
+	return 0
+}
+
+// labEvaluation - числовые значения и флаг, полученные из текстовых полей теста
+type labEvaluation struct {
+	ResultValue, ResultValue2 *float64
+	RangeLow, RangeHigh       *float64
+	RangeLow2, RangeHigh2     *float64
+	Flag                      string
+}
+
+// evaluateLabResult разбирает результат, референсный и критический интервалы теста
+func evaluateLabResult(result, referenceRange, criticalRange string) labEvaluation {
+	var e labEvaluation
+	values := parseLabResult(result)
+	if len(values) > 0 {
+		e.ResultValue = &values[0]
+	}
+	if len(values) > 1 {
+		e.ResultValue2 = &values[1]
+	}
+	ranges := parseLabRange(referenceRange)
+	if len(ranges) > 0 {
+		e.RangeLow, e.RangeHigh = ranges[0].Low, ranges[0].High
+	}
+	if len(ranges) > 1 {
+		e.RangeLow2, e.RangeHigh2 = ranges[1].Low, ranges[1].High
+	}
+	critical := parseLabRange(criticalRange)
+
+	// Флаг вычисляется, только если у результата и интервала одинаковое число компонентов
+	if len(values) == 0 || len(values) != len(ranges) {
+		return e
+	}
+	for i, v := range values {
+		var crit *labRange
+		if i < len(critical) {
+			crit = &critical[i]
+		}
+		if flag := labFlag(v, ranges[i], crit); flagSeverity(flag) > flagSeverity(e.Flag) {
+			e.Flag = flag
+		}
+	}
+	return e
+}
+
+// BeforeSave обновляет числовые значения и флаг при создании и сохранении теста
+func (t *MedicalTest) BeforeSave(tx *gorm.DB) error {
+	e := evaluateLabResult(t.Result, t.ReferenceRange, t.CriticalRange)
+	t.ResultValue, t.ResultValue2 = e.ResultValue, e.ResultValue2
+	t.RangeLow, t.RangeHigh = e.RangeLow, e.RangeHigh
+	t.RangeLow2, t.RangeHigh2 = e.RangeLow2, e.RangeHigh2
+	t.Flag = e.Flag
+	return nil
+}
+
+// GetPatientAbnormalResults godoc
+// @Summary Получить отклонения в анализах пациента
+// @Description Получить результаты тестов пациента вне референсного интервала (флаги L, H, LL, HH)
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пациента"
+// @Param critical query bool false "Только критические отклонения (LL, HH)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, name, -name, date, -date, created_at, -created_at) default(date)
+// @Success 200 {object} Page[MedicalTest]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /patients/{id}/abnormal-results [get]
+func getPatientAbnormalResults(c *gin.Context) {
+	id := c.Param("id")
+	p, ok := paginate(c, medicalTestList)
+	if !ok {
+		return
+	}
+
+	flags := abnormalFlags
+	if value := c.Query("critical"); value != "" {
+		critical, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid 'critical' parameter"})
+			return
+		}
+		if critical {
+			flags = criticalFlags
+		}
+	}
+
+	query := db.Joins("JOIN appointments ON appointments.id = medical_tests.appointment_id").
+		Preload("Appointment").
+		Where("appointments.patient_id = ? AND medical_tests.flag IN ?", id, flags)
+	query = scopeQuery(c, permTestsRead, query, "appointments.patient_id", "appointments.doctor_id")
+
+	var tests []MedicalTest
+	if err := p.apply(query).Find(&tests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	page := p.page(tests)
+	user := currentUser(c)
+	for i := range page.Data {
+		redactAppointment(user, &page.Data[i].Appointment)
+	}
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestParseLabResult(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []float64
+	}{
+		{"5.2", []float64{5.2}},
+		{"5,2", []float64{5.2}},
+		{"5,2 ммоль/л", []float64{5.2}},
+		{" 140 ", []float64{140}},
+		{"-3", []float64{-3}},
+		{"<0.1", []float64{0.1}},
+		{"> 90", []float64{90}},
+		{"≤5", []float64{5}},
+		{"120/80", []float64{120, 80}},
+		{"120 / 80 мм рт. ст.", []float64{120, 80}},
+		{"отрицательно", nil},
+		{"следы", nil},
+		{"", nil},
+		{"5.2.1", nil},
+		{"1+", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := parseLabResult(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("parseLabResult(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// formatLabRanges записывает интервалы строкой для сравнения: "low..high", отсутствующая граница - "*"
+func formatLabRanges(ranges []labRange) string {
+	bound := func(v *float64) string {
+		if v == nil {
+			return "*"
+		}
+		return fmt.Sprint(*v)
+	}
+	var s string
+	for i, r := range ranges {
+		if i > 0 {
+			s += " / "
+		}
+		s += bound(r.Low) + ".." + bound(r.High)
+	}
+	return s
+}
+
+func TestParseLabRange(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" - не интервал
+	}{
+		{"3.5-5.2", "3.5..5.2"},
+		{"3,5 - 5,2", "3.5..5.2"},
+		{"3.5–5.2", "3.5..5.2"},
+		{"3.5..5.2", "3.5..5.2"},
+		{"3.9-6.1 ммоль/л", "3.9..6.1"},
+		{"от 3.5 до 5.2", "3.5..5.2"},
+		{"От 120 До 140", "120..140"},
+		{"<5.2", "*..5.2"},
+		{"<=5.2", "*..5.2"},
+		{"до 5", "*..5"},
+		{">60", "60..*"},
+		{"≥ 60", "60..*"},
+		{"от 60", "60..*"},
+		{"-5-5", "-5..5"},
+		{"90-139/60-89", "90..139 / 60..89"},
+		{"90-139/60-89 мм рт. ст.", "90..139 / 60..89"},
+		{"<140/<90", "*..140 / *..90"},
+		{"5.2-3.5", ""},
+		{"норма", ""},
+		{"", ""},
+		{"1-2/3-4/5-6", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := formatLabRanges(parseLabRange(tt.in)); got != tt.want {
+				t.Errorf("parseLabRange(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateLabResult(t *testing.T) {
+	tests := []struct {
+		result, reference, critical string
+		flag                        string
+	}{
+		// a-b
+		{"4.5", "3.5-5.2", "", flagNormal},
+		{"3.5", "3.5-5.2", "", flagNormal},
+		{"5.2", "3.5-5.2", "", flagNormal},
+		{"3.4", "3.5-5.2", "", flagLow},
+		{"5.3", "3.5-5.2", "", flagHigh},
+		// <x и >x
+		{"4.9", "<5.2", "", flagNormal},
+		{"6", "<5.2", "", flagHigh},
+		{"-1", "<5.2", "", flagNormal},
+		{"75", ">60", "", flagNormal},
+		{"45", ">60", "", flagLow},
+		// критический интервал
+		{"3.1", "3.5-5.2", "3.0-6.5", flagLow},
+		{"2.4", "3.5-5.2", "2.5-6.5", flagCriticalLow},
+		{"6.6", "3.5-5.2", "2.5-6.5", flagCriticalHigh},
+		{"15", "<10", ">1", flagHigh},
+		{"0.5", "<10", ">1", flagCriticalLow},
+		// запятая в качестве десятичного разделителя
+		{"5,3", "3,5-5,2", "", flagHigh},
+		{"4,1 ммоль/л", "3.5-5.2", "", flagNormal},
+		// нечисловые результаты и интервалы
+		{"отрицательно", "отрицательно", "", ""},
+		{"положительно", "3.5-5.2", "", ""},
+		{"4.5", "норма", "", ""},
+		{"4.5", "", "", ""},
+		// составные значения
+		{"120/80", "90-139/60-89", "", flagNormal},
+		{"150/80", "90-139/60-89", "", flagHigh},
+		{"120/55", "90-139/60-89", "", flagLow},
+		{"185/95", "90-139/60-89", "<180/<110", flagCriticalHigh},
+		{"120/80", "3.5-5.2", "", ""},
+		{"5", "90-139/60-89", "", ""},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s in %s", tt.result, tt.reference)
+		if tt.critical != "" {
+			name += " critical " + tt.critical
+		}
+		t.Run(name, func(t *testing.T) {
+			if got := evaluateLabResult(tt.result, tt.reference, tt.critical).Flag; got != tt.flag {
+				t.Errorf("flag = %q, want %q", got, tt.flag)
+			}
+		})
+	}
+}
+
+func TestEvaluateLabResultValues(t *testing.T) {
+	e := evaluateLabResult("120/80", "90-139/60-89", "")
+	got := formatLabRanges([]labRange{{e.ResultValue, e.ResultValue2}, {e.RangeLow, e.RangeHigh}, {e.RangeLow2, e.RangeHigh2}})
+	if want := "120..80 / 90..139 / 60..89"; got != want {
+		t.Errorf("values = %q, want %q", got, want)
+	}
+
+	e = evaluateLabResult("<0,1", "<5", "")
+	got = formatLabRanges([]labRange{{e.ResultValue, e.ResultValue2}, {e.RangeLow, e.RangeHigh}, {e.RangeLow2, e.RangeHigh2}})
+	if want := "0.1..* / *..5 / *..*"; got != want {
+		t.Errorf("values = %q, want %q", got, want)
+	}
+}
+
+func TestMigrationLabResultValues(t *testing.T) {
+	forEachDatabaseAt(t, 8, func(t *testing.T) {
+		statements := []string{
+			"INSERT INTO patients (id, full_name, birth_date, gender) VALUES (1, 'Смирнова Анна', '1980-05-17', 'female')",
+			"INSERT INTO doctors (id, full_name, specialization) VALUES (1, 'Иванов Иван', 'Терапевт')",
+			"INSERT INTO appointments (id, patient_id, doctor_id, date, end_date) VALUES (1, 1, 1, '2030-03-04 10:00:00', '2030-03-04 10:30:00')",
+		}
+		tests := []struct {
+			result, reference string
+			flag              string
+		}{
+			{"5,3", "3,5-5,2", flagHigh},
+			{"4.5", "от 3.5 до 5.2", flagNormal},
+			{"45", ">60", flagLow},
+			{"150/55", "90-139/60-89", flagHigh},
+			{"отрицательно", "отрицательно", ""},
+		}
+		for i, tt := range tests {
+			statements = append(statements, fmt.Sprintf(
+				"INSERT INTO medical_tests (id, appointment_id, name, result, reference_range) VALUES (%d, 1, 'Тест', '%s', '%s')",
+				i+1, tt.result, tt.reference))
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := migrateUp(db, 9); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, tt := range tests {
+			var row m0009MedicalTest
+			if err := db.First(&row, i+1).Error; err != nil {
+				t.Fatal(err)
+			}
+			if row.Flag != tt.flag {
+				t.Errorf("%s in %s: flag = %q, want %q", tt.result, tt.reference, row.Flag, tt.flag)
+			}
+			if tt.flag != "" && (row.ResultValue == nil || row.RangeLow == nil && row.RangeHigh == nil) {
+				t.Errorf("%s in %s: numeric values are not filled", tt.result, tt.reference)
+			}
+		}
+	})
+}
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	_ "demeda/docs"
@@ -10,7 +19,6 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -23,6 +31,11 @@ import (
 // @BasePath /
 // @schemes http
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access-токен в формате "Bearer <token>", выдается POST /auth/login
+
 // ErrorResponse представляет стандартный ответ об ошибке
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -33,11 +46,14 @@ type ErrorResponse struct {
 type Patient struct {
 	ID             uint             `gorm:"primaryKey" json:"id"`
 	CreatedAt      time.Time        `json:"created_at"`
+	DeletedAt      gorm.DeletedAt   `gorm:"index" json:"deleted_at" swaggertype:"string"`
 	FullName       string           `gorm:"not null" json:"full_name"`
 	BirthDate      time.Time        `gorm:"not null" json:"birth_date"`
 	Gender         string           `gorm:"not null;check:gender IN ('male','female')" json:"gender"`
 	Phone          string           `json:"phone"`
 	Email          string           `json:"email"`
+	SearchName     string           `gorm:"not null;default:''" json:"-"` // ФИО для поиска, заполняется BeforeSave
+	SearchPhone    string           `gorm:"not null;default:''" json:"-"` // цифры телефона для поиска
 	Appointments   []Appointment    `json:"appointments,omitempty"`
 	MedicalHistory []MedicalHistory `json:"medical_history,omitempty"`
 }
@@ -45,57 +61,86 @@ type Patient struct {
 // Doctor представляет врача клиники
 // @Description Информация о враче
 type Doctor struct {
-	ID             uint          `gorm:"primaryKey" json:"id"`
-	CreatedAt      time.Time     `json:"created_at"`
-	FullName       string        `gorm:"not null" json:"full_name"`
-	Specialization string        `gorm:"not null" json:"specialization"`
-	Phone          string        `json:"phone"`
-	Email          string        `json:"email"`
-	Appointments   []Appointment `json:"appointments,omitempty"`
+	ID                   uint                      `gorm:"primaryKey" json:"id"`
+	CreatedAt            time.Time                 `json:"created_at"`
+	FullName             string                    `gorm:"not null" json:"full_name"`
+	Specialization       string                    `gorm:"not null" json:"specialization"`
+	Phone                string                    `json:"phone"`
+	Email                string                    `json:"email"`
+	Active               bool                      `gorm:"not null;default:true" json:"active"`
+	DeactivatedAt        *time.Time                `json:"deactivated_at,omitempty"`
+	SearchSpecialization string                    `gorm:"not null;default:''" json:"-"` // специализация для поиска, заполняется BeforeSave
+	Appointments         []Appointment             `json:"appointments,omitempty"`
+	Schedules            []DoctorSchedule          `json:"schedules,omitempty"`
+	ScheduleExceptions   []DoctorScheduleException `json:"schedule_exceptions,omitempty"`
 }
 
 // Appointment представляет медицинский прием
 // @Description Информация о медицинском приеме
 type Appointment struct {
-	ID           uint          `gorm:"primaryKey" json:"id"`
-	CreatedAt    time.Time     `json:"created_at"`
-	PatientID    uint          `gorm:"not null" json:"patient_id"`
-	DoctorID     uint          `gorm:"not null" json:"doctor_id"`
-	Date         time.Time     `gorm:"not null" json:"date"`
-	Diagnosis    string        `json:"diagnosis"`
-	Treatment    string        `json:"treatment"`
-	Notes        string        `json:"notes"`
-	Patient      Patient       `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
-	Doctor       Doctor        `gorm:"foreignKey:DoctorID" json:"doctor,omitempty"`
-	MedicalTests []MedicalTest `json:"medical_tests,omitempty"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at" swaggertype:"string"`
+	PatientID       uint           `gorm:"not null" json:"patient_id"`
+	DoctorID        uint           `gorm:"not null" json:"doctor_id"`
+	Date            time.Time      `gorm:"not null;index" json:"date"`
+	EndDate         time.Time      `gorm:"index" json:"end_date"`
+	Status          string         `gorm:"not null;default:'scheduled';index" json:"status"` // scheduled, checked_in, in_progress, completed, cancelled, no_show
+	StatusReason    string         `json:"status_reason,omitempty"`                          // причина отмены или комментарий к неявке
+	Diagnosis       string         `json:"diagnosis"`                                        // заметка врача; кодированные диагнозы - в diagnoses
+	Treatment       string         `json:"treatment"`
+	Notes           string         `json:"notes"`
+	SearchDiagnosis string         `gorm:"not null;default:''" json:"-"` // диагноз для поиска, заполняется BeforeSave
+	Patient         Patient        `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
+	Doctor          Doctor         `gorm:"foreignKey:DoctorID" json:"doctor,omitempty"`
+	MedicalTests    []MedicalTest  `json:"medical_tests,omitempty"`
+
+	// Диагнозы по МКБ-10: основной и сопутствующие
+	Diagnoses []AppointmentDiagnosis `json:"diagnoses,omitempty"`
+
+	// Предупреждения об аллергии на препараты, впервые упомянутые в лечении; только в ответе на изменение
+	AllergyWarnings []AllergyWarning `gorm:"-" json:"allergy_warnings,omitempty"`
+
+	// Взаимодействия препаратов, впервые упомянутых в лечении, с действующими назначениями; только в ответе на изменение
+	InteractionWarnings []InteractionWarning `gorm:"-" json:"interaction_warnings,omitempty"`
 }
 
 // MedicalTest представляет медицинский тест
 // @Description Результаты медицинских тестов
 type MedicalTest struct {
-	ID             uint        `gorm:"primaryKey" json:"id"`
-	CreatedAt      time.Time   `json:"created_at"`
-	AppointmentID  uint        `gorm:"not null" json:"appointment_id"`
-	Name           string      `gorm:"not null" json:"name"`
-	Result         string      `json:"result"`
-	Unit           string      `json:"unit"`
-	ReferenceRange string      `json:"reference_range"`
-	Appointment    Appointment `gorm:"foreignKey:AppointmentID" json:"appointment,omitempty"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at" swaggertype:"string"`
+	AppointmentID  uint           `gorm:"not null" json:"appointment_id"`
+	Name           string         `gorm:"not null" json:"name"`
+	Result         string         `json:"result"`
+	Unit           string         `json:"unit"`
+	ReferenceRange string         `json:"reference_range"`
+	CriticalRange  string         `json:"critical_range,omitempty"` // интервал, вне которого результат критический
+	ResultValue    *float64       `json:"result_value,omitempty"`   // числовой результат; для составного (120/80) - первая часть
+	ResultValue2   *float64       `json:"result_value2,omitempty"`  // вторая часть составного результата
+	RangeLow       *float64       `json:"range_low,omitempty"`      // нижняя граница референсного интервала
+	RangeHigh      *float64       `json:"range_high,omitempty"`     // верхняя граница референсного интервала
+	RangeLow2      *float64       `json:"range_low2,omitempty"`     // границы для второй части составного результата
+	RangeHigh2     *float64       `json:"range_high2,omitempty"`
+	Flag           string         `gorm:"index" json:"flag,omitempty"` // N, L, H, LL, HH; пусто, если результат не числовой
+	Appointment    Appointment    `gorm:"foreignKey:AppointmentID" json:"appointment,omitempty"`
 }
 
 // MedicalHistory представляет запись медицинского анамнеза
 // @Description Медицинский анамнез пациента
 type MedicalHistory struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	PatientID   uint      `gorm:"not null" json:"patient_id"`
-	HistoryType string    `gorm:"not null" json:"history_type"`
-	Description string    `gorm:"not null" json:"description"`
-	StartDate   time.Time `json:"start_date"`
-	Severity    string    `json:"severity"`
-	Status      string    `json:"status"`
-	Notes       string    `json:"notes"`
-	Patient     Patient   `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at" swaggertype:"string"`
+	PatientID   uint           `gorm:"not null" json:"patient_id"`
+	HistoryType string         `gorm:"not null" json:"history_type"`
+	Description string         `gorm:"not null" json:"description"`
+	StartDate   time.Time      `json:"start_date"`
+	Severity    string         `json:"severity"`
+	Status      string         `json:"status"`
+	Notes       string         `json:"notes"`
+	Patient     Patient        `gorm:"foreignKey:PatientID" json:"patient,omitempty"`
 }
 
 // DTO для создания/обновления записей
@@ -107,13 +152,33 @@ type CreatePatientRequest struct {
 	Email     string    `json:"email"`
 }
 
+type CreateDoctorRequest struct {
+	FullName       string `json:"full_name" binding:"required"`
+	Specialization string `json:"specialization" binding:"required"`
+	Phone          string `json:"phone"`
+	Email          string `json:"email" binding:"omitempty,email"`
+}
+
 type CreateAppointmentRequest struct {
 	PatientID uint      `json:"patient_id" binding:"required"`
 	DoctorID  uint      `json:"doctor_id" binding:"required"`
 	Date      time.Time `json:"date" binding:"required"`
+	Duration  int       `json:"duration" binding:"omitempty,min=5,max=480"` // длительность в минутах; при создании по умолчанию 30, при изменении - прежняя
 	Diagnosis string    `json:"diagnosis"`
 	Treatment string    `json:"treatment"`
 	Notes     string    `json:"notes"`
+
+	// Подтверждение лечения вопреки тяжелой аллергии пациента, с обязательной причиной
+	OverrideAllergy bool   `json:"override_allergy"`
+	OverrideReason  string `json:"override_reason"`
+}
+
+type CreateMedicalTestRequest struct {
+	Name           string `json:"name" binding:"required"`
+	Result         string `json:"result"`
+	Unit           string `json:"unit"`
+	ReferenceRange string `json:"reference_range"`
+	CriticalRange  string `json:"critical_range"` // необязательный интервал для флагов LL и HH
 }
 
 type CreateMedicalHistoryRequest struct {
@@ -129,27 +194,65 @@ type CreateMedicalHistoryRequest struct {
 var db *gorm.DB
 
 func main() {
-	var err error
-	db, err = gorm.Open(sqlite.Open("clinic.db"), &gorm.Config{})
-	if err != nil {
-		panic("Failed to connect to database")
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
-	// Автоматическое создание таблиц
-	err = db.AutoMigrate(&Patient{}, &Doctor{}, &Appointment{}, &MedicalTest{}, &MedicalHistory{})
-	if err != nil {
-		panic("Database migration failed")
+	switch command {
+	case "serve":
+		runServer(args)
+	case "seed":
+		runSeed(args)
+	case "config":
+		runConfig(args)
+	case "migrate":
+		runMigrate(args)
+	case "user":
+		runUser(args)
+	case "interactions":
+		runInteractions(args)
+	case "icd10":
+		runICD10(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nUsage:\n  demeda [serve] [--config=path]\n  demeda seed [--config=path] [--profile=demo] [--file=path] [--force]\n  demeda migrate up|down|status [--config=path]\n  demeda config print [--config=path]\n  demeda user add --username=name [--password=secret] [--config=path]\n  demeda interactions import [--file=path] [--replace] [--config=path]\n  demeda icd10 import [--file=path] [--replace] [--config=path]\n", command)
+		os.Exit(2)
 	}
+}
 
-	// Генерация тестовых данных
-	seedDatabase(db)
+// setupLogging настраивает уровень логирования сервиса и режим gin
+func setupLogging() {
+	var level slog.Level
+	level.UnmarshalText([]byte(config.LogLevel))
+	slog.SetLogLoggerLevel(level)
 
-	// Настройка роутера
-	router := gin.Default()
+	if config.LogLevel == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+}
 
-	// CORS middleware
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+// corsMiddleware разрешает кросс-доменные запросы только с перечисленных origin ("*" - с любых)
+func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		switch {
+		case allowAll:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case allowed[origin]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
@@ -159,53 +262,234 @@ func main() {
 		}
 
 		c.Next()
-	})
+	}
+}
+
+// seedOnStartup загружает набор данных seed_profile, если база пуста
+func seedOnStartup() {
+	fixture, err := loadFixture(config.SeedProfile, "")
+	if err != nil {
+		panic(err)
+	}
+	err = seedDatabase(db, fixture, false)
+	switch {
+	case errors.Is(err, errDatabaseNotEmpty):
+		slog.Info("База данных не пуста, загрузка тестовых данных пропущена", "profile", config.SeedProfile)
+	case err != nil:
+		panic(err)
+	default:
+		slog.Info("Загружены тестовые данные", "profile", config.SeedProfile)
+	}
+}
+
+// runServer запускает HTTP-сервер. Тестовые данные загружаются только при заданном seed_profile
+// или командой seed
+func runServer(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := configFlag(flags)
+	flags.Parse(args)
+	loadConfigOrExit(*configPath)
+	setupLogging()
+	ensureJWTSecret()
+
+	openDatabase()
+	requireCurrentSchema()
+	if config.SeedProfile != "" {
+		seedOnStartup()
+	}
+
+	// Запуск сервера
+	router := setupRouter()
+
+	server := &http.Server{
+		Addr:         config.ListenAddr,
+		Handler:      router,
+		ReadTimeout:  time.Duration(config.ReadTimeout),
+		WriteTimeout: time.Duration(config.WriteTimeout),
+		IdleTimeout:  time.Duration(config.IdleTimeout),
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Ошибка сервера", "error", err)
+			os.Exit(1)
+		}
+	}()
+	slog.Info("Сервер запущен", "addr", config.ListenAddr)
+	slog.Info("Swagger документация доступна по адресу /swagger/index.html")
+
+	// Корректное завершение по SIGINT/SIGTERM
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout))
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Ошибка при остановке сервера", "error", err)
+	}
+}
+
+// setupRouter регистрирует маршруты API
+func setupRouter() *gin.Engine {
+	router := gin.Default()
+	router.Use(corsMiddleware(config.CORSAllowedOrigins))
+
+	// Аутентификация
+	auth := router.Group("/auth")
+	{
+		auth.POST("/login", login)
+		auth.POST("/refresh", refreshTokens)
+		auth.POST("/logout", authRequired(), logout)
+	}
+
+	// Все остальные маршруты API доступны только с действующим access-токеном.
+	// Права ролей на маршруты и записи задаются матрицей policy (policy.go).
+	api := router.Group("", authRequired())
 
 	// Группа маршрутов для пациентов
-	patients := router.Group("/patients")
+	patients := api.Group("/patients")
 	{
-		patients.GET("", getPatients)
-		patients.GET("/:id", getPatient)
-		patients.POST("", createPatient)
-		patients.PUT("/:id", updatePatient)
-		patients.DELETE("/:id", deletePatient)
-		patients.GET("/:id/appointments", getPatientAppointments)
-		patients.GET("/:id/medical-history", getPatientMedicalHistory)
+		patients.GET("", requirePermission(permPatientsRead), getPatients)
+		patients.GET("/search", requirePermission(permPatientsRead), searchPatients)
+		patients.GET("/:id", requirePermission(permPatientsRead), getPatient)
+		patients.POST("", requirePermission(permPatientsWrite), createPatient)
+		patients.PUT("/:id", requirePermission(permPatientsWrite), updatePatient)
+		patients.DELETE("/:id", requirePermission(permPatientsWrite), deletePatient)
+		patients.POST("/:id/restore", requirePermission(permPatientsWrite), restorePatient)
+		patients.GET("/:id/appointments", requirePermission(permAppointmentsRead), getPatientAppointments)
+		patients.GET("/:id/medical-history", requirePermission(permHistoryRead), getPatientMedicalHistory)
+		patients.GET("/:id/abnormal-results", requirePermission(permTestsRead), getPatientAbnormalResults)
+		patients.GET("/:id/tests/trends", requirePermission(permTestsRead), getPatientTestTrend)
+		patients.GET("/:id/medications", requirePermission(permPrescriptionsRead), getPatientMedications)
+		patients.GET("/:id/interactions", requirePermission(permPrescriptionsRead), checkPatientInteractions)
 	}
 
 	// Группа маршрутов для врачей
-	doctors := router.Group("/doctors")
+	doctors := api.Group("/doctors")
 	{
-		doctors.GET("", getDoctors)
-		doctors.GET("/:id", getDoctor)
-		doctors.GET("/:id/appointments", getDoctorAppointments)
+		doctors.GET("", requirePermission(permDoctorsRead), getDoctors)
+		doctors.GET("/:id", requirePermission(permDoctorsRead), getDoctor)
+		doctors.POST("", requirePermission(permDoctorsWrite), createDoctor)
+		doctors.PUT("/:id", requirePermission(permDoctorsWrite), updateDoctor)
+		doctors.DELETE("/:id", requirePermission(permDoctorsWrite), deleteDoctor)
+		doctors.GET("/:id/appointments", requirePermission(permAppointmentsRead), getDoctorAppointments)
+		doctors.GET("/:id/schedule", requirePermission(permDoctorsRead), getDoctorSchedule)
+		doctors.PUT("/:id/schedule", requirePermission(permSchedulesWrite), updateDoctorSchedule)
+		doctors.GET("/:id/schedule/exceptions", requirePermission(permDoctorsRead), getDoctorScheduleExceptions)
+		doctors.POST("/:id/schedule/exceptions", requirePermission(permSchedulesWrite), createDoctorScheduleException)
+		doctors.DELETE("/:id/schedule/exceptions/:exceptionId", requirePermission(permSchedulesWrite), deleteDoctorScheduleException)
+		doctors.GET("/:id/slots", requirePermission(permDoctorsRead), getDoctorSlots)
 	}
 
 	// Группа маршрутов для приемов
-	appointments := router.Group("/appointments")
+	appointments := api.Group("/appointments")
 	{
-		appointments.GET("", getAppointments)
-		appointments.GET("/:id", getAppointment)
-		appointments.POST("", createAppointment)
-		appointments.PUT("/:id", updateAppointment)
-		appointments.DELETE("/:id", deleteAppointment)
-		appointments.GET("/:id/tests", getAppointmentTests)
+		appointments.GET("", requirePermission(permAppointmentsRead), getAppointments)
+		appointments.GET("/:id", requirePermission(permAppointmentsRead), getAppointment)
+		appointments.POST("", requirePermission(permAppointmentsWrite), createAppointment)
+		appointments.PUT("/:id", requirePermission(permAppointmentsWrite), updateAppointment)
+		appointments.DELETE("/:id", requirePermission(permAppointmentsWrite), deleteAppointment)
+		appointments.POST("/:id/restore", requirePermission(permAppointmentsWrite), restoreAppointment)
+		appointments.POST("/:id/check-in", requirePermission(permAppointmentsWrite), checkInAppointment)
+		appointments.POST("/:id/start", requirePermission(permClinicalWrite), startAppointment)
+		appointments.POST("/:id/complete", requirePermission(permClinicalWrite), completeAppointment)
+		appointments.POST("/:id/cancel", requirePermission(permAppointmentsWrite), cancelAppointment)
+		appointments.POST("/:id/no-show", requirePermission(permAppointmentsWrite), noShowAppointment)
+		appointments.GET("/:id/tests", requirePermission(permTestsRead), getAppointmentTests)
+		appointments.POST("/:id/tests", requirePermission(permTestsWrite), createAppointmentTest)
+		appointments.POST("/:id/prescriptions", requirePermission(permPrescriptionsWrite), createAppointmentPrescription)
+		appointments.GET("/:id/diagnoses", requirePermission(permClinicalRead), getAppointmentDiagnoses)
+		appointments.POST("/:id/diagnoses", requirePermission(permClinicalWrite), createAppointmentDiagnosis)
+	}
+
+	// Группа маршрутов для медицинских тестов
+	tests := api.Group("/tests")
+	{
+		tests.GET("", requirePermission(permTestsRead), getMedicalTests)
+		tests.GET("/synonyms", requirePermission(permTestsRead), getTestSynonyms)
+		tests.POST("/synonyms", requirePermission(permTestsWrite), createTestSynonym)
+		tests.DELETE("/synonyms/:id", requirePermission(permTestsWrite), deleteTestSynonym)
+		tests.GET("/:id", requirePermission(permTestsRead), getMedicalTest)
+		tests.PUT("/:id", requirePermission(permTestsWrite), updateMedicalTest)
+		tests.DELETE("/:id", requirePermission(permTestsWrite), deleteMedicalTest)
+		tests.POST("/:id/restore", requirePermission(permTestsWrite), restoreMedicalTest)
 	}
 
 	// Группа маршрутов для анамнеза
-	medicalHistory := router.Group("/medical_history")
+	medicalHistory := api.Group("/medical_history")
 	{
-		medicalHistory.GET("", getMedicalHistory)
-		medicalHistory.POST("", createMedicalHistory)
-		medicalHistory.DELETE("/:id", deleteMedicalHistory)
+		medicalHistory.GET("", requirePermission(permHistoryRead), getMedicalHistory)
+		medicalHistory.POST("", requirePermission(permHistoryWrite), createMedicalHistory)
+		medicalHistory.DELETE("/:id", requirePermission(permHistoryWrite), deleteMedicalHistory)
+		medicalHistory.POST("/:id/restore", requirePermission(permHistoryWrite), restoreMedicalHistory)
 	}
 
-	// Запуск сервера
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Таблица групп препаратов для проверки аллергий
+	drugClasses := api.Group("/drug-classes")
+	{
+		drugClasses.GET("", requirePermission(permPrescriptionsRead), getDrugClasses)
+		drugClasses.POST("", requirePermission(permDrugClassesWrite), createDrugClass)
+		drugClasses.DELETE("/:id", requirePermission(permDrugClassesWrite), deleteDrugClass)
+	}
+
+	// База лекарственных взаимодействий
+	api.GET("/interactions", requirePermission(permPrescriptionsRead), getDrugInteractions)
+
+	// Диагнозы приемов и справочник МКБ-10
+	diagnoses := api.Group("/diagnoses")
+	{
+		diagnoses.PUT("/:id", requirePermission(permClinicalWrite), updateDiagnosis)
+		diagnoses.DELETE("/:id", requirePermission(permClinicalWrite), deleteDiagnosis)
+	}
+	icd10 := api.Group("/icd10")
+	{
+		icd10.GET("", requirePermission(permClinicalRead), searchICD10)
+		icd10.GET("/:code", requirePermission(permClinicalRead), getICD10Code)
+	}
+
+	// Группа маршрутов для назначений препаратов
+	prescriptions := api.Group("/prescriptions")
+	{
+		prescriptions.GET("", requirePermission(permPrescriptionsRead), getPrescriptions)
+		prescriptions.GET("/:id", requirePermission(permPrescriptionsRead), getPrescription)
+		prescriptions.PUT("/:id", requirePermission(permPrescriptionsWrite), updatePrescription)
+		prescriptions.DELETE("/:id", requirePermission(permPrescriptionsWrite), deletePrescription)
+		prescriptions.POST("/:id/restore", requirePermission(permPrescriptionsWrite), restorePrescription)
+		prescriptions.POST("/:id/discontinue", requirePermission(permPrescriptionsWrite), discontinuePrescription)
+	}
+
+	// FHIR R4: чтение и поиск ресурсов, загрузка Bundle; CapabilityStatement доступен без аутентификации
+	router.GET(fhirBasePath+"/metadata", getFHIRMetadata)
+	fhir := api.Group(fhirBasePath)
+	{
+		fhir.POST("", importFHIRBundle)
+		fhir.GET("/Patient", requireFHIRPermission(permPatientsRead), searchFHIRPatients)
+		fhir.GET("/Patient/:id", requireFHIRPermission(permPatientsRead), getFHIRPatient)
+		fhir.GET("/Practitioner", requireFHIRPermission(permDoctorsRead), searchFHIRPractitioners)
+		fhir.GET("/Practitioner/:id", requireFHIRPermission(permDoctorsRead), getFHIRPractitioner)
+		fhir.GET("/Encounter", requireFHIRPermission(permAppointmentsRead), searchFHIREncounters)
+		fhir.GET("/Encounter/:id", requireFHIRPermission(permAppointmentsRead), getFHIREncounter)
+		fhir.GET("/Appointment", requireFHIRPermission(permAppointmentsRead), searchFHIRAppointments)
+		fhir.GET("/Appointment/:id", requireFHIRPermission(permAppointmentsRead), getFHIRAppointment)
+		fhir.GET("/Observation", requireFHIRPermission(permTestsRead), searchFHIRObservations)
+		fhir.GET("/Observation/:id", requireFHIRPermission(permTestsRead), getFHIRObservation)
+		fhir.GET("/Condition", searchFHIRConditions)
+		fhir.GET("/Condition/:id", getFHIRCondition)
+		fhir.GET("/AllergyIntolerance", requireFHIRPermission(permHistoryRead), searchFHIRAllergies)
+		fhir.GET("/AllergyIntolerance/:id", requireFHIRPermission(permHistoryRead), getFHIRAllergy)
+	}
 
-	fmt.Println("Сервер запущен на http://localhost:8080")
-	fmt.Println("Swagger документация доступна на http://localhost:8080/swagger/index.html")
-	router.Run(":8080")
+	// Журнал аудита
+	audit := api.Group("/audit")
+	{
+		audit.GET("", requirePermission(permAuditRead), getAuditLog)
+		audit.GET("/verify", requirePermission(permAuditRead), verifyAuditLog)
+	}
+
+	// Документация API
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	return router
 }
 
 // Обработчики для пациентов
@@ -216,16 +500,44 @@ func main() {
 // @Tags patients
 // @Accept json
 // @Produce json
-// @Success 200 {array} Patient
+// @Security BearerAuth
+// @Param gender query string false "Фильтр по полу" Enums(male, female)
+// @Param include_deleted query bool false "Включить удаленных пациентов (только для администратора)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, full_name, -full_name, birth_date, -birth_date, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Patient]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients [get]
 func getPatients(c *gin.Context) {
+	query, ok := includeDeleted(c, db)
+	if !ok {
+		return
+	}
+	p, ok := paginate(c, patientList)
+	if !ok {
+		return
+	}
+	query = scopeQuery(c, permPatientsRead, query, "id", "")
+
+	if gender := c.Query("gender"); gender != "" {
+		query = query.Where("gender = ?", gender)
+	}
+
 	var patients []Patient
-	if err := db.Find(&patients).Error; err != nil {
+	if err := p.apply(query).Find(&patients).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, patients)
+	page := p.page(patients)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
 }
 
 // GetPatient godoc
@@ -234,8 +546,11 @@ func getPatients(c *gin.Context) {
 // @Tags patients
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID пациента"
 // @Success 200 {object} Patient
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients/{id} [get]
@@ -246,6 +561,23 @@ func getPatient(c *gin.Context) {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Patient not found"})
 		return
 	}
+	if !authorize(c, permPatientsRead, resource{PatientID: patient.ID}) {
+		return
+	}
+
+	user := currentUser(c)
+	if !user.can(permHistoryRead, resource{PatientID: patient.ID}) {
+		patient.MedicalHistory = nil
+	}
+	if !user.can(permAppointmentsRead, resource{PatientID: patient.ID}) {
+		patient.Appointments = nil
+	}
+	redactAppointments(user, patient.Appointments)
+
+	refs := append([]auditRef{patient.auditRef()}, auditRefs(patient.Appointments)...)
+	if !auditRead(c, append(refs, auditRefs(patient.MedicalHistory)...)...) {
+		return
+	}
 	c.JSON(http.StatusOK, patient)
 }
 
@@ -255,9 +587,12 @@ func getPatient(c *gin.Context) {
 // @Tags patients
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param patient body CreatePatientRequest true "Данные пациента"
 // @Success 201 {object} Patient
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients [post]
 func createPatient(c *gin.Context) {
@@ -275,7 +610,13 @@ func createPatient(c *gin.Context) {
 		Email:     req.Email,
 	}
 
-	if err := db.Create(&patient).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&patient).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditActionCreate, patient.auditRef())
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -289,10 +630,13 @@ func createPatient(c *gin.Context) {
 // @Tags patients
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID пациента"
 // @Param patient body CreatePatientRequest true "Обновленные данные пациента"
 // @Success 200 {object} Patient
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients/{id} [put]
@@ -310,13 +654,20 @@ func updatePatient(c *gin.Context) {
 		return
 	}
 
+	before := patient
 	patient.FullName = req.FullName
 	patient.BirthDate = req.BirthDate
 	patient.Gender = req.Gender
 	patient.Phone = req.Phone
 	patient.Email = req.Email
 
-	if err := db.Save(&patient).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&patient).Error; err != nil {
+			return err
+		}
+		return recordAuditUpdate(tx, c, patient.auditRef(), before, patient)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -326,17 +677,30 @@ func updatePatient(c *gin.Context) {
 
 // DeletePatient godoc
 // @Summary Удалить пациента
-// @Description Удалить запись пациента из системы
+// @Description Пометить пациента удаленным вместе с его приемами, их тестами и назначениями и анамнезом. Записи можно восстановить через POST /patients/{id}/restore
 // @Tags patients
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID пациента"
 // @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients/{id} [delete]
 func deletePatient(c *gin.Context) {
 	id := c.Param("id")
-	if err := db.Delete(&Patient{}, id).Error; err != nil {
+	var patient Patient
+	if err := db.First(&patient, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Patient not found"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return archivePatient(tx, c, patient, deletionTime())
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -349,18 +713,36 @@ func deletePatient(c *gin.Context) {
 // @Tags patients
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID пациента"
-// @Success 200 {array} Appointment
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, date, -date, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Appointment]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients/{id}/appointments [get]
 func getPatientAppointments(c *gin.Context) {
 	id := c.Param("id")
+	p, ok := paginate(c, appointmentList)
+	if !ok {
+		return
+	}
 	var appointments []Appointment
-	if err := db.Preload("Doctor").Where("patient_id = ?", id).Find(&appointments).Error; err != nil {
+	query := scopeQuery(c, permAppointmentsRead, db.Preload("Doctor"), "patient_id", "doctor_id")
+	if err := p.apply(query.Where("patient_id = ?", id)).Find(&appointments).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, appointments)
+	page := p.page(appointments)
+	redactAppointments(currentUser(c), page.Data)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
 }
 
 // GetPatientMedicalHistory godoc
@@ -369,38 +751,78 @@ func getPatientAppointments(c *gin.Context) {
 // @Tags patients
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID пациента"
-// @Success 200 {array} MedicalHistory
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, start_date, -start_date, history_type, -history_type, created_at, -created_at) default(id)
+// @Success 200 {object} Page[MedicalHistory]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /patients/{id}/medical-history [get]
 func getPatientMedicalHistory(c *gin.Context) {
 	id := c.Param("id")
+	p, ok := paginate(c, medicalHistoryList)
+	if !ok {
+		return
+	}
 	var history []MedicalHistory
-	if err := db.Where("patient_id = ?", id).Find(&history).Error; err != nil {
+	query := scopeQuery(c, permHistoryRead, db, "patient_id", "")
+	if err := p.apply(query.Where("patient_id = ?", id)).Find(&history).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, history)
+	page := p.page(history)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
 }
 
 // Обработчики для врачей
 
 // GetDoctors godoc
 // @Summary Получить список врачей
-// @Description Получить список всех врачей клиники
+// @Description Получить список работающих врачей клиники
 // @Tags doctors
 // @Accept json
 // @Produce json
-// @Success 200 {array} Doctor
+// @Security BearerAuth
+// @Param include_inactive query bool false "Включить уволенных врачей"
+// @Param specialization query string false "Фильтр по специализации без учета регистра"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, full_name, -full_name, specialization, -specialization, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Doctor]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /doctors [get]
 func getDoctors(c *gin.Context) {
+	p, ok := paginate(c, doctorList)
+	if !ok {
+		return
+	}
 	var doctors []Doctor
-	if err := db.Find(&doctors).Error; err != nil {
+	query := db
+	if c.Query("include_inactive") != "true" {
+		query = query.Where("active = ?", true)
+	}
+
+	if specialization := c.Query("specialization"); specialization != "" {
+		query = query.Where("search_specialization = ?", normalizeSearchText(specialization))
+	}
+
+	if err := p.apply(query).Find(&doctors).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, doctors)
+	respondPage(c, p.page(doctors))
 }
 
 // GetDoctor godoc
@@ -409,8 +831,11 @@ func getDoctors(c *gin.Context) {
 // @Tags doctors
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID врача"
 // @Success 200 {object} Doctor
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /doctors/{id} [get]
 func getDoctor(c *gin.Context) {
@@ -423,58 +848,281 @@ func getDoctor(c *gin.Context) {
 	c.JSON(http.StatusOK, doctor)
 }
 
+// CreateDoctor godoc
+// @Summary Добавить врача
+// @Description Добавить нового врача в штат клиники
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param doctor body CreateDoctorRequest true "Данные врача"
+// @Success 201 {object} Doctor
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors [post]
+func createDoctor(c *gin.Context) {
+	var req CreateDoctorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	doctor := Doctor{
+		FullName:       req.FullName,
+		Specialization: req.Specialization,
+		Phone:          req.Phone,
+		Email:          req.Email,
+		Active:         true,
+	}
+
+	if err := db.Create(&doctor).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, doctor)
+}
+
+// UpdateDoctor godoc
+// @Summary Обновить данные врача
+// @Description Обновить информацию о враче
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Param doctor body CreateDoctorRequest true "Обновленные данные врача"
+// @Success 200 {object} Doctor
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id} [put]
+func updateDoctor(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+
+	var req CreateDoctorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	doctor.FullName = req.FullName
+	doctor.Specialization = req.Specialization
+	doctor.Phone = req.Phone
+	doctor.Email = req.Email
+
+	if err := db.Save(&doctor).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, doctor)
+}
+
+// DeleteDoctor godoc
+// @Summary Уволить врача
+// @Description Деактивировать врача. Прошедшие приемы сохраняются за врачом. Будущие приемы обрабатываются согласно future_appointments: block (по умолчанию) - отказать, если они есть; reassign - перевести к врачу reassign_to; cancel - отменить (приемы переводятся в статус cancelled). Завершенные и отмененные приемы и неявки не учитываются
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Param future_appointments query string false "Что делать с будущими приемами" Enums(block, reassign, cancel) default(block)
+// @Param reassign_to query int false "ID врача, к которому переводятся приемы (обязателен для reassign)"
+// @Success 200 {object} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ConflictResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id} [delete]
+func deleteDoctor(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+
+	mode := c.DefaultQuery("future_appointments", "block")
+	var target Doctor
+	switch mode {
+	case "block", "cancel":
+	case "reassign":
+		targetID, err := strconv.ParseUint(c.Query("reassign_to"), 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "reassign_to must be a doctor ID"})
+			return
+		}
+		if err := db.Where("active = ?", true).First(&target, targetID).Error; err != nil || target.ID == doctor.ID {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "reassign_to must reference another active doctor"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "future_appointments must be one of: block, reassign, cancel"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var future []Appointment
+		if err := tx.Where("doctor_id = ? AND date >= ?", doctor.ID, time.Now()).
+			Where("status NOT IN ?", closedStatuses).
+			Order("date").Find(&future).Error; err != nil {
+			return err
+		}
+
+		if len(future) > 0 {
+			switch mode {
+			case "block":
+				ids := make([]uint, len(future))
+				for i, a := range future {
+					ids[i] = a.ID
+				}
+				return &bookingConflictError{IDs: ids, Message: "doctor has future appointments"}
+			case "cancel":
+				for i := range future {
+					if err := transitionAppointment(tx, c, &future[i], statusCancelled, "cancel", "doctor is no longer active"); err != nil {
+						return fmt.Errorf("appointment %d: %w", future[i].ID, err)
+					}
+				}
+			case "reassign":
+				for i := range future {
+					before := future[i]
+					future[i].DoctorID = target.ID
+					if err := validateAndSaveAppointment(tx, &future[i]); err != nil {
+						return fmt.Errorf("appointment %d: %w", future[i].ID, err)
+					}
+					if err := recordAuditUpdate(tx, c, future[i].auditRef(), before, future[i]); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		now := time.Now()
+		doctor.Active = false
+		doctor.DeactivatedAt = &now
+		return tx.Save(&doctor).Error
+	})
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "Doctor deactivated")
+}
+
 // GetDoctorAppointments godoc
 // @Summary Получить приемы врача
 // @Description Получить список всех приемов конкретного врача
 // @Tags doctors
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID врача"
-// @Success 200 {array} Appointment
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, date, -date, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Appointment]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /doctors/{id}/appointments [get]
 func getDoctorAppointments(c *gin.Context) {
 	id := c.Param("id")
+	p, ok := paginate(c, appointmentList)
+	if !ok {
+		return
+	}
 	var appointments []Appointment
-	if err := db.Preload("Patient").Where("doctor_id = ?", id).Find(&appointments).Error; err != nil {
+	query := scopeQuery(c, permAppointmentsRead, db.Preload("Patient"), "patient_id", "doctor_id")
+	if err := p.apply(query.Where("doctor_id = ?", id)).Find(&appointments).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, appointments)
+	page := p.page(appointments)
+	redactAppointments(currentUser(c), page.Data)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
 }
 
 // Обработчики для приемов
 
 // GetAppointments godoc
 // @Summary Получить список приемов
-// @Description Получить список медицинских приемов с возможностью фильтрации
+// @Description Получить список медицинских приемов с возможностью фильтрации. Все заданные фильтры объединяются через AND
 // @Tags appointments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param patient_id query int false "Фильтр по ID пациента"
 // @Param doctor_id query int false "Фильтр по ID врача"
-// @Success 200 {array} Appointment
+// @Param from query string false "Дата приема не раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param to query string false "Дата приема раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param period query string false "Приемы за сегодня, предстоящие или прошедшие" Enums(today, upcoming, past)
+// @Param status query string false "Статус приема" Enums(scheduled, checked_in, in_progress, completed, cancelled, no_show)
+// @Param specialization query string false "Фильтр по специализации врача без учета регистра"
+// @Param diagnosis query string false "Подстрока диагноза без учета регистра и ё/е (требует доступа к клиническим данным)"
+// @Param diagnosis_code query string false "Код МКБ-10 или его начало, например I10 или J0 (требует доступа к клиническим данным)"
+// @Param has_tests query bool false "Только приемы с тестами (true) или без них (false)"
+// @Param include_deleted query bool false "Включить удаленные приемы (только для администратора)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, date, -date, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Appointment]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /appointments [get]
 func getAppointments(c *gin.Context) {
-	var appointments []Appointment
-	query := db.Preload("Patient").Preload("Doctor")
-
-	// Фильтрация по patient_id если указана
-	if patientID := c.Query("patient_id"); patientID != "" {
-		query = query.Where("patient_id = ?", patientID)
+	query, ok := includeDeleted(c, db.Preload("Patient").Preload("Doctor"))
+	if !ok {
+		return
 	}
-
-	// Фильтрация по doctor_id если указана
-	if doctorID := c.Query("doctor_id"); doctorID != "" {
-		query = query.Where("doctor_id = ?", doctorID)
+	p, ok := paginate(c, appointmentList)
+	if !ok {
+		return
+	}
+	filter, err := parseAppointmentFilter(c)
+	if errors.Is(err, errDiagnosisFilterForbidden) {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
 	}
 
-	if err := query.Find(&appointments).Error; err != nil {
+	var appointments []Appointment
+	query = scopeQuery(c, permAppointmentsRead, query, "patient_id", "doctor_id")
+	query = filter.apply(query, time.Now())
+
+	if err := p.apply(query).Find(&appointments).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, appointments)
+	page := p.page(appointments)
+	redactAppointments(currentUser(c), page.Data)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
 }
 
 // GetAppointment godoc
@@ -483,29 +1131,52 @@ func getAppointments(c *gin.Context) {
 // @Tags appointments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID приема"
 // @Success 200 {object} Appointment
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /appointments/{id} [get]
 func getAppointment(c *gin.Context) {
 	id := c.Param("id")
 	var appointment Appointment
-	if err := db.Preload("Patient").Preload("Doctor").Preload("MedicalTests").First(&appointment, id).Error; err != nil {
+	query := db.Preload("Patient").Preload("Doctor").Preload("MedicalTests").
+		Preload("Diagnoses", func(tx *gorm.DB) *gorm.DB { return tx.Order(diagnosisOrder) })
+	if err := query.First(&appointment, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
 		return
 	}
+	if !authorize(c, permAppointmentsRead, appointmentResource(appointment)) {
+		return
+	}
+
+	user := currentUser(c)
+	if !user.can(permTestsRead, appointmentResource(appointment)) {
+		appointment.MedicalTests = nil
+	}
+	redactAppointment(user, &appointment)
+	refs := append([]auditRef{appointment.auditRef()}, auditRefs(appointment.MedicalTests)...)
+	if !auditRead(c, append(refs, auditRefs(appointment.Diagnoses)...)...) {
+		return
+	}
 	c.JSON(http.StatusOK, appointment)
 }
 
 // CreateAppointment godoc
 // @Summary Создать новый прием
-// @Description Создать запись о медицинском приеме
+// @Description Создать запись о медицинском приеме. Прием создается в статусе scheduled, диагноз и лечение указываются после начала приема
 // @Tags appointments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param appointment body CreateAppointmentRequest true "Данные приема"
 // @Success 201 {object} Appointment
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ConflictResponse
+// @Failure 422 {object} ReferenceErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /appointments [post]
 func createAppointment(c *gin.Context) {
@@ -519,30 +1190,49 @@ func createAppointment(c *gin.Context) {
 		PatientID: req.PatientID,
 		DoctorID:  req.DoctorID,
 		Date:      req.Date,
-		Diagnosis: req.Diagnosis,
-		Treatment: req.Treatment,
+		EndDate:   req.Date.Add(appointmentDuration(req.Duration)),
+		Status:    statusScheduled,
 		Notes:     req.Notes,
 	}
+	if !authorize(c, permAppointmentsWrite, appointmentResource(appointment)) {
+		return
+	}
 
-	if err := db.Create(&appointment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	// Диагноз и лечение записываются только после начала приема
+	user := currentUser(c)
+	if user.can(permClinicalWrite, appointmentResource(appointment)) && (req.Diagnosis != "" || req.Treatment != "") {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: errClinicalNotInProgress.Error()})
 		return
 	}
 
+	err := saveAppointment(&appointment, func(tx *gorm.DB) error {
+		return recordAudit(tx, c, auditActionCreate, appointment.auditRef())
+	})
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	redactAppointment(user, &appointment)
 	c.JSON(http.StatusCreated, appointment)
 }
 
 // UpdateAppointment godoc
 // @Summary Обновить данные приема
-// @Description Обновить информацию о медицинском приеме
+// @Description Обновить информацию о медицинском приеме. Перенести прием или сменить врача и пациента можно только в статусе scheduled, изменить диагноз и лечение - только в статусах in_progress и completed. Препараты, впервые упомянутые в лечении, сверяются с аллергиями пациента
 // @Tags appointments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID приема"
 // @Param appointment body CreateAppointmentRequest true "Обновленные данные приема"
 // @Success 200 {object} Appointment
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ConflictResponse
+// @Failure 422 {object} ReferenceErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /appointments/{id} [put]
 func updateAppointment(c *gin.Context) {
@@ -552,6 +1242,9 @@ func updateAppointment(c *gin.Context) {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
 		return
 	}
+	if !authorize(c, permAppointmentsWrite, appointmentResource(appointment)) {
+		return
+	}
 
 	var req CreateAppointmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -559,34 +1252,118 @@ func updateAppointment(c *gin.Context) {
 		return
 	}
 
+	before := appointment
 	appointment.PatientID = req.PatientID
 	appointment.DoctorID = req.DoctorID
 	appointment.Date = req.Date
-	appointment.Diagnosis = req.Diagnosis
-	appointment.Treatment = req.Treatment
+	// Без duration прием сохраняет прежнюю длительность, а не сбрасывается к 30 минутам
+	duration := before.EndDate.Sub(before.Date)
+	if req.Duration > 0 || duration <= 0 {
+		duration = appointmentDuration(req.Duration)
+	}
+	appointment.EndDate = req.Date.Add(duration)
 	appointment.Notes = req.Notes
+	if !authorize(c, permAppointmentsWrite, appointmentResource(appointment)) {
+		return
+	}
 
-	if err := db.Save(&appointment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	// Перенести или передать другому врачу можно только запланированный прием
+	rescheduled := appointment.PatientID != before.PatientID || appointment.DoctorID != before.DoctorID ||
+		!appointment.Date.Equal(before.Date) || !appointment.EndDate.Equal(before.EndDate)
+	if rescheduled && appointment.Status != statusScheduled {
+		respondBookingError(c, &appointmentStatusError{Status: appointment.Status, Action: "reschedule"})
+		return
+	}
+
+	// Без права на клинические данные диагноз и лечение остаются прежними
+	user := currentUser(c)
+	if user.can(permClinicalWrite, appointmentResource(appointment)) {
+		if (req.Diagnosis != before.Diagnosis || req.Treatment != before.Treatment) && !appointment.clinicalAllowed() {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: errClinicalNotInProgress.Error()})
+			return
+		}
+		appointment.Diagnosis = req.Diagnosis
+		appointment.Treatment = req.Treatment
+	}
+
+	// Препараты, впервые упомянутые в лечении, сверяются с аллергиями пациента и его действующими назначениями
+	var allergies allergyCheck
+	var interactions []InteractionWarning
+	if appointment.Treatment != before.Treatment {
+		drugs, err := treatmentDrugs(db, before.Treatment, appointment.Treatment)
+		if err == nil {
+			allergies, err = checkAllergies(db, appointment.PatientID, drugs, req.OverrideAllergy, req.OverrideReason)
+		}
+		if err == nil {
+			interactions, err = checkInteractions(db, appointment.PatientID, drugs, 0)
+		}
+		if err != nil {
+			respondAllergyError(c, err)
+			return
+		}
+	}
+
+	audit := func(tx *gorm.DB) error {
+		if err := recordAuditUpdate(tx, c, appointment.auditRef(), before, appointment); err != nil {
+			return err
+		}
+		if err := recordAllergyAlert(tx, c, appointment.auditRef(), allergies); err != nil {
+			return err
+		}
+		return recordInteractionAlert(tx, c, appointment.auditRef(), interactions)
+	}
+	var err error
+	if rescheduled {
+		err = saveAppointment(&appointment, audit)
+	} else {
+		// Время и участники не меняются, проверять расписание и пересечения не нужно
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&appointment).Error; err != nil {
+				return err
+			}
+			return audit(tx)
+		})
+	}
+	if err != nil {
+		respondBookingError(c, err)
 		return
 	}
 
+	appointment.AllergyWarnings = allergies.Warnings
+	appointment.InteractionWarnings = interactions
+	redactAppointment(user, &appointment)
 	c.JSON(http.StatusOK, appointment)
 }
 
 // DeleteAppointment godoc
 // @Summary Удалить прием
-// @Description Удалить запись о медицинском приеме
+// @Description Пометить прием удаленным вместе с его тестами и назначениями. Прием можно восстановить через POST /appointments/{id}/restore
 // @Tags appointments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID приема"
 // @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /appointments/{id} [delete]
 func deleteAppointment(c *gin.Context) {
 	id := c.Param("id")
-	if err := db.Delete(&Appointment{}, id).Error; err != nil {
+	var appointment Appointment
+	if err := db.First(&appointment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, permAppointmentsWrite, appointmentResource(appointment)) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return archiveAppointments(tx, c, []Appointment{appointment}, deletionTime())
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -599,18 +1376,299 @@ func deleteAppointment(c *gin.Context) {
 // @Tags appointments
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID приема"
-// @Success 200 {array} MedicalTest
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, name, -name, created_at, -created_at) default(id)
+// @Success 200 {object} Page[MedicalTest]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /appointments/{id}/tests [get]
 func getAppointmentTests(c *gin.Context) {
 	id := c.Param("id")
+	var appointment Appointment
+	if err := db.First(&appointment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, permTestsRead, appointmentResource(appointment)) {
+		return
+	}
+
+	p, ok := paginate(c, appointmentTestList)
+	if !ok {
+		return
+	}
 	var tests []MedicalTest
-	if err := db.Where("appointment_id = ?", id).Find(&tests).Error; err != nil {
+	if err := p.apply(db.Where("appointment_id = ?", appointment.ID)).Find(&tests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	page := p.page(tests)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
+}
+
+// CreateAppointmentTest godoc
+// @Summary Добавить результат теста
+// @Description Добавить результат медицинского теста к приему
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Param test body CreateMedicalTestRequest true "Результат теста"
+// @Success 201 {object} MedicalTest
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/tests [post]
+func createAppointmentTest(c *gin.Context) {
+	id := c.Param("id")
+	var appointment Appointment
+	if err := db.First(&appointment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, permTestsWrite, appointmentResource(appointment)) {
+		return
+	}
+
+	var req CreateMedicalTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	test := MedicalTest{
+		AppointmentID:  appointment.ID,
+		Name:           req.Name,
+		Result:         req.Result,
+		Unit:           req.Unit,
+		ReferenceRange: req.ReferenceRange,
+		CriticalRange:  req.CriticalRange,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&test).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditActionCreate, test.auditRef())
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, tests)
+
+	c.JSON(http.StatusCreated, test)
+}
+
+// Обработчики для медицинских тестов
+
+// GetMedicalTests godoc
+// @Summary Получить список тестов
+// @Description Получить результаты медицинских тестов по всем приемам с возможностью фильтрации
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param patient_id query int false "Фильтр по ID пациента"
+// @Param name query string false "Фильтр по названию теста"
+// @Param from query string false "Дата приема не раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param to query string false "Дата приема раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param include_deleted query bool false "Включить удаленные тесты (только для администратора)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(date, -date, id, -id, name, -name, created_at, -created_at) default(date)
+// @Success 200 {object} Page[MedicalTest]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests [get]
+func getMedicalTests(c *gin.Context) {
+	query, ok := includeDeleted(c, db.Joins("JOIN appointments ON appointments.id = medical_tests.appointment_id").
+		Preload("Appointment"))
+	if !ok {
+		return
+	}
+	p, ok := paginate(c, medicalTestList)
+	if !ok {
+		return
+	}
+	var tests []MedicalTest
+	query = scopeQuery(c, permTestsRead, query, "appointments.patient_id", "appointments.doctor_id")
+
+	if patientID := c.Query("patient_id"); patientID != "" {
+		query = query.Where("appointments.patient_id = ?", patientID)
+	}
+
+	if name := c.Query("name"); name != "" {
+		query = query.Where("medical_tests.name = ?", name)
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := parseTimeParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid 'from' parameter"})
+			return
+		}
+		query = query.Where("appointments.date >= ?", from)
+	}
+
+	if value := c.Query("to"); value != "" {
+		to, err := parseTimeParam(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid 'to' parameter"})
+			return
+		}
+		query = query.Where("appointments.date < ?", to)
+	}
+
+	if err := p.apply(query).Find(&tests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	page := p.page(tests)
+	user := currentUser(c)
+	for i := range page.Data {
+		redactAppointment(user, &page.Data[i].Appointment)
+	}
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
+}
+
+// GetMedicalTest godoc
+// @Summary Получить тест по ID
+// @Description Получить результат медицинского теста
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID теста"
+// @Success 200 {object} MedicalTest
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /tests/{id} [get]
+func getMedicalTest(c *gin.Context) {
+	id := c.Param("id")
+	var test MedicalTest
+	if err := db.Preload("Appointment").First(&test, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Medical test not found"})
+		return
+	}
+	if !authorize(c, permTestsRead, appointmentResource(test.Appointment)) {
+		return
+	}
+
+	redactAppointment(currentUser(c), &test.Appointment)
+	if !auditRead(c, test.auditRef()) {
+		return
+	}
+	c.JSON(http.StatusOK, test)
+}
+
+// UpdateMedicalTest godoc
+// @Summary Обновить результат теста
+// @Description Обновить результат медицинского теста
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID теста"
+// @Param test body CreateMedicalTestRequest true "Обновленный результат теста"
+// @Success 200 {object} MedicalTest
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests/{id} [put]
+func updateMedicalTest(c *gin.Context) {
+	id := c.Param("id")
+	var test MedicalTest
+	if err := db.Preload("Appointment").First(&test, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Medical test not found"})
+		return
+	}
+	if !authorize(c, permTestsWrite, appointmentResource(test.Appointment)) {
+		return
+	}
+
+	var req CreateMedicalTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	before := test
+	test.Name = req.Name
+	test.Result = req.Result
+	test.Unit = req.Unit
+	test.ReferenceRange = req.ReferenceRange
+	test.CriticalRange = req.CriticalRange
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Appointment").Save(&test).Error; err != nil {
+			return err
+		}
+		return recordAuditUpdate(tx, c, test.auditRef(), before, test)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	redactAppointment(currentUser(c), &test.Appointment)
+	c.JSON(http.StatusOK, test)
+}
+
+// DeleteMedicalTest godoc
+// @Summary Удалить тест
+// @Description Пометить результат теста удаленным. Тест можно восстановить через POST /tests/{id}/restore
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID теста"
+// @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests/{id} [delete]
+func deleteMedicalTest(c *gin.Context) {
+	id := c.Param("id")
+	var test MedicalTest
+	if err := db.Preload("Appointment").First(&test, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Medical test not found"})
+		return
+	}
+	if !authorize(c, permTestsWrite, appointmentResource(test.Appointment)) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return archive(tx, c, []MedicalTest{test}, deletionTime())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, "Medical test deleted")
 }
 
 // Обработчики для анамнеза
@@ -621,14 +1679,31 @@ func getAppointmentTests(c *gin.Context) {
 // @Tags medical-history
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param patient_id query int false "Фильтр по ID пациента"
 // @Param type query string false "Фильтр по типу анамнеза"
-// @Success 200 {array} MedicalHistory
+// @Param include_deleted query bool false "Включить удаленные записи (только для администратора)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, start_date, -start_date, history_type, -history_type, created_at, -created_at) default(id)
+// @Success 200 {object} Page[MedicalHistory]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /medical-history [get]
 func getMedicalHistory(c *gin.Context) {
+	query, ok := includeDeleted(c, db.Preload("Patient"))
+	if !ok {
+		return
+	}
+	p, ok := paginate(c, medicalHistoryList)
+	if !ok {
+		return
+	}
 	var history []MedicalHistory
-	query := db.Preload("Patient")
+	query = scopeQuery(c, permHistoryRead, query, "patient_id", "")
 
 	if patientID := c.Query("patient_id"); patientID != "" {
 		query = query.Where("patient_id = ?", patientID)
@@ -638,11 +1713,15 @@ func getMedicalHistory(c *gin.Context) {
 		query = query.Where("history_type = ?", historyType)
 	}
 
-	if err := query.Find(&history).Error; err != nil {
+	if err := p.apply(query).Find(&history).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, history)
+	page := p.page(history)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
 }
 
 // CreateMedicalHistory godoc
@@ -651,9 +1730,13 @@ func getMedicalHistory(c *gin.Context) {
 // @Tags medical-history
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param history body CreateMedicalHistoryRequest true "Данные анамнеза"
 // @Success 201 {object} MedicalHistory
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 422 {object} ReferenceErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /medical-history [post]
 func createMedicalHistory(c *gin.Context) {
@@ -662,6 +1745,9 @@ func createMedicalHistory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
+	if !authorize(c, permHistoryWrite, resource{PatientID: req.PatientID}) {
+		return
+	}
 
 	history := MedicalHistory{
 		PatientID:   req.PatientID,
@@ -673,8 +1759,19 @@ func createMedicalHistory(c *gin.Context) {
 		Notes:       req.Notes,
 	}
 
-	if err := db.Create(&history).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := requireReference(tx, &Patient{}, "patient_id", "patient", history.PatientID); err != nil {
+			return err
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, c, auditActionCreate, history.auditRef())
+	})
+	if err != nil {
+		if !respondReferenceError(c, err) {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		}
 		return
 	}
 
@@ -683,93 +1780,35 @@ func createMedicalHistory(c *gin.Context) {
 
 // DeleteMedicalHistory godoc
 // @Summary Удалить запись анамнеза
-// @Description Удалить запись из медицинского анамнеза
+// @Description Пометить запись анамнеза удаленной. Запись можно восстановить через POST /medical-history/{id}/restore
 // @Tags medical-history
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "ID записи анамнеза"
 // @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /medical-history/{id} [delete]
 func deleteMedicalHistory(c *gin.Context) {
 	id := c.Param("id")
-	if err := db.Delete(&MedicalHistory{}, id).Error; err != nil {
+	var history MedicalHistory
+	if err := db.First(&history, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Medical history record not found"})
+		return
+	}
+	if !authorize(c, permHistoryWrite, resource{PatientID: history.PatientID}) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return archive(tx, c, []MedicalHistory{history}, deletionTime())
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, "Medical history record deleted")
 }
-
-func seedDatabase(db *gorm.DB) {
-	// Очистка существующих данных
-	db.Exec("DELETE FROM medical_histories")
-	db.Exec("DELETE FROM medical_tests")
-	db.Exec("DELETE FROM appointments")
-	db.Exec("DELETE FROM patients")
-	db.Exec("DELETE FROM doctors")
-
-	// Генерация пациентов
-	patients := []Patient{
-		{FullName: "Иванов Иван Иванович", BirthDate: time.Date(1985, 5, 15, 0, 0, 0, 0, time.UTC), Gender: "male", Phone: "+79990000001", Email: "ivanov@mail.ru"},
-		{FullName: "Петрова Мария Сергеевна", BirthDate: time.Date(1990, 8, 22, 0, 0, 0, 0, time.UTC), Gender: "female", Phone: "+79990000002", Email: "petrova@mail.ru"},
-		{FullName: "Сидоров Алексей Владимирович", BirthDate: time.Date(1978, 3, 10, 0, 0, 0, 0, time.UTC), Gender: "male", Phone: "+79990000003", Email: "sidorov@mail.ru"},
-		{FullName: "Кузнецова Елена Викторовна", BirthDate: time.Date(1982, 11, 5, 0, 0, 0, 0, time.UTC), Gender: "female", Phone: "+79990000004", Email: "kuznetsova@mail.ru"},
-		{FullName: "Смирнов Дмитрий Петрович", BirthDate: time.Date(1995, 7, 30, 0, 0, 0, 0, time.UTC), Gender: "male", Phone: "+79990000005", Email: "smirnov@mail.ru"},
-	}
-	db.Create(&patients)
-
-	// Генерация врачей
-	doctors := []Doctor{
-		{FullName: "Прохоров Андрей Васильевич", Specialization: "Кардиолог", Phone: "+79991111111", Email: "prokhorov@clinic.ru"},
-		{FullName: "Громова Ольга Игоревна", Specialization: "Невролог", Phone: "+79991111112", Email: "gromova@clinic.ru"},
-		{FullName: "Белов Станислав Михайлович", Specialization: "Терапевт", Phone: "+79991111113", Email: "belov@clinic.ru"},
-		{FullName: "Ковальчук Анна Денисовна", Specialization: "Офтальмолог", Phone: "+79991111114", Email: "kovalchuk@clinic.ru"},
-	}
-	db.Create(&doctors)
-
-	// Генерация приемов
-	appointments := []Appointment{
-		{PatientID: 1, DoctorID: 1, Date: time.Now().Add(-24 * time.Hour), Diagnosis: "Гипертония", Treatment: "Контроль давления, лизиноприл 10 мг 1 раз в день", Notes: "Жалобы на головные боли"},
-		{PatientID: 2, DoctorID: 2, Date: time.Now().Add(-12 * time.Hour), Diagnosis: "Мигрень", Treatment: "Ибупрофен при болях, режим сна", Notes: "Рекомендован отдых"},
-		{PatientID: 3, DoctorID: 3, Date: time.Now().Add(-6 * time.Hour), Diagnosis: "ОРВИ", Treatment: "Обильное питье, парацетамол", Notes: "Температура 37.8"},
-		{PatientID: 4, DoctorID: 4, Date: time.Now().Add(-3 * time.Hour), Diagnosis: "Конъюнктивит", Treatment: "Глазные капли Офтальмоферон", Notes: "Назначен повторный прием через 5 дней"},
-		{PatientID: 5, DoctorID: 1, Date: time.Now(), Diagnosis: "Аритмия", Treatment: "Холтеровское мониторирование", Notes: "Направлен на дополнительное обследование"},
-	}
-	db.Create(&appointments)
-
-	// Генерация медицинских тестов
-	medicalTests := []MedicalTest{
-		{AppointmentID: 1, Name: "Артериальное давление", Result: "140/90", Unit: "мм рт.ст.", ReferenceRange: "120/80"},
-		{AppointmentID: 1, Name: "Холестерин", Result: "5.2", Unit: "ммоль/л", ReferenceRange: "3.5-5.2"},
-		{AppointmentID: 2, Name: "МРТ головного мозга", Result: "Без патологий", Unit: "-", ReferenceRange: "-"},
-		{AppointmentID: 3, Name: "Температура тела", Result: "37.8", Unit: "°C", ReferenceRange: "36.6"},
-		{AppointmentID: 4, Name: "Острота зрения", Result: "0.8", Unit: "усл.ед.", ReferenceRange: "1.0"},
-		{AppointmentID: 5, Name: "ЭКГ", Result: "Мерцательная аритмия", Unit: "-", ReferenceRange: "Синусовый ритм"},
-	}
-	db.Create(&medicalTests)
-
-	// Генерация анамнеза
-	medicalHistories := []MedicalHistory{
-		// Аллергии
-		{PatientID: 1, HistoryType: "allergy", Description: "Аллергия на пенициллин", StartDate: time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "severe", Status: "active", Notes: "Анафилактический шок при приеме"},
-		{PatientID: 2, HistoryType: "allergy", Description: "Сезонная аллергия на пыльцу", StartDate: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "moderate", Status: "active", Notes: "Обострение весной"},
-
-		// Хронические заболевания
-		{PatientID: 1, HistoryType: "chronic", Description: "Артериальная гипертензия", StartDate: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "moderate", Status: "chronic", Notes: "Постоянный прием препаратов"},
-		{PatientID: 3, HistoryType: "chronic", Description: "Сахарный диабет 2 типа", StartDate: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "mild", Status: "chronic", Notes: "Контроль диеты"},
-		{PatientID: 4, HistoryType: "chronic", Description: "Бронхиальная астма", StartDate: time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "mild", Status: "chronic", Notes: "Ингалятор по необходимости"},
-
-		// Перенесенные операции
-		{PatientID: 2, HistoryType: "surgery", Description: "Аппендэктомия", StartDate: time.Date(2015, 6, 15, 0, 0, 0, 0, time.UTC), Severity: "moderate", Status: "resolved", Notes: "Восстановление прошло без осложнений"},
-		{PatientID: 5, HistoryType: "surgery", Description: "Артроскопия коленного сустава", StartDate: time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC), Severity: "moderate", Status: "resolved", Notes: "Спортивная травма"},
-
-		// Семейный анамнез
-		{PatientID: 1, HistoryType: "family", Description: "Инфаркт миокарда у отца в 55 лет", StartDate: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "severe", Status: "active", Notes: "Наследственная предрасположенность"},
-		{PatientID: 3, HistoryType: "family", Description: "Онкологические заболевания у родственников", StartDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "moderate", Status: "active", Notes: "Бабушка - рак молочной железы"},
-
-		// Вредные привычки
-		{PatientID: 3, HistoryType: "habit", Description: "Курение", StartDate: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "moderate", Status: "active", Notes: "10 сигарет в день, 20 лет стажа"},
-		{PatientID: 5, HistoryType: "habit", Description: "Злоупотребление алкоголем", StartDate: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Severity: "mild", Status: "resolved", Notes: "Воздержание 2 года"},
-	}
-	db.Create(&medicalHistories)
-}
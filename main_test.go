@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUpdateAppointmentKeepsDuration(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		doctorUser := createTestUser(t, roleDoctor, doctor.ID)
+		registrar := createTestUser(t, roleRegistrar, 0)
+
+		tests := []struct {
+			name    string
+			user    User
+			status  string
+			req     CreateAppointmentRequest
+			wantEnd time.Time
+		}{
+			{
+				name:    "clinical fields of a visit in progress",
+				user:    doctorUser,
+				status:  statusInProgress,
+				req:     CreateAppointmentRequest{Diagnosis: "ОРВИ", Treatment: "Обильное питье"},
+				wantEnd: testTime(11, 0),
+			},
+			{
+				name:    "notes of a scheduled visit",
+				user:    registrar,
+				status:  statusScheduled,
+				req:     CreateAppointmentRequest{Notes: "Принести выписку"},
+				wantEnd: testTime(11, 0),
+			},
+			{
+				name:    "explicit duration",
+				user:    registrar,
+				status:  statusScheduled,
+				req:     CreateAppointmentRequest{Duration: 45},
+				wantEnd: testTime(10, 45),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID,
+					Date: testTime(10, 0), EndDate: testTime(11, 0), Status: tt.status})
+				t.Cleanup(func() { db.Unscoped().Delete(&appointment) })
+
+				req := tt.req
+				req.PatientID, req.DoctorID, req.Date = patient.ID, doctor.ID, appointment.Date
+				w := apiRequest(t, tt.user, http.MethodPut, fmt.Sprintf("/appointments/%d", appointment.ID), req)
+				got := decodeResponse[Appointment](t, w, http.StatusOK)
+				if !got.EndDate.Equal(tt.wantEnd) {
+					t.Errorf("end_date = %v, want %v", got.EndDate, tt.wantEnd)
+				}
+
+				var stored Appointment
+				if err := db.First(&stored, appointment.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				if !stored.EndDate.Equal(tt.wantEnd) {
+					t.Errorf("stored end_date = %v, want %v", stored.EndDate, tt.wantEnd)
+				}
+			})
+		}
+	})
+}
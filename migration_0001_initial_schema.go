@@ -0,0 +1,125 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Снимок схемы, которую до появления миграций создавал AutoMigrate.
+// Для баз, созданных старыми версиями, миграция только досоздает недостающее.
+
+type m0001Patient struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	FullName  string    `gorm:"not null"`
+	BirthDate time.Time `gorm:"not null"`
+	Gender    string    `gorm:"not null;check:gender IN ('male','female')"`
+	Phone     string
+	Email     string
+}
+
+func (m0001Patient) TableName() string { return "patients" }
+
+type m0001Doctor struct {
+	ID             uint `gorm:"primaryKey"`
+	CreatedAt      time.Time
+	FullName       string `gorm:"not null"`
+	Specialization string `gorm:"not null"`
+	Phone          string
+	Email          string
+	Active         bool `gorm:"not null;default:true"`
+	DeactivatedAt  *time.Time
+}
+
+func (m0001Doctor) TableName() string { return "doctors" }
+
+type m0001DoctorSchedule struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	DoctorID   uint   `gorm:"not null;index"`
+	Weekday    int    `gorm:"not null;check:weekday BETWEEN 1 AND 7"`
+	StartTime  string `gorm:"not null"`
+	EndTime    string `gorm:"not null"`
+	BreakStart string
+	BreakEnd   string
+}
+
+func (m0001DoctorSchedule) TableName() string { return "doctor_schedules" }
+
+type m0001DoctorScheduleException struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	DoctorID  uint      `gorm:"not null;index"`
+	StartDate time.Time `gorm:"not null"`
+	EndDate   time.Time `gorm:"not null"`
+	Reason    string
+}
+
+func (m0001DoctorScheduleException) TableName() string { return "doctor_schedule_exceptions" }
+
+type m0001Appointment struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	PatientID uint      `gorm:"not null"`
+	DoctorID  uint      `gorm:"not null"`
+	Date      time.Time `gorm:"not null;index"`
+	EndDate   time.Time `gorm:"index"`
+	Diagnosis string
+	Treatment string
+	Notes     string
+}
+
+func (m0001Appointment) TableName() string { return "appointments" }
+
+type m0001MedicalTest struct {
+	ID             uint `gorm:"primaryKey"`
+	CreatedAt      time.Time
+	AppointmentID  uint   `gorm:"not null"`
+	Name           string `gorm:"not null"`
+	Result         string
+	Unit           string
+	ReferenceRange string
+}
+
+func (m0001MedicalTest) TableName() string { return "medical_tests" }
+
+type m0001MedicalHistory struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	PatientID   uint   `gorm:"not null"`
+	HistoryType string `gorm:"not null"`
+	Description string `gorm:"not null"`
+	StartDate   time.Time
+	Severity    string
+	Status      string
+	Notes       string
+}
+
+func (m0001MedicalHistory) TableName() string { return "medical_histories" }
+
+func m0001Tables() []interface{} {
+	return []interface{}{
+		&m0001Patient{},
+		&m0001Doctor{},
+		&m0001DoctorSchedule{},
+		&m0001DoctorScheduleException{},
+		&m0001Appointment{},
+		&m0001MedicalTest{},
+		&m0001MedicalHistory{},
+	}
+}
+
+func migrateInitialSchemaUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(m0001Tables()...)
+}
+
+func migrateInitialSchemaDown(tx *gorm.DB) error {
+	tables := m0001Tables()
+	for i := len(tables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(tables[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
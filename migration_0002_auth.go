@@ -0,0 +1,59 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Учетные записи пользователей и выданные им токены
+
+type m0002User struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	Username     string `gorm:"not null;uniqueIndex"`
+	PasswordHash string `gorm:"not null"`
+	Active       bool   `gorm:"not null;default:true"`
+}
+
+func (m0002User) TableName() string { return "users" }
+
+type m0002RefreshToken struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+}
+
+func (m0002RefreshToken) TableName() string { return "refresh_tokens" }
+
+type m0002RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (m0002RevokedToken) TableName() string { return "revoked_tokens" }
+
+func m0002Tables() []interface{} {
+	return []interface{}{
+		&m0002User{},
+		&m0002RefreshToken{},
+		&m0002RevokedToken{},
+	}
+}
+
+func migrateAuthUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(m0002Tables()...)
+}
+
+func migrateAuthDown(tx *gorm.DB) error {
+	tables := m0002Tables()
+	for i := len(tables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(tables[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Роли пользователей и их связь с врачами и пациентами.
+// Пользователи, созданные до появления ролей, получают роль admin, чтобы сохранить прежний доступ.
+
+type m0003User struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	Username     string `gorm:"not null;uniqueIndex"`
+	PasswordHash string `gorm:"not null"`
+	Active       bool   `gorm:"not null;default:true"`
+	Role         string `gorm:"not null;default:'admin'"`
+	DoctorID     *uint
+	PatientID    *uint
+}
+
+func (m0003User) TableName() string { return "users" }
+
+func migrateUserRolesUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&m0003User{})
+}
+
+func migrateUserRolesDown(tx *gorm.DB) error {
+	for _, column := range []string{"PatientID", "DoctorID", "Role"} {
+		if err := tx.Migrator().DropColumn(&m0003User{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Журнал аудита. Изменение и удаление его записей запрещено на уровне базы данных.
+
+type m0004AuditLog struct {
+	ID         uint      `gorm:"primaryKey"`
+	CreatedAt  time.Time `gorm:"not null"`
+	UserID     uint      `gorm:"not null;index"`
+	Username   string    `gorm:"not null"`
+	Action     string    `gorm:"not null"`
+	EntityType string    `gorm:"not null;index:idx_audit_logs_entity"`
+	EntityID   uint      `gorm:"not null;index:idx_audit_logs_entity"`
+	ClientIP   string
+	Changes    string `gorm:"type:text"`
+	PrevHash   string `gorm:"not null"`
+	Hash       string `gorm:"not null;uniqueIndex"`
+}
+
+func (m0004AuditLog) TableName() string { return "audit_logs" }
+
+func migrateAuditLogUp(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&m0004AuditLog{}); err != nil {
+		return err
+	}
+
+	var statements []string
+	if tx.Dialector.Name() == "postgres" {
+		statements = []string{
+			"CREATE RULE audit_logs_no_update AS ON UPDATE TO audit_logs DO INSTEAD NOTHING",
+			"CREATE RULE audit_logs_no_delete AS ON DELETE TO audit_logs DO INSTEAD NOTHING",
+		}
+	} else {
+		statements = []string{
+			"CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON audit_logs BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+			"CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON audit_logs BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+		}
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateAuditLogDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0004AuditLog{})
+}
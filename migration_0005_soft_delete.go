@@ -0,0 +1,52 @@
+package main
+
+import (
+	"gorm.io/gorm"
+)
+
+// Мягкое удаление пациентов, приемов, тестов и записей анамнеза: вместо удаления строки
+// заполняется deleted_at.
+
+type m0005Patient struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0005Patient) TableName() string { return "patients" }
+
+type m0005Appointment struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0005Appointment) TableName() string { return "appointments" }
+
+type m0005MedicalTest struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0005MedicalTest) TableName() string { return "medical_tests" }
+
+type m0005MedicalHistory struct {
+	ID        uint           `gorm:"primaryKey"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (m0005MedicalHistory) TableName() string { return "medical_histories" }
+
+func migrateSoftDeleteUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&m0005Patient{}, &m0005Appointment{}, &m0005MedicalTest{}, &m0005MedicalHistory{})
+}
+
+func migrateSoftDeleteDown(tx *gorm.DB) error {
+	for _, model := range []interface{}{&m0005MedicalHistory{}, &m0005MedicalTest{}, &m0005Appointment{}, &m0005Patient{}} {
+		if err := tx.Migrator().DropIndex(model, "DeletedAt"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(model, "DeletedAt"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
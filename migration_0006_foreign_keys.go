@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Внешние ключи для связей приемов, тестов, анамнеза и графиков врачей.
+// SQLite не умеет добавлять ограничения к существующей таблице, поэтому GORM пересоздает
+// таблицу; индексы при этом теряются и создаются заново. Проверка ключей откладывается
+// до конца транзакции (defer_foreign_keys), так как пересоздание таблицы временно
+// нарушает ссылки на нее.
+
+type m0006Patient struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (m0006Patient) TableName() string { return "patients" }
+
+type m0006Doctor struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (m0006Doctor) TableName() string { return "doctors" }
+
+type m0006DoctorSchedule struct {
+	ID       uint        `gorm:"primaryKey"`
+	DoctorID uint        `gorm:"not null;index"`
+	Doctor   m0006Doctor `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0006DoctorSchedule) TableName() string { return "doctor_schedules" }
+
+type m0006DoctorScheduleException struct {
+	ID       uint        `gorm:"primaryKey"`
+	DoctorID uint        `gorm:"not null;index"`
+	Doctor   m0006Doctor `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0006DoctorScheduleException) TableName() string { return "doctor_schedule_exceptions" }
+
+type m0006Appointment struct {
+	ID        uint           `gorm:"primaryKey"`
+	PatientID uint           `gorm:"not null"`
+	DoctorID  uint           `gorm:"not null"`
+	Date      time.Time      `gorm:"not null;index"`
+	EndDate   time.Time      `gorm:"index"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Patient   m0006Patient   `gorm:"constraint:OnDelete:RESTRICT"`
+	Doctor    m0006Doctor    `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0006Appointment) TableName() string { return "appointments" }
+
+type m0006MedicalTest struct {
+	ID            uint             `gorm:"primaryKey"`
+	AppointmentID uint             `gorm:"not null"`
+	DeletedAt     gorm.DeletedAt   `gorm:"index"`
+	Appointment   m0006Appointment `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0006MedicalTest) TableName() string { return "medical_tests" }
+
+type m0006MedicalHistory struct {
+	ID        uint           `gorm:"primaryKey"`
+	PatientID uint           `gorm:"not null"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Patient   m0006Patient   `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0006MedicalHistory) TableName() string { return "medical_histories" }
+
+// m0006ForeignKey описывает внешний ключ: модель и ее поле связи, столбцы для поиска
+// нарушающих строк и индексы таблицы, которые нужно восстановить после ее пересоздания
+type m0006ForeignKey struct {
+	model    interface{}
+	relation string
+	table    string
+	column   string
+	refTable string
+	indexes  []string
+}
+
+// m0006ForeignKeys перечисляет ключи так, что родительская таблица пересоздается раньше дочерней
+func m0006ForeignKeys() []m0006ForeignKey {
+	return []m0006ForeignKey{
+		{&m0006DoctorSchedule{}, "Doctor", "doctor_schedules", "doctor_id", "doctors", []string{"DoctorID"}},
+		{&m0006DoctorScheduleException{}, "Doctor", "doctor_schedule_exceptions", "doctor_id", "doctors", []string{"DoctorID"}},
+		{&m0006Appointment{}, "Patient", "appointments", "patient_id", "patients", []string{"Date", "EndDate", "DeletedAt"}},
+		{&m0006Appointment{}, "Doctor", "appointments", "doctor_id", "doctors", []string{"Date", "EndDate", "DeletedAt"}},
+		{&m0006MedicalTest{}, "Appointment", "medical_tests", "appointment_id", "appointments", []string{"DeletedAt"}},
+		{&m0006MedicalHistory{}, "Patient", "medical_histories", "patient_id", "patients", []string{"DeletedAt"}},
+	}
+}
+
+// m0006CheckOrphans отказывает в миграции, если в базе уже есть ссылки на несуществующие записи
+func m0006CheckOrphans(tx *gorm.DB) error {
+	for _, fk := range m0006ForeignKeys() {
+		var count int64
+		err := tx.Table(fk.table + " AS c").
+			Joins(fmt.Sprintf("LEFT JOIN %s AS p ON p.id = c.%s", fk.refTable, fk.column)).
+			Where("p.id IS NULL").
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%d row(s) in %s reference missing %s via %s; fix or remove them before migrating",
+				count, fk.table, fk.refTable, fk.column)
+		}
+	}
+	return nil
+}
+
+// m0006RestoreIndexes создает индексы, потерянные при пересоздании таблицы в SQLite
+func m0006RestoreIndexes(tx *gorm.DB, fk m0006ForeignKey) error {
+	for _, field := range fk.indexes {
+		if tx.Migrator().HasIndex(fk.model, field) {
+			continue
+		}
+		if err := tx.Migrator().CreateIndex(fk.model, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateForeignKeysUp(tx *gorm.DB) error {
+	if err := m0006CheckOrphans(tx); err != nil {
+		return err
+	}
+	if tx.Dialector.Name() == "sqlite" {
+		if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+			return err
+		}
+	}
+
+	for _, fk := range m0006ForeignKeys() {
+		if !tx.Migrator().HasConstraint(fk.model, fk.relation) {
+			if err := tx.Migrator().CreateConstraint(fk.model, fk.relation); err != nil {
+				return fmt.Errorf("%s.%s: %w", fk.table, fk.column, err)
+			}
+		}
+		if err := m0006RestoreIndexes(tx, fk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateForeignKeysDown(tx *gorm.DB) error {
+	if tx.Dialector.Name() == "sqlite" {
+		if err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error; err != nil {
+			return err
+		}
+	}
+
+	// Дочерние таблицы пересоздаются раньше родительских
+	fks := m0006ForeignKeys()
+	for i := len(fks) - 1; i >= 0; i-- {
+		fk := fks[i]
+		if tx.Migrator().HasConstraint(fk.model, fk.relation) {
+			if err := tx.Migrator().DropConstraint(fk.model, fk.relation); err != nil {
+				return fmt.Errorf("%s.%s: %w", fk.table, fk.column, err)
+			}
+		}
+		if err := m0006RestoreIndexes(tx, fk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
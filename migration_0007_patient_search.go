@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Поиск пациентов: нормализованные ФИО и телефон и, если драйвер SQLite собран с FTS5,
+// внешний полнотекстовый индекс patients_fts, который поддерживается триггерами.
+// Триграммный токенизатор находит любую подстроку длиной от трех символов.
+// Нормализация существующих записей зафиксирована в самой миграции (m0007Normalize*),
+// чтобы правка normalizeSearchText и normalizePhone не меняла ее результат.
+
+type m0007Patient struct {
+	ID          uint   `gorm:"primaryKey"`
+	FullName    string `gorm:"not null"`
+	Phone       string
+	SearchName  string `gorm:"not null;default:''"`
+	SearchPhone string `gorm:"not null;default:''"`
+}
+
+func (m0007Patient) TableName() string { return "patients" }
+
+// m0007FTSStatements создают индекс patients_fts и триггеры его синхронизации
+var m0007FTSStatements = []string{
+	`CREATE VIRTUAL TABLE patients_fts USING fts5(
+		search_name, search_phone, email,
+		content='patients', content_rowid='id', tokenize='trigram')`,
+	`CREATE TRIGGER patients_fts_ai AFTER INSERT ON patients BEGIN
+		INSERT INTO patients_fts(rowid, search_name, search_phone, email)
+		VALUES (new.id, new.search_name, new.search_phone, lower(coalesce(new.email, '')));
+	END`,
+	`CREATE TRIGGER patients_fts_ad AFTER DELETE ON patients BEGIN
+		INSERT INTO patients_fts(patients_fts, rowid, search_name, search_phone, email)
+		VALUES ('delete', old.id, old.search_name, old.search_phone, lower(coalesce(old.email, '')));
+	END`,
+	`CREATE TRIGGER patients_fts_au AFTER UPDATE ON patients BEGIN
+		INSERT INTO patients_fts(patients_fts, rowid, search_name, search_phone, email)
+		VALUES ('delete', old.id, old.search_name, old.search_phone, lower(coalesce(old.email, '')));
+		INSERT INTO patients_fts(rowid, search_name, search_phone, email)
+		VALUES (new.id, new.search_name, new.search_phone, lower(coalesce(new.email, '')));
+	END`,
+}
+
+func m0007NormalizeSearchText(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func m0007NormalizePhone(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if len(digits) == 11 && digits[0] == '8' {
+		digits = "7" + digits[1:]
+	}
+	return digits
+}
+
+func migratePatientSearchUp(tx *gorm.DB) error {
+	for _, field := range []string{"SearchName", "SearchPhone"} {
+		if tx.Migrator().HasColumn(&m0007Patient{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&m0007Patient{}, field); err != nil {
+			return err
+		}
+	}
+
+	// Заполнение нормализованных полей у существующих пациентов, включая удаленных
+	var batch []m0007Patient
+	err := tx.Select("id", "full_name", "phone").FindInBatches(&batch, 500, func(b *gorm.DB, _ int) error {
+		for _, p := range batch {
+			err := b.Model(&m0007Patient{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+				"search_name":  m0007NormalizeSearchText(p.FullName),
+				"search_phone": m0007NormalizePhone(p.Phone),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if !sqliteHasFTS5(tx) {
+		return nil
+	}
+	// Индекс хранит email в нижнем регистре: триграммный токенизатор учитывает регистр
+	for _, stmt := range m0007FTSStatements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("patients_fts: %w", err)
+		}
+	}
+	// Внешний индекс заполняется из patients по тем же правилам, что и в триггерах
+	return tx.Exec(`INSERT INTO patients_fts(rowid, search_name, search_phone, email)
+		SELECT id, search_name, search_phone, lower(coalesce(email, '')) FROM patients`).Error
+}
+
+func migratePatientSearchDown(tx *gorm.DB) error {
+	for _, stmt := range []string{
+		"DROP TRIGGER IF EXISTS patients_fts_au",
+		"DROP TRIGGER IF EXISTS patients_fts_ad",
+		"DROP TRIGGER IF EXISTS patients_fts_ai",
+		"DROP TABLE IF EXISTS patients_fts",
+	} {
+		if tx.Dialector.Name() != "sqlite" {
+			break
+		}
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	// ALTER TABLE вместо Migrator().DropColumn: в SQLite GORM пересоздает таблицу,
+	// а на patients ссылаются внешние ключи
+	for _, column := range []string{"search_phone", "search_name"} {
+		if !tx.Migrator().HasColumn(&m0007Patient{}, column) {
+			continue
+		}
+		if err := tx.Exec("ALTER TABLE patients DROP COLUMN " + column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
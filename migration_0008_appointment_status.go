@@ -0,0 +1,53 @@
+package main
+
+import (
+	"gorm.io/gorm"
+)
+
+// Статус приема. Существующие приемы с записанным диагнозом или лечением считаются
+// завершенными, остальные - запланированными.
+
+type m0008Appointment struct {
+	ID           uint   `gorm:"primaryKey"`
+	Status       string `gorm:"not null;default:'scheduled';index"`
+	StatusReason string
+}
+
+func (m0008Appointment) TableName() string { return "appointments" }
+
+func migrateAppointmentStatusUp(tx *gorm.DB) error {
+	for _, field := range []string{"Status", "StatusReason"} {
+		if tx.Migrator().HasColumn(&m0008Appointment{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&m0008Appointment{}, field); err != nil {
+			return err
+		}
+	}
+	if !tx.Migrator().HasIndex(&m0008Appointment{}, "Status") {
+		if err := tx.Migrator().CreateIndex(&m0008Appointment{}, "Status"); err != nil {
+			return err
+		}
+	}
+	return tx.Exec(`UPDATE appointments SET status = 'completed'
+		WHERE COALESCE(diagnosis, '') <> '' OR COALESCE(treatment, '') <> ''`).Error
+}
+
+func migrateAppointmentStatusDown(tx *gorm.DB) error {
+	if tx.Migrator().HasIndex(&m0008Appointment{}, "Status") {
+		if err := tx.Migrator().DropIndex(&m0008Appointment{}, "Status"); err != nil {
+			return err
+		}
+	}
+	// ALTER TABLE вместо Migrator().DropColumn: в SQLite GORM пересоздает таблицу,
+	// а на appointments ссылаются внешние ключи
+	for _, column := range []string{"status_reason", "status"} {
+		if !tx.Migrator().HasColumn(&m0008Appointment{}, column) {
+			continue
+		}
+		if err := tx.Exec("ALTER TABLE appointments DROP COLUMN " + column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Числовые значения, границы референсного интервала и флаг отклонения результата теста.
+// Для существующих тестов значения вычисляются из текстовых полей. Разбор скопирован из
+// labresults.go на момент создания миграции: последующие изменения формата не должны
+// менять результат уже выпущенной миграции.
+
+type m0009MedicalTest struct {
+	ID             uint `gorm:"primaryKey"`
+	Result         string
+	ReferenceRange string
+	CriticalRange  string
+	ResultValue    *float64
+	ResultValue2   *float64
+	RangeLow       *float64
+	RangeHigh      *float64
+	RangeLow2      *float64
+	RangeHigh2     *float64
+	Flag           string `gorm:"index"`
+}
+
+func (m0009MedicalTest) TableName() string { return "medical_tests" }
+
+// m0009Columns - добавляемые столбцы в порядке создания
+var m0009Columns = []string{
+	"CriticalRange", "ResultValue", "ResultValue2", "RangeLow", "RangeHigh", "RangeLow2", "RangeHigh2", "Flag",
+}
+
+func migrateLabResultValuesUp(tx *gorm.DB) error {
+	for _, field := range m0009Columns {
+		if tx.Migrator().HasColumn(&m0009MedicalTest{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&m0009MedicalTest{}, field); err != nil {
+			return err
+		}
+	}
+	if !tx.Migrator().HasIndex(&m0009MedicalTest{}, "Flag") {
+		if err := tx.Migrator().CreateIndex(&m0009MedicalTest{}, "Flag"); err != nil {
+			return err
+		}
+	}
+
+	// Вычисление значений для существующих тестов, включая удаленные
+	var batch []m0009MedicalTest
+	return tx.Select("id", "result", "reference_range").FindInBatches(&batch, 500, func(b *gorm.DB, _ int) error {
+		for _, t := range batch {
+			e := m0009EvaluateLabResult(t.Result, t.ReferenceRange)
+			err := b.Model(&m0009MedicalTest{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+				"result_value":  e.ResultValue,
+				"result_value2": e.ResultValue2,
+				"range_low":     e.RangeLow,
+				"range_high":    e.RangeHigh,
+				"range_low2":    e.RangeLow2,
+				"range_high2":   e.RangeHigh2,
+				"flag":          e.Flag,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+const m0009LabNumber = `([-+]?\d+(?:[.,]\d+)?)`
+
+var (
+	m0009LabResultRe = regexp.MustCompile(`^(?:[<>≤≥]=?\s*)?` + m0009LabNumber + `(?:\s*/\s*` + m0009LabNumber + `)?(?:\s+\D*)?$`)
+
+	m0009LabRangeRes = []struct {
+		re        *regexp.Regexp
+		low, high int
+	}{
+		{regexp.MustCompile(`^` + m0009LabNumber + `\s*(?:-|–|—|\.\.)\s*` + m0009LabNumber + `$`), 1, 2},
+		{regexp.MustCompile(`^(?i:от)\s*` + m0009LabNumber + `\s*(?i:до)\s*` + m0009LabNumber + `$`), 1, 2},
+		{regexp.MustCompile(`^(?:<=?|≤|(?i:до))\s*` + m0009LabNumber + `$`), 0, 1},
+		{regexp.MustCompile(`^(?:>=?|≥|(?i:от))\s*` + m0009LabNumber + `$`), 1, 0},
+	}
+
+	m0009LabUnitSuffixRe = regexp.MustCompile(`\s+\D*$`)
+)
+
+type m0009LabRange struct {
+	Low  *float64
+	High *float64
+}
+
+type m0009LabEvaluation struct {
+	ResultValue, ResultValue2 *float64
+	RangeLow, RangeHigh       *float64
+	RangeLow2, RangeHigh2     *float64
+	Flag                      string
+}
+
+func m0009ParseLabNumber(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return v, err == nil
+}
+
+func m0009ParseLabResult(s string) []float64 {
+	m := m0009LabResultRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil
+	}
+	var values []float64
+	for _, part := range m[1:] {
+		if part == "" {
+			continue
+		}
+		v, ok := m0009ParseLabNumber(part)
+		if !ok {
+			return nil
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func m0009ParseLabRange(s string) []m0009LabRange {
+	s = strings.TrimSpace(s)
+	if r, ok := m0009ParseLabRangeComponent(m0009LabUnitSuffixRe.ReplaceAllString(s, "")); ok {
+		return []m0009LabRange{r}
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return nil
+	}
+	ranges := make([]m0009LabRange, len(parts))
+	for i, part := range parts {
+		r, ok := m0009ParseLabRangeComponent(strings.TrimSpace(m0009LabUnitSuffixRe.ReplaceAllString(strings.TrimSpace(part), "")))
+		if !ok {
+			return nil
+		}
+		ranges[i] = r
+	}
+	return ranges
+}
+
+func m0009ParseLabRangeComponent(s string) (m0009LabRange, bool) {
+	for _, form := range m0009LabRangeRes {
+		m := form.re.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		var r m0009LabRange
+		for _, bound := range []struct {
+			group  int
+			target **float64
+		}{{form.low, &r.Low}, {form.high, &r.High}} {
+			if bound.group == 0 {
+				continue
+			}
+			v, ok := m0009ParseLabNumber(m[bound.group])
+			if !ok {
+				return m0009LabRange{}, false
+			}
+			*bound.target = &v
+		}
+		if r.Low != nil && r.High != nil && *r.Low > *r.High {
+			return m0009LabRange{}, false
+		}
+		return r, true
+	}
+	return m0009LabRange{}, false
+}
+
+// m0009EvaluateLabResult - evaluateLabResult без критического интервала, которого у
+// существующих тестов еще нет: флаги N, L и H, для составного результата - худший
+func m0009EvaluateLabResult(result, referenceRange string) m0009LabEvaluation {
+	var e m0009LabEvaluation
+	values := m0009ParseLabResult(result)
+	if len(values) > 0 {
+		e.ResultValue = &values[0]
+	}
+	if len(values) > 1 {
+		e.ResultValue2 = &values[1]
+	}
+	ranges := m0009ParseLabRange(referenceRange)
+	if len(ranges) > 0 {
+		e.RangeLow, e.RangeHigh = ranges[0].Low, ranges[0].High
+	}
+	if len(ranges) > 1 {
+		e.RangeLow2, e.RangeHigh2 = ranges[1].Low, ranges[1].High
+	}
+	if len(values) == 0 || len(values) != len(ranges) {
+		return e
+	}
+	e.Flag = "N"
+	for i, v := range values {
+		switch {
+		case e.Flag != "N":
+			return e
+		case ranges[i].Low != nil && v < *ranges[i].Low:
+			e.Flag = "L"
+		case ranges[i].High != nil && v > *ranges[i].High:
+			e.Flag = "H"
+		}
+	}
+	return e
+}
+
+func migrateLabResultValuesDown(tx *gorm.DB) error {
+	if tx.Migrator().HasIndex(&m0009MedicalTest{}, "Flag") {
+		if err := tx.Migrator().DropIndex(&m0009MedicalTest{}, "Flag"); err != nil {
+			return err
+		}
+	}
+	// ALTER TABLE вместо Migrator().DropColumn: в SQLite GORM пересоздает таблицу
+	for i := len(m0009Columns) - 1; i >= 0; i-- {
+		field := m0009Columns[i]
+		if !tx.Migrator().HasColumn(&m0009MedicalTest{}, field) {
+			continue
+		}
+		column := tx.NamingStrategy.ColumnName("", field)
+		if err := tx.Exec("ALTER TABLE medical_tests DROP COLUMN " + column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Таблица синонимов названий тестов с распространенными сокращениями.
+// Синонимы хранятся в нормализованном виде (см. normalizeSearchText).
+
+type m0010TestSynonym struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Alias     string `gorm:"not null;uniqueIndex"`
+	Name      string `gorm:"not null;index"`
+}
+
+func (m0010TestSynonym) TableName() string { return "test_synonyms" }
+
+func migrateTestSynonymsUp(tx *gorm.DB) error {
+	if err := tx.Migrator().CreateTable(&m0010TestSynonym{}); err != nil {
+		return err
+	}
+	now := time.Now()
+	synonyms := []m0010TestSynonym{
+		{Alias: "хс", Name: "Холестерин"},
+		{Alias: "общий холестерин", Name: "Холестерин"},
+		{Alias: "холестерин общий", Name: "Холестерин"},
+		{Alias: "ад", Name: "Артериальное давление"},
+		{Alias: "давление", Name: "Артериальное давление"},
+		{Alias: "глюкоза крови", Name: "Глюкоза"},
+		{Alias: "сахар крови", Name: "Глюкоза"},
+		{Alias: "hb", Name: "Гемоглобин"},
+		{Alias: "hgb", Name: "Гемоглобин"},
+		{Alias: "тг", Name: "Триглицериды"},
+	}
+	for i := range synonyms {
+		synonyms[i].CreatedAt = now
+	}
+	return tx.Create(&synonyms).Error
+}
+
+func migrateTestSynonymsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0010TestSynonym{})
+}
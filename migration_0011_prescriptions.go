@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Назначения лекарственных препаратов. Назначение ссылается на прием, пациента и
+// назначившего врача; пациент и врач дублируются из приема для выборок по ним.
+
+type m0011Patient struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (m0011Patient) TableName() string { return "patients" }
+
+type m0011Doctor struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (m0011Doctor) TableName() string { return "doctors" }
+
+type m0011Appointment struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (m0011Appointment) TableName() string { return "appointments" }
+
+type m0011Prescription struct {
+	ID                uint `gorm:"primaryKey"`
+	CreatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+	AppointmentID     uint           `gorm:"not null;index"`
+	PatientID         uint           `gorm:"not null;index"`
+	DoctorID          uint           `gorm:"not null"`
+	Drug              string         `gorm:"not null"`
+	Dose              float64        `gorm:"not null"`
+	DoseUnit          string         `gorm:"not null"`
+	Route             string         `gorm:"not null"`
+	Frequency         string         `gorm:"not null"`
+	DurationDays      int            `gorm:"not null;default:0"`
+	StartDate         time.Time      `gorm:"not null"`
+	EndDate           *time.Time
+	DiscontinuedAt    *time.Time
+	DiscontinueReason string
+	Notes             string
+	Appointment       m0011Appointment `gorm:"constraint:OnDelete:RESTRICT"`
+	Patient           m0011Patient     `gorm:"constraint:OnDelete:RESTRICT"`
+	Doctor            m0011Doctor      `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0011Prescription) TableName() string { return "prescriptions" }
+
+func migratePrescriptionsUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&m0011Prescription{})
+}
+
+func migratePrescriptionsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0011Prescription{})
+}
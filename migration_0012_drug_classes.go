@@ -0,0 +1,56 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Таблица фармакологических групп препаратов для проверки назначений по аллергиям.
+// Названия хранятся в нормализованном виде (см. normalizeSearchText).
+
+type m0012DrugClass struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Drug      string `gorm:"not null;uniqueIndex:idx_drug_classes_drug_class"`
+	Class     string `gorm:"not null;uniqueIndex:idx_drug_classes_drug_class;index"`
+}
+
+func (m0012DrugClass) TableName() string { return "drug_classes" }
+
+// m0012DrugClasses - начальное наполнение: группа и относящиеся к ней препараты
+var m0012DrugClasses = []struct {
+	class string
+	drugs []string
+}{
+	{"пенициллины", []string{"пенициллин", "бензилпенициллин", "амоксициллин", "ампициллин", "оксациллин", "амоксиклав", "аугментин", "флемоксин"}},
+	{"цефалоспорины", []string{"цефалоспорин", "цефазолин", "цефалексин", "цефуроксим", "цефтриаксон", "цефотаксим", "цефиксим", "цефепим"}},
+	{"карбапенемы", []string{"имипенем", "меропенем", "эртапенем"}},
+	{"макролиды", []string{"макролид", "азитромицин", "кларитромицин", "эритромицин", "джозамицин", "сумамед"}},
+	{"фторхинолоны", []string{"фторхинолон", "ципрофлоксацин", "левофлоксацин", "моксифлоксацин", "офлоксацин"}},
+	{"тетрациклины", []string{"тетрациклин", "доксициклин", "миноциклин"}},
+	{"сульфаниламиды", []string{"сульфаниламид", "сульфаметоксазол", "ко тримоксазол", "бисептол", "сульфасалазин"}},
+	{"нпвп", []string{"ибупрофен", "диклофенак", "напроксен", "кетопрофен", "кеторолак", "нимесулид", "мелоксикам", "индометацин", "аспирин", "ацетилсалициловая кислота"}},
+	{"ингибиторы апф", []string{"лизиноприл", "эналаприл", "каптоприл", "периндоприл", "рамиприл"}},
+	{"местные анестетики", []string{"лидокаин", "новокаин", "прокаин", "артикаин", "ультракаин"}},
+	{"опиоиды", []string{"морфин", "кодеин", "трамадол", "фентанил"}},
+	{"препараты йода", []string{"йод", "йодид калия", "йодсодержащий контраст", "йогексол", "омнипак"}},
+}
+
+func migrateDrugClassesUp(tx *gorm.DB) error {
+	if err := tx.Migrator().CreateTable(&m0012DrugClass{}); err != nil {
+		return err
+	}
+	now := time.Now()
+	var rows []m0012DrugClass
+	for _, group := range m0012DrugClasses {
+		for _, drug := range group.drugs {
+			rows = append(rows, m0012DrugClass{CreatedAt: now, Drug: drug, Class: group.class})
+		}
+	}
+	return tx.Create(&rows).Error
+}
+
+func migrateDrugClassesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0012DrugClass{})
+}
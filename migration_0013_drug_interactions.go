@@ -0,0 +1,29 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Таблица лекарственных взаимодействий. Пара препаратов или групп хранится один раз,
+// в порядке drug_a <= drug_b; таблица заполняется командой interactions import.
+
+type m0013DrugInteraction struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	DrugA     string `gorm:"not null;uniqueIndex:idx_drug_interactions_pair"`
+	DrugB     string `gorm:"not null;uniqueIndex:idx_drug_interactions_pair;index"`
+	Severity  string `gorm:"not null"`
+	Rationale string `gorm:"not null"`
+}
+
+func (m0013DrugInteraction) TableName() string { return "drug_interactions" }
+
+func migrateDrugInteractionsUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&m0013DrugInteraction{})
+}
+
+func migrateDrugInteractionsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0013DrugInteraction{})
+}
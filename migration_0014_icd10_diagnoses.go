@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Справочник МКБ-10 и кодированные диагнозы приемов. Справочник заполняется командой
+// icd10 import; диагноз хранит код и название без внешнего ключа на справочник, чтобы
+// замена справочника не затрагивала поставленные диагнозы.
+
+type m0014Appointment struct {
+	ID uint `gorm:"primaryKey"`
+}
+
+func (m0014Appointment) TableName() string { return "appointments" }
+
+type m0014ICD10Code struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	Code       string `gorm:"not null;uniqueIndex"`
+	Title      string `gorm:"not null"`
+	SearchText string `gorm:"not null"`
+}
+
+func (m0014ICD10Code) TableName() string { return "icd10_codes" }
+
+type m0014AppointmentDiagnosis struct {
+	ID            uint `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	AppointmentID uint   `gorm:"not null;index;uniqueIndex:idx_appointment_diagnoses_code"`
+	Code          string `gorm:"not null;index;uniqueIndex:idx_appointment_diagnoses_code"`
+	Title         string `gorm:"not null"`
+	Kind          string `gorm:"not null"`
+	Note          string
+	Appointment   m0014Appointment `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+func (m0014AppointmentDiagnosis) TableName() string { return "appointment_diagnoses" }
+
+func migrateICD10DiagnosesUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&m0014ICD10Code{}, &m0014AppointmentDiagnosis{})
+}
+
+func migrateICD10DiagnosesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0014AppointmentDiagnosis{}, &m0014ICD10Code{})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Идентификаторы записей во внешних системах (FHIR identifier: система и значение),
+// по которым загрузка Bundle находит уже загруженные записи. Одна пара система-значение
+// принадлежит одной записи каждого типа ресурса.
+
+type m0015ExternalIdentifier struct {
+	ID           uint `gorm:"primaryKey"`
+	CreatedAt    time.Time
+	ResourceType string `gorm:"not null;uniqueIndex:idx_external_identifiers_key"`
+	System       string `gorm:"not null;uniqueIndex:idx_external_identifiers_key"`
+	Value        string `gorm:"not null;uniqueIndex:idx_external_identifiers_key"`
+	EntityID     uint   `gorm:"not null;index"`
+}
+
+func (m0015ExternalIdentifier) TableName() string { return "external_identifiers" }
+
+func migrateExternalIdentifiersUp(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&m0015ExternalIdentifier{})
+}
+
+func migrateExternalIdentifiersDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&m0015ExternalIdentifier{})
+}
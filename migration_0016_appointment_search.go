@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Нормализованные диагноз приема и специализация врача для фильтров поиска приемов.
+// LIKE в SQLite не учитывает регистр только для латиницы, поэтому поиск по кириллице
+// выполняется по тексту, приведенному к нижнему регистру заранее. Существующие записи
+// заполняются копией normalizeSearchText, чтобы результат миграции не зависел от ее
+// последующих изменений.
+
+type m0016Appointment struct {
+	ID              uint `gorm:"primaryKey"`
+	Diagnosis       string
+	SearchDiagnosis string `gorm:"not null;default:''"`
+}
+
+func (m0016Appointment) TableName() string { return "appointments" }
+
+type m0016Doctor struct {
+	ID                   uint   `gorm:"primaryKey"`
+	Specialization       string `gorm:"not null"`
+	SearchSpecialization string `gorm:"not null;default:''"`
+}
+
+func (m0016Doctor) TableName() string { return "doctors" }
+
+func m0016NormalizeSearchText(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func migrateAppointmentSearchUp(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&m0016Appointment{}, "SearchDiagnosis") {
+		if err := tx.Migrator().AddColumn(&m0016Appointment{}, "SearchDiagnosis"); err != nil {
+			return err
+		}
+	}
+	if !tx.Migrator().HasColumn(&m0016Doctor{}, "SearchSpecialization") {
+		if err := tx.Migrator().AddColumn(&m0016Doctor{}, "SearchSpecialization"); err != nil {
+			return err
+		}
+	}
+
+	// Заполнение у существующих записей, включая удаленные приемы
+	var appointments []m0016Appointment
+	err := tx.Select("id", "diagnosis").Where("diagnosis <> ''").FindInBatches(&appointments, 500, func(b *gorm.DB, _ int) error {
+		for _, a := range appointments {
+			err := b.Model(&m0016Appointment{}).Where("id = ?", a.ID).
+				Update("search_diagnosis", m0016NormalizeSearchText(a.Diagnosis)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var doctors []m0016Doctor
+	return tx.Select("id", "specialization").FindInBatches(&doctors, 500, func(b *gorm.DB, _ int) error {
+		for _, d := range doctors {
+			err := b.Model(&m0016Doctor{}).Where("id = ?", d.ID).
+				Update("search_specialization", m0016NormalizeSearchText(d.Specialization)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func migrateAppointmentSearchDown(tx *gorm.DB) error {
+	// ALTER TABLE вместо Migrator().DropColumn: в SQLite GORM пересоздает таблицу,
+	// а на приемы и врачей ссылаются внешние ключи
+	for _, column := range []struct{ table, name string }{
+		{"doctors", "search_specialization"},
+		{"appointments", "search_diagnosis"},
+	} {
+		if !tx.Migrator().HasColumn(column.table, column.name) {
+			continue
+		}
+		if err := tx.Exec("ALTER TABLE " + column.table + " DROP COLUMN " + column.name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
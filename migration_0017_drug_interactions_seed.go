@@ -0,0 +1,83 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Начальное наполнение базы лекарственных взаимодействий. До этой миграции таблица
+// оставалась пустой, пока не выполнена команда interactions import, и проверка назначений
+// молча не находила взаимодействий. Миграция загружает копию встроенной базы
+// (knowledge/interactions.csv на момент ее создания), только если таблица пуста, чтобы
+// не затронуть загруженную ранее базу. Названия уже нормализованы, пары упорядочены.
+
+type m0017DrugInteraction struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	DrugA     string
+	DrugB     string
+	Severity  string
+	Rationale string
+}
+
+func (m0017DrugInteraction) TableName() string { return "drug_interactions" }
+
+// m0017DrugInteractions - drug_a, drug_b, тяжесть и обоснование
+var m0017DrugInteractions = [][4]string{
+	{"ингибиторы апф", "спиронолактон", "major", "Оба препарата задерживают калий: риск тяжелой гиперкалиемии, особенно при сниженной функции почек. Контроль калия и креатинина"},
+	{"ингибиторы апф", "калия хлорид", "major", "Препараты калия на фоне ингибитора АПФ повышают риск гиперкалиемии"},
+	{"аспаркам", "ингибиторы апф", "major", "Препараты калия на фоне ингибитора АПФ повышают риск гиперкалиемии"},
+	{"ингибиторы апф", "нпвп", "moderate", "НПВП ослабляют гипотензивный эффект ингибиторов АПФ и повышают риск острого почечного повреждения, особенно у пожилых и при обезвоживании"},
+	{"нпвп", "нпвп", "moderate", "Одновременный прием двух НПВП повышает риск желудочно-кишечных кровотечений без усиления обезболивающего эффекта"},
+	{"аспирин", "ибупрофен", "moderate", "Ибупрофен конкурентно снижает антиагрегантный эффект низких доз аспирина; аспирин принимать за 2 часа до ибупрофена"},
+	{"варфарин", "нпвп", "major", "НПВП повышают риск кровотечений на фоне варфарина за счет влияния на тромбоциты и слизистую ЖКТ"},
+	{"варфарин", "макролиды", "major", "Кларитромицин и эритромицин угнетают метаболизм варфарина (CYP3A4): рост МНО и риск кровотечения"},
+	{"варфарин", "фторхинолоны", "major", "Фторхинолоны усиливают антикоагулянтный эффект варфарина; требуется контроль МНО"},
+	{"варфарин", "парацетамол", "minor", "Регулярный прием парацетамола более 2 г в сутки может повышать МНО"},
+	{"кларитромицин", "симвастатин", "contraindicated", "Кларитромицин многократно повышает концентрацию симвастатина: риск миопатии и рабдомиолиза"},
+	{"аторвастатин", "кларитромицин", "major", "Кларитромицин повышает концентрацию аторвастатина: риск миопатии; ограничить дозу статина"},
+	{"нитроглицерин", "силденафил", "contraindicated", "Сочетание с нитратами вызывает резкое и тяжелое снижение артериального давления"},
+	{"изосорбида динитрат", "силденафил", "contraindicated", "Сочетание с нитратами вызывает резкое и тяжелое снижение артериального давления"},
+	{"трамадол", "флуоксетин", "major", "Риск серотонинового синдрома и снижения судорожного порога"},
+	{"сертралин", "трамадол", "major", "Риск серотонинового синдрома и снижения судорожного порога"},
+	{"метотрексат", "сульфаниламиды", "major", "Ко-тримоксазол и другие сульфаниламиды усиливают угнетение кроветворения метотрексатом"},
+	{"метотрексат", "нпвп", "major", "НПВП замедляют выведение метотрексата и повышают его токсичность"},
+	{"амиодарон", "дигоксин", "major", "Амиодарон повышает концентрацию дигоксина в крови: риск гликозидной интоксикации; снизить дозу дигоксина"},
+	{"клопидогрел", "омепразол", "moderate", "Омепразол ослабляет превращение клопидогрела в активный метаболит; предпочтителен пантопразол"},
+	{"теофиллин", "ципрофлоксацин", "major", "Ципрофлоксацин угнетает метаболизм теофиллина: риск судорог и аритмий"},
+	{"йодсодержащий контраст", "метформин", "major", "Контрастные вещества могут вызвать почечную недостаточность и лактоацидоз; метформин отменяют на 48 часов"},
+	{"йогексол", "метформин", "major", "Контрастные вещества могут вызвать почечную недостаточность и лактоацидоз; метформин отменяют на 48 часов"},
+	{"пропранолол", "сальбутамол", "major", "Неселективные бета-блокаторы блокируют действие бета-агонистов и могут вызвать бронхоспазм у больных астмой"},
+	{"инсулин", "пропранолол", "moderate", "Бета-блокаторы маскируют симптомы гипогликемии и замедляют восстановление уровня глюкозы"},
+	{"бисопролол", "верапамил", "major", "Суммирование отрицательного хронотропного действия: брадикардия и атриовентрикулярная блокада"},
+}
+
+func migrateDrugInteractionsSeedUp(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Model(&m0017DrugInteraction{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]m0017DrugInteraction, len(m0017DrugInteractions))
+	for i, r := range m0017DrugInteractions {
+		rows[i] = m0017DrugInteraction{CreatedAt: now, DrugA: r[0], DrugB: r[1], Severity: r[2], Rationale: r[3]}
+	}
+	return tx.Create(&rows).Error
+}
+
+// migrateDrugInteractionsSeedDown удаляет записи начального наполнения, которые не были
+// изменены; загруженные командой interactions import пары остаются
+func migrateDrugInteractionsSeedDown(tx *gorm.DB) error {
+	for _, r := range m0017DrugInteractions {
+		err := tx.Where("drug_a = ? AND drug_b = ? AND severity = ? AND rationale = ?", r[0], r[1], r[2], r[3]).
+			Delete(&m0017DrugInteraction{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migration описывает одну версию схемы базы данных. Миграции не должны ссылаться
+// на модели API: они меняются вместе с кодом, поэтому каждая миграция использует
+// собственные структуры-снимки таблиц на момент своего создания.
+type migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// schemaMigration - запись о примененной миграции
+type schemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrations - все миграции в порядке возрастания версий. Новые миграции добавляются только в конец.
+var migrations = []migration{
+	{Version: 1, Name: "initial_schema", Up: migrateInitialSchemaUp, Down: migrateInitialSchemaDown},
+	{Version: 2, Name: "auth", Up: migrateAuthUp, Down: migrateAuthDown},
+	{Version: 3, Name: "user_roles", Up: migrateUserRolesUp, Down: migrateUserRolesDown},
+	{Version: 4, Name: "audit_log", Up: migrateAuditLogUp, Down: migrateAuditLogDown},
+	{Version: 5, Name: "soft_delete", Up: migrateSoftDeleteUp, Down: migrateSoftDeleteDown},
+	{Version: 6, Name: "foreign_keys", Up: migrateForeignKeysUp, Down: migrateForeignKeysDown},
+	{Version: 7, Name: "patient_search", Up: migratePatientSearchUp, Down: migratePatientSearchDown},
+	{Version: 8, Name: "appointment_status", Up: migrateAppointmentStatusUp, Down: migrateAppointmentStatusDown},
+	{Version: 9, Name: "lab_result_values", Up: migrateLabResultValuesUp, Down: migrateLabResultValuesDown},
+	{Version: 10, Name: "test_synonyms", Up: migrateTestSynonymsUp, Down: migrateTestSynonymsDown},
+	{Version: 11, Name: "prescriptions", Up: migratePrescriptionsUp, Down: migratePrescriptionsDown},
+	{Version: 12, Name: "drug_classes", Up: migrateDrugClassesUp, Down: migrateDrugClassesDown},
+	{Version: 13, Name: "drug_interactions", Up: migrateDrugInteractionsUp, Down: migrateDrugInteractionsDown},
+	{Version: 14, Name: "icd10_diagnoses", Up: migrateICD10DiagnosesUp, Down: migrateICD10DiagnosesDown},
+	{Version: 15, Name: "external_identifiers", Up: migrateExternalIdentifiersUp, Down: migrateExternalIdentifiersDown},
+	{Version: 16, Name: "appointment_search", Up: migrateAppointmentSearchUp, Down: migrateAppointmentSearchDown},
+	{Version: 17, Name: "drug_interactions_seed", Up: migrateDrugInteractionsSeedUp, Down: migrateDrugInteractionsSeedDown},
+}
+
+// errSchemaOutdated возвращается, если схема базы отстает от версии бинарного файла
+var errSchemaOutdated = errors.New("database schema is outdated, run 'demeda migrate up'")
+
+// latestSchemaVersion возвращает версию схемы, которую ожидает бинарный файл
+func latestSchemaVersion() uint {
+	return migrations[len(migrations)-1].Version
+}
+
+// appliedMigrations возвращает примененные миграции, упорядоченные по версии
+func appliedMigrations(db *gorm.DB) ([]schemaMigration, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return nil, nil
+	}
+	var applied []schemaMigration
+	err := db.Order("version").Find(&applied).Error
+	return applied, err
+}
+
+// currentSchemaVersion возвращает версию последней примененной миграции (0 для пустой базы)
+func currentSchemaVersion(db *gorm.DB) (uint, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// checkSchemaVersion проверяет, что схема базы соответствует бинарному файлу
+func checkSchemaVersion(db *gorm.DB) error {
+	current, err := currentSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	latest := latestSchemaVersion()
+	switch {
+	case current < latest:
+		return fmt.Errorf("%w (database: %d, binary: %d)", errSchemaOutdated, current, latest)
+	case current > latest:
+		return fmt.Errorf("database schema version %d is newer than this binary supports (%d)", current, latest)
+	}
+	return nil
+}
+
+// migrateUp применяет миграции до версии target включительно. Каждая миграция
+// выполняется в отдельной транзакции вместе с записью в schema_migrations.
+func migrateUp(db *gorm.DB, target uint) ([]migration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	current, err := currentSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []migration
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// migrateDown откатывает steps последних примененных миграций
+func migrateDown(db *gorm.DB, steps int) ([]migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var done []migration
+	for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+		m, ok := byVersion[applied[i].Version]
+		if !ok {
+			return done, fmt.Errorf("migration %d is not known to this binary", applied[i].Version)
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// runMigrate обрабатывает команду migrate
+func runMigrate(args []string) {
+	usage := "Usage:\n  demeda migrate up [--config=path] [--to=version]\n  demeda migrate down [--config=path] [--steps=1]\n  demeda migrate status [--config=path]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	configPath := configFlag(flags)
+	to := flags.Uint("to", latestSchemaVersion(), "target version for up")
+	steps := flags.Int("steps", 1, "number of migrations to roll back for down")
+	flags.Parse(args[1:])
+	loadConfigOrExit(*configPath)
+	openDatabase()
+
+	var done []migration
+	var err error
+	switch args[0] {
+	case "up":
+		done, err = migrateUp(db, *to)
+		for _, m := range done {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		done, err = migrateDown(db, *steps)
+		for _, m := range done {
+			fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		err = printMigrationStatus(db)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+// printMigrationStatus выводит список миграций с отметкой о применении
+func printMigrationStatus(db *gorm.DB) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	appliedAt := make(map[uint]time.Time, len(applied))
+	for _, m := range applied {
+		appliedAt[m.Version] = m.AppliedAt
+	}
+
+	for _, m := range migrations {
+		state := "pending"
+		if at, ok := appliedAt[m.Version]; ok {
+			state = "applied " + at.Format(time.RFC3339)
+		}
+		fmt.Printf("%4s  %-40s %s\n", strconv.FormatUint(uint64(m.Version), 10), m.Name, state)
+	}
+
+	current, _ := currentSchemaVersion(db)
+	fmt.Printf("\ncurrent version: %d, latest: %d\n", current, latestSchemaVersion())
+	return nil
+}
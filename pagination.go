@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Списки выдаются постранично по курсору (keyset pagination): записи упорядочиваются по
+// столбцу сортировки и ID, а курсор хранит эти значения у последней выданной записи.
+// В отличие от offset страницы не сдвигаются при добавлении и удалении записей, и
+// стоимость запроса не растет с номером страницы.
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// Page представляет страницу списка
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"` // курсор следующей страницы; пуст на последней
+}
+
+// sortKey описывает столбец, по которому разрешено сортировать список
+type sortKey[T any] struct {
+	column string      // столбец в запросе
+	value  func(T) any // значение столбца у записи для курсора
+}
+
+// listSpec описывает постраничную выдачу списка записей типа T
+type listSpec[T any] struct {
+	idColumn    string // столбец ID в запросе
+	id          func(T) uint
+	sorts       map[string]sortKey[T] // ключ - значение параметра sort без знака "-"
+	defaultSort string
+}
+
+// pageCursor - содержимое курсора: сортировка, значение ее столбца и ID последней записи
+type pageCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// pagination - разобранные параметры limit, sort и cursor запроса
+type pagination[T any] struct {
+	spec   listSpec[T]
+	limit  int
+	sort   string // значение sort как в запросе, со знаком "-" для убывания
+	key    sortKey[T]
+	desc   bool
+	cursor *pageCursor
+	after  any // значение столбца сортировки из курсора
+}
+
+// sortValues возвращает допустимые значения параметра sort
+func (s listSpec[T]) sortValues() []string {
+	values := make([]string, 0, len(s.sorts))
+	for name := range s.sorts {
+		values = append(values, name)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// parsePagination разбирает limit, sort и cursor
+func parsePagination[T any](c *gin.Context, spec listSpec[T]) (pagination[T], error) {
+	p := pagination[T]{spec: spec, limit: defaultPageLimit, sort: c.DefaultQuery("sort", spec.defaultSort)}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		p.limit = limit
+	}
+
+	name := strings.TrimPrefix(p.sort, "-")
+	key, ok := spec.sorts[name]
+	if !ok {
+		return p, fmt.Errorf("sort must be one of: %s (prefix with '-' for descending order)",
+			strings.Join(spec.sortValues(), ", "))
+	}
+	p.key = key
+	p.desc = strings.HasPrefix(p.sort, "-")
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		if cursor.Sort != p.sort {
+			return p, errors.New("cursor was issued for a different sort order")
+		}
+		after, err := cursorValue(cursor, key)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		p.cursor = cursor
+		p.after = after
+	}
+	return p, nil
+}
+
+// paginate разбирает параметры страницы и при ошибке отвечает 400
+func paginate[T any](c *gin.Context, spec listSpec[T]) (pagination[T], bool) {
+	p, err := parsePagination(c, spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return p, false
+	}
+	return p, true
+}
+
+// decodeCursor разбирает курсор из параметра запроса
+func decodeCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// cursorValue возвращает значение столбца сортировки из курсора, приведенное к типу поля записи
+func cursorValue[T any](cursor *pageCursor, key sortKey[T]) (any, error) {
+	var zero T
+	value := reflect.New(reflect.TypeOf(key.value(zero)))
+	if err := json.Unmarshal(cursor.Value, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
+
+// apply добавляет к запросу условие продолжения после курсора, порядок и лимит.
+// Запрашивается на одну запись больше, чтобы узнать, есть ли следующая страница.
+func (p pagination[T]) apply(query *gorm.DB) *gorm.DB {
+	direction, op := "ASC", ">"
+	if p.desc {
+		direction, op = "DESC", "<"
+	}
+	if p.cursor != nil {
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[3]s ? OR (%[1]s = ? AND %[2]s %[3]s ?))", p.key.column, p.spec.idColumn, op),
+			p.after, p.after, p.cursor.ID)
+	}
+	order := fmt.Sprintf("%s %s", p.key.column, direction)
+	if p.key.column != p.spec.idColumn {
+		order += fmt.Sprintf(", %s %s", p.spec.idColumn, direction)
+	}
+	return query.Order(order).Limit(p.limit + 1)
+}
+
+// page формирует страницу из результата запроса, построенного apply
+func (p pagination[T]) page(items []T) Page[T] {
+	page := Page[T]{Data: items}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if len(items) <= p.limit {
+		return page
+	}
+
+	page.Data = items[:p.limit]
+	last := page.Data[p.limit-1]
+	value, _ := json.Marshal(p.key.value(last))
+	data, _ := json.Marshal(pageCursor{Sort: p.sort, Value: value, ID: p.spec.id(last)})
+	page.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	return page
+}
+
+// respondPage отправляет страницу и заголовок Link со ссылкой на следующую страницу
+func respondPage[T any](c *gin.Context, page Page[T]) {
+	if page.NextCursor != "" {
+		next := *c.Request.URL
+		query := next.Query()
+		query.Set("cursor", page.NextCursor)
+		next.RawQuery = query.Encode()
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// Списки API и допустимые для них сортировки
+
+var patientList = listSpec[Patient]{
+	idColumn: "id",
+	id:       func(p Patient) uint { return p.ID },
+	sorts: map[string]sortKey[Patient]{
+		"id":         {"id", func(p Patient) any { return p.ID }},
+		"full_name":  {"full_name", func(p Patient) any { return p.FullName }},
+		"birth_date": {"birth_date", func(p Patient) any { return p.BirthDate }},
+		"created_at": {"created_at", func(p Patient) any { return p.CreatedAt }},
+	},
+	defaultSort: "id",
+}
+
+var doctorList = listSpec[Doctor]{
+	idColumn: "id",
+	id:       func(d Doctor) uint { return d.ID },
+	sorts: map[string]sortKey[Doctor]{
+		"id":             {"id", func(d Doctor) any { return d.ID }},
+		"full_name":      {"full_name", func(d Doctor) any { return d.FullName }},
+		"specialization": {"specialization", func(d Doctor) any { return d.Specialization }},
+		"created_at":     {"created_at", func(d Doctor) any { return d.CreatedAt }},
+	},
+	defaultSort: "id",
+}
+
+var appointmentList = listSpec[Appointment]{
+	idColumn: "id",
+	id:       func(a Appointment) uint { return a.ID },
+	sorts: map[string]sortKey[Appointment]{
+		"id":         {"id", func(a Appointment) any { return a.ID }},
+		"date":       {"date", func(a Appointment) any { return a.Date }},
+		"created_at": {"created_at", func(a Appointment) any { return a.CreatedAt }},
+	},
+	defaultSort: "id",
+}
+
+// medicalTestList сортирует по столбцам теста и по дате приема (запрос соединяет таблицы)
+var medicalTestList = listSpec[MedicalTest]{
+	idColumn: "medical_tests.id",
+	id:       func(t MedicalTest) uint { return t.ID },
+	sorts: map[string]sortKey[MedicalTest]{
+		"id":         {"medical_tests.id", func(t MedicalTest) any { return t.ID }},
+		"name":       {"medical_tests.name", func(t MedicalTest) any { return t.Name }},
+		"date":       {"appointments.date", func(t MedicalTest) any { return t.Appointment.Date }},
+		"created_at": {"medical_tests.created_at", func(t MedicalTest) any { return t.CreatedAt }},
+	},
+	defaultSort: "date",
+}
+
+// appointmentTestList - тесты одного приема
+var appointmentTestList = listSpec[MedicalTest]{
+	idColumn: "id",
+	id:       func(t MedicalTest) uint { return t.ID },
+	sorts: map[string]sortKey[MedicalTest]{
+		"id":         {"id", func(t MedicalTest) any { return t.ID }},
+		"name":       {"name", func(t MedicalTest) any { return t.Name }},
+		"created_at": {"created_at", func(t MedicalTest) any { return t.CreatedAt }},
+	},
+	defaultSort: "id",
+}
+
+var medicalHistoryList = listSpec[MedicalHistory]{
+	idColumn: "id",
+	id:       func(h MedicalHistory) uint { return h.ID },
+	sorts: map[string]sortKey[MedicalHistory]{
+		"id":           {"id", func(h MedicalHistory) any { return h.ID }},
+		"start_date":   {"start_date", func(h MedicalHistory) any { return h.StartDate }},
+		"history_type": {"history_type", func(h MedicalHistory) any { return h.HistoryType }},
+		"created_at":   {"created_at", func(h MedicalHistory) any { return h.CreatedAt }},
+	},
+	defaultSort: "id",
+}
+
+var prescriptionList = listSpec[Prescription]{
+	idColumn: "prescriptions.id",
+	id:       func(p Prescription) uint { return p.ID },
+	sorts: map[string]sortKey[Prescription]{
+		"id":         {"prescriptions.id", func(p Prescription) any { return p.ID }},
+		"start_date": {"prescriptions.start_date", func(p Prescription) any { return p.StartDate }},
+		"drug":       {"prescriptions.drug", func(p Prescription) any { return p.Drug }},
+		"created_at": {"prescriptions.created_at", func(p Prescription) any { return p.CreatedAt }},
+	},
+	defaultSort: "id",
+}
+
+var auditLogList = listSpec[AuditLog]{
+	idColumn: "id",
+	id:       func(e AuditLog) uint { return e.ID },
+	sorts: map[string]sortKey[AuditLog]{
+		"id": {"id", func(e AuditLog) any { return e.ID }},
+	},
+	defaultSort: "id",
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+var linkNextPattern = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+// walkPages проходит список по ссылкам из заголовка Link, начиная с path, и возвращает
+// ID записей в порядке выдачи. Проверяет, что заголовок и next_cursor согласованы
+// и что на последней странице их нет.
+func walkPages[T any](t *testing.T, user User, path string, id func(T) uint) []uint {
+	t.Helper()
+	var ids []uint
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatalf("%s: too many pages", path)
+		}
+		w := apiRequest(t, user, http.MethodGet, path, nil)
+		page := decodeResponse[Page[T]](t, w, http.StatusOK)
+		for _, item := range page.Data {
+			ids = append(ids, id(item))
+		}
+
+		link := w.Header().Get("Link")
+		if page.NextCursor == "" {
+			if link != "" {
+				t.Fatalf("%s: Link %q on the last page", path, link)
+			}
+			return ids
+		}
+		match := linkNextPattern.FindStringSubmatch(link)
+		if match == nil {
+			t.Fatalf("%s: Link = %q, want <...>; rel=\"next\"", path, link)
+		}
+		next, err := url.Parse(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := next.Query().Get("cursor"); got != page.NextCursor {
+			t.Fatalf("%s: Link cursor %q, next_cursor %q", path, got, page.NextCursor)
+		}
+		if len(page.Data) == 0 {
+			t.Fatalf("%s: empty page with a next cursor", path)
+		}
+		path = match[1]
+	}
+}
+
+// sortedIDs возвращает ID записей в порядке (less, ID), как их должен выдавать список
+func sortedIDs[T any](items []T, id func(T) uint, compare func(a, b T) int, desc bool) []uint {
+	items = slices.Clone(items)
+	sort.SliceStable(items, func(i, j int) bool {
+		c := compare(items[i], items[j])
+		if c == 0 {
+			c = int(id(items[i])) - int(id(items[j]))
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = id(item)
+	}
+	return ids
+}
+
+func TestPaginationWithTies(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+
+		// Повторяющиеся имена и даты рождения вперемешку по ID, время создания у всех одно
+		born := func(year int) time.Time { return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC) }
+		fixtures := []struct {
+			name string
+			born time.Time
+		}{
+			{"Петров Петр", born(1980)},
+			{"Иванов Иван", born(1990)},
+			{"Петров Петр", born(1970)},
+			{"Иванов Иван", born(1980)},
+			{"Сидоров Сидор", born(1990)},
+			{"Иванов Иван", born(1980)},
+			{"Петров Петр", born(1990)},
+		}
+		var patients []Patient
+		for _, f := range fixtures {
+			patient := Patient{FullName: f.name, BirthDate: f.born, Gender: "male"}
+			if err := db.Create(&patient).Error; err != nil {
+				t.Fatal(err)
+			}
+			patients = append(patients, patient)
+		}
+		created := time.Date(2030, time.March, 1, 9, 0, 0, 0, time.UTC)
+		if err := db.Model(&Patient{}).Where("1 = 1").Update("created_at", created).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		patientID := func(p Patient) uint { return p.ID }
+		compare := map[string]func(a, b Patient) int{
+			"id":         func(a, b Patient) int { return 0 },
+			"full_name":  func(a, b Patient) int { return compareStrings(a.FullName, b.FullName) },
+			"birth_date": func(a, b Patient) int { return a.BirthDate.Compare(b.BirthDate) },
+			"created_at": func(a, b Patient) int { return 0 },
+		}
+		for name, cmp := range compare {
+			for _, desc := range []bool{false, true} {
+				sortParam := name
+				if desc {
+					sortParam = "-" + name
+				}
+				want := sortedIDs(patients, patientID, cmp, desc)
+				for _, limit := range []int{1, 2, 3, len(patients), len(patients) + 1} {
+					t.Run(fmt.Sprintf("sort=%s limit=%d", sortParam, limit), func(t *testing.T) {
+						got := walkPages(t, admin, fmt.Sprintf("/patients?sort=%s&limit=%d", sortParam, limit), patientID)
+						if !slices.Equal(got, want) {
+							t.Errorf("ids = %v, want %v", got, want)
+						}
+					})
+				}
+			}
+		}
+	})
+}
+
+// compareStrings сравнивает строки побайтово, как BINARY-сравнение SQLite
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func TestPaginationJoinedSortTies(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		otherDoctor := createTestDoctor(t, "Петров Петр", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+
+		// Тесты сортируются по дате приема: у двух приемов она совпадает, у каждого несколько тестов
+		early := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(9, 0)})
+		first := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(10, 0)})
+		second := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: otherDoctor.ID, Date: testTime(10, 0)})
+		var tests []MedicalTest
+		for _, appointment := range []Appointment{first, early, second, first, second, early, first} {
+			test := MedicalTest{AppointmentID: appointment.ID, Name: "Глюкоза", Result: "5.2", Appointment: appointment}
+			if err := db.Omit("Appointment").Create(&test).Error; err != nil {
+				t.Fatal(err)
+			}
+			tests = append(tests, test)
+		}
+
+		testID := func(m MedicalTest) uint { return m.ID }
+		byDate := func(a, b MedicalTest) int { return a.Appointment.Date.Compare(b.Appointment.Date) }
+		for _, desc := range []bool{false, true} {
+			sortParam := "date"
+			if desc {
+				sortParam = "-date"
+			}
+			want := sortedIDs(tests, testID, byDate, desc)
+			for _, limit := range []int{1, 2, 3} {
+				t.Run(fmt.Sprintf("sort=%s limit=%d", sortParam, limit), func(t *testing.T) {
+					got := walkPages(t, admin, fmt.Sprintf("/tests?sort=%s&limit=%d", sortParam, limit), testID)
+					if !slices.Equal(got, want) {
+						t.Errorf("ids = %v, want %v", got, want)
+					}
+				})
+			}
+		}
+	})
+}
+
+func TestPaginationSurvivesInsertsAndDeletes(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		var patients []Patient
+		for i := 0; i < 4; i++ {
+			patients = append(patients, createTestPatient(t, "Иванов Иван", ""))
+		}
+
+		w := apiRequest(t, admin, http.MethodGet, "/patients?sort=full_name&limit=2", nil)
+		page := decodeResponse[Page[Patient]](t, w, http.StatusOK)
+		if len(page.Data) != 2 || page.Data[0].ID != patients[0].ID || page.Data[1].ID != patients[1].ID {
+			t.Fatalf("first page = %v", page.Data)
+		}
+
+		// Запись перед курсором удалена, в конец добавлена новая с тем же именем:
+		// следующие страницы не пропускают и не повторяют записи
+		if err := db.Delete(&patients[0]).Error; err != nil {
+			t.Fatal(err)
+		}
+		added := createTestPatient(t, "Иванов Иван", "")
+
+		next := linkNextPattern.FindStringSubmatch(w.Header().Get("Link"))
+		if next == nil {
+			t.Fatalf("no Link header on the first page")
+		}
+		got := walkPages(t, admin, next[1], func(p Patient) uint { return p.ID })
+		if want := []uint{patients[2].ID, patients[3].ID, added.ID}; !slices.Equal(got, want) {
+			t.Errorf("ids after the first page = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestPaginationInvalidParameters(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		createTestPatient(t, "Иванов Иван", "")
+		createTestPatient(t, "Петров Петр", "")
+
+		w := apiRequest(t, admin, http.MethodGet, "/patients?sort=full_name&limit=1", nil)
+		cursor := decodeResponse[Page[Patient]](t, w, http.StatusOK).NextCursor
+		if cursor == "" {
+			t.Fatal("no next cursor")
+		}
+		if !linkNextPattern.MatchString(w.Header().Get("Link")) {
+			t.Errorf("Link = %q", w.Header().Get("Link"))
+		}
+
+		tests := []struct {
+			name  string
+			query string
+		}{
+			{"limit zero", "limit=0"},
+			{"limit too large", fmt.Sprintf("limit=%d", maxPageLimit+1)},
+			{"limit not a number", "limit=ten"},
+			{"unknown sort", "sort=phone"},
+			{"cursor not base64", "cursor=%21%21%21"},
+			{"cursor not json", "cursor=bm90LWpzb24"},
+			{"cursor for another sort", "sort=birth_date&cursor=" + cursor},
+			{"cursor for another direction", "sort=-full_name&cursor=" + cursor},
+			{"cursor with wrong value type", "sort=birth_date&cursor=eyJzIjoiYmlydGhfZGF0ZSIsInYiOjQyLCJpZCI6MX0"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := apiRequest(t, admin, http.MethodGet, "/patients?"+tt.query, nil)
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want 400: %s", w.Code, w.Body.String())
+				}
+			})
+		}
+
+		// Курсор первой страницы ведет на последнюю: без next_cursor и заголовка Link
+		w = apiRequest(t, admin, http.MethodGet, "/patients?sort=full_name&limit=1&cursor="+cursor, nil)
+		page := decodeResponse[Page[Patient]](t, w, http.StatusOK)
+		if len(page.Data) != 1 || page.Data[0].FullName != "Петров Петр" || page.NextCursor != "" || w.Header().Get("Link") != "" {
+			t.Errorf("last page = %+v, Link %q", page, w.Header().Get("Link"))
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Роли пользователей
+const (
+	roleAdmin         = "admin"
+	roleRegistrar     = "registrar"
+	roleDoctor        = "doctor"
+	roleLabTechnician = "lab_technician"
+	rolePatient       = "patient"
+	roleAuditor       = "auditor" // сотрудник, проверяющий журнал аудита
+)
+
+// permission - действие над группой данных клиники
+type permission string
+
+const (
+	permPatientsRead       permission = "patients.read"
+	permPatientsWrite      permission = "patients.write"
+	permDoctorsRead        permission = "doctors.read"
+	permDoctorsWrite       permission = "doctors.write"
+	permSchedulesWrite     permission = "schedules.write"
+	permAppointmentsRead   permission = "appointments.read"
+	permAppointmentsWrite  permission = "appointments.write"
+	permClinicalRead       permission = "clinical.read" // диагноз и лечение в приемах
+	permClinicalWrite      permission = "clinical.write"
+	permTestsRead          permission = "tests.read"
+	permTestsWrite         permission = "tests.write"
+	permHistoryRead        permission = "history.read"
+	permHistoryWrite       permission = "history.write"
+	permPrescriptionsRead  permission = "prescriptions.read"
+	permPrescriptionsWrite permission = "prescriptions.write"
+	permDrugClassesWrite   permission = "drug_classes.write" // таблица групп препаратов, общая для клиники
+	permAuditRead          permission = "audit.read"
+	permDeletedRead        permission = "deleted.read" // просмотр удаленных записей (?include_deleted=true)
+)
+
+// scope определяет, к каким записям относится право
+type scope int
+
+const (
+	scopeNone scope = iota
+	scopeOwn        // только записи, связанные с пользователем (его прием, его анамнез)
+	scopeAll
+)
+
+// policy - матрица прав ролей. Отсутствие права в матрице означает запрет.
+var policy = map[string]map[permission]scope{
+	roleAdmin: {
+		permPatientsRead:       scopeAll,
+		permPatientsWrite:      scopeAll,
+		permDoctorsRead:        scopeAll,
+		permDoctorsWrite:       scopeAll,
+		permSchedulesWrite:     scopeAll,
+		permAppointmentsRead:   scopeAll,
+		permAppointmentsWrite:  scopeAll,
+		permClinicalRead:       scopeAll,
+		permClinicalWrite:      scopeAll,
+		permTestsRead:          scopeAll,
+		permTestsWrite:         scopeAll,
+		permHistoryRead:        scopeAll,
+		permHistoryWrite:       scopeAll,
+		permPrescriptionsRead:  scopeAll,
+		permPrescriptionsWrite: scopeAll,
+		permDrugClassesWrite:   scopeAll,
+		permAuditRead:          scopeAll,
+		permDeletedRead:        scopeAll,
+	},
+	roleRegistrar: {
+		permPatientsRead:      scopeAll,
+		permPatientsWrite:     scopeAll,
+		permDoctorsRead:       scopeAll,
+		permSchedulesWrite:    scopeAll,
+		permAppointmentsRead:  scopeAll,
+		permAppointmentsWrite: scopeAll,
+	},
+	roleDoctor: {
+		permPatientsRead:       scopeAll,
+		permDoctorsRead:        scopeAll,
+		permSchedulesWrite:     scopeOwn,
+		permAppointmentsRead:   scopeAll,
+		permAppointmentsWrite:  scopeOwn,
+		permClinicalRead:       scopeAll,
+		permClinicalWrite:      scopeOwn,
+		permTestsRead:          scopeAll,
+		permTestsWrite:         scopeAll,
+		permHistoryRead:        scopeAll,
+		permHistoryWrite:       scopeAll,
+		permPrescriptionsRead:  scopeAll,
+		permPrescriptionsWrite: scopeOwn,
+	},
+	roleLabTechnician: {
+		permPatientsRead:     scopeAll,
+		permDoctorsRead:      scopeAll,
+		permAppointmentsRead: scopeAll,
+		permTestsRead:        scopeAll,
+		permTestsWrite:       scopeAll,
+	},
+	rolePatient: {
+		permPatientsRead:      scopeOwn,
+		permDoctorsRead:       scopeAll,
+		permAppointmentsRead:  scopeOwn,
+		permClinicalRead:      scopeOwn,
+		permTestsRead:         scopeOwn,
+		permHistoryRead:       scopeOwn,
+		permPrescriptionsRead: scopeOwn,
+	},
+	roleAuditor: {
+		permAuditRead: scopeAll,
+	},
+}
+
+// resource - атрибуты записи, по которым проверяется право с областью scopeOwn
+type resource struct {
+	PatientID uint
+	DoctorID  uint
+}
+
+// appointmentResource возвращает атрибуты доступа к приему
+func appointmentResource(a Appointment) resource {
+	return resource{PatientID: a.PatientID, DoctorID: a.DoctorID}
+}
+
+// validRole проверяет, что роль объявлена в матрице прав
+func validRole(role string) bool {
+	_, ok := policy[role]
+	return ok
+}
+
+// scope возвращает область действия права для роли пользователя
+func (u User) scope(p permission) scope {
+	return policy[u.Role][p]
+}
+
+// owns проверяет, что запись связана с пользователем: для врача - его прием,
+// для пациента - его собственные данные
+func (u User) owns(r resource) bool {
+	switch u.Role {
+	case roleDoctor:
+		return u.DoctorID != nil && r.DoctorID == *u.DoctorID
+	case rolePatient:
+		return u.PatientID != nil && r.PatientID == *u.PatientID
+	}
+	return false
+}
+
+// can проверяет право пользователя на действие с конкретной записью
+func (u User) can(p permission, r resource) bool {
+	switch u.scope(p) {
+	case scopeAll:
+		return true
+	case scopeOwn:
+		return u.owns(r)
+	}
+	return false
+}
+
+// currentUser возвращает пользователя, аутентифицированного authRequired
+func currentUser(c *gin.Context) User {
+	return c.MustGet("user").(User)
+}
+
+// requirePermission пропускает запрос, если роль пользователя имеет право хотя бы на свои записи.
+// Проверка конкретной записи выполняется обработчиком через authorize.
+func requirePermission(p permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if currentUser(c).scope(p) == scopeNone {
+			abortForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorize проверяет право текущего пользователя на запись и при отказе отвечает 403
+func authorize(c *gin.Context, p permission, r resource) bool {
+	if currentUser(c).can(p, r) {
+		return true
+	}
+	abortForbidden(c)
+	return false
+}
+
+// scopeQuery ограничивает выборку записями пользователя, если право выдано только на свои записи.
+// patientColumn и doctorColumn - столбцы, связывающие запись с пациентом и врачом; пустое
+// имя означает, что такой связи нет и записи пользователю недоступны.
+func scopeQuery(c *gin.Context, p permission, query *gorm.DB, patientColumn, doctorColumn string) *gorm.DB {
+	user := currentUser(c)
+	if user.scope(p) != scopeOwn {
+		return query
+	}
+
+	switch {
+	case user.Role == rolePatient && user.PatientID != nil && patientColumn != "":
+		return query.Where(patientColumn+" = ?", *user.PatientID)
+	case user.Role == roleDoctor && user.DoctorID != nil && doctorColumn != "":
+		return query.Where(doctorColumn+" = ?", *user.DoctorID)
+	default:
+		return query.Where("1 = 0")
+	}
+}
+
+// redactAppointment скрывает диагноз и лечение, если пользователю не разрешено их читать
+func redactAppointment(user User, a *Appointment) {
+	if !user.can(permClinicalRead, appointmentResource(*a)) {
+		a.Diagnosis = ""
+		a.Treatment = ""
+		a.Diagnoses = nil
+	}
+}
+
+// redactAppointments применяет redactAppointment к списку приемов
+func redactAppointments(user User, appointments []Appointment) {
+	for i := range appointments {
+		redactAppointment(user, &appointments[i])
+	}
+}
+
+// abortForbidden прерывает запрос с ответом 403
+func abortForbidden(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{Error: "access denied"})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestUserCan(t *testing.T) {
+	doctorID, otherDoctorID := uint(1), uint(2)
+	patientID, otherPatientID := uint(10), uint(20)
+	own := resource{PatientID: patientID, DoctorID: doctorID}
+	other := resource{PatientID: otherPatientID, DoctorID: otherDoctorID}
+
+	admin := User{Role: roleAdmin}
+	registrar := User{Role: roleRegistrar}
+	doctor := User{Role: roleDoctor, DoctorID: &doctorID}
+	unlinkedDoctor := User{Role: roleDoctor}
+	labTechnician := User{Role: roleLabTechnician}
+	patient := User{Role: rolePatient, PatientID: &patientID}
+	auditor := User{Role: roleAuditor}
+
+	tests := []struct {
+		name string
+		user User
+		perm permission
+		res  resource
+		want bool
+	}{
+		{"admin reads clinical data", admin, permClinicalRead, other, true},
+		{"admin reads audit log", admin, permAuditRead, resource{}, true},
+
+		{"registrar reads appointments", registrar, permAppointmentsRead, other, true},
+		{"registrar books appointments", registrar, permAppointmentsWrite, other, true},
+		{"registrar cannot read diagnosis and treatment", registrar, permClinicalRead, other, false},
+		{"registrar cannot write diagnosis and treatment", registrar, permClinicalWrite, other, false},
+		{"registrar cannot read tests", registrar, permTestsRead, other, false},
+		{"registrar cannot read history", registrar, permHistoryRead, other, false},
+		{"registrar cannot read prescriptions", registrar, permPrescriptionsRead, other, false},
+
+		{"doctor edits own appointment", doctor, permAppointmentsWrite, own, true},
+		{"doctor cannot edit other doctor's appointment", doctor, permAppointmentsWrite, other, false},
+		{"doctor writes diagnosis in own appointment", doctor, permClinicalWrite, own, true},
+		{"doctor cannot write diagnosis in other appointment", doctor, permClinicalWrite, other, false},
+		{"doctor reads clinical data of any appointment", doctor, permClinicalRead, other, true},
+		{"doctor prescribes in own appointment", doctor, permPrescriptionsWrite, own, true},
+		{"doctor cannot prescribe in other appointment", doctor, permPrescriptionsWrite, other, false},
+		{"doctor cannot manage doctors", doctor, permDoctorsWrite, own, false},
+		{"doctor without linked record owns nothing", unlinkedDoctor, permAppointmentsWrite, resource{}, false},
+
+		{"lab technician writes tests", labTechnician, permTestsWrite, other, true},
+		{"lab technician cannot read diagnosis", labTechnician, permClinicalRead, other, false},
+
+		{"patient reads own record", patient, permPatientsRead, own, true},
+		{"patient cannot read other patient", patient, permPatientsRead, other, false},
+		{"patient reads own history", patient, permHistoryRead, own, true},
+		{"patient cannot read other history", patient, permHistoryRead, other, false},
+		{"patient reads own tests", patient, permTestsRead, own, true},
+		{"patient cannot read other tests", patient, permTestsRead, other, false},
+		{"patient reads own diagnosis", patient, permClinicalRead, own, true},
+		{"patient cannot book appointments", patient, permAppointmentsWrite, own, false},
+		{"patient reads doctors", patient, permDoctorsRead, other, true},
+
+		{"auditor reads audit log", auditor, permAuditRead, resource{}, true},
+		{"auditor cannot read patients", auditor, permPatientsRead, other, false},
+		{"unknown role has no permissions", User{Role: "janitor"}, permPatientsRead, other, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.can(tt.perm, tt.res); got != tt.want {
+				t.Errorf("can(%s) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	doctorID, patientID := uint(1), uint(10)
+	tests := []struct {
+		name string
+		user User
+		perm permission
+		res  resource
+		want bool
+	}{
+		{"doctor own appointment", User{Role: roleDoctor, DoctorID: &doctorID}, permAppointmentsWrite, resource{DoctorID: 1}, true},
+		{"doctor other appointment", User{Role: roleDoctor, DoctorID: &doctorID}, permAppointmentsWrite, resource{DoctorID: 2}, false},
+		{"patient own tests", User{Role: rolePatient, PatientID: &patientID}, permTestsRead, resource{PatientID: 10}, true},
+		{"patient other tests", User{Role: rolePatient, PatientID: &patientID}, permTestsRead, resource{PatientID: 20}, false},
+		{"registrar diagnosis", User{Role: roleRegistrar}, permClinicalRead, resource{PatientID: 10, DoctorID: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := testContext(tt.user)
+			if got := authorize(c, tt.perm, tt.res); got != tt.want {
+				t.Fatalf("authorize = %v, want %v", got, tt.want)
+			}
+			if !tt.want && (w.Code != http.StatusForbidden || !c.IsAborted()) {
+				t.Errorf("denied request: status %d, aborted %v; want 403 and aborted", w.Code, c.IsAborted())
+			}
+		})
+	}
+}
+
+func TestScopeQuery(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		otherDoctor := createTestDoctor(t, "Петров Петр", "Хирург")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+
+		own := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID})
+		ownPatientOtherDoctor := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: otherDoctor.ID})
+		ownDoctorOtherPatient := createTestAppointment(t, Appointment{PatientID: otherPatient.ID, DoctorID: doctor.ID, Date: testTime(11, 0)})
+		foreign := createTestAppointment(t, Appointment{PatientID: otherPatient.ID, DoctorID: otherDoctor.ID, Date: testTime(11, 0)})
+		all := []uint{own.ID, ownPatientOtherDoctor.ID, ownDoctorOtherPatient.ID, foreign.ID}
+
+		tests := []struct {
+			name          string
+			user          User
+			perm          permission
+			patientColumn string
+			doctorColumn  string
+			want          []uint
+		}{
+			{"admin sees everything", User{Role: roleAdmin}, permAppointmentsRead, "patient_id", "doctor_id", all},
+			{"doctor reads all appointments", User{Role: roleDoctor, DoctorID: &doctor.ID}, permAppointmentsRead, "patient_id", "doctor_id", all},
+			{"doctor edits only own appointments", User{Role: roleDoctor, DoctorID: &doctor.ID}, permAppointmentsWrite, "patient_id", "doctor_id",
+				[]uint{own.ID, ownDoctorOtherPatient.ID}},
+			{"patient sees only own appointments", User{Role: rolePatient, PatientID: &patient.ID}, permAppointmentsRead, "patient_id", "doctor_id",
+				[]uint{own.ID, ownPatientOtherDoctor.ID}},
+			{"patient tests follow appointment owner", User{Role: rolePatient, PatientID: &patient.ID}, permTestsRead, "patient_id", "",
+				[]uint{own.ID, ownPatientOtherDoctor.ID}},
+			{"no patient column means no own records", User{Role: rolePatient, PatientID: &patient.ID}, permTestsRead, "", "doctor_id", nil},
+			{"patient without linked record sees nothing", User{Role: rolePatient}, permAppointmentsRead, "patient_id", "doctor_id", nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				c, _ := testContext(tt.user)
+				var ids []uint
+				query := scopeQuery(c, tt.perm, db.Model(&Appointment{}), tt.patientColumn, tt.doctorColumn)
+				if err := query.Order("id").Pluck("id", &ids).Error; err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("ids = %v, want %v", ids, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestRedactAppointment(t *testing.T) {
+	doctorID, patientID, otherPatientID := uint(1), uint(10), uint(20)
+	tests := []struct {
+		name    string
+		user    User
+		patient uint
+		visible bool
+	}{
+		{"admin", User{Role: roleAdmin}, patientID, true},
+		{"doctor of any appointment", User{Role: roleDoctor, DoctorID: &doctorID}, otherPatientID, true},
+		{"registrar", User{Role: roleRegistrar}, patientID, false},
+		{"lab technician", User{Role: roleLabTechnician}, patientID, false},
+		{"patient own appointment", User{Role: rolePatient, PatientID: &patientID}, patientID, true},
+		{"patient other appointment", User{Role: rolePatient, PatientID: &patientID}, otherPatientID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Appointment{
+				PatientID: tt.patient,
+				DoctorID:  2,
+				Diagnosis: "ОРВИ",
+				Treatment: "Парацетамол 500 мг",
+				Notes:     "Повторный прием",
+				Diagnoses: []AppointmentDiagnosis{{Code: "J06.9"}},
+			}
+			redactAppointment(tt.user, &a)
+
+			visible := a.Diagnosis != "" && a.Treatment != "" && len(a.Diagnoses) == 1
+			hidden := a.Diagnosis == "" && a.Treatment == "" && a.Diagnoses == nil
+			if tt.visible && !visible || !tt.visible && !hidden {
+				t.Errorf("diagnosis %q, treatment %q, diagnoses %v; want visible=%v", a.Diagnosis, a.Treatment, a.Diagnoses, tt.visible)
+			}
+			if a.Notes == "" {
+				t.Error("notes must not be redacted")
+			}
+		})
+	}
+}
+
+func TestAPIAccessControl(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		otherDoctor := createTestDoctor(t, "Петров Петр", "Хирург")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		otherPatient := createTestPatient(t, "Кузнецова Мария", "")
+
+		own := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID,
+			Status: statusInProgress, Diagnosis: "ОРВИ", Treatment: "Парацетамол"})
+		foreign := createTestAppointment(t, Appointment{PatientID: otherPatient.ID, DoctorID: otherDoctor.ID,
+			Status: statusInProgress, Diagnosis: "Гастрит", Treatment: "Омепразол"})
+		for _, a := range []Appointment{own, foreign} {
+			test := MedicalTest{AppointmentID: a.ID, Name: "Глюкоза", Result: "5.1", Unit: "ммоль/л", ReferenceRange: "3.9-6.1"}
+			if err := db.Create(&test).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, p := range []Patient{patient, otherPatient} {
+			history := MedicalHistory{PatientID: p.ID, HistoryType: "chronic_disease", Description: "Гипертония"}
+			if err := db.Create(&history).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		registrar := createTestUser(t, roleRegistrar, 0)
+		doctorUser := createTestUser(t, roleDoctor, doctor.ID)
+		patientUser := createTestUser(t, rolePatient, patient.ID)
+
+		t.Run("registrar does not see diagnosis and treatment", func(t *testing.T) {
+			got := decodeResponse[Appointment](t, apiRequest(t, registrar, http.MethodGet, fmt.Sprintf("/appointments/%d", own.ID), nil), http.StatusOK)
+			if got.Diagnosis != "" || got.Treatment != "" {
+				t.Errorf("diagnosis %q, treatment %q; want both hidden", got.Diagnosis, got.Treatment)
+			}
+			list := decodeResponse[Page[Appointment]](t, apiRequest(t, registrar, http.MethodGet, "/appointments", nil), http.StatusOK)
+			for _, a := range list.Data {
+				if a.Diagnosis != "" || a.Treatment != "" {
+					t.Errorf("appointment %d in list exposes clinical data", a.ID)
+				}
+			}
+			if w := apiRequest(t, registrar, http.MethodGet, fmt.Sprintf("/appointments/%d/diagnoses", own.ID), nil); w.Code != http.StatusForbidden {
+				t.Errorf("diagnoses: status %d, want 403", w.Code)
+			}
+		})
+
+		t.Run("doctor edits only own appointments", func(t *testing.T) {
+			update := func(a Appointment) CreateAppointmentRequest {
+				return CreateAppointmentRequest{PatientID: a.PatientID, DoctorID: a.DoctorID, Date: a.Date,
+					Diagnosis: a.Diagnosis + " (уточнен)", Treatment: a.Treatment, Notes: "Осмотрен"}
+			}
+			if w := apiRequest(t, doctorUser, http.MethodPut, fmt.Sprintf("/appointments/%d", own.ID), update(own)); w.Code != http.StatusOK {
+				t.Errorf("own appointment: status %d, want 200: %s", w.Code, w.Body.String())
+			}
+			if w := apiRequest(t, doctorUser, http.MethodPut, fmt.Sprintf("/appointments/%d", foreign.ID), update(foreign)); w.Code != http.StatusForbidden {
+				t.Errorf("other doctor's appointment: status %d, want 403", w.Code)
+			}
+			var stored Appointment
+			if err := db.First(&stored, foreign.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Diagnosis != foreign.Diagnosis {
+				t.Errorf("foreign diagnosis changed to %q", stored.Diagnosis)
+			}
+		})
+
+		t.Run("patient sees only own history and tests", func(t *testing.T) {
+			history := decodeResponse[Page[MedicalHistory]](t, apiRequest(t, patientUser, http.MethodGet, "/medical_history", nil), http.StatusOK)
+			if len(history.Data) != 1 || history.Data[0].PatientID != patient.ID {
+				t.Errorf("history = %+v, want only patient %d", history.Data, patient.ID)
+			}
+			foreignHistory := decodeResponse[Page[MedicalHistory]](t,
+				apiRequest(t, patientUser, http.MethodGet, fmt.Sprintf("/patients/%d/medical-history", otherPatient.ID), nil), http.StatusOK)
+			if len(foreignHistory.Data) != 0 {
+				t.Errorf("other patient's history = %+v, want empty", foreignHistory.Data)
+			}
+
+			tests := decodeResponse[Page[MedicalTest]](t, apiRequest(t, patientUser, http.MethodGet, "/tests", nil), http.StatusOK)
+			if len(tests.Data) != 1 || tests.Data[0].AppointmentID != own.ID {
+				t.Errorf("tests = %+v, want only tests of appointment %d", tests.Data, own.ID)
+			}
+			if w := apiRequest(t, patientUser, http.MethodGet, fmt.Sprintf("/appointments/%d/tests", foreign.ID), nil); w.Code != http.StatusForbidden {
+				t.Errorf("other patient's tests: status %d, want 403", w.Code)
+			}
+		})
+	})
+}
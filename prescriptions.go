@@ -0,0 +1,532 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Назначения лекарственных препаратов. Назначение делается на приеме, который уже
+// начат или завершен, и наследует от него пациента и врача. Срок приема задается
+// длительностью в днях или датой окончания; без них назначение бессрочное.
+// Назначение активно, пока оно не отменено и не наступила дата окончания. Отмена
+// сохраняет запись в истории вместе с причиной.
+
+// prescriptionRoutes - допустимые пути введения препарата
+var prescriptionRoutes = []string{
+	"oral", "sublingual", "buccal", "inhalation", "nasal", "ophthalmic", "otic",
+	"topical", "transdermal", "rectal", "vaginal", "iv", "im", "sc",
+}
+
+// errPrescriptionInactive возвращается при изменении или отмене отмененного или завершенного назначения
+var errPrescriptionInactive = errors.New("prescription is no longer active")
+
+// Prescription представляет назначение лекарственного препарата
+// @Description Назначение препарата на приеме
+type Prescription struct {
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"deleted_at" swaggertype:"string"`
+	AppointmentID     uint           `gorm:"not null;index" json:"appointment_id"`
+	PatientID         uint           `gorm:"not null;index" json:"patient_id"`
+	DoctorID          uint           `gorm:"not null" json:"doctor_id"` // назначивший врач - врач приема
+	Drug              string         `gorm:"not null" json:"drug"`
+	Dose              float64        `gorm:"not null" json:"dose"`
+	DoseUnit          string         `gorm:"not null" json:"dose_unit"`               // мг, мл, таб, капли, ЕД
+	Route             string         `gorm:"not null" json:"route"`                   // oral, sublingual, inhalation, iv, im, sc, ...
+	Frequency         string         `gorm:"not null" json:"frequency"`               // "2 раза в день", "каждые 8 часов"
+	DurationDays      int            `gorm:"not null;default:0" json:"duration_days"` // 0 - срок задан датой окончания или не ограничен
+	StartDate         time.Time      `gorm:"not null" json:"start_date"`
+	EndDate           *time.Time     `json:"end_date,omitempty"` // нет у бессрочного назначения
+	DiscontinuedAt    *time.Time     `json:"discontinued_at,omitempty"`
+	DiscontinueReason string         `json:"discontinue_reason,omitempty"`
+	Notes             string         `json:"notes"`
+	Active            bool           `gorm:"-" json:"active"` // не отменено и не закончилось
+
+	// Предупреждения об аллергии пациента на препарат; только в ответе на создание и изменение
+	AllergyWarnings []AllergyWarning `gorm:"-" json:"allergy_warnings,omitempty"`
+
+	// Взаимодействия препарата с другими действующими назначениями пациента; только в ответе на создание и изменение
+	InteractionWarnings []InteractionWarning `gorm:"-" json:"interaction_warnings,omitempty"`
+}
+
+type CreatePrescriptionRequest struct {
+	Drug         string     `json:"drug" binding:"required"`
+	Dose         float64    `json:"dose" binding:"required,gt=0"`
+	DoseUnit     string     `json:"dose_unit" binding:"required"`
+	Route        string     `json:"route" binding:"required"`
+	Frequency    string     `json:"frequency" binding:"required"`
+	DurationDays int        `json:"duration_days" binding:"omitempty,min=1,max=3650"` // длительность курса в днях
+	StartDate    *time.Time `json:"start_date"`                                       // по умолчанию - дата приема
+	EndDate      *time.Time `json:"end_date"`                                         // вместо duration_days
+	Notes        string     `json:"notes"`
+
+	// Подтверждение назначения вопреки тяжелой аллергии пациента, с обязательной причиной
+	OverrideAllergy bool   `json:"override_allergy"`
+	OverrideReason  string `json:"override_reason"`
+}
+
+// DiscontinuePrescriptionRequest представляет запрос на отмену назначения
+type DiscontinuePrescriptionRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// activeAt сообщает, действует ли назначение в момент now
+func (p Prescription) activeAt(now time.Time) bool {
+	return p.DiscontinuedAt == nil && (p.EndDate == nil || p.EndDate.After(now))
+}
+
+// AfterFind вычисляет признак активности загруженного назначения
+func (p *Prescription) AfterFind(tx *gorm.DB) error {
+	p.Active = p.activeAt(time.Now())
+	return nil
+}
+
+// validPrescriptionRoute проверяет, что путь введения допустим
+func validPrescriptionRoute(route string) bool {
+	for _, r := range prescriptionRoutes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPrescriptionRequest переносит поля запроса в назначение. Без start_date курс
+// начинается с defaultStart; дата окончания вычисляется по длительности курса.
+func applyPrescriptionRequest(p *Prescription, req CreatePrescriptionRequest, defaultStart time.Time) error {
+	if !validPrescriptionRoute(req.Route) {
+		return errors.New("route must be one of: " + strings.Join(prescriptionRoutes, ", "))
+	}
+	if req.DurationDays > 0 && req.EndDate != nil {
+		return errors.New("specify either duration_days or end_date, not both")
+	}
+
+	start := defaultStart
+	if req.StartDate != nil {
+		start = *req.StartDate
+	}
+	var end *time.Time
+	switch {
+	case req.DurationDays > 0:
+		t := start.AddDate(0, 0, req.DurationDays)
+		end = &t
+	case req.EndDate != nil:
+		if !req.EndDate.After(start) {
+			return errors.New("end_date must be after start_date")
+		}
+		end = req.EndDate
+	}
+
+	p.Drug = strings.TrimSpace(req.Drug)
+	p.Dose = req.Dose
+	p.DoseUnit = strings.TrimSpace(req.DoseUnit)
+	p.Route = req.Route
+	p.Frequency = strings.TrimSpace(req.Frequency)
+	p.DurationDays = req.DurationDays
+	p.StartDate = start
+	p.EndDate = end
+	p.Notes = req.Notes
+	return nil
+}
+
+// activePrescriptionCondition отбирает назначения, действующие в момент, переданный параметром
+const activePrescriptionCondition = "prescriptions.discontinued_at IS NULL AND (prescriptions.end_date IS NULL OR prescriptions.end_date > ?)"
+
+// filterActivePrescriptions применяет параметр ?active=true|false к выборке назначений
+func filterActivePrescriptions(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	value := c.Query("active")
+	if value == "" {
+		return query, true
+	}
+	active, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid 'active' parameter"})
+		return nil, false
+	}
+	now := time.Now()
+	if active {
+		return query.Where(activePrescriptionCondition, now), true
+	}
+	return query.Where("(prescriptions.discontinued_at IS NOT NULL OR prescriptions.end_date <= ?)", now), true
+}
+
+// listPrescriptions выдает страницу назначений по запросу query
+func listPrescriptions(c *gin.Context, query *gorm.DB) {
+	p, ok := paginate(c, prescriptionList)
+	if !ok {
+		return
+	}
+	query, ok = filterActivePrescriptions(c, scopeQuery(c, permPrescriptionsRead, query, "prescriptions.patient_id", "prescriptions.doctor_id"))
+	if !ok {
+		return
+	}
+
+	var prescriptions []Prescription
+	if err := p.apply(query).Find(&prescriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	page := p.page(prescriptions)
+	if !auditRead(c, auditRefs(page.Data)...) {
+		return
+	}
+	respondPage(c, page)
+}
+
+// loadPrescription загружает назначение и проверяет право perm; при ошибке ответ уже отправлен
+func loadPrescription(c *gin.Context, perm permission) (Prescription, bool) {
+	var prescription Prescription
+	if err := db.First(&prescription, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Prescription not found"})
+		return prescription, false
+	}
+	res := resource{PatientID: prescription.PatientID, DoctorID: prescription.DoctorID}
+	if !authorize(c, perm, res) {
+		return prescription, false
+	}
+	return prescription, true
+}
+
+// CreateAppointmentPrescription godoc
+// @Summary Назначить препарат
+// @Description Добавить назначение препарата к приему. Прием должен быть начат или завершен. Срок задается duration_days или end_date; без них назначение бессрочное. Препарат сверяется с аллергиями пациента: при совпадении назначение сохраняется с предупреждениями, при тяжелой аллергии - только с override_allergy и override_reason
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Param prescription body CreatePrescriptionRequest true "Назначение"
+// @Success 201 {object} Prescription
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} AllergyConflictResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/prescriptions [post]
+func createAppointmentPrescription(c *gin.Context) {
+	id := c.Param("id")
+	var appointment Appointment
+	if err := db.First(&appointment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, permPrescriptionsWrite, appointmentResource(appointment)) {
+		return
+	}
+
+	var req CreatePrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !appointment.clinicalAllowed() {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "prescriptions can only be added once the visit is in progress"})
+		return
+	}
+
+	prescription := Prescription{
+		AppointmentID: appointment.ID,
+		PatientID:     appointment.PatientID,
+		DoctorID:      appointment.DoctorID,
+	}
+	if err := applyPrescriptionRequest(&prescription, req, appointment.Date); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	allergies, err := checkAllergies(db, prescription.PatientID, []string{prescription.Drug}, req.OverrideAllergy, req.OverrideReason)
+	if err != nil {
+		respondAllergyError(c, err)
+		return
+	}
+	interactions, err := checkInteractions(db, prescription.PatientID, []string{prescription.Drug}, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&prescription).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, c, auditActionCreate, prescription.auditRef()); err != nil {
+			return err
+		}
+		if err := recordAllergyAlert(tx, c, prescription.auditRef(), allergies); err != nil {
+			return err
+		}
+		return recordInteractionAlert(tx, c, prescription.auditRef(), interactions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	prescription.Active = prescription.activeAt(time.Now())
+	prescription.AllergyWarnings = allergies.Warnings
+	prescription.InteractionWarnings = interactions
+	c.JSON(http.StatusCreated, prescription)
+}
+
+// GetPrescriptions godoc
+// @Summary Получить список назначений
+// @Description Получить назначения препаратов с возможностью фильтрации
+// @Tags prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param patient_id query int false "Фильтр по ID пациента"
+// @Param appointment_id query int false "Фильтр по ID приема"
+// @Param active query bool false "Только действующие (true) или только отмененные и завершенные (false)"
+// @Param include_deleted query bool false "Включить удаленные назначения (только для администратора)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, start_date, -start_date, drug, -drug, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Prescription]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /prescriptions [get]
+func getPrescriptions(c *gin.Context) {
+	query, ok := includeDeleted(c, db.Model(&Prescription{}))
+	if !ok {
+		return
+	}
+	if patientID := c.Query("patient_id"); patientID != "" {
+		query = query.Where("prescriptions.patient_id = ?", patientID)
+	}
+	if appointmentID := c.Query("appointment_id"); appointmentID != "" {
+		query = query.Where("prescriptions.appointment_id = ?", appointmentID)
+	}
+	listPrescriptions(c, query)
+}
+
+// GetPatientMedications godoc
+// @Summary Получить лекарства пациента
+// @Description Получить назначения препаратов пациента; с active=true - только действующие
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пациента"
+// @Param active query bool false "Только действующие (true) или только отмененные и завершенные (false)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не больше 200)"
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param sort query string false "Сортировка; '-' перед полем - по убыванию" Enums(id, -id, start_date, -start_date, drug, -drug, created_at, -created_at) default(id)
+// @Success 200 {object} Page[Prescription]
+// @Header 200 {string} Link "Ссылка на следующую страницу (rel=\"next\")"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /patients/{id}/medications [get]
+func getPatientMedications(c *gin.Context) {
+	listPrescriptions(c, db.Where("prescriptions.patient_id = ?", c.Param("id")))
+}
+
+// GetPrescription godoc
+// @Summary Получить назначение по ID
+// @Description Получить назначение препарата
+// @Tags prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID назначения"
+// @Success 200 {object} Prescription
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /prescriptions/{id} [get]
+func getPrescription(c *gin.Context) {
+	prescription, ok := loadPrescription(c, permPrescriptionsRead)
+	if !ok {
+		return
+	}
+	if !auditRead(c, prescription.auditRef()) {
+		return
+	}
+	c.JSON(http.StatusOK, prescription)
+}
+
+// UpdatePrescription godoc
+// @Summary Обновить назначение
+// @Description Изменить препарат, дозировку или срок действующего назначения. Новый препарат сверяется с аллергиями пациента, как при назначении
+// @Tags prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID назначения"
+// @Param prescription body CreatePrescriptionRequest true "Обновленное назначение"
+// @Success 200 {object} Prescription
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} AllergyConflictResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /prescriptions/{id} [put]
+func updatePrescription(c *gin.Context) {
+	prescription, ok := loadPrescription(c, permPrescriptionsWrite)
+	if !ok {
+		return
+	}
+
+	var req CreatePrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !prescription.Active {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: errPrescriptionInactive.Error()})
+		return
+	}
+
+	before := prescription
+	if err := applyPrescriptionRequest(&prescription, req, prescription.StartDate); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	prescription.Active = prescription.activeAt(time.Now())
+
+	// Препарат сверяется с аллергиями и другими назначениями, только если он изменился
+	var drugs []string
+	if normalizeSearchText(prescription.Drug) != normalizeSearchText(before.Drug) {
+		drugs = []string{prescription.Drug}
+	}
+	allergies, err := checkAllergies(db, prescription.PatientID, drugs, req.OverrideAllergy, req.OverrideReason)
+	if err != nil {
+		respondAllergyError(c, err)
+		return
+	}
+	interactions, err := checkInteractions(db, prescription.PatientID, drugs, prescription.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&prescription).Error; err != nil {
+			return err
+		}
+		if err := recordAuditUpdate(tx, c, prescription.auditRef(), before, prescription); err != nil {
+			return err
+		}
+		if err := recordAllergyAlert(tx, c, prescription.auditRef(), allergies); err != nil {
+			return err
+		}
+		return recordInteractionAlert(tx, c, prescription.auditRef(), interactions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	prescription.AllergyWarnings = allergies.Warnings
+	prescription.InteractionWarnings = interactions
+	c.JSON(http.StatusOK, prescription)
+}
+
+// DiscontinuePrescription godoc
+// @Summary Отменить назначение
+// @Description Прекратить прием препарата с указанием причины. Отмененное назначение остается в истории
+// @Tags prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID назначения"
+// @Param request body DiscontinuePrescriptionRequest true "Причина отмены"
+// @Success 200 {object} Prescription
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /prescriptions/{id}/discontinue [post]
+func discontinuePrescription(c *gin.Context) {
+	prescription, ok := loadPrescription(c, permPrescriptionsWrite)
+	if !ok {
+		return
+	}
+
+	var req DiscontinuePrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "reason is required"})
+		return
+	}
+	if !prescription.Active {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: errPrescriptionInactive.Error()})
+		return
+	}
+
+	before := prescription
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Условный UPDATE: из двух параллельных отмен выполнится только одна
+		result := tx.Model(&Prescription{}).Where("id = ? AND discontinued_at IS NULL", prescription.ID).
+			Updates(map[string]interface{}{"discontinued_at": now, "discontinue_reason": reason})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPrescriptionInactive
+		}
+		prescription.DiscontinuedAt = &now
+		prescription.DiscontinueReason = reason
+		prescription.Active = false
+		return recordAuditUpdate(tx, c, prescription.auditRef(), before, prescription)
+	})
+	if errors.Is(err, errPrescriptionInactive) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prescription)
+}
+
+// DeletePrescription godoc
+// @Summary Удалить назначение
+// @Description Пометить назначение удаленным, например ошибочно внесенное. Для прекращения приема препарата используйте POST /prescriptions/{id}/discontinue. Назначение можно восстановить через POST /prescriptions/{id}/restore
+// @Tags prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID назначения"
+// @Success 200 {object} string
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /prescriptions/{id} [delete]
+func deletePrescription(c *gin.Context) {
+	prescription, ok := loadPrescription(c, permPrescriptionsWrite)
+	if !ok {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return archive(tx, c, []Prescription{prescription}, deletionTime())
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, "Prescription deleted")
+}
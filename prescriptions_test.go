@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// prescriptionIDs возвращает ID назначений страницы ответа
+func prescriptionIDs(t *testing.T, user User, path string) []uint {
+	t.Helper()
+	page := decodeResponse[Page[Prescription]](t, apiRequest(t, user, http.MethodGet, path, nil), http.StatusOK)
+	ids := make([]uint, 0, len(page.Data))
+	for _, p := range page.Data {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestCreatePrescription(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		request := CreatePrescriptionRequest{Drug: "Метформин", Dose: 500, DoseUnit: "мг", Route: "oral", Frequency: "2 раза в день"}
+		path := func(a Appointment) string { return fmt.Sprintf("/appointments/%d/prescriptions", a.ID) }
+
+		// До начала приема назначать нельзя
+		for i, status := range []string{statusScheduled, statusCheckedIn, statusCancelled} {
+			appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(9+i, 0), Status: status})
+			if w := apiRequest(t, user, http.MethodPost, path(appointment), request); w.Code != http.StatusConflict {
+				t.Errorf("%s visit: status %d, want 409: %s", status, w.Code, w.Body.String())
+			}
+		}
+		var count int64
+		db.Model(&Prescription{}).Count(&count)
+		if count != 0 {
+			t.Fatalf("%d prescriptions saved for visits that have not started", count)
+		}
+
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(13, 0), Status: statusInProgress})
+		end := appointment.Date.AddDate(0, 0, 10)
+		both := request
+		both.DurationDays, both.EndDate = 7, &end
+		if w := apiRequest(t, user, http.MethodPost, path(appointment), both); w.Code != http.StatusBadRequest {
+			t.Errorf("duration_days with end_date: status %d, want 400", w.Code)
+		}
+		early := request
+		before := appointment.Date.AddDate(0, 0, -1)
+		early.EndDate = &before
+		if w := apiRequest(t, user, http.MethodPost, path(appointment), early); w.Code != http.StatusBadRequest {
+			t.Errorf("end_date before start: status %d, want 400", w.Code)
+		}
+		route := request
+		route.Route = "внутрь"
+		if w := apiRequest(t, user, http.MethodPost, path(appointment), route); w.Code != http.StatusBadRequest {
+			t.Errorf("unknown route: status %d, want 400", w.Code)
+		}
+
+		// Срок курса отсчитывается от даты приема
+		course := request
+		course.DurationDays = 7
+		created := decodeResponse[Prescription](t, apiRequest(t, user, http.MethodPost, path(appointment), course), http.StatusCreated)
+		if !created.StartDate.Equal(appointment.Date) {
+			t.Errorf("start_date = %v, want visit date %v", created.StartDate, appointment.Date)
+		}
+		if want := appointment.Date.AddDate(0, 0, 7); created.EndDate == nil || !created.EndDate.Equal(want) {
+			t.Errorf("end_date = %v, want %v", created.EndDate, want)
+		}
+		if created.PatientID != patient.ID || created.DoctorID != doctor.ID {
+			t.Errorf("created = %+v, want patient and doctor of the visit", created)
+		}
+
+		byDate := request
+		byDate.Drug = "Аторвастатин"
+		byDate.EndDate = &end
+		created = decodeResponse[Prescription](t, apiRequest(t, user, http.MethodPost, path(appointment), byDate), http.StatusCreated)
+		if created.DurationDays != 0 || created.EndDate == nil || !created.EndDate.Equal(end) {
+			t.Errorf("created = %+v, want end_date %v without duration", created, end)
+		}
+
+		completed := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(14, 0), Status: statusCompleted})
+		decodeResponse[Prescription](t, apiRequest(t, user, http.MethodPost, path(completed), request), http.StatusCreated)
+
+		// Прием другого врача
+		other := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: createTestDoctor(t, "Петров Петр", "Хирург").ID, Status: statusInProgress})
+		if w := apiRequest(t, user, http.MethodPost, path(other), request); w.Code != http.StatusForbidden {
+			t.Errorf("visit of another doctor: status %d, want 403", w.Code)
+		}
+	})
+}
+
+func TestPrescriptionActiveFilter(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Status: statusCompleted})
+		now := time.Now()
+		endless := createTestPrescription(t, appointment, "Метформин", nil)
+		running := createTestPrescription(t, appointment, "Аторвастатин", func(p *Prescription) {
+			end := now.AddDate(0, 0, 3)
+			p.EndDate = &end
+		})
+		finished := createTestPrescription(t, appointment, "Амоксициллин", func(p *Prescription) {
+			end := now.AddDate(0, 0, -1)
+			p.EndDate = &end
+		})
+		stopped := createTestPrescription(t, appointment, "Эналаприл", func(p *Prescription) {
+			p.DiscontinuedAt = &now
+			p.DiscontinueReason = "кашель"
+		})
+		user := createTestUser(t, roleDoctor, doctor.ID)
+
+		tests := []struct {
+			query string
+			want  []uint
+		}{
+			{"", []uint{endless.ID, running.ID, finished.ID, stopped.ID}},
+			{"?active=true", []uint{endless.ID, running.ID}},
+			{"?active=false", []uint{finished.ID, stopped.ID}},
+		}
+		for _, tt := range tests {
+			for _, path := range []string{"/prescriptions", fmt.Sprintf("/patients/%d/medications", patient.ID)} {
+				if got := prescriptionIDs(t, user, path+tt.query); !slices.Equal(got, tt.want) {
+					t.Errorf("GET %s%s = %v, want %v", path, tt.query, got, tt.want)
+				}
+			}
+		}
+
+		page := decodeResponse[Page[Prescription]](t, apiRequest(t, user, http.MethodGet, "/prescriptions", nil), http.StatusOK)
+		for _, p := range page.Data {
+			if want := p.ID == endless.ID || p.ID == running.ID; p.Active != want {
+				t.Errorf("prescription %d (%s): active = %v, want %v", p.ID, p.Drug, p.Active, want)
+			}
+		}
+		if w := apiRequest(t, user, http.MethodGet, "/prescriptions?active=yes", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("active=yes: status %d, want 400", w.Code)
+		}
+	})
+}
+
+func TestDiscontinuePrescription(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		appointment := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Status: statusCompleted})
+		prescription := createTestPrescription(t, appointment, "Эналаприл", nil)
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		path := fmt.Sprintf("/prescriptions/%d/discontinue", prescription.ID)
+
+		for _, body := range []interface{}{nil, DiscontinuePrescriptionRequest{}, DiscontinuePrescriptionRequest{Reason: "  \t"}} {
+			if w := apiRequest(t, user, http.MethodPost, path, body); w.Code != http.StatusBadRequest {
+				t.Errorf("discontinue with %+v: status %d, want 400", body, w.Code)
+			}
+		}
+		var stored Prescription
+		if err := db.First(&stored, prescription.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.DiscontinuedAt != nil || !stored.Active {
+			t.Fatalf("prescription discontinued without a reason: %+v", stored)
+		}
+
+		discontinued := decodeResponse[Prescription](t, apiRequest(t, user, http.MethodPost, path,
+			DiscontinuePrescriptionRequest{Reason: " сухой кашель "}), http.StatusOK)
+		if discontinued.Active || discontinued.DiscontinuedAt == nil || discontinued.DiscontinueReason != "сухой кашель" {
+			t.Errorf("discontinued = %+v, want inactive with trimmed reason", discontinued)
+		}
+
+		// Повторная отмена и отмена завершенного курса - конфликт
+		if w := apiRequest(t, user, http.MethodPost, path, DiscontinuePrescriptionRequest{Reason: "повторно"}); w.Code != http.StatusConflict {
+			t.Errorf("repeated discontinue: status %d, want 409", w.Code)
+		}
+		if err := db.First(&stored, prescription.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.DiscontinueReason != "сухой кашель" {
+			t.Errorf("reason = %q after repeated discontinue, want the first one", stored.DiscontinueReason)
+		}
+		finished := createTestPrescription(t, appointment, "Амоксициллин", func(p *Prescription) {
+			end := time.Now().AddDate(0, 0, -1)
+			p.EndDate = &end
+		})
+		if w := apiRequest(t, user, http.MethodPost, fmt.Sprintf("/prescriptions/%d/discontinue", finished.ID),
+			DiscontinuePrescriptionRequest{Reason: "курс окончен"}); w.Code != http.StatusConflict {
+			t.Errorf("discontinue finished course: status %d, want 409", w.Code)
+		}
+		if w := apiRequest(t, user, http.MethodPost, "/prescriptions/999999/discontinue", DiscontinuePrescriptionRequest{Reason: "нет"}); w.Code != http.StatusNotFound {
+			t.Errorf("discontinue missing prescription: status %d, want 404", w.Code)
+		}
+	})
+}
+
+func TestPrescriptionPatientAccess(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		other := createTestPatient(t, "Кузнецова Мария", "")
+		own := createTestPrescription(t, createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Status: statusCompleted}), "Метформин", nil)
+		foreign := createTestPrescription(t, createTestAppointment(t, Appointment{PatientID: other.ID, DoctorID: doctor.ID, Date: testTime(11, 0), Status: statusCompleted}), "Варфарин", nil)
+		user := createTestUser(t, rolePatient, patient.ID)
+
+		// Фильтр по чужому пациенту не расширяет видимость
+		for _, path := range []string{
+			"/prescriptions",
+			fmt.Sprintf("/prescriptions?patient_id=%d", other.ID),
+			fmt.Sprintf("/patients/%d/medications", other.ID),
+		} {
+			got := prescriptionIDs(t, user, path)
+			if slices.Contains(got, foreign.ID) {
+				t.Errorf("GET %s = %v, contains prescription of another patient", path, got)
+			}
+		}
+		if got := prescriptionIDs(t, user, fmt.Sprintf("/patients/%d/medications", patient.ID)); !slices.Equal(got, []uint{own.ID}) {
+			t.Errorf("own medications = %v, want [%d]", got, own.ID)
+		}
+
+		decodeResponse[Prescription](t, apiRequest(t, user, http.MethodGet, fmt.Sprintf("/prescriptions/%d", own.ID), nil), http.StatusOK)
+		if w := apiRequest(t, user, http.MethodGet, fmt.Sprintf("/prescriptions/%d", foreign.ID), nil); w.Code != http.StatusForbidden {
+			t.Errorf("GET prescription of another patient: status %d, want 403", w.Code)
+		}
+		if w := apiRequest(t, user, http.MethodPost, fmt.Sprintf("/prescriptions/%d/discontinue", own.ID),
+			DiscontinuePrescriptionRequest{Reason: "не хочу"}); w.Code != http.StatusForbidden {
+			t.Errorf("discontinue as patient: status %d, want 403", w.Code)
+		}
+	})
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultAppointmentDuration - длительность приема по умолчанию
+const defaultAppointmentDuration = 30 * time.Minute
+
+// maxSlotsRange - максимальный период, за который можно запросить свободные слоты
+const maxSlotsRange = 31 * 24 * time.Hour
+
+// DoctorSchedule представляет рабочие часы врача в один из дней недели
+// @Description Рабочие часы врача
+type DoctorSchedule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	DoctorID   uint      `gorm:"not null;index" json:"doctor_id"`
+	Weekday    int       `gorm:"not null;check:weekday BETWEEN 1 AND 7" json:"weekday"`
+	StartTime  string    `gorm:"not null" json:"start_time"`
+	EndTime    string    `gorm:"not null" json:"end_time"`
+	BreakStart string    `json:"break_start"`
+	BreakEnd   string    `json:"break_end"`
+}
+
+// DoctorScheduleException представляет период, когда врач не принимает (отпуск, больничный и т.п.)
+// @Description Исключение из графика работы врача
+type DoctorScheduleException struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	DoctorID  uint      `gorm:"not null;index" json:"doctor_id"`
+	StartDate time.Time `gorm:"not null" json:"start_date"`
+	EndDate   time.Time `gorm:"not null" json:"end_date"`
+	Reason    string    `json:"reason"`
+}
+
+// TimeSlot представляет свободный интервал для записи
+// @Description Свободный слот для записи к врачу
+type TimeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type DoctorScheduleDayRequest struct {
+	Weekday    int    `json:"weekday" binding:"required,min=1,max=7"`
+	StartTime  string `json:"start_time" binding:"required"`
+	EndTime    string `json:"end_time" binding:"required"`
+	BreakStart string `json:"break_start"`
+	BreakEnd   string `json:"break_end"`
+}
+
+type UpdateDoctorScheduleRequest struct {
+	Days []DoctorScheduleDayRequest `json:"days" binding:"dive"`
+}
+
+type CreateScheduleExceptionRequest struct {
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+	Reason    string    `json:"reason"`
+}
+
+// errOutsideWorkingHours возвращается при попытке записи вне рабочего времени врача
+var errOutsideWorkingHours = errors.New("appointment is outside of the doctor's working hours")
+
+// parseClock разбирает время суток в формате ЧЧ:ММ и возвращает смещение от полуночи
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// isoWeekday возвращает номер дня недели от 1 (понедельник) до 7 (воскресенье)
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// validate проверяет корректность рабочих часов и перерыва
+func (d DoctorScheduleDayRequest) validate() error {
+	start, err := parseClock(d.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(d.EndTime)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("weekday %d: end_time must be after start_time", d.Weekday)
+	}
+
+	if d.BreakStart == "" && d.BreakEnd == "" {
+		return nil
+	}
+	if d.BreakStart == "" || d.BreakEnd == "" {
+		return fmt.Errorf("weekday %d: both break_start and break_end are required", d.Weekday)
+	}
+	breakStart, err := parseClock(d.BreakStart)
+	if err != nil {
+		return err
+	}
+	breakEnd, err := parseClock(d.BreakEnd)
+	if err != nil {
+		return err
+	}
+	if breakStart < start || breakEnd > end || breakEnd <= breakStart {
+		return fmt.Errorf("weekday %d: break must lie within working hours", d.Weekday)
+	}
+	return nil
+}
+
+// workingIntervals возвращает рабочие интервалы врача в пределах [from, to) с учетом перерывов и исключений
+func workingIntervals(tx *gorm.DB, doctorID uint, from, to time.Time) ([]TimeSlot, error) {
+	var schedules []DoctorSchedule
+	if err := tx.Where("doctor_id = ?", doctorID).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	byWeekday := make(map[int]DoctorSchedule, len(schedules))
+	for _, s := range schedules {
+		byWeekday[s.Weekday] = s
+	}
+
+	var exceptions []DoctorScheduleException
+	if err := tx.Where("doctor_id = ? AND start_date < ? AND end_date > ?", doctorID, to, from).
+		Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+
+	var intervals []TimeSlot
+	from = from.In(time.Local)
+	to = to.In(time.Local)
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local); day.Before(to); day = day.AddDate(0, 0, 1) {
+		s, ok := byWeekday[isoWeekday(day)]
+		if !ok {
+			continue
+		}
+
+		// Значения проверены при сохранении графика
+		start, _ := parseClock(s.StartTime)
+		end, _ := parseClock(s.EndTime)
+		dayIntervals := []TimeSlot{{Start: day.Add(start), End: day.Add(end)}}
+		if s.BreakStart != "" && s.BreakEnd != "" {
+			breakStart, _ := parseClock(s.BreakStart)
+			breakEnd, _ := parseClock(s.BreakEnd)
+			dayIntervals = subtractInterval(dayIntervals, TimeSlot{Start: day.Add(breakStart), End: day.Add(breakEnd)})
+		}
+		intervals = append(intervals, dayIntervals...)
+	}
+
+	for _, e := range exceptions {
+		intervals = subtractInterval(intervals, TimeSlot{Start: e.StartDate, End: e.EndDate})
+	}
+	return clipIntervals(intervals, from, to), nil
+}
+
+// clipIntervals обрезает интервалы по границам периода [from, to)
+func clipIntervals(intervals []TimeSlot, from, to time.Time) []TimeSlot {
+	result := make([]TimeSlot, 0, len(intervals))
+	for _, in := range intervals {
+		if in.Start.Before(from) {
+			in.Start = from
+		}
+		if in.End.After(to) {
+			in.End = to
+		}
+		if in.Start.Before(in.End) {
+			result = append(result, in)
+		}
+	}
+	return result
+}
+
+// subtractInterval вычитает интервал cut из каждого интервала списка
+func subtractInterval(intervals []TimeSlot, cut TimeSlot) []TimeSlot {
+	result := make([]TimeSlot, 0, len(intervals))
+	for _, in := range intervals {
+		if !cut.Start.Before(in.End) || !cut.End.After(in.Start) {
+			result = append(result, in)
+			continue
+		}
+		if in.Start.Before(cut.Start) {
+			result = append(result, TimeSlot{Start: in.Start, End: cut.Start})
+		}
+		if cut.End.Before(in.End) {
+			result = append(result, TimeSlot{Start: cut.End, End: in.End})
+		}
+	}
+	return result
+}
+
+// checkDoctorWorkingTime проверяет, что интервал [start, end) целиком попадает в рабочее время врача
+func checkDoctorWorkingTime(tx *gorm.DB, doctorID uint, start, end time.Time) error {
+	intervals, err := workingIntervals(tx, doctorID, start, end)
+	if err != nil {
+		return err
+	}
+	for _, in := range intervals {
+		if !in.Start.After(start) && !in.End.Before(end) {
+			return nil
+		}
+	}
+	return errOutsideWorkingHours
+}
+
+// parseTimeParam разбирает границу периода в формате RFC3339 или ГГГГ-ММ-ДД
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// GetDoctorSchedule godoc
+// @Summary Получить график работы врача
+// @Description Получить недельный график работы врача. Дни недели нумеруются от 1 (понедельник) до 7 (воскресенье)
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Success 200 {array} DoctorSchedule
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id}/schedule [get]
+func getDoctorSchedule(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+
+	var schedules []DoctorSchedule
+	if err := db.Where("doctor_id = ?", doctor.ID).Order("weekday").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// UpdateDoctorSchedule godoc
+// @Summary Обновить график работы врача
+// @Description Заменить недельный график работы врача. Дни недели нумеруются от 1 (понедельник) до 7 (воскресенье), время указывается в формате ЧЧ:ММ
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Param schedule body UpdateDoctorScheduleRequest true "Недельный график"
+// @Success 200 {array} DoctorSchedule
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id}/schedule [put]
+func updateDoctorSchedule(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+	if !authorize(c, permSchedulesWrite, resource{DoctorID: doctor.ID}) {
+		return
+	}
+
+	var req UpdateDoctorScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	seen := make(map[int]bool)
+	schedules := make([]DoctorSchedule, 0, len(req.Days))
+	for _, day := range req.Days {
+		if seen[day.Weekday] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("weekday %d is specified more than once", day.Weekday)})
+			return
+		}
+		seen[day.Weekday] = true
+		if err := day.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		schedules = append(schedules, DoctorSchedule{
+			DoctorID:   doctor.ID,
+			Weekday:    day.Weekday,
+			StartTime:  day.StartTime,
+			EndTime:    day.EndTime,
+			BreakStart: day.BreakStart,
+			BreakEnd:   day.BreakEnd,
+		})
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Weekday < schedules[j].Weekday })
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("doctor_id = ?", doctor.ID).Delete(&DoctorSchedule{}).Error; err != nil {
+			return err
+		}
+		if len(schedules) == 0 {
+			return nil
+		}
+		return tx.Create(&schedules).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetDoctorScheduleExceptions godoc
+// @Summary Получить исключения из графика врача
+// @Description Получить список отпусков и других периодов, когда врач не принимает
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Success 200 {array} DoctorScheduleException
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id}/schedule/exceptions [get]
+func getDoctorScheduleExceptions(c *gin.Context) {
+	id := c.Param("id")
+	var exceptions []DoctorScheduleException
+	if err := db.Where("doctor_id = ?", id).Order("start_date").Find(&exceptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, exceptions)
+}
+
+// CreateDoctorScheduleException godoc
+// @Summary Добавить исключение в график врача
+// @Description Добавить период [start_date, end_date), в который врач не принимает (отпуск, больничный)
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Param exception body CreateScheduleExceptionRequest true "Период отсутствия"
+// @Success 201 {object} DoctorScheduleException
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id}/schedule/exceptions [post]
+func createDoctorScheduleException(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+	if !authorize(c, permSchedulesWrite, resource{DoctorID: doctor.ID}) {
+		return
+	}
+
+	var req CreateScheduleExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !req.EndDate.After(req.StartDate) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "end_date must be after start_date"})
+		return
+	}
+
+	exception := DoctorScheduleException{
+		DoctorID:  doctor.ID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+	}
+
+	if err := db.Create(&exception).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exception)
+}
+
+// DeleteDoctorScheduleException godoc
+// @Summary Удалить исключение из графика врача
+// @Description Удалить период отсутствия врача
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Param exceptionId path int true "ID исключения"
+// @Success 200 {object} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id}/schedule/exceptions/{exceptionId} [delete]
+func deleteDoctorScheduleException(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+	if !authorize(c, permSchedulesWrite, resource{DoctorID: doctor.ID}) {
+		return
+	}
+
+	exceptionID, err := strconv.ParseUint(c.Param("exceptionId"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid schedule exception id"})
+		return
+	}
+	result := db.Where("doctor_id = ?", doctor.ID).Delete(&DoctorScheduleException{}, exceptionID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Schedule exception not found"})
+		return
+	}
+	c.JSON(http.StatusOK, "Schedule exception deleted")
+}
+
+// GetDoctorSlots godoc
+// @Summary Получить свободные слоты врача
+// @Description Рассчитать свободные для записи интервалы с учетом графика, перерывов, исключений и существующих приемов
+// @Tags doctors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID врача"
+// @Param from query string true "Начало периода (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param to query string true "Конец периода (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param duration query int false "Длительность слота в минутах" default(30)
+// @Success 200 {array} TimeSlot
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /doctors/{id}/slots [get]
+func getDoctorSlots(c *gin.Context) {
+	id := c.Param("id")
+	var doctor Doctor
+	if err := db.First(&doctor, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Doctor not found"})
+		return
+	}
+
+	if !doctor.Active {
+		c.JSON(http.StatusOK, []TimeSlot{})
+		return
+	}
+
+	from, err := parseTimeParam(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid or missing 'from' parameter"})
+		return
+	}
+	to, err := parseTimeParam(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid or missing 'to' parameter"})
+		return
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "'to' must be after 'from'"})
+		return
+	}
+	if to.Sub(from) > maxSlotsRange {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "requested period is too long"})
+		return
+	}
+
+	duration := defaultAppointmentDuration
+	if value := c.Query("duration"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes <= 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "'duration' must be a positive number of minutes"})
+			return
+		}
+		duration = time.Duration(minutes) * time.Minute
+	}
+
+	intervals, err := workingIntervals(db, doctor.ID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var appointments []Appointment
+	if err := db.Where("doctor_id = ? AND date < ? AND end_date > ?", doctor.ID, to, from).
+		Where("status NOT IN ?", freeSlotStatuses).
+		Find(&appointments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	for _, a := range appointments {
+		intervals = subtractInterval(intervals, TimeSlot{Start: a.Date, End: a.EndDate})
+	}
+
+	slots := make([]TimeSlot, 0)
+	for _, in := range intervals {
+		for start := in.Start; !start.Add(duration).After(in.End); start = start.Add(duration) {
+			slots = append(slots, TimeSlot{Start: start, End: start.Add(duration)})
+		}
+	}
+	c.JSON(http.StatusOK, slots)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeleteDoctorScheduleException(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		other := createTestDoctor(t, "Петров Петр", "Терапевт")
+
+		create := func(doctor Doctor) DoctorScheduleException {
+			t.Helper()
+			return decodeResponse[DoctorScheduleException](t, apiRequest(t, admin, http.MethodPost,
+				fmt.Sprintf("/doctors/%d/schedule/exceptions", doctor.ID), CreateScheduleExceptionRequest{
+					StartDate: testTime(12, 0), EndDate: testTime(14, 0), Reason: "Конференция",
+				}), http.StatusCreated)
+		}
+		exception := create(doctor)
+		otherException := create(other)
+
+		path := fmt.Sprintf("/doctors/%d/schedule/exceptions/", doctor.ID)
+		tests := []struct {
+			name   string
+			id     string
+			status int
+		}{
+			{"not a number", "id%3E0", http.StatusBadRequest},
+			{"negative", "-1", http.StatusBadRequest},
+			{"missing", "999999", http.StatusNotFound},
+			{"another doctor's exception", fmt.Sprint(otherException.ID), http.StatusNotFound},
+			{"own exception", fmt.Sprint(exception.ID), http.StatusOK},
+			{"already deleted", fmt.Sprint(exception.ID), http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			if w := apiRequest(t, admin, http.MethodDelete, path+tt.id, nil); w.Code != tt.status {
+				t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.status, w.Body.String())
+			}
+		}
+
+		var left []DoctorScheduleException
+		if err := db.Order("id").Find(&left).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != 1 || left[0].ID != otherException.ID {
+			t.Errorf("exceptions left = %+v, want only %d", left, otherException.ID)
+		}
+	})
+}
+
+// createTestWorkday сокращает понедельник врача до 09:00-14:00 с перерывом 12:00-13:00,
+// добавляет исключение 10:00-10:30 тестового дня и делает воскресенье выходным
+func createTestWorkday(t *testing.T, doctor Doctor) {
+	t.Helper()
+	err := db.Model(&DoctorSchedule{}).Where("doctor_id = ? AND weekday = ?", doctor.ID, 1).Updates(map[string]interface{}{
+		"start_time": "09:00", "end_time": "14:00", "break_start": "12:00", "break_end": "13:00",
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Where("doctor_id = ? AND weekday = ?", doctor.ID, 7).Delete(&DoctorSchedule{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	exception := DoctorScheduleException{DoctorID: doctor.ID, StartDate: testTime(10, 0), EndDate: testTime(10, 30), Reason: "Планерка"}
+	if err := db.Create(&exception).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetDoctorSlots(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		registrar := createTestUser(t, roleRegistrar, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		createTestWorkday(t, doctor)
+		createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(11, 0)})
+		// Отмененный прием и неявка время не занимают
+		createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(9, 0), Status: statusCancelled})
+		createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(13, 0), Status: statusNoShow})
+
+		slots := func(query string) []time.Time {
+			t.Helper()
+			w := apiRequest(t, registrar, http.MethodGet, fmt.Sprintf("/doctors/%d/slots?%s", doctor.ID, query), nil)
+			var starts []time.Time
+			for _, slot := range decodeResponse[[]TimeSlot](t, w, http.StatusOK) {
+				starts = append(starts, slot.Start)
+			}
+			return starts
+		}
+		at := func(times ...[2]int) []time.Time {
+			result := make([]time.Time, len(times))
+			for i, hm := range times {
+				result[i] = testTime(hm[0], hm[1])
+			}
+			return result
+		}
+		equal := func(a, b []time.Time) bool {
+			return slices.EqualFunc(a, b, func(x, y time.Time) bool { return x.Equal(y) })
+		}
+
+		tests := []struct {
+			name  string
+			query string
+			want  []time.Time
+		}{
+			{"whole day", "from=2030-03-04&to=2030-03-05",
+				at([2]int{9, 0}, [2]int{9, 30}, [2]int{10, 30}, [2]int{11, 30}, [2]int{13, 0}, [2]int{13, 30})},
+			{"longer slots do not fit into gaps", "from=2030-03-04&to=2030-03-05&duration=45",
+				at([2]int{9, 0}, [2]int{13, 0})},
+			{"period clips working hours", "from=" + url.QueryEscape(testTime(9, 15).Format(time.RFC3339)) + "&to=" + url.QueryEscape(testTime(11, 0).Format(time.RFC3339)),
+				at([2]int{9, 15}, [2]int{10, 30})},
+			{"day off", "from=2030-03-10&to=2030-03-11", nil},
+		}
+		for _, tt := range tests {
+			if got := slots(tt.query); !equal(got, tt.want) {
+				t.Errorf("%s: slots = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+
+		for _, query := range []string{
+			"to=2030-03-05",
+			"from=2030-03-04&to=tomorrow",
+			"from=2030-03-05&to=2030-03-04",
+			"from=2030-03-01&to=2030-04-15",
+			"from=2030-03-04&to=2030-03-05&duration=0",
+		} {
+			if w := apiRequest(t, registrar, http.MethodGet, fmt.Sprintf("/doctors/%d/slots?%s", doctor.ID, query), nil); w.Code != http.StatusBadRequest {
+				t.Errorf("slots?%s: status %d, want 400", query, w.Code)
+			}
+		}
+
+		// У уволенного врача свободного времени нет
+		if err := db.Model(&doctor).Update("active", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got := slots("from=2030-03-04&to=2030-03-05"); len(got) != 0 {
+			t.Errorf("slots of inactive doctor = %v, want none", got)
+		}
+	})
+}
+
+func TestCheckDoctorWorkingTime(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		createTestWorkday(t, doctor)
+
+		tests := []struct {
+			name       string
+			start, end time.Time
+			ok         bool
+		}{
+			{"start of the day", testTime(9, 0), testTime(9, 30), true},
+			{"right before the break", testTime(11, 30), testTime(12, 0), true},
+			{"right after the break", testTime(13, 0), testTime(13, 30), true},
+			{"end of the day", testTime(13, 30), testTime(14, 0), true},
+			{"before opening", testTime(8, 45), testTime(9, 15), false},
+			{"after closing", testTime(13, 45), testTime(14, 15), false},
+			{"during the break", testTime(12, 15), testTime(12, 45), false},
+			{"across the break", testTime(11, 30), testTime(13, 30), false},
+			{"during an exception", testTime(10, 15), testTime(10, 45), false},
+			{"day off", testTime(10, 0).AddDate(0, 0, 6), testTime(10, 30).AddDate(0, 0, 6), false},
+		}
+		for _, tt := range tests {
+			err := checkDoctorWorkingTime(db, doctor.ID, tt.start, tt.end)
+			if tt.ok && err != nil {
+				t.Errorf("%s: %v, want nil", tt.name, err)
+			}
+			if !tt.ok && !errors.Is(err, errOutsideWorkingHours) {
+				t.Errorf("%s: %v, want errOutsideWorkingHours", tt.name, err)
+			}
+		}
+
+		// Запись и перенос вне рабочего времени отклоняются
+		registrar := createTestUser(t, roleRegistrar, 0)
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		request := CreateAppointmentRequest{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(12, 15)}
+		if w := apiRequest(t, registrar, http.MethodPost, "/appointments", request); w.Code != http.StatusBadRequest {
+			t.Errorf("booking during the break: status %d, want 400", w.Code)
+		}
+		request.Date = testTime(13, 0)
+		created := decodeResponse[Appointment](t, apiRequest(t, registrar, http.MethodPost, "/appointments", request), http.StatusCreated)
+		request.Date = testTime(10, 0)
+		if w := apiRequest(t, registrar, http.MethodPut, fmt.Sprintf("/appointments/%d", created.ID), request); w.Code != http.StatusBadRequest {
+			t.Errorf("rescheduling into an exception: status %d, want 400", w.Code)
+		}
+		var stored Appointment
+		if err := db.First(&stored, created.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !stored.Date.Equal(testTime(13, 0)) {
+			t.Errorf("appointment moved to %v", stored.Date)
+		}
+	})
+}
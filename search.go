@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Периоды для фильтра приемов period
+const (
+	periodToday    = "today"
+	periodUpcoming = "upcoming"
+	periodPast     = "past"
+)
+
+// errDiagnosisFilterForbidden возвращается при поиске по диагнозу без права читать диагнозы
+var errDiagnosisFilterForbidden = errors.New("diagnosis filter requires access to clinical data")
+
+// appointmentFilter - параметры поиска приемов. Все заданные условия объединяются через AND.
+type appointmentFilter struct {
+	PatientID      *uint
+	DoctorID       *uint
+	From           *time.Time // дата приема не раньше
+	To             *time.Time // дата приема раньше
+	Period         string
+	Status         string
+	Specialization string
+	Diagnosis      string // подстрока диагноза
+	DiagnosisCode  string // код МКБ-10 или его начало
+	HasTests       *bool
+}
+
+// parseAppointmentFilter разбирает и проверяет параметры поиска приемов
+func parseAppointmentFilter(c *gin.Context) (appointmentFilter, error) {
+	var f appointmentFilter
+
+	for name, target := range map[string]**uint{"patient_id": &f.PatientID, "doctor_id": &f.DoctorID} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				return f, fmt.Errorf("invalid '%s' parameter", name)
+			}
+			v := uint(id)
+			*target = &v
+		}
+	}
+
+	for name, target := range map[string]**time.Time{"from": &f.From, "to": &f.To} {
+		if value := c.Query(name); value != "" {
+			t, err := parseTimeParam(value)
+			if err != nil {
+				return f, fmt.Errorf("invalid '%s' parameter", name)
+			}
+			*target = &t
+		}
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return f, errors.New("'from' must be before 'to'")
+	}
+
+	switch f.Period = c.Query("period"); f.Period {
+	case "", periodToday, periodUpcoming, periodPast:
+	default:
+		return f, fmt.Errorf("period must be one of: %s, %s, %s", periodToday, periodUpcoming, periodPast)
+	}
+
+	if f.Status = c.Query("status"); f.Status != "" && !validAppointmentStatus(f.Status) {
+		return f, fmt.Errorf("status must be one of: %s", strings.Join(appointmentStatuses, ", "))
+	}
+
+	f.Specialization = strings.TrimSpace(c.Query("specialization"))
+
+	f.Diagnosis = strings.TrimSpace(c.Query("diagnosis"))
+	if f.Diagnosis != "" && currentUser(c).scope(permClinicalRead) == scopeNone {
+		return f, errDiagnosisFilterForbidden
+	}
+
+	if value := c.Query("diagnosis_code"); value != "" {
+		if currentUser(c).scope(permClinicalRead) == scopeNone {
+			return f, errDiagnosisFilterForbidden
+		}
+		code, ok := icd10CodePrefix(value)
+		if !ok {
+			return f, errors.New("invalid 'diagnosis_code' parameter")
+		}
+		f.DiagnosisCode = code
+	}
+
+	if value := c.Query("has_tests"); value != "" {
+		hasTests, err := strconv.ParseBool(value)
+		if err != nil {
+			return f, errors.New("invalid 'has_tests' parameter")
+		}
+		f.HasTests = &hasTests
+	}
+	return f, nil
+}
+
+// apply добавляет условия поиска к запросу приемов; now - текущее время для period
+func (f appointmentFilter) apply(query *gorm.DB, now time.Time) *gorm.DB {
+	if f.PatientID != nil {
+		query = query.Where("appointments.patient_id = ?", *f.PatientID)
+	}
+	if f.DoctorID != nil {
+		query = query.Where("appointments.doctor_id = ?", *f.DoctorID)
+	}
+	if f.From != nil {
+		query = query.Where("appointments.date >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("appointments.date < ?", *f.To)
+	}
+
+	switch f.Period {
+	case periodToday:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		query = query.Where("appointments.date >= ? AND appointments.date < ?", start, start.AddDate(0, 0, 1))
+	case periodUpcoming:
+		query = query.Where("appointments.date >= ?", now)
+	case periodPast:
+		query = query.Where("appointments.date < ?", now)
+	}
+
+	if f.Status != "" {
+		query = query.Where("appointments.status = ?", f.Status)
+	}
+
+	// Специализация и диагноз сравниваются в нормализованном виде: LIKE в SQLite
+	// не учитывает регистр только для латиницы
+	if f.Specialization != "" {
+		query = query.Where("appointments.doctor_id IN (?)",
+			db.Model(&Doctor{}).Select("id").Where("search_specialization = ?", normalizeSearchText(f.Specialization)))
+	}
+
+	if f.Diagnosis != "" {
+		query = query.Where(`appointments.search_diagnosis LIKE ? ESCAPE '\'`, likePattern(normalizeSearchText(f.Diagnosis)))
+	}
+
+	if f.DiagnosisCode != "" {
+		diagnoses := db.Model(&AppointmentDiagnosis{}).Select("1").
+			Where("appointment_diagnoses.appointment_id = appointments.id AND appointment_diagnoses.code LIKE ?", f.DiagnosisCode+"%")
+		query = query.Where("EXISTS (?)", diagnoses)
+	}
+
+	if f.HasTests != nil {
+		tests := db.Model(&MedicalTest{}).Select("1").Where("medical_tests.appointment_id = appointments.id")
+		if *f.HasTests {
+			query = query.Where("EXISTS (?)", tests)
+		} else {
+			query = query.Where("NOT EXISTS (?)", tests)
+		}
+	}
+	return query
+}
+
+// likePattern экранирует служебные символы LIKE и превращает строку в шаблон поиска подстроки
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+// Поиск пациентов. ФИО и телефон хранятся также в нормализованном виде (search_name,
+// search_phone): ФИО в нижнем регистре с е вместо ё и без знаков препинания, телефон -
+// только цифры. Кандидаты отбираются индексом FTS5 (таблица patients_fts с триграммным
+// токенизатором, ищет подстроки), если база и бинарный файл его поддерживают, иначе
+// через LIKE. Окончательный порядок по релевантности вычисляется одинаково для обоих способов.
+
+const (
+	patientsFTSTable         = "patients_fts"
+	patientSearchCandidates  = 200 // сколько кандидатов ранжируется
+	defaultPatientSearchSize = 20
+	maxPatientSearchSize     = 100
+)
+
+// usePatientFTS - искать пациентов через индекс FTS5; определяется initPatientSearch
+var usePatientFTS bool
+
+// initPatientSearch выбирает способ поиска пациентов. Индекс FTS5 обновляется триггерами
+// на таблице patients, поэтому бинарный файл без FTS5 не сможет изменять пациентов в базе
+// с таким индексом - это ошибка конфигурации.
+func initPatientSearch(db *gorm.DB) error {
+	usePatientFTS = false
+	if !db.Migrator().HasTable(patientsFTSTable) {
+		return nil
+	}
+	if !sqliteHasFTS5(db) {
+		return errors.New("database has an FTS5 patient search index, but this binary is built without FTS5; rebuild with -tags sqlite_fts5")
+	}
+	usePatientFTS = true
+	return nil
+}
+
+// normalizeSearchText приводит текст к виду для поиска: нижний регистр, е вместо ё,
+// слова разделены одним пробелом, знаки препинания отброшены
+func normalizeSearchText(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// normalizePhone оставляет в номере только цифры; российский номер с 8 в начале
+// приводится к виду с 7
+func normalizePhone(s string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if len(digits) == 11 && digits[0] == '8' {
+		digits = "7" + digits[1:]
+	}
+	return digits
+}
+
+// BeforeSave обновляет нормализованные поля поиска при создании и сохранении пациента
+func (p *Patient) BeforeSave(tx *gorm.DB) error {
+	p.SearchName = normalizeSearchText(p.FullName)
+	p.SearchPhone = normalizePhone(p.Phone)
+	return nil
+}
+
+// BeforeSave обновляет нормализованный диагноз для фильтра приемов diagnosis
+func (a *Appointment) BeforeSave(tx *gorm.DB) error {
+	a.SearchDiagnosis = normalizeSearchText(a.Diagnosis)
+	return nil
+}
+
+// BeforeSave обновляет нормализованную специализацию для фильтров specialization
+func (d *Doctor) BeforeSave(tx *gorm.DB) error {
+	d.SearchSpecialization = normalizeSearchText(d.Specialization)
+	return nil
+}
+
+// patientQuery - разобранная строка поиска. Строка с @ ищется в email, строка без букв -
+// в телефоне, иначе каждое слово ищется в ФИО, телефоне или email (в любом порядке).
+type patientQuery struct {
+	email string
+	phone string
+	words []string
+}
+
+// parsePatientQuery разбирает строку поиска пациентов
+func parsePatientQuery(q string) (patientQuery, error) {
+	q = strings.TrimSpace(q)
+	switch {
+	case q == "":
+		return patientQuery{}, errors.New("'q' is required")
+	case strings.Contains(q, "@"):
+		return patientQuery{email: strings.ToLower(q)}, nil
+	case strings.IndexFunc(q, unicode.IsLetter) < 0:
+		phone := normalizePhone(q)
+		// Начало номера с 8 и не короче городского номера - это код выхода на межгород
+		if len(phone) >= 8 && phone[0] == '8' {
+			phone = "7" + phone[1:]
+		}
+		if phone != "" {
+			return patientQuery{phone: phone}, nil
+		}
+		return patientQuery{}, errors.New("'q' must contain letters or digits")
+	}
+	return patientQuery{words: strings.Fields(normalizeSearchText(q))}, nil
+}
+
+// ftsMatch возвращает запрос MATCH для индекса FTS5. Триграммный индекс не находит
+// подстроки короче трех символов, для таких запросов возвращается false.
+func (q patientQuery) ftsMatch() (string, bool) {
+	phrase := func(s string) (string, bool) {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`, utf8.RuneCountInString(s) >= 3
+	}
+	switch {
+	case q.email != "":
+		p, ok := phrase(q.email)
+		return "email : " + p, ok
+	case q.phone != "":
+		p, ok := phrase(q.phone)
+		return "search_phone : " + p, ok
+	}
+	terms := make([]string, len(q.words))
+	for i, word := range q.words {
+		p, ok := phrase(word)
+		if !ok {
+			return "", false
+		}
+		terms[i] = p
+	}
+	return strings.Join(terms, " AND "), true
+}
+
+// filter добавляет к запросу условия поиска через LIKE
+func (q patientQuery) filter(query *gorm.DB) *gorm.DB {
+	const like = ` LIKE ? ESCAPE '\'`
+	switch {
+	case q.email != "":
+		return query.Where("LOWER(email)"+like, likePattern(q.email))
+	case q.phone != "":
+		return query.Where("search_phone"+like, likePattern(q.phone))
+	}
+	for _, word := range q.words {
+		pattern := likePattern(word)
+		query = query.Where("(search_name"+like+" OR search_phone"+like+" OR LOWER(email)"+like+")",
+			pattern, pattern, pattern)
+	}
+	return query
+}
+
+// score оценивает релевантность пациента: точное совпадение выше совпадения начала,
+// совпадение начала выше совпадения внутри слова
+func (q patientQuery) score(p Patient) int {
+	switch {
+	case q.email != "":
+		email := strings.ToLower(p.Email)
+		switch {
+		case email == q.email:
+			return 100
+		case strings.HasPrefix(email, q.email):
+			return 50
+		}
+		return 10
+	case q.phone != "":
+		switch {
+		case p.SearchPhone == q.phone:
+			return 100
+		case strings.HasSuffix(p.SearchPhone, q.phone):
+			return 60
+		case strings.HasPrefix(p.SearchPhone, q.phone):
+			return 40
+		}
+		return 10
+	}
+
+	nameWords := strings.Fields(p.SearchName)
+	score := 0
+	for _, word := range q.words {
+		best := 1 // слово найдено в телефоне или email
+		for _, name := range nameWords {
+			switch {
+			case name == word:
+				best = max(best, 30)
+			case strings.HasPrefix(name, word):
+				best = max(best, 20)
+			case strings.Contains(name, word):
+				best = max(best, 5)
+			}
+		}
+		score += best
+	}
+	if strings.Join(q.words, " ") == p.SearchName {
+		score += 50
+	}
+	return score
+}
+
+// findPatients возвращает пациентов, подходящих под запрос, в порядке релевантности
+func findPatients(query *gorm.DB, q patientQuery, limit int) ([]Patient, error) {
+	if match, ok := q.ftsMatch(); ok && usePatientFTS {
+		var ids []uint
+		err := db.Raw("SELECT rowid FROM "+patientsFTSTable+" WHERE "+patientsFTSTable+" MATCH ? ORDER BY rank LIMIT ?",
+			match, patientSearchCandidates).Scan(&ids).Error
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("id IN ?", ids)
+	} else {
+		query = q.filter(query)
+	}
+
+	var patients []Patient
+	if err := query.Limit(patientSearchCandidates).Find(&patients).Error; err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint]int, len(patients))
+	for _, p := range patients {
+		scores[p.ID] = q.score(p)
+	}
+	sort.SliceStable(patients, func(i, j int) bool {
+		if a, b := scores[patients[i].ID], scores[patients[j].ID]; a != b {
+			return a > b
+		}
+		if patients[i].FullName != patients[j].FullName {
+			return patients[i].FullName < patients[j].FullName
+		}
+		return patients[i].ID < patients[j].ID
+	})
+	if len(patients) > limit {
+		patients = patients[:limit]
+	}
+	return patients, nil
+}
+
+// SearchPatients godoc
+// @Summary Поиск пациентов
+// @Description Найти пациентов по ФИО (без учета регистра, ё/е и порядка слов), телефону (по цифрам, в любом формате) или email. Результаты упорядочены по релевантности, постраничная выдача не поддерживается
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Строка поиска: слова ФИО, номер телефона или email"
+// @Param limit query int false "Максимум результатов (по умолчанию 20, не больше 100)"
+// @Success 200 {object} Page[Patient]
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /patients/search [get]
+func searchPatients(c *gin.Context) {
+	q, err := parsePatientQuery(c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	limit := defaultPatientSearchSize
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPatientSearchSize {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("limit must be between 1 and %d", maxPatientSearchSize)})
+			return
+		}
+	}
+
+	patients, err := findPatients(scopeQuery(c, permPatientsRead, db, "id", ""), q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !auditRead(c, auditRefs(patients)...) {
+		return
+	}
+	c.JSON(http.StatusOK, Page[Patient]{Data: patients})
+}
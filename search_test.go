@@ -0,0 +1,284 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSearchText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Иванов Иван Иванович", "иванов иван иванович"},
+		{"  ИВАНОВ   иван ", "иванов иван"},
+		{"Алёна", "алена"},
+		{"ЁЛКИН Пётр", "елкин петр"},
+		{"Римский-Корсаков Н.А.", "римский корсаков н а"},
+		{"O'Brien", "o brien"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeSearchText(tt.in); got != tt.want {
+			t.Errorf("normalizeSearchText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"+7 (912) 345-67-89", "79123456789"},
+		{"8 912 345 67 89", "79123456789"},
+		{"8-912-345-67-89", "79123456789"},
+		{"79123456789", "79123456789"},
+		{"9123456789", "9123456789"},
+		{"345-67-89", "3456789"},
+		{"81234", "81234"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizePhone(tt.in); got != tt.want {
+			t.Errorf("normalizePhone(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchPatients(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		ivanov := createTestPatient(t, "Иванов Иван Иванович", "+7 (912) 345-67-89")
+		ivanova := createTestPatient(t, "Иванова Алёна Петровна", "8 912 000 11 22")
+		petrov := createTestPatient(t, "Петров Иван Сергеевич", "+7 912 111 22 33")
+		slivanov := createTestPatient(t, "Сливанов Олег", "")
+		if err := db.Model(&ivanov).Update("email", "Ivanov@Example.com").Error; err != nil {
+			t.Fatal(err)
+		}
+		admin := createTestUser(t, roleAdmin, 0)
+
+		tests := []struct {
+			name string
+			q    string
+			want []uint // в порядке релевантности
+		}{
+			{"exact word before prefix before substring", "иванов", []uint{ivanov.ID, ivanova.ID, slivanov.ID}},
+			{"all words must match", "Иван Иванов", []uint{ivanov.ID, ivanova.ID, slivanov.ID}},
+			{"word order does not matter", "иванович иванов", []uint{ivanov.ID}},
+			{"full name ranks first", "Петров Иван Сергеевич", []uint{petrov.ID}},
+			{"case insensitive", "ПЕТРОВ", []uint{petrov.ID, ivanova.ID}},
+			{"е finds ё", "алена", []uint{ivanova.ID}},
+			{"ё finds е", "ИВАНОВА АЛЁНА", []uint{ivanova.ID}},
+			{"short words fall back to substring search", "ал", []uint{ivanova.ID}},
+			{"international phone format", "+7 (912) 345-67-89", []uint{ivanov.ID}},
+			{"phone with leading 8", "8 912 345 67 89", []uint{ivanov.ID}},
+			{"phone digits only", "89123456789", []uint{ivanov.ID}},
+			{"local part of phone", "345-67-89", []uint{ivanov.ID}},
+			{"phone suffix before match inside number", "1122", []uint{ivanova.ID, petrov.ID}},
+			{"email case insensitive", "ivanov@example.COM", []uint{ivanov.ID}},
+			{"nothing found", "Сидоров", []uint{}},
+		}
+
+		modes := []bool{false}
+		if usePatientFTS {
+			modes = append(modes, true)
+		}
+		defer func(saved bool) { usePatientFTS = saved }(usePatientFTS)
+		for _, fts := range modes {
+			usePatientFTS = fts
+			mode := "like"
+			if fts {
+				mode = "fts5"
+			}
+			t.Run(mode, func(t *testing.T) {
+				for _, tt := range tests {
+					t.Run(tt.name, func(t *testing.T) {
+						w := apiRequest(t, admin, http.MethodGet, "/patients/search?q="+url.QueryEscape(tt.q), nil)
+						page := decodeResponse[Page[Patient]](t, w, http.StatusOK)
+						ids := []uint{}
+						for _, p := range page.Data {
+							ids = append(ids, p.ID)
+						}
+						if !slices.Equal(ids, tt.want) {
+							t.Errorf("search %q = %v, want %v", tt.q, ids, tt.want)
+						}
+					})
+				}
+			})
+		}
+	})
+}
+
+func TestMigrationPatientSearch(t *testing.T) {
+	forEachDatabaseAt(t, 6, func(t *testing.T) {
+		statements := []string{
+			"INSERT INTO patients (id, full_name, birth_date, gender, phone) VALUES (1, 'Ёлкина  Алёна', '1980-05-17', 'female', '8 (912) 345-67-89')",
+			"INSERT INTO patients (id, full_name, birth_date, gender, phone) VALUES (2, 'Smith, John', '1975-01-02', 'male', '+44 20 7946 0958')",
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := migrateUp(db, 7); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[uint][2]string{
+			1: {"елкина алена", "79123456789"},
+			2: {"smith john", "442079460958"},
+		}
+		for id, w := range want {
+			var p m0007Patient
+			if err := db.First(&p, id).Error; err != nil {
+				t.Fatal(err)
+			}
+			if p.SearchName != w[0] || p.SearchPhone != w[1] {
+				t.Errorf("patient %d: search_name %q, search_phone %q; want %q, %q", id, p.SearchName, p.SearchPhone, w[0], w[1])
+			}
+		}
+	})
+}
+
+func TestAppointmentFilter(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		therapist := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		cardiologist := createTestDoctor(t, "Петров Петр", "Кардиолог")
+		anna := createTestPatient(t, "Смирнова Анна", "")
+		maria := createTestPatient(t, "Кузнецова Мария", "")
+
+		cold := createTestAppointment(t, Appointment{PatientID: anna.ID, DoctorID: therapist.ID,
+			Date: testTime(10, 0), Status: statusCompleted, Diagnosis: "ОРВИ, острый ринит"})
+		hypertension := createTestAppointment(t, Appointment{PatientID: maria.ID, DoctorID: cardiologist.ID,
+			Date: testDay.AddDate(0, 0, 1).Add(10 * time.Hour), Diagnosis: "Гипертония, осложнённая"})
+		angina := createTestAppointment(t, Appointment{PatientID: anna.ID, DoctorID: cardiologist.ID,
+			Date: testDay.AddDate(0, 0, 2).Add(10 * time.Hour), Diagnosis: "Стенокардия напряжения"})
+		oldCold := createTestAppointment(t, Appointment{PatientID: maria.ID, DoctorID: therapist.ID,
+			Date: time.Date(2020, time.January, 10, 9, 0, 0, 0, time.Local), Status: statusCompleted, Diagnosis: "Орви"})
+		for _, a := range []Appointment{cold, angina} {
+			if err := db.Create(&MedicalTest{AppointmentID: a.ID, Name: "Общий анализ крови"}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Текущее время для period - утро дня приема hypertension
+		now := testDay.AddDate(0, 0, 1).Add(9 * time.Hour)
+		admin := User{Role: roleAdmin}
+
+		tests := []struct {
+			query string
+			want  []uint
+		}{
+			{"", []uint{cold.ID, hypertension.ID, angina.ID, oldCold.ID}},
+			{"diagnosis=орви", []uint{cold.ID, oldCold.ID}},
+			{"diagnosis=ОРВИ", []uint{cold.ID, oldCold.ID}},
+			{"diagnosis=Острый+Ринит", []uint{cold.ID}},
+			{"diagnosis=ринит,", []uint{cold.ID}},
+			{"diagnosis=осложненная", []uint{hypertension.ID}},
+			{"diagnosis=ОСЛОЖНЁННАЯ", []uint{hypertension.ID}},
+			{"diagnosis=100%25", []uint{}},
+			{"specialization=кардиолог", []uint{hypertension.ID, angina.ID}},
+			{"specialization=+ТЕРАПЕВТ+", []uint{cold.ID, oldCold.ID}},
+			{"specialization=Хирург", []uint{}},
+			{"from=2030-03-05", []uint{hypertension.ID, angina.ID}},
+			{"to=2030-03-05", []uint{cold.ID, oldCold.ID}},
+			{"from=2030-03-04&to=2030-03-06", []uint{cold.ID, hypertension.ID}},
+			{"period=past", []uint{cold.ID, oldCold.ID}},
+			{"period=upcoming", []uint{hypertension.ID, angina.ID}},
+			{"period=today", []uint{hypertension.ID}},
+			{"has_tests=true", []uint{cold.ID, angina.ID}},
+			{"has_tests=false", []uint{hypertension.ID, oldCold.ID}},
+			{"specialization=терапевт&diagnosis=орви&has_tests=true&from=2030-01-01", []uint{cold.ID}},
+			{"specialization=кардиолог&period=upcoming&has_tests=false", []uint{hypertension.ID}},
+			{"diagnosis=орви&to=2030-01-01", []uint{oldCold.ID}},
+			{"diagnosis=орви&period=past&has_tests=false", []uint{oldCold.ID}},
+			{"specialization=кардиолог&diagnosis=орви", []uint{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				c, _ := testContext(admin)
+				c.Request.URL.RawQuery = tt.query
+				f, err := parseAppointmentFilter(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids := []uint{}
+				if err := f.apply(db.Model(&Appointment{}), now).Order("id").Pluck("id", &ids).Error; err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("ids = %v, want %v", ids, tt.want)
+				}
+			})
+		}
+
+		t.Run("invalid parameters", func(t *testing.T) {
+			for _, query := range []string{"from=2030-03-05&to=2030-03-05", "from=вчера", "period=tomorrow", "has_tests=maybe", "diagnosis_code=ОРВИ"} {
+				c, _ := testContext(admin)
+				c.Request.URL.RawQuery = query
+				if _, err := parseAppointmentFilter(c); err == nil {
+					t.Errorf("%s: expected an error", query)
+				}
+			}
+			c, _ := testContext(User{Role: roleRegistrar})
+			c.Request.URL.RawQuery = "diagnosis=орви"
+			if _, err := parseAppointmentFilter(c); err != errDiagnosisFilterForbidden {
+				t.Errorf("registrar diagnosis filter: err = %v, want %v", err, errDiagnosisFilterForbidden)
+			}
+		})
+
+		t.Run("api", func(t *testing.T) {
+			user := createTestUser(t, roleAdmin, 0)
+			page := decodeResponse[Page[Appointment]](t,
+				apiRequest(t, user, http.MethodGet, "/appointments?"+url.Values{"diagnosis": {"Орви"}, "specialization": {"терапевт"}}.Encode(), nil), http.StatusOK)
+			if len(page.Data) != 2 || page.Data[0].ID != cold.ID || page.Data[1].ID != oldCold.ID {
+				t.Errorf("appointments = %+v, want %d and %d", page.Data, cold.ID, oldCold.ID)
+			}
+
+			doctors := decodeResponse[Page[Doctor]](t,
+				apiRequest(t, user, http.MethodGet, "/doctors?specialization="+url.QueryEscape("КАРДИОЛОГ"), nil), http.StatusOK)
+			if len(doctors.Data) != 1 || doctors.Data[0].ID != cardiologist.ID {
+				t.Errorf("doctors = %+v, want only %d", doctors.Data, cardiologist.ID)
+			}
+		})
+	})
+}
+
+func TestMigrationAppointmentSearch(t *testing.T) {
+	forEachDatabaseAt(t, 15, func(t *testing.T) {
+		statements := []string{
+			"INSERT INTO patients (id, full_name, birth_date, gender) VALUES (1, 'Смирнова Анна', '1980-05-17', 'female')",
+			"INSERT INTO doctors (id, full_name, specialization) VALUES (1, 'Иванов Иван', 'Врач Общей Практики')",
+			"INSERT INTO appointments (id, patient_id, doctor_id, date, end_date, diagnosis) VALUES (1, 1, 1, '2030-03-04 10:00:00', '2030-03-04 10:30:00', 'ОРВИ, осложнённое')",
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := migrateUp(db, 16); err != nil {
+			t.Fatal(err)
+		}
+
+		var appointment m0016Appointment
+		if err := db.First(&appointment, 1).Error; err != nil {
+			t.Fatal(err)
+		}
+		if want := "орви осложненное"; appointment.SearchDiagnosis != want {
+			t.Errorf("search_diagnosis = %q, want %q", appointment.SearchDiagnosis, want)
+		}
+		var doctor m0016Doctor
+		if err := db.First(&doctor, 1).Error; err != nil {
+			t.Fatal(err)
+		}
+		if want := "врач общей практики"; doctor.SearchSpecialization != want {
+			t.Errorf("search_specialization = %q, want %q", doctor.SearchSpecialization, want)
+		}
+
+		if _, err := migrateDown(db, 1); err != nil {
+			t.Fatal(err)
+		}
+		if db.Migrator().HasColumn("appointments", "search_diagnosis") || db.Migrator().HasColumn("doctors", "search_specialization") {
+			t.Error("columns remain after migrate down")
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fixturesFS содержит встроенные в бинарный файл наборы тестовых данных
+//
+//go:embed fixtures/*.json
+var fixturesFS embed.FS
+
+// seedTables - таблицы данных клиники, очищаемые и заполняемые при загрузке тестовых данных, в порядке удаления
+var seedTables = []string{
+	"external_identifiers",
+	"appointment_diagnoses",
+	"prescriptions",
+	"medical_histories",
+	"medical_tests",
+	"appointments",
+	"doctor_schedule_exceptions",
+	"doctor_schedules",
+	"doctors",
+	"patients",
+}
+
+// seedFixture описывает формат файла с тестовыми данными.
+// Поля записей совпадают с JSON-представлением моделей API, связи задаются явными ID.
+type seedFixture struct {
+	Patients         []Patient            `json:"patients"`
+	Doctors          []Doctor             `json:"doctors"`
+	DoctorSchedules  []DoctorSchedule     `json:"doctor_schedules"`
+	Appointments     []fixtureAppointment `json:"appointments"`
+	MedicalTests     []MedicalTest        `json:"medical_tests"`
+	MedicalHistories []MedicalHistory     `json:"medical_histories"`
+	Prescriptions    []Prescription       `json:"prescriptions"`
+
+	// Диагнозы по МКБ-10 с кодом и названием; справочник для загрузки не нужен
+	Diagnoses []AppointmentDiagnosis `json:"diagnoses"`
+}
+
+// fixtureAppointment - прием в наборе данных. Вместо абсолютной даты можно указать
+// date_offset - смещение относительно момента загрузки (например, "-24h")
+type fixtureAppointment struct {
+	Appointment
+	DateOffset string `json:"date_offset"`
+}
+
+// errDatabaseNotEmpty возвращается при попытке загрузить данные в непустую базу без --force
+var errDatabaseNotEmpty = errors.New("database is not empty, use --force to replace existing data")
+
+// runSeed обрабатывает команду seed
+func runSeed(args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	configPath := configFlag(flags)
+	profile := flags.String("profile", "demo", "name of the built-in data set (fixtures/<profile>.json)")
+	file := flags.String("file", "", "path to a fixture file, overrides --profile")
+	force := flags.Bool("force", false, "delete all existing clinic data before loading")
+	flags.Parse(args)
+	loadConfigOrExit(*configPath)
+
+	fixture, err := loadFixture(*profile, *file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		os.Exit(1)
+	}
+
+	openDatabase()
+	requireCurrentSchema()
+	if err := seedDatabase(db, fixture, *force); err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Загружено: %d пациентов, %d врачей, %d приемов, %d диагнозов, %d тестов, %d записей анамнеза, %d назначений\n",
+		len(fixture.Patients), len(fixture.Doctors), len(fixture.Appointments), len(fixture.Diagnoses),
+		len(fixture.MedicalTests), len(fixture.MedicalHistories), len(fixture.Prescriptions))
+}
+
+// loadFixture читает набор данных из файла или из встроенного профиля
+func loadFixture(profile, file string) (*seedFixture, error) {
+	var data []byte
+	var err error
+	if file != "" {
+		data, err = os.ReadFile(file)
+	} else {
+		data, err = fs.ReadFile(fixturesFS, "fixtures/"+profile+".json")
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("unknown profile %q", profile)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture seedFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+	return &fixture, nil
+}
+
+// seedDatabase загружает набор данных в одной транзакции. В непустую базу данные
+// загружаются только с force, при этом все существующие данные клиники удаляются,
+// так что повторный запуск всегда приводит базу к одному и тому же состоянию.
+func seedDatabase(db *gorm.DB, fixture *seedFixture, force bool) error {
+	now := time.Now()
+	appointments := make([]Appointment, len(fixture.Appointments))
+	for i, a := range fixture.Appointments {
+		appointment := a.Appointment
+		if a.DateOffset != "" {
+			offset, err := time.ParseDuration(a.DateOffset)
+			if err != nil {
+				return fmt.Errorf("appointment %d: invalid date_offset: %w", a.ID, err)
+			}
+			appointment.Date = now.Add(offset)
+		}
+		if appointment.EndDate.IsZero() {
+			appointment.EndDate = appointment.Date.Add(defaultAppointmentDuration)
+		}
+		if appointment.Status == "" {
+			appointment.Status = statusScheduled
+		} else if !validAppointmentStatus(appointment.Status) {
+			return fmt.Errorf("appointment %d: invalid status %q", a.ID, appointment.Status)
+		}
+		appointments[i] = appointment
+	}
+
+	// Пациент, врач и начало курса назначения по умолчанию берутся из приема
+	byID := make(map[uint]Appointment, len(appointments))
+	for _, a := range appointments {
+		byID[a.ID] = a
+	}
+	prescriptions := make([]Prescription, len(fixture.Prescriptions))
+	for i, p := range fixture.Prescriptions {
+		appointment, ok := byID[p.AppointmentID]
+		if !ok {
+			return fmt.Errorf("prescription #%d: unknown appointment %d", i+1, p.AppointmentID)
+		}
+		p.PatientID = appointment.PatientID
+		p.DoctorID = appointment.DoctorID
+		if p.StartDate.IsZero() {
+			p.StartDate = appointment.Date
+		}
+		if p.DurationDays > 0 && p.EndDate == nil {
+			end := p.StartDate.AddDate(0, 0, p.DurationDays)
+			p.EndDate = &end
+		}
+		prescriptions[i] = p
+	}
+	for i, d := range fixture.Diagnoses {
+		if _, ok := byID[d.AppointmentID]; !ok {
+			return fmt.Errorf("diagnosis #%d: unknown appointment %d", i+1, d.AppointmentID)
+		}
+		if !validDiagnosisKind(d.Kind) {
+			return fmt.Errorf("diagnosis #%d: invalid kind %q", i+1, d.Kind)
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		empty, err := isDatabaseEmpty(tx)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			if !force {
+				return errDatabaseNotEmpty
+			}
+			for _, table := range seedTables {
+				if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		batches := []interface{}{
+			&fixture.Patients,
+			&fixture.Doctors,
+			&fixture.DoctorSchedules,
+			&appointments,
+			&fixture.Diagnoses,
+			&fixture.MedicalTests,
+			&fixture.MedicalHistories,
+			&prescriptions,
+		}
+		for _, batch := range batches {
+			if reflect.ValueOf(batch).Elem().Len() == 0 {
+				continue
+			}
+			if err := tx.Omit(clause.Associations).CreateInBatches(batch, 100).Error; err != nil {
+				return err
+			}
+		}
+		return resetSequences(tx, seedTables)
+	})
+}
+
+// isDatabaseEmpty проверяет, что ни в одной из таблиц клиники нет записей
+func isDatabaseEmpty(tx *gorm.DB) (bool, error) {
+	for _, table := range seedTables {
+		var count int64
+		if err := tx.Table(table).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Пациенты, приемы, тесты, назначения и записи анамнеза не удаляются физически, а
+// помечаются временем удаления (deleted_at). Удаление каскадное: вместе с пациентом
+// удаляются его приемы с тестами и назначениями и анамнез, вместе с приемом - его тесты
+// и назначения. Все записи каскада получают одно и то же время удаления, по которому
+// восстановление возвращает их вместе.
+
+// errParentDeleted возвращается при восстановлении записи, родительская запись которой удалена
+var errParentDeleted = errors.New("parent record is deleted, restore it first")
+
+// deletionTime возвращает время удаления для каскада. Время округляется до микросекунд -
+// точности хранения в PostgreSQL, чтобы записи каскада можно было найти по равенству.
+func deletionTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// archive помечает записи удаленными и записывает удаление в журнал аудита
+func archive[T audited](tx *gorm.DB, c *gin.Context, records []T, at time.Time) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(records))
+	for i, record := range records {
+		ids[i] = record.auditRef().EntityID
+	}
+	if err := tx.Model(new(T)).Where("id IN ?", ids).Update("deleted_at", at).Error; err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := recordAudit(tx, c, auditActionDelete, record.auditRef()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unarchive снимает с записей отметку об удалении и записывает восстановление в журнал аудита
+func unarchive[T audited](tx *gorm.DB, c *gin.Context, records []T) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(records))
+	for i, record := range records {
+		ids[i] = record.auditRef().EntityID
+	}
+	if err := tx.Unscoped().Model(new(T)).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := recordAudit(tx, c, auditActionRestore, record.auditRef()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveAppointments удаляет приемы вместе с их тестами и назначениями
+func archiveAppointments(tx *gorm.DB, c *gin.Context, appointments []Appointment, at time.Time) error {
+	if len(appointments) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(appointments))
+	for i, a := range appointments {
+		ids[i] = a.ID
+	}
+	var tests []MedicalTest
+	if err := tx.Where("appointment_id IN ?", ids).Find(&tests).Error; err != nil {
+		return err
+	}
+	if err := archive(tx, c, tests, at); err != nil {
+		return err
+	}
+	var prescriptions []Prescription
+	if err := tx.Where("appointment_id IN ?", ids).Find(&prescriptions).Error; err != nil {
+		return err
+	}
+	if err := archive(tx, c, prescriptions, at); err != nil {
+		return err
+	}
+	return archive(tx, c, appointments, at)
+}
+
+// archivePatient удаляет пациента вместе с его приемами (с тестами и назначениями) и анамнезом
+func archivePatient(tx *gorm.DB, c *gin.Context, patient Patient, at time.Time) error {
+	var appointments []Appointment
+	if err := tx.Where("patient_id = ?", patient.ID).Find(&appointments).Error; err != nil {
+		return err
+	}
+	if err := archiveAppointments(tx, c, appointments, at); err != nil {
+		return err
+	}
+
+	var history []MedicalHistory
+	if err := tx.Where("patient_id = ?", patient.ID).Find(&history).Error; err != nil {
+		return err
+	}
+	if err := archive(tx, c, history, at); err != nil {
+		return err
+	}
+	return archive(tx, c, []Patient{patient}, at)
+}
+
+// restoreAppointments восстанавливает приемы, тесты и назначения, удаленные вместе с ними.
+// Прием, время которого за это время занято другим приемом врача или пациента, не восстанавливается.
+func restoreAppointments(tx *gorm.DB, c *gin.Context, appointments []Appointment, at time.Time) error {
+	if len(appointments) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(appointments))
+	for i := range appointments {
+		conflicts, err := findAppointmentConflicts(tx, &appointments[i])
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return &bookingConflictError{IDs: conflicts}
+		}
+		ids[i] = appointments[i].ID
+	}
+
+	var tests []MedicalTest
+	if err := tx.Unscoped().Where("appointment_id IN ? AND deleted_at = ?", ids, at).Find(&tests).Error; err != nil {
+		return err
+	}
+	var prescriptions []Prescription
+	if err := tx.Unscoped().Where("appointment_id IN ? AND deleted_at = ?", ids, at).Find(&prescriptions).Error; err != nil {
+		return err
+	}
+	if err := unarchive(tx, c, appointments); err != nil {
+		return err
+	}
+	if err := unarchive(tx, c, tests); err != nil {
+		return err
+	}
+	return unarchive(tx, c, prescriptions)
+}
+
+// includeDeleted добавляет к выборке удаленные записи, если запрошено ?include_deleted=true.
+// Удаленные записи видит только администратор; остальным отвечает 403.
+func includeDeleted(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	if c.Query("include_deleted") != "true" {
+		return query, true
+	}
+	if currentUser(c).scope(permDeletedRead) != scopeAll {
+		abortForbidden(c)
+		return nil, false
+	}
+	return query.Unscoped(), true
+}
+
+// respondRestoreError отправляет ответ, соответствующий ошибке восстановления
+func respondRestoreError(c *gin.Context, err error) {
+	if errors.Is(err, errParentDeleted) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		return
+	}
+	respondBookingError(c, err)
+}
+
+// Обработчики восстановления
+
+// RestorePatient godoc
+// @Summary Восстановить пациента
+// @Description Восстановить удаленного пациента вместе с приемами, тестами, назначениями и анамнезом, удаленными вместе с ним
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пациента"
+// @Success 200 {object} Patient
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ConflictResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /patients/{id}/restore [post]
+func restorePatient(c *gin.Context) {
+	id := c.Param("id")
+	var patient Patient
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&patient, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Deleted patient not found"})
+		return
+	}
+
+	at := patient.DeletedAt.Time
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var appointments []Appointment
+		if err := tx.Unscoped().Where("patient_id = ? AND deleted_at = ?", patient.ID, at).Find(&appointments).Error; err != nil {
+			return err
+		}
+		if err := restoreAppointments(tx, c, appointments, at); err != nil {
+			return err
+		}
+
+		var history []MedicalHistory
+		if err := tx.Unscoped().Where("patient_id = ? AND deleted_at = ?", patient.ID, at).Find(&history).Error; err != nil {
+			return err
+		}
+		if err := unarchive(tx, c, history); err != nil {
+			return err
+		}
+		return unarchive(tx, c, []Patient{patient})
+	})
+	if err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+
+	patient.DeletedAt = gorm.DeletedAt{}
+	c.JSON(http.StatusOK, patient)
+}
+
+// RestoreAppointment godoc
+// @Summary Восстановить прием
+// @Description Восстановить удаленный прием вместе с тестами и назначениями, удаленными вместе с ним. Пациент приема не должен быть удален
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Success 200 {object} Appointment
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ConflictResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/restore [post]
+func restoreAppointment(c *gin.Context) {
+	id := c.Param("id")
+	var appointment Appointment
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&appointment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Deleted appointment not found"})
+		return
+	}
+	if !authorize(c, permAppointmentsWrite, appointmentResource(appointment)) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&Patient{}, appointment.PatientID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errParentDeleted
+			}
+			return err
+		}
+		return restoreAppointments(tx, c, []Appointment{appointment}, appointment.DeletedAt.Time)
+	})
+	if err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+
+	appointment.DeletedAt = gorm.DeletedAt{}
+	redactAppointment(currentUser(c), &appointment)
+	c.JSON(http.StatusOK, appointment)
+}
+
+// RestoreMedicalTest godoc
+// @Summary Восстановить тест
+// @Description Восстановить удаленный результат теста. Прием теста не должен быть удален
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID теста"
+// @Success 200 {object} MedicalTest
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests/{id}/restore [post]
+func restoreMedicalTest(c *gin.Context) {
+	id := c.Param("id")
+	var test MedicalTest
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&test, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Deleted medical test not found"})
+		return
+	}
+
+	var appointment Appointment
+	if err := db.Unscoped().First(&appointment, test.AppointmentID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !authorize(c, permTestsWrite, appointmentResource(appointment)) {
+		return
+	}
+	if appointment.DeletedAt.Valid {
+		respondRestoreError(c, errParentDeleted)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return unarchive(tx, c, []MedicalTest{test})
+	})
+	if err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+
+	test.DeletedAt = gorm.DeletedAt{}
+	c.JSON(http.StatusOK, test)
+}
+
+// RestorePrescription godoc
+// @Summary Восстановить назначение
+// @Description Восстановить удаленное назначение препарата. Прием назначения не должен быть удален
+// @Tags prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID назначения"
+// @Success 200 {object} Prescription
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /prescriptions/{id}/restore [post]
+func restorePrescription(c *gin.Context) {
+	id := c.Param("id")
+	var prescription Prescription
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&prescription, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Deleted prescription not found"})
+		return
+	}
+	if !authorize(c, permPrescriptionsWrite, resource{PatientID: prescription.PatientID, DoctorID: prescription.DoctorID}) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&Appointment{}, prescription.AppointmentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errParentDeleted
+			}
+			return err
+		}
+		return unarchive(tx, c, []Prescription{prescription})
+	})
+	if err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+
+	prescription.DeletedAt = gorm.DeletedAt{}
+	c.JSON(http.StatusOK, prescription)
+}
+
+// RestoreMedicalHistory godoc
+// @Summary Восстановить запись анамнеза
+// @Description Восстановить удаленную запись анамнеза. Пациент не должен быть удален
+// @Tags medical-history
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID записи анамнеза"
+// @Success 200 {object} MedicalHistory
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /medical-history/{id}/restore [post]
+func restoreMedicalHistory(c *gin.Context) {
+	id := c.Param("id")
+	var history MedicalHistory
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&history, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Deleted medical history record not found"})
+		return
+	}
+	if !authorize(c, permHistoryWrite, resource{PatientID: history.PatientID}) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&Patient{}, history.PatientID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errParentDeleted
+			}
+			return err
+		}
+		return unarchive(tx, c, []MedicalHistory{history})
+	})
+	if err != nil {
+		respondRestoreError(c, err)
+		return
+	}
+
+	history.DeletedAt = gorm.DeletedAt{}
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// patientRecords - ID видимых (не удаленных) записей пациента по таблицам каскада
+type patientRecords struct {
+	Appointments, Tests, Prescriptions, History []uint
+}
+
+// visibleRecords собирает ID записей пациента, которые не помечены удаленными
+func visibleRecords(t *testing.T, patientID uint) patientRecords {
+	t.Helper()
+	var r patientRecords
+	pluck := func(model interface{}, query string, dest *[]uint) {
+		t.Helper()
+		if err := db.Model(model).Where(query, patientID).Order("id").Pluck("id", dest).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	pluck(&Appointment{}, "patient_id = ?", &r.Appointments)
+	pluck(&MedicalTest{}, "appointment_id IN (SELECT id FROM appointments WHERE patient_id = ?)", &r.Tests)
+	pluck(&Prescription{}, "patient_id = ?", &r.Prescriptions)
+	pluck(&MedicalHistory{}, "patient_id = ?", &r.History)
+	return r
+}
+
+func (r patientRecords) equal(other patientRecords) bool {
+	return slices.Equal(r.Appointments, other.Appointments) && slices.Equal(r.Tests, other.Tests) &&
+		slices.Equal(r.Prescriptions, other.Prescriptions) && slices.Equal(r.History, other.History)
+}
+
+// createTestMedicalTest добавляет к приему результат теста
+func createTestMedicalTest(t *testing.T, appointmentID uint, name string) MedicalTest {
+	t.Helper()
+	test := MedicalTest{AppointmentID: appointmentID, Name: name, Result: "5.2", Unit: "ммоль/л"}
+	if err := db.Create(&test).Error; err != nil {
+		t.Fatal(err)
+	}
+	return test
+}
+
+func TestArchivePatient(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		first := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(9, 0), Status: statusCompleted})
+		second := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(11, 0), Status: statusCompleted})
+		createTestMedicalTest(t, first.ID, "Глюкоза")
+		createTestMedicalTest(t, second.ID, "Холестерин")
+		createTestPrescription(t, first, "Метформин", nil)
+		createTestPrescription(t, second, "Аторвастатин", nil)
+		createTestHistory(t, patient.ID, historyTypeAllergy, "Аллергия на пенициллин", "severe", "active")
+		createTestHistory(t, patient.ID, "chronic", "Сахарный диабет 2 типа", "moderate", "active")
+
+		// Записи, удаленные по отдельности до удаления пациента
+		deleted := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID, Date: testTime(13, 0), Status: statusCompleted})
+		deletedWithVisit := createTestMedicalTest(t, deleted.ID, "Креатинин")
+		deletedTest := createTestMedicalTest(t, first.ID, "ТТГ")
+		deletedPrescription := createTestPrescription(t, second, "Эналаприл", nil)
+		deletedHistory := createTestHistory(t, patient.ID, "surgery", "Аппендэктомия", "mild", "resolved")
+		for _, path := range []string{
+			fmt.Sprintf("/appointments/%d", deleted.ID),
+			fmt.Sprintf("/tests/%d", deletedTest.ID),
+			fmt.Sprintf("/prescriptions/%d", deletedPrescription.ID),
+			fmt.Sprintf("/medical_history/%d", deletedHistory.ID),
+		} {
+			if w := apiRequest(t, admin, http.MethodDelete, path, nil); w.Code != http.StatusOK {
+				t.Fatalf("DELETE %s: status %d: %s", path, w.Code, w.Body.String())
+			}
+		}
+
+		// Записи другого пациента каскад не затрагивает
+		other := createTestPatient(t, "Кузнецова Мария", "")
+		otherVisit := createTestAppointment(t, Appointment{PatientID: other.ID, DoctorID: doctor.ID, Date: testTime(15, 0), Status: statusCompleted})
+		createTestMedicalTest(t, otherVisit.ID, "Глюкоза")
+		createTestPrescription(t, otherVisit, "Метформин", nil)
+		createTestHistory(t, other.ID, "chronic", "Гипертония", "moderate", "active")
+		otherBefore := visibleRecords(t, other.ID)
+
+		before := visibleRecords(t, patient.ID)
+		if len(before.Appointments) != 2 || len(before.Tests) != 2 || len(before.Prescriptions) != 2 || len(before.History) != 2 {
+			t.Fatalf("records before delete = %+v, want two of each", before)
+		}
+
+		if w := apiRequest(t, admin, http.MethodDelete, fmt.Sprintf("/patients/%d", patient.ID), nil); w.Code != http.StatusOK {
+			t.Fatalf("DELETE patient: status %d: %s", w.Code, w.Body.String())
+		}
+		if got := visibleRecords(t, patient.ID); !got.equal(patientRecords{}) {
+			t.Errorf("records after delete = %+v, want none", got)
+		}
+		if got := visibleRecords(t, other.ID); !got.equal(otherBefore) {
+			t.Errorf("records of another patient = %+v, want %+v", got, otherBefore)
+		}
+		if w := apiRequest(t, admin, http.MethodGet, fmt.Sprintf("/patients/%d", patient.ID), nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET deleted patient: status %d, want 404", w.Code)
+		}
+		for _, path := range []string{
+			fmt.Sprintf("/patients/%d/appointments", patient.ID),
+			fmt.Sprintf("/patients/%d/medications", patient.ID),
+		} {
+			w := apiRequest(t, admin, http.MethodGet, path, nil)
+			if page := decodeResponse[Page[map[string]any]](t, w, http.StatusOK); len(page.Data) != 0 {
+				t.Errorf("GET %s = %d records, want none", path, len(page.Data))
+			}
+		}
+
+		// Записи каскада помечены одним временем удаления, удаленные раньше - своим
+		var patientAfter Patient
+		if err := db.Unscoped().First(&patientAfter, patient.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		var cascaded, separate int64
+		db.Unscoped().Model(&Appointment{}).Where("patient_id = ? AND deleted_at = ?", patient.ID, patientAfter.DeletedAt.Time).Count(&cascaded)
+		db.Unscoped().Model(&Appointment{}).Where("id = ? AND deleted_at <> ?", deleted.ID, patientAfter.DeletedAt.Time).Count(&separate)
+		if cascaded != 2 || separate != 1 {
+			t.Errorf("appointments deleted with the patient = %d, separately = %d; want 2 and 1", cascaded, separate)
+		}
+
+		restored := decodeResponse[Patient](t, apiRequest(t, admin, http.MethodPost, fmt.Sprintf("/patients/%d/restore", patient.ID), nil), http.StatusOK)
+		if restored.ID != patient.ID || restored.DeletedAt.Valid {
+			t.Errorf("restored = %+v, want the patient without deleted_at", restored)
+		}
+		if got := visibleRecords(t, patient.ID); !got.equal(before) {
+			t.Errorf("records after restore = %+v, want %+v", got, before)
+		}
+		decodeResponse[Patient](t, apiRequest(t, admin, http.MethodGet, fmt.Sprintf("/patients/%d", patient.ID), nil), http.StatusOK)
+
+		// Удаленные раньше записи остаются удаленными
+		for _, record := range []struct {
+			model interface{}
+			id    uint
+		}{
+			{&Appointment{}, deleted.ID},
+			{&MedicalTest{}, deletedWithVisit.ID},
+			{&MedicalTest{}, deletedTest.ID},
+			{&Prescription{}, deletedPrescription.ID},
+			{&MedicalHistory{}, deletedHistory.ID},
+		} {
+			var n int64
+			db.Model(record.model).Where("id = ?", record.id).Count(&n)
+			if n != 0 {
+				t.Errorf("%T %d was restored with the patient", record.model, record.id)
+			}
+		}
+		if w := apiRequest(t, admin, http.MethodPost, fmt.Sprintf("/patients/%d/restore", patient.ID), nil); w.Code != http.StatusNotFound {
+			t.Errorf("repeated restore: status %d, want 404", w.Code)
+		}
+	})
+}
+
+func TestRestorePatientConflict(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		admin := createTestUser(t, roleAdmin, 0)
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		visit := createTestAppointment(t, Appointment{PatientID: patient.ID, DoctorID: doctor.ID})
+		createTestHistory(t, patient.ID, "chronic", "Гипертония", "moderate", "active")
+		if w := apiRequest(t, admin, http.MethodDelete, fmt.Sprintf("/patients/%d", patient.ID), nil); w.Code != http.StatusOK {
+			t.Fatalf("DELETE patient: status %d: %s", w.Code, w.Body.String())
+		}
+
+		// Время приема занято другим пациентом: восстановление отменяется целиком
+		taken := createTestAppointment(t, Appointment{PatientID: createTestPatient(t, "Кузнецова Мария", "").ID, DoctorID: doctor.ID, Date: visit.Date})
+		conflict := decodeResponse[ConflictResponse](t, apiRequest(t, admin, http.MethodPost, fmt.Sprintf("/patients/%d/restore", patient.ID), nil), http.StatusConflict)
+		if !slices.Contains(conflict.ConflictingAppointmentIDs, taken.ID) {
+			t.Errorf("conflict = %+v, want appointment %d", conflict, taken.ID)
+		}
+		if got := visibleRecords(t, patient.ID); !got.equal(patientRecords{}) {
+			t.Errorf("records after failed restore = %+v, want none", got)
+		}
+		var n int64
+		db.Model(&Patient{}).Where("id = ?", patient.ID).Count(&n)
+		if n != 0 {
+			t.Error("patient restored despite the conflict")
+		}
+	})
+}
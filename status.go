@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Статусы приема. Прием создается запланированным и проходит путь
+// scheduled → checked_in → in_progress → completed; запланированный прием можно
+// отменить (cancelled) или отметить неявку пациента (no_show). completed, cancelled
+// и no_show - конечные статусы.
+const (
+	statusScheduled  = "scheduled"
+	statusCheckedIn  = "checked_in"
+	statusInProgress = "in_progress"
+	statusCompleted  = "completed"
+	statusCancelled  = "cancelled"
+	statusNoShow     = "no_show"
+)
+
+// appointmentTransitions - допустимые переходы между статусами приема
+var appointmentTransitions = map[string][]string{
+	statusScheduled:  {statusCheckedIn, statusCancelled, statusNoShow},
+	statusCheckedIn:  {statusInProgress},
+	statusInProgress: {statusCompleted},
+}
+
+// appointmentStatuses - все статусы приема в порядке жизненного цикла
+var appointmentStatuses = []string{
+	statusScheduled, statusCheckedIn, statusInProgress, statusCompleted, statusCancelled, statusNoShow,
+}
+
+// freeSlotStatuses - статусы приемов, которые не занимают время врача и пациента
+var freeSlotStatuses = []string{statusCancelled, statusNoShow}
+
+// closedStatuses - конечные статусы приема
+var closedStatuses = []string{statusCompleted, statusCancelled, statusNoShow}
+
+// errClinicalNotInProgress возвращается при записи диагноза или лечения до начала приема
+var errClinicalNotInProgress = errors.New("diagnosis and treatment can only be recorded once the visit is in progress")
+
+// errNoShowTooEarly возвращается при отметке неявки до начала приема
+var errNoShowTooEarly = errors.New("appointment has not started yet")
+
+// appointmentStatusError возвращается, если действие недопустимо в текущем статусе приема
+type appointmentStatusError struct {
+	Status string
+	Action string
+}
+
+func (e *appointmentStatusError) Error() string {
+	return fmt.Sprintf("cannot %s an appointment with status %s", e.Action, e.Status)
+}
+
+// AppointmentStatusRequest представляет запрос на отмену приема или отметку неявки
+type AppointmentStatusRequest struct {
+	Reason string `json:"reason"` // причина; для отмены обязательна
+}
+
+// validAppointmentStatus проверяет, что статус приема существует
+func validAppointmentStatus(status string) bool {
+	for _, s := range appointmentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// canTransition проверяет, что из статуса from разрешен переход в to
+func canTransition(from, to string) bool {
+	for _, next := range appointmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// occupiesSlot сообщает, занимает ли прием время врача и пациента: отмененный прием
+// и прием, на который пациент не пришел, время не занимают
+func (a Appointment) occupiesSlot() bool {
+	return a.Status != statusCancelled && a.Status != statusNoShow
+}
+
+// clinicalAllowed сообщает, можно ли в текущем статусе записывать диагноз и лечение
+func (a Appointment) clinicalAllowed() bool {
+	return a.Status == statusInProgress || a.Status == statusCompleted
+}
+
+// transitionAppointment переводит прием в статус to. Статус меняется условным UPDATE,
+// поэтому из двух параллельных переходов выполнится только один.
+func transitionAppointment(tx *gorm.DB, c *gin.Context, appointment *Appointment, to, action, reason string) error {
+	if !canTransition(appointment.Status, to) {
+		return &appointmentStatusError{Status: appointment.Status, Action: action}
+	}
+
+	before := *appointment
+	result := tx.Model(appointment).Where("status = ?", before.Status).Updates(map[string]interface{}{
+		"status":        to,
+		"status_reason": reason,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var current Appointment
+		if err := tx.Select("status").First(&current, appointment.ID).Error; err != nil {
+			return err
+		}
+		return &appointmentStatusError{Status: current.Status, Action: action}
+	}
+
+	appointment.Status = to
+	appointment.StatusReason = reason
+	return recordAuditUpdate(tx, c, appointment.auditRef(), before, *appointment)
+}
+
+// changeAppointmentStatus обрабатывает запрос на смену статуса приема с проверкой права perm
+func changeAppointmentStatus(c *gin.Context, perm permission, to, action, reason string) {
+	id := c.Param("id")
+	var appointment Appointment
+	if err := db.First(&appointment, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Appointment not found"})
+		return
+	}
+	if !authorize(c, perm, appointmentResource(appointment)) {
+		return
+	}
+	if to == statusNoShow && time.Now().Before(appointment.Date) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: errNoShowTooEarly.Error()})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return transitionAppointment(tx, c, &appointment, to, action, reason)
+	})
+	if err != nil {
+		respondBookingError(c, err)
+		return
+	}
+
+	redactAppointment(currentUser(c), &appointment)
+	c.JSON(http.StatusOK, appointment)
+}
+
+// CheckInAppointment godoc
+// @Summary Отметить приход пациента
+// @Description Перевести запланированный прием в статус checked_in
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Success 200 {object} Appointment
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/check-in [post]
+func checkInAppointment(c *gin.Context) {
+	changeAppointmentStatus(c, permAppointmentsWrite, statusCheckedIn, "check in", "")
+}
+
+// StartAppointment godoc
+// @Summary Начать прием
+// @Description Перевести прием из статуса checked_in в in_progress. После начала приема можно записывать диагноз и лечение
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Success 200 {object} Appointment
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/start [post]
+func startAppointment(c *gin.Context) {
+	changeAppointmentStatus(c, permClinicalWrite, statusInProgress, "start", "")
+}
+
+// CompleteAppointment godoc
+// @Summary Завершить прием
+// @Description Перевести прием из статуса in_progress в completed
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Success 200 {object} Appointment
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/complete [post]
+func completeAppointment(c *gin.Context) {
+	changeAppointmentStatus(c, permClinicalWrite, statusCompleted, "complete", "")
+}
+
+// CancelAppointment godoc
+// @Summary Отменить прием
+// @Description Отменить запланированный прием с указанием причины. Отмененный прием остается в истории и не занимает время врача
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Param request body AppointmentStatusRequest true "Причина отмены"
+// @Success 200 {object} Appointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/cancel [post]
+func cancelAppointment(c *gin.Context) {
+	var req AppointmentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "reason is required"})
+		return
+	}
+	changeAppointmentStatus(c, permAppointmentsWrite, statusCancelled, "cancel", reason)
+}
+
+// NoShowAppointment godoc
+// @Summary Отметить неявку
+// @Description Отметить, что пациент не пришел на запланированный прием. Доступно после времени начала приема
+// @Tags appointments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID приема"
+// @Param request body AppointmentStatusRequest false "Комментарий"
+// @Success 200 {object} Appointment
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /appointments/{id}/no-show [post]
+func noShowAppointment(c *gin.Context) {
+	var req AppointmentStatusRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	changeAppointmentStatus(c, permAppointmentsWrite, statusNoShow, "mark as no-show", strings.TrimSpace(req.Reason))
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Динамика результатов тестов пациента. Тесты одного показателя могут называться
+// по-разному ("ХС", "Холестерин") и иметь разные единицы (ммоль/л, мг/дл): названия
+// сводятся к основному через таблицу синонимов, а значения пересчитываются в единицу
+// последнего результата или в запрошенную.
+
+// TestSynonym связывает альтернативное название теста с основным
+// @Description Синоним названия теста
+type TestSynonym struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Alias     string    `gorm:"not null;uniqueIndex" json:"alias"` // альтернативное название
+	Name      string    `gorm:"not null;index" json:"name"`        // основное название
+}
+
+type CreateTestSynonymRequest struct {
+	Alias string `json:"alias" binding:"required"`
+	Name  string `json:"name" binding:"required"`
+}
+
+// TrendPoint - результат теста на временной оси
+type TrendPoint struct {
+	TestID        uint      `json:"test_id"`
+	AppointmentID uint      `json:"appointment_id"`
+	Date          time.Time `json:"date"` // дата приема
+	Name          string    `json:"name"` // название теста, как оно записано
+	Value         float64   `json:"value"`
+	Value2        *float64  `json:"value2,omitempty"` // вторая часть составного результата
+	Flag          string    `json:"flag,omitempty"`
+}
+
+// TrendStats - сводные показатели ряда значений
+type TrendStats struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	Slope float64 `json:"slope_per_day"` // наклон линейной регрессии, единиц в сутки; 0 для одной точки
+}
+
+// TestTrend - динамика показателя пациента
+type TestTrend struct {
+	Name     string       `json:"name"`  // основное название показателя
+	Names    []string     `json:"names"` // названия, считающиеся этим показателем, в нормализованном виде
+	Unit     string       `json:"unit"`
+	Points   []TrendPoint `json:"points"`
+	Stats    *TrendStats  `json:"stats,omitempty"`  // нет, если нет ни одной точки
+	Stats2   *TrendStats  `json:"stats2,omitempty"` // для второй части составного результата
+	Excluded int          `json:"excluded"`         // результаты без числового значения или с непереводимой единицей
+}
+
+// unitAliases приводит записи единиц измерения к одному обозначению
+var unitAliases = map[string]string{
+	"ммоль/л":  "mmol/l",
+	"mmol/l":   "mmol/l",
+	"мкмоль/л": "umol/l",
+	"µmol/l":   "umol/l",
+	"umol/l":   "umol/l",
+	"мг/дл":    "mg/dl",
+	"mg/dl":    "mg/dl",
+	"г/л":      "g/l",
+	"g/l":      "g/l",
+	"г/дл":     "g/dl",
+	"g/dl":     "g/dl",
+	"ммртст":   "mmhg",
+	"ммрт.ст.": "mmhg",
+	"ммрт.ст":  "mmhg",
+	"mmhg":     "mmhg",
+	"кпа":      "kpa",
+	"kpa":      "kpa",
+}
+
+// unitConversions - множители перевода между единицами, не зависящие от показателя
+var unitConversions = map[[2]string]float64{
+	{"mmol/l", "umol/l"}: 1000,
+	{"g/dl", "g/l"}:      10,
+	{"g/l", "mg/dl"}:     100,
+	{"kpa", "mmhg"}:      7.50062,
+}
+
+// analyteUnitConversions - множители, зависящие от показателя (молярная масса);
+// ключ - основное название в нормализованном виде
+var analyteUnitConversions = map[string]map[[2]string]float64{
+	"холестерин":   {{"mmol/l", "mg/dl"}: 38.67},
+	"глюкоза":      {{"mmol/l", "mg/dl"}: 18.016},
+	"триглицериды": {{"mmol/l", "mg/dl"}: 88.57},
+	"креатинин":    {{"umol/l", "mg/dl"}: 1 / 88.42},
+}
+
+// normalizeUnit приводит единицу измерения к ключу таблиц перевода
+func normalizeUnit(unit string) string {
+	key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(unit)), " ", "")
+	if alias, ok := unitAliases[key]; ok {
+		return alias
+	}
+	return key
+}
+
+// unitFactor возвращает множитель перевода значения из единицы from в to
+func unitFactor(analyte, from, to string) (float64, bool) {
+	from, to = normalizeUnit(from), normalizeUnit(to)
+	if from == to {
+		return 1, true
+	}
+	for _, table := range []map[[2]string]float64{analyteUnitConversions[analyte], unitConversions} {
+		if f, ok := table[[2]string{from, to}]; ok {
+			return f, true
+		}
+		if f, ok := table[[2]string{to, from}]; ok {
+			return 1 / f, true
+		}
+	}
+	return 0, false
+}
+
+// convertTrendValue переводит значение в единицу ряда; переведенные значения округляются
+// до тысячных, чтобы не выдавать погрешность множителя
+func convertTrendValue(value, factor float64) float64 {
+	if factor == 1 {
+		return value
+	}
+	return math.Round(value*factor*1000) / 1000
+}
+
+// resolveTestName возвращает основное название показателя и все его названия
+// в нормализованном виде
+func resolveTestName(tx *gorm.DB, name string) (string, map[string]bool, error) {
+	key := normalizeSearchText(name)
+	canonical := name
+	var synonym TestSynonym
+	err := tx.Where("alias = ?", key).First(&synonym).Error
+	switch {
+	case err == nil:
+		canonical = synonym.Name
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return "", nil, err
+	}
+
+	canonicalKey := normalizeSearchText(canonical)
+	names := map[string]bool{canonicalKey: true}
+	var aliases []TestSynonym
+	if err := tx.Find(&aliases).Error; err != nil {
+		return "", nil, err
+	}
+	for _, s := range aliases {
+		if normalizeSearchText(s.Name) == canonicalKey {
+			names[s.Alias] = true
+		}
+	}
+	return canonical, names, nil
+}
+
+// trendStats вычисляет минимум, максимум, среднее и наклон линейной регрессии по времени
+func trendStats(dates []time.Time, values []float64) *TrendStats {
+	if len(values) == 0 {
+		return nil
+	}
+	stats := TrendStats{Min: values[0], Max: values[0]}
+	var sumX, sumY float64
+	xs := make([]float64, len(values))
+	for i, v := range values {
+		stats.Min = math.Min(stats.Min, v)
+		stats.Max = math.Max(stats.Max, v)
+		xs[i] = dates[i].Sub(dates[0]).Hours() / 24
+		sumX += xs[i]
+		sumY += v
+	}
+	n := float64(len(values))
+	stats.Mean = sumY / n
+
+	var cov, varX float64
+	for i, v := range values {
+		dx := xs[i] - sumX/n
+		cov += dx * (v - stats.Mean)
+		varX += dx * dx
+	}
+	if varX > 0 {
+		stats.Slope = cov / varX
+	}
+	return &stats
+}
+
+// GetPatientTestTrend godoc
+// @Summary Получить динамику показателя пациента
+// @Description Получить ряд числовых результатов теста пациента по датам приемов с учетом синонимов названия и пересчетом единиц, а также минимум, максимум, среднее и наклон
+// @Tags patients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID пациента"
+// @Param name query string true "Название теста или его синоним"
+// @Param unit query string false "Единица измерения ряда (по умолчанию - единица последнего результата)"
+// @Param from query string false "Дата приема не раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Param to query string false "Дата приема раньше (RFC3339 или ГГГГ-ММ-ДД)"
+// @Success 200 {object} TestTrend
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /patients/{id}/tests/trends [get]
+func getPatientTestTrend(c *gin.Context) {
+	id := c.Param("id")
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "'name' is required"})
+		return
+	}
+
+	query := db.Joins("JOIN appointments ON appointments.id = medical_tests.appointment_id").
+		Preload("Appointment").
+		Where("appointments.patient_id = ?", id)
+	query = scopeQuery(c, permTestsRead, query, "appointments.patient_id", "appointments.doctor_id")
+	for param, op := range map[string]string{"from": ">=", "to": "<"} {
+		if value := c.Query(param); value != "" {
+			t, err := parseTimeParam(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid '" + param + "' parameter"})
+				return
+			}
+			query = query.Where("appointments.date "+op+" ?", t)
+		}
+	}
+
+	canonical, names, err := resolveTestName(db, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Названия сравниваются после нормализации, что в SQLite невозможно для кириллицы,
+	// поэтому тесты пациента отбираются по названию уже в Go
+	var all []MedicalTest
+	if err := query.Order("appointments.date, medical_tests.id").Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	var tests []MedicalTest
+	for _, t := range all {
+		if names[normalizeSearchText(t.Name)] {
+			tests = append(tests, t)
+		}
+	}
+
+	trend := TestTrend{Name: canonical, Unit: strings.TrimSpace(c.Query("unit")), Points: []TrendPoint{}}
+	for key := range names {
+		trend.Names = append(trend.Names, key)
+	}
+	sort.Strings(trend.Names)
+	if trend.Unit == "" {
+		for i := len(tests) - 1; i >= 0; i-- {
+			if tests[i].ResultValue != nil {
+				trend.Unit = tests[i].Unit
+				break
+			}
+		}
+	}
+
+	analyte := normalizeSearchText(canonical)
+	var dates, dates2 []time.Time
+	var values, values2 []float64
+	for _, t := range tests {
+		factor, ok := unitFactor(analyte, t.Unit, trend.Unit)
+		if t.ResultValue == nil || !ok {
+			trend.Excluded++
+			continue
+		}
+		point := TrendPoint{
+			TestID:        t.ID,
+			AppointmentID: t.AppointmentID,
+			Date:          t.Appointment.Date,
+			Name:          t.Name,
+			Value:         convertTrendValue(*t.ResultValue, factor),
+			Flag:          t.Flag,
+		}
+		dates = append(dates, point.Date)
+		values = append(values, point.Value)
+		if t.ResultValue2 != nil {
+			v := convertTrendValue(*t.ResultValue2, factor)
+			point.Value2 = &v
+			dates2 = append(dates2, point.Date)
+			values2 = append(values2, v)
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	trend.Stats = trendStats(dates, values)
+	trend.Stats2 = trendStats(dates2, values2)
+
+	if !auditRead(c, auditRefs(tests)...) {
+		return
+	}
+	c.JSON(http.StatusOK, trend)
+}
+
+// GetTestSynonyms godoc
+// @Summary Получить синонимы названий тестов
+// @Description Получить таблицу синонимов, по которой названия тестов сводятся к основному при построении динамики
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} TestSynonym
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests/synonyms [get]
+func getTestSynonyms(c *gin.Context) {
+	var synonyms []TestSynonym
+	if err := db.Order("name, alias").Find(&synonyms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, synonyms)
+}
+
+// CreateTestSynonym godoc
+// @Summary Добавить синоним названия теста
+// @Description Добавить альтернативное название теста. Синоним хранится в нормализованном виде (нижний регистр, е вместо ё)
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param synonym body CreateTestSynonymRequest true "Синоним и основное название"
+// @Success 201 {object} TestSynonym
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests/synonyms [post]
+func createTestSynonym(c *gin.Context) {
+	var req CreateTestSynonymRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	synonym := TestSynonym{Alias: normalizeSearchText(req.Alias), Name: strings.TrimSpace(req.Name)}
+	if synonym.Alias == "" || synonym.Name == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "alias and name must not be empty"})
+		return
+	}
+	if synonym.Alias == normalizeSearchText(synonym.Name) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "alias must differ from name"})
+		return
+	}
+
+	// Синонимы не образуют цепочек: основное название не может быть чьим-то синонимом
+	var existing []TestSynonym
+	err := db.Where("alias IN ?", []string{synonym.Alias, normalizeSearchText(synonym.Name)}).Find(&existing).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	for _, s := range existing {
+		if s.Alias == synonym.Alias {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "synonym already exists"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "name is itself a synonym of " + s.Name})
+		return
+	}
+	if err := db.Create(&synonym).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, synonym)
+}
+
+// DeleteTestSynonym godoc
+// @Summary Удалить синоним названия теста
+// @Description Удалить альтернативное название теста
+// @Tags tests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID синонима"
+// @Success 200 {object} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tests/synonyms/{id} [delete]
+func deleteTestSynonym(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid synonym id"})
+		return
+	}
+	result := db.Delete(&TestSynonym{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Synonym not found"})
+		return
+	}
+	c.JSON(http.StatusOK, "Synonym deleted")
+}
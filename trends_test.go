@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeleteTestSynonym(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		technician := createTestUser(t, roleLabTechnician, 0)
+		count := func() int64 {
+			t.Helper()
+			var n int64
+			if err := db.Model(&TestSynonym{}).Count(&n).Error; err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+		seeded := count()
+		if seeded == 0 {
+			t.Fatal("test synonyms are not seeded")
+		}
+
+		for _, id := range []string{"id>0", "1%20OR%201=1", "-1"} {
+			if w := apiRequest(t, technician, http.MethodDelete, "/tests/synonyms/"+id, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("DELETE /tests/synonyms/%s: status %d, want 400", id, w.Code)
+			}
+		}
+		if w := apiRequest(t, technician, http.MethodDelete, "/tests/synonyms/999999", nil); w.Code != http.StatusNotFound {
+			t.Errorf("DELETE missing synonym: status %d, want 404", w.Code)
+		}
+		if got := count(); got != seeded {
+			t.Fatalf("%d synonyms left, want %d", got, seeded)
+		}
+
+		synonym := decodeResponse[TestSynonym](t, apiRequest(t, technician, http.MethodPost, "/tests/synonyms",
+			CreateTestSynonymRequest{Alias: "ГКГ", Name: "Гликированный гемоглобин"}), http.StatusCreated)
+		if w := apiRequest(t, technician, http.MethodDelete, fmt.Sprintf("/tests/synonyms/%d", synonym.ID), nil); w.Code != http.StatusOK {
+			t.Errorf("DELETE synonym: status %d, want 200: %s", w.Code, w.Body.String())
+		}
+		if got := count(); got != seeded {
+			t.Errorf("%d synonyms left, want %d", got, seeded)
+		}
+	})
+}
+
+func TestPatientTestTrend(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T) {
+		doctor := createTestDoctor(t, "Иванов Иван", "Терапевт")
+		patient := createTestPatient(t, "Смирнова Анна", "")
+		user := createTestUser(t, roleDoctor, doctor.ID)
+		day := func(n int) time.Time { return testTime(10, 0).Add(time.Duration(n) * 24 * time.Hour) }
+		result := func(patientID uint, n int, name, value, unit string) MedicalTest {
+			t.Helper()
+			appointment := createTestAppointment(t, Appointment{PatientID: patientID, DoctorID: doctor.ID, Date: day(n), Status: statusCompleted})
+			test := MedicalTest{AppointmentID: appointment.ID, Name: name, Result: value, Unit: unit}
+			if err := db.Create(&test).Error; err != nil {
+				t.Fatal(err)
+			}
+			return test
+		}
+
+		// Холестерин растет на 0,05 ммоль/л в сутки; один результат записан в мг/дл
+		series := []MedicalTest{
+			result(patient.ID, 0, "ХС", "5.0", "ммоль/л"),
+			result(patient.ID, 10, "Холестерин общий", "5.5", "mmol/l"),
+			result(patient.ID, 20, "холестерин", "232.02", "мг/дл"),
+			result(patient.ID, 30, "Холестерин", "6.5", "ммоль/л"),
+		}
+		// Не попадают в ряд: результат без числа и с единицей, которую нельзя перевести
+		result(patient.ID, 15, "ХС", "гемолиз", "ммоль/л")
+		result(patient.ID, 5, "хс", "2.3", "ЕД/л")
+		// Другой показатель и чужой пациент
+		result(patient.ID, 7, "Глюкоза", "5.1", "ммоль/л")
+		result(createTestPatient(t, "Кузнецова Мария", "").ID, 40, "Холестерин", "9.9", "ммоль/л")
+
+		trend := func(query string) TestTrend {
+			t.Helper()
+			return decodeResponse[TestTrend](t, apiRequest(t, user, http.MethodGet,
+				fmt.Sprintf("/patients/%d/tests/trends?%s", patient.ID, query), nil), http.StatusOK)
+		}
+		points := func(trend TestTrend) (ids []uint, values []float64) {
+			for _, p := range trend.Points {
+				ids = append(ids, p.TestID)
+				values = append(values, p.Value)
+			}
+			return ids, values
+		}
+		near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+		seriesIDs := []uint{series[0].ID, series[1].ID, series[2].ID, series[3].ID}
+
+		// Синоним сводится к основному названию, ряд - в единице последнего результата
+		got := trend("name=" + url.QueryEscape("ХС"))
+		if got.Name != "Холестерин" || got.Unit != "ммоль/л" {
+			t.Errorf("name, unit = %q, %q; want Холестерин, ммоль/л", got.Name, got.Unit)
+		}
+		if want := []string{"общий холестерин", "холестерин", "холестерин общий", "хс"}; !slices.Equal(got.Names, want) {
+			t.Errorf("names = %v, want %v", got.Names, want)
+		}
+		ids, values := points(got)
+		if !slices.Equal(ids, seriesIDs) || !slices.EqualFunc(values, []float64{5, 5.5, 6, 6.5}, near) {
+			t.Errorf("points = %v %v, want %v [5 5.5 6 6.5]", ids, values, seriesIDs)
+		}
+		if got.Excluded != 2 {
+			t.Errorf("excluded = %d, want 2", got.Excluded)
+		}
+		if s := got.Stats; s == nil || !near(s.Min, 5) || !near(s.Max, 6.5) || !near(s.Mean, 5.75) || !near(s.Slope, 0.05) {
+			t.Errorf("stats = %+v, want min 5, max 6.5, mean 5.75, slope 0.05", got.Stats)
+		}
+
+		// Пересчет в мг/дл по молярной массе холестерина
+		got = trend("name=" + url.QueryEscape("Холестерин") + "&unit=mg/dl")
+		_, values = points(got)
+		if want := []float64{193.35, 212.685, 232.02, 251.355}; !slices.EqualFunc(values, want, near) {
+			t.Errorf("values in mg/dl = %v, want %v", values, want)
+		}
+		if got.Excluded != 2 || got.Stats == nil || math.Abs(got.Stats.Slope-0.05*38.67) > 1e-6 {
+			t.Errorf("mg/dl: excluded = %d, stats = %+v; want 2 and slope %v", got.Excluded, got.Stats, 0.05*38.67)
+		}
+
+		// Единица, в которую ничего не переводится, исключает все результаты
+		got = trend("name=" + url.QueryEscape("ХС") + "&unit=mmHg")
+		if len(got.Points) != 0 || got.Stats != nil || got.Excluded != 6 {
+			t.Errorf("mmHg: points = %v, stats = %+v, excluded = %d; want none, nil, 6", got.Points, got.Stats, got.Excluded)
+		}
+
+		// Период по дате приема: from включительно, to - нет
+		got = trend(fmt.Sprintf("name=%s&from=%s&to=%s", url.QueryEscape("ХС"), day(10).Format("2006-01-02"), day(30).Format("2006-01-02")))
+		ids, _ = points(got)
+		if !slices.Equal(ids, seriesIDs[1:3]) || got.Excluded != 1 {
+			t.Errorf("period: points = %v, excluded = %d; want %v and 1", ids, got.Excluded, seriesIDs[1:3])
+		}
+
+		if w := apiRequest(t, user, http.MethodGet, fmt.Sprintf("/patients/%d/tests/trends", patient.ID), nil); w.Code != http.StatusBadRequest {
+			t.Errorf("without name: status %d, want 400", w.Code)
+		}
+	})
+}